@@ -0,0 +1,234 @@
+package rdapclient
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// The v1 surface: api/v1.txt lists every declaration of the stable API, one
+// per line in the format below. Lines may be added when something is
+// promoted to stable; none may change or disappear before v2. Exported names
+// missing from the file are experimental.
+
+// apiPackages are the packages that carry stable API, by directory.
+var apiPackages = map[string]string{
+	".":     "github.com/datum-labs/rdap",
+	"graph": "github.com/datum-labs/rdap/graph",
+}
+
+func TestAPIStability(t *testing.T) {
+	f, err := os.Open(filepath.Join("api", "v1.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	current := map[string]bool{}
+	byKey := map[string]string{}
+	for dir, path := range apiPackages {
+		lines, err := apiLines(dir, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range lines {
+			current[l] = true
+			byKey[apiKey.FindString(l)] = l
+		}
+	}
+
+	sc := bufio.NewScanner(f)
+	n := 0
+	for sc.Scan() {
+		l := strings.TrimSpace(sc.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		n++
+		if current[l] {
+			continue
+		}
+		if now, ok := byKey[apiKey.FindString(l)]; ok {
+			t.Errorf("stable API changed:\n\tv1:  %s\n\tnow: %s", l, now)
+		} else {
+			t.Errorf("stable API removed: %s", l)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("api/v1.txt lists no declarations")
+	}
+}
+
+// apiKey matches the part of an API line that names the declaration, so a
+// line whose signature changed can be told from one that was removed.
+var apiKey = regexp.MustCompile(`^pkg [^,]+, (?:(?:func|const|var) \w+|method \([^)]*\) \w+|type \w+(?: (?:struct|interface), (?:embedded )?[\w.*]+)?)`)
+
+// apiLines lists the exported declarations of the package in dir, in the
+// style of the Go distribution's api files:
+//
+//	pkg github.com/datum-labs/rdap, func New(...Option) *Client
+//	pkg github.com/datum-labs/rdap, method (*Client) Domain(context.Context, string) (*Domain, error)
+//	pkg github.com/datum-labs/rdap, type Domain struct, LDHName string
+//	pkg github.com/datum-labs/rdap, const KindDomain Kind = "domain"
+//
+// Parameter names are left out, so renaming one is not a change.
+func apiLines(dir, path string) ([]string, error) {
+	bp, err := build.Default.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var out []string
+	emit := func(format string, args ...any) {
+		out = append(out, "pkg "+path+", "+fmt.Sprintf(format, args...))
+	}
+	expr := func(e ast.Expr) string {
+		var b bytes.Buffer
+		_ = printer.Fprint(&b, fset, e)
+		return strings.Join(strings.Fields(b.String()), " ")
+	}
+	list := func(fl *ast.FieldList) []string {
+		var ts []string
+		if fl == nil {
+			return nil
+		}
+		for _, f := range fl.List {
+			for range max(1, len(f.Names)) {
+				ts = append(ts, expr(f.Type))
+			}
+		}
+		return ts
+	}
+	sig := func(ft *ast.FuncType) string {
+		s := "(" + strings.Join(list(ft.Params), ", ") + ")"
+		switch res := list(ft.Results); len(res) {
+		case 0:
+		case 1:
+			s += " " + res[0]
+		default:
+			s += " (" + strings.Join(res, ", ") + ")"
+		}
+		return s
+	}
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range f.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				if !d.Name.IsExported() {
+					continue
+				}
+				if d.Recv == nil {
+					emit("func %s%s", d.Name.Name, sig(d.Type))
+					continue
+				}
+				recv := expr(d.Recv.List[0].Type)
+				if !ast.IsExported(strings.TrimPrefix(recv, "*")) {
+					continue
+				}
+				emit("method (%s) %s%s", recv, d.Name.Name, sig(d.Type))
+			case *ast.GenDecl:
+				var typ, val string // carried down a const group
+				for _, s := range d.Specs {
+					switch s := s.(type) {
+					case *ast.TypeSpec:
+						if !s.Name.IsExported() {
+							continue
+						}
+						typeLines(s, expr, sig, emit)
+					case *ast.ValueSpec:
+						if s.Type != nil || len(s.Values) > 0 {
+							typ, val = "", ""
+							if s.Type != nil {
+								typ = " " + expr(s.Type)
+							}
+						}
+						for i, n := range s.Names {
+							if !n.IsExported() {
+								continue
+							}
+							if d.Tok == token.CONST {
+								val = ""
+								if i < len(s.Values) {
+									if lit, ok := s.Values[i].(*ast.BasicLit); ok {
+										val = " = " + lit.Value
+									}
+								}
+								emit("const %s%s%s", n.Name, typ, val)
+							} else {
+								emit("var %s%s", n.Name, typ)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// typeLines lists a type declaration: structs and interfaces one line per
+// exported field or method, so adding one is not a change to the others.
+func typeLines(s *ast.TypeSpec, expr func(ast.Expr) string, sig func(*ast.FuncType) string, emit func(string, ...any)) {
+	name := s.Name.Name
+	if s.TypeParams != nil {
+		var ps []string
+		for _, p := range s.TypeParams.List {
+			for _, n := range p.Names {
+				ps = append(ps, n.Name+" "+expr(p.Type))
+			}
+		}
+		name += "[" + strings.Join(ps, ", ") + "]"
+	}
+	if s.Assign.IsValid() {
+		emit("type %s = %s", name, expr(s.Type))
+		return
+	}
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		emit("type %s struct", name)
+		for _, f := range t.Fields.List {
+			if len(f.Names) == 0 {
+				emit("type %s struct, embedded %s", name, expr(f.Type))
+				continue
+			}
+			for _, n := range f.Names {
+				if n.IsExported() {
+					emit("type %s struct, %s %s", name, n.Name, expr(f.Type))
+				}
+			}
+		}
+	case *ast.InterfaceType:
+		emit("type %s interface", name)
+		for _, m := range t.Methods.List {
+			switch ft := m.Type.(type) {
+			case *ast.FuncType:
+				for _, n := range m.Names {
+					emit("type %s interface, %s%s", name, n.Name, sig(ft))
+				}
+			default:
+				emit("type %s interface, embedded %s", name, expr(m.Type))
+			}
+		}
+	default:
+		emit("type %s %s", name, expr(s.Type))
+	}
+}
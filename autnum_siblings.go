@@ -0,0 +1,95 @@
+package rdapclient
+
+import (
+	"context"
+	"strings"
+)
+
+// siblingPivotRoles are the roles of an autnum's entities that stand for the
+// operator holding it, in the order SiblingAutnums pivots through them.
+var siblingPivotRoles = []string{"registrant", "maintainer"}
+
+// SiblingAutnum is an autnum held by the same organization as the one
+// SiblingAutnums was asked about, with how it was found.
+type SiblingAutnum struct {
+	Autnum Autnum `json:"autnum"`
+	// Via is the handle of the entity pivoted through, and Role its role on
+	// the queried autnum ("registrant", "maintainer").
+	Via  string `json:"via"`
+	Role string `json:"role"`
+	// Source is where the autnum was listed: the entity record's URL for
+	// inline autnums, or the autnum search URL the entity links to.
+	Source string `json:"source"`
+}
+
+// SiblingAutnums looks up asn and returns the other autnums of the
+// organization holding it: for each registrant or maintainer entity, the
+// autnums its record lists inline or through its linked autnum search (see
+// EntityAutnums), fetching the full entity record when the embedded one lists
+// neither. Each ASN range is returned once, attributed to the first pivot that
+// found it; asn itself is left out. Pivot failures do not stop the others; the
+// first is returned with whatever was found.
+func (c *Client) SiblingAutnums(ctx context.Context, asn string) ([]SiblingAutnum, error) {
+	a, err := c.Autnum(ctx, asn)
+	if err != nil {
+		return nil, err
+	}
+	type span struct{ start, end int64 }
+	seen := map[span]bool{{a.StartAutnum, a.EndAutnum}: true}
+	pivoted := map[string]bool{}
+	var (
+		out      []SiblingAutnum
+		firstErr error
+	)
+	for _, role := range siblingPivotRoles {
+		for i := range a.Entities {
+			e := a.Entities[i]
+			key := e.Handle + "|" + selfHref(e.Links)
+			if !hasRole(e.Roles, role) || pivoted[key] {
+				continue
+			}
+			pivoted[key] = true
+			found, source, err := c.entityAutnumsFrom(ctx, &e)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			for _, s := range found {
+				k := span{s.StartAutnum, s.EndAutnum}
+				if seen[k] {
+					continue
+				}
+				seen[k] = true
+				out = append(out, SiblingAutnum{Autnum: s, Via: e.Handle, Role: role, Source: source})
+			}
+		}
+	}
+	return out, firstErr
+}
+
+// entityAutnumsFrom returns e's autnums and where they were listed, fetching
+// e's own record when the embedded copy has neither inline autnums nor an
+// autnum search link.
+func (c *Client) entityAutnumsFrom(ctx context.Context, e *Entity) ([]Autnum, string, error) {
+	if len(e.Autnums) == 0 && searchLinkHref(e.Links, []string{"autnums"}) == "" {
+		full, err := c.entityRecord(ctx, e)
+		if err != nil {
+			return nil, "", err
+		}
+		e = full
+	}
+	source := searchLinkHref(e.Links, []string{"autnums"})
+	if len(e.Autnums) > 0 || source == "" {
+		source = selfHref(e.Links)
+	}
+	found, err := c.EntityAutnums(ctx, e)
+	return found, source, err
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
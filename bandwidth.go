@@ -0,0 +1,181 @@
+package rdapclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ByteBudget caps the response bytes the client downloads per calendar month
+// (UTC), for registries that informally limit total transfer. Zero limits
+// are unlimited.
+type ByteBudget struct {
+	// Monthly caps the bytes from all registries together.
+	Monthly int64 `json:"monthly,omitempty" yaml:"monthly,omitempty"`
+	// PerRegistry caps each registry host not listed in Registries.
+	PerRegistry int64 `json:"perRegistry,omitempty" yaml:"perRegistry,omitempty"`
+	// Registries caps named registry hosts ("rdap.verisign.com").
+	Registries map[string]int64 `json:"registries,omitempty" yaml:"registries,omitempty"`
+	// Jobs caps the bytes fetched under a WithJob context of that name.
+	Jobs map[string]int64 `json:"jobs,omitempty" yaml:"jobs,omitempty"`
+}
+
+// BandwidthUsage is the response bytes downloaded in Month ("2006-01", UTC),
+// in total, per registry host and per job. Cache hits and 304s cost nothing.
+type BandwidthUsage struct {
+	Month      string           `json:"month"`
+	Total      int64            `json:"total"`
+	Registries map[string]int64 `json:"registries,omitempty"`
+	Jobs       map[string]int64 `json:"jobs,omitempty"`
+}
+
+// Scopes of an ErrBudgetExceeded.
+const (
+	BudgetMonthly  = "monthly"
+	BudgetRegistry = "registry"
+	BudgetJob      = "job"
+)
+
+// ErrBudgetExceeded is returned, before any request is sent, once a
+// ByteBudget limit is used up. Name is the registry host or job for those
+// scopes. Batch runs treat it as a pause until ResetAt, the start of the
+// next month.
+type ErrBudgetExceeded struct {
+	Scope   string
+	Name    string
+	Used    int64
+	Limit   int64
+	ResetAt time.Time
+}
+
+func (e *ErrBudgetExceeded) Error() string {
+	scope := e.Scope
+	if e.Name != "" {
+		scope += " " + e.Name
+	}
+	return fmt.Sprintf("rdap byte budget exceeded: %s used %d of %d bytes this month (resets %s)", scope, e.Used, e.Limit, e.ResetAt.Format("2006-01-02"))
+}
+
+type jobKey struct{}
+
+// WithJob labels the requests made under ctx as job name, for per-job
+// bandwidth accounting and ByteBudget.Jobs.
+func WithJob(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, jobKey{}, name)
+}
+
+// JobFrom returns the job name set by WithJob, or "".
+func JobFrom(ctx context.Context) string {
+	name, _ := ctx.Value(jobKey{}).(string)
+	return name
+}
+
+// bandwidthMeter counts response bytes for the current month and enforces
+// the client's ByteBudget.
+type bandwidthMeter struct {
+	mu     sync.Mutex
+	budget ByteBudget
+	usage  BandwidthUsage
+}
+
+func monthOf(t time.Time) string { return t.UTC().Format("2006-01") }
+
+// nextMonth is the start of the month after t's, UTC.
+func nextMonth(t time.Time) time.Time {
+	y, m, _ := t.UTC().Date()
+	return time.Date(y, m+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// roll starts a new month's usage when now is past the current one. The
+// caller holds mu.
+func (b *bandwidthMeter) roll(now time.Time) {
+	if month := monthOf(now); b.usage.Month != month {
+		b.usage = BandwidthUsage{Month: month}
+	}
+}
+
+// check reports whether a request to host under job may be sent.
+func (b *bandwidthMeter) check(host, job string, now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now)
+	over := func(scope, name string, used, limit int64) error {
+		if limit > 0 && used >= limit {
+			return &ErrBudgetExceeded{Scope: scope, Name: name, Used: used, Limit: limit, ResetAt: nextMonth(now)}
+		}
+		return nil
+	}
+	if err := over(BudgetMonthly, "", b.usage.Total, b.budget.Monthly); err != nil {
+		return err
+	}
+	limit, ok := b.budget.Registries[host]
+	if !ok {
+		limit = b.budget.PerRegistry
+	}
+	if err := over(BudgetRegistry, host, b.usage.Registries[host], limit); err != nil {
+		return err
+	}
+	if job != "" {
+		return over(BudgetJob, job, b.usage.Jobs[job], b.budget.Jobs[job])
+	}
+	return nil
+}
+
+// add counts n response bytes from host under job.
+func (b *bandwidthMeter) add(host, job string, n int64, now time.Time) {
+	if n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now)
+	b.usage.Total += n
+	if b.usage.Registries == nil {
+		b.usage.Registries = map[string]int64{}
+	}
+	b.usage.Registries[host] += n
+	if job != "" {
+		if b.usage.Jobs == nil {
+			b.usage.Jobs = map[string]int64{}
+		}
+		b.usage.Jobs[job] += n
+	}
+}
+
+// BandwidthUsage returns the bytes downloaded this month.
+func (c *Client) BandwidthUsage() BandwidthUsage {
+	b := c.bandwidth
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(c.now())
+	u := b.usage
+	u.Registries = cloneCounts(b.usage.Registries)
+	u.Jobs = cloneCounts(b.usage.Jobs)
+	return u
+}
+
+// SetBandwidthUsage restores usage saved by a previous process (BandwidthUsage,
+// persisted as JSON), so budgets hold across restarts. Usage from an earlier
+// month is ignored.
+func (c *Client) SetBandwidthUsage(u BandwidthUsage) {
+	b := c.bandwidth
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if u.Month != monthOf(c.now()) {
+		return
+	}
+	u.Registries, u.Jobs = cloneCounts(u.Registries), cloneCounts(u.Jobs)
+	b.usage = u
+}
+
+func cloneCounts(m map[string]int64) map[string]int64 {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]int64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
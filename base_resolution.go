@@ -0,0 +1,96 @@
+package rdapclient
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Sources of a BaseResolution.
+const (
+	BaseFromBootstrap = "bootstrap" // an entry of the bootstrap file
+	BaseFromFallback  = "fallback"  // no entry matched, or the file could not be fetched
+)
+
+// BaseResolution is which RDAP base a query is sent to, and why.
+type BaseResolution struct {
+	Query string `json:"query"`
+	Kind  Kind   `json:"kind"`
+	Base  string `json:"base"`
+	// Source is BaseFromBootstrap or BaseFromFallback.
+	Source string `json:"source"`
+	// Entry is the bootstrap entry that matched: a TLD, CIDR prefix, ASN range
+	// or object tag.
+	Entry string `json:"entry,omitempty"`
+	// Bootstrap is the bootstrap file consulted; Override is set when it is not
+	// IANA's (WithBootstrapURL and friends, or the config and env equivalents).
+	Bootstrap string `json:"bootstrap"`
+	Override  bool   `json:"override,omitempty"`
+}
+
+// ExplainBase resolves q (a domain or nameserver name, IP address or prefix,
+// ASN or tagged entity handle, classified as Lookup does) to its RDAP base like
+// BaseForDomain, BaseForIP, BaseForASN and BaseForEntity, and reports where the
+// base came from.
+func (c *Client) ExplainBase(ctx context.Context, q string) (*BaseResolution, error) {
+	s := strings.TrimSpace(q)
+	r := &BaseResolution{Query: s, Kind: classify(s, LookupOptions{})[0]}
+	var key string
+	switch r.Kind {
+	case KindAutnum:
+		n, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(s), "AS"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if r.Base, err = c.BaseForASN(ctx, s); err != nil {
+			return nil, err
+		}
+		r.Bootstrap, key = c.asnBootstrapURL, fmt.Sprintf("asn:%d", n)
+	case KindEntity:
+		r.Base, _ = c.BaseForEntity(ctx, s)
+		r.Bootstrap, key = c.objectTagBootstrapURL, "tag:"+handleTag(s)
+	case KindIP:
+		addr, err := netip.ParseAddr(s)
+		if p, perr := netip.ParsePrefix(s); perr == nil {
+			addr, err = p.Addr(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if r.Base, err = c.BaseForIP(ctx, s); err != nil {
+			return nil, err
+		}
+		r.Bootstrap, key = c.ipBootstrapFile(addr), "ip:"+addr.String()
+	default:
+		if r.Kind != KindNameserver {
+			r.Kind = KindDomain
+		}
+		fqdn, err := NormalizeFQDN(s)
+		if err != nil {
+			return nil, err
+		}
+		if r.Base, err = c.rdapBaseForDomain(ctx, fqdn); err != nil {
+			return nil, err
+		}
+		r.Bootstrap = c.bootstrapURL
+		tld := lastLabel(fqdn)
+		if _, ok := c.dnsBases.Get(tld); ok {
+			r.Entry = tld
+		} else if _, ok := c.dnsBases.Stale(tld); ok {
+			r.Entry = tld
+		}
+	}
+	r.Source = BaseFromFallback
+	if key != "" {
+		if base, ok := c.rdapBaseCache.Get(key); ok && base != "" {
+			r.Source = BaseFromBootstrap
+			r.Entry, _ = c.baseEntries.Get(key)
+		}
+	} else if r.Entry != "" {
+		r.Source = BaseFromBootstrap
+	}
+	r.Override = !strings.HasPrefix(r.Bootstrap, "https://data.iana.org/rdap/")
+	return r, nil
+}
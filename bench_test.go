@@ -0,0 +1,163 @@
+package rdapclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Benchmarks cover the hot paths: decode, caches, bootstrap longest-prefix
+// match and an end-to-end batch against a local mock server. Compare runs with
+// `make bench-compare` (benchstat) before and after performance-sensitive changes.
+
+func BenchmarkParseObject_Golden(b *testing.B) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "golden", "*.json"))
+	var objs []map[string]any
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var m map[string]any
+		if err := json.Unmarshal(raw, &m); err != nil {
+			b.Fatal(err)
+		}
+		objs = append(objs, m)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseObject(objs[i%len(objs)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDecode_BytesToObject measures the full path getJSON callers pay:
+// body bytes -> map -> typed object.
+func BenchmarkDecode_BytesToObject(b *testing.B) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "golden", "verisign_domain_example.com.json"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(raw)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var m map[string]any
+		if err := json.Unmarshal(raw, &m); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := ParseObject(m); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRespCache_StoreGet(b *testing.B) {
+	rc := newRespCache(512, time.Hour)
+	body := []byte(`{"objectClassName":"domain","ldhName":"example.com"}`)
+	h := http.Header{"Cache-Control": {"max-age=600"}, "Etag": {`"x"`}}
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("https://rdap.example/domain/d%d.example", i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := keys[i%len(keys)]
+		if _, ok := rc.Get(k); !ok {
+			rc.Store(k, body, h)
+		}
+	}
+}
+
+func BenchmarkTTLCache_SetGetParallel(b *testing.B) {
+	c := newTTLCache[string](time.Hour, 256)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			k := "tld" + fmt.Sprint(i%512)
+			if _, ok := c.Get(k); !ok {
+				c.Set(k, "https://rdap.example")
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkBootstrapIP_LPM measures the longest-prefix match over an
+// IANA-sized ipv4 service list. The per-address base cache is bypassed by
+// querying a fresh address every iteration.
+func BenchmarkBootstrapIP_LPM(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString(`{"services":[`)
+	for i := 0; i < 256; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, `[["%d.0.0.0/8","%d.128.0.0/9"],["https://rir%d.example/"]]`, i, i, i%5)
+	}
+	sb.WriteString(`]}`)
+	body := []byte(sb.String())
+
+	c := New(WithHTTPDoer(staticDoer(http.StatusOK, body)))
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ip := fmt.Sprintf("%d.%d.%d.%d", i%256, (i>>8)%256, (i>>16)%256, 1)
+		if _, err := c.rdapBaseForIP(ctx, ip); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkBatch_MockServer runs concurrent domain lookups end to end
+// (bootstrap, HTTP, decode, cache) against an in-process server.
+func BenchmarkBatch_MockServer(b *testing.B) {
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/dns.json"):
+			w.Header().Set("Cache-Control", "max-age=3600")
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/"]]]}`, srvURL))
+		case strings.HasPrefix(r.URL.Path, "/domain/"):
+			name := strings.TrimPrefix(r.URL.Path, "/domain/")
+			w.Header().Set("Cache-Control", "no-store")
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"objectClassName":"domain","ldhName":%q,"status":["active"]}`, name))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+
+	c := New(WithBootstrapURL(ts.URL + "/dns.json"))
+	ctx := context.Background()
+	const batch = 32
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		for j := 0; j < batch; j++ {
+			wg.Add(1)
+			go func(j int) {
+				defer wg.Done()
+				if _, err := c.Domain(ctx, fmt.Sprintf("d%d.example", j)); err != nil {
+					b.Error(err)
+				}
+			}(j)
+		}
+		wg.Wait()
+	}
+}
@@ -9,6 +9,37 @@ import (
 	"strings"
 )
 
+// BaseForDomain returns the RDAP base URL a lookup of fqdn is sent to: the
+// DNS bootstrap's service for its TLD, or the default base when there is none.
+// Nothing but the bootstrap file is fetched, so proxies and schedulers can
+// route by registry without looking anything up.
+func (c *Client) BaseForDomain(ctx context.Context, fqdn string) (string, error) {
+	fqdn, err := NormalizeFQDN(fqdn)
+	if err != nil {
+		return "", err
+	}
+	return c.rdapBaseForDomain(ctx, fqdn)
+}
+
+// BaseForIP is BaseForDomain for an IP address or CIDR prefix, from the IP
+// bootstrap (longest matching prefix).
+func (c *Client) BaseForIP(ctx context.Context, ipOrCIDR string) (string, error) {
+	return c.rdapBaseForIP(ctx, ipOrCIDR)
+}
+
+// BaseForASN is BaseForDomain for an autnum ("AS15169" or "15169"), from the
+// ASN bootstrap.
+func (c *Client) BaseForASN(ctx context.Context, asn string) (string, error) {
+	return c.rdapBaseForASN(ctx, asn)
+}
+
+// BaseForEntity is BaseForDomain for an entity handle, from the object-tags
+// bootstrap by the tag it ends in ("ABC123-ARIN"). Handles without a
+// registered tag go to rdap.org.
+func (c *Client) BaseForEntity(ctx context.Context, handle string) (string, error) {
+	return c.rdapBaseForEntity(ctx, strings.TrimSpace(handle), ""), nil
+}
+
 func (c *Client) rdapBaseForDomain(ctx context.Context, fqdn string) (string, error) {
 	return c.rdapBaseForTLD(ctx, lastLabel(fqdn))
 }
@@ -17,7 +48,18 @@ func (c *Client) rdapBaseForTLD(ctx context.Context, tld string) (string, error)
 	return c.resolveBaseFromBootstrapDNS(ctx, tld)
 }
 
+// fetchBootstrap refreshes dnsBases from the DNS bootstrap file.
+// Concurrent calls share one request.
 func (c *Client) fetchBootstrap(ctx context.Context, force bool) error {
+	key := c.bootstrapURL
+	if force {
+		key += "#force"
+	}
+	_, err, _ := c.flights.Do(ctx, key, func() (any, error) { return nil, c.doFetchBootstrap(ctx, force) })
+	return err
+}
+
+func (c *Client) doFetchBootstrap(ctx context.Context, force bool) error {
 	reqCtx, cancel := context.WithTimeout(ctx, c.baseTimeout)
 	defer cancel()
 
@@ -40,39 +82,59 @@ func (c *Client) fetchBootstrap(ctx context.Context, force bool) error {
 		return err
 	}
 	defer resp.Body.Close()
+	c.log.DebugContext(ctx, "rdap bootstrap GET", "url", c.bootstrapURL, "status", resp.StatusCode)
 
 	switch resp.StatusCode {
 	case http.StatusNotModified:
+		// The kept body (from disk after a restart) is still current.
+		if body := c.respCache.FreshBody(c.bootstrapURL); body != nil && c.loadDNSBootstrap(body) == nil {
+			c.respCache.UpdateFreshness(c.bootstrapURL, resp.Header)
+		}
 		return nil
 	case http.StatusOK:
 		body, err := io.ReadAll(io.LimitReader(resp.Body, 512*1024))
 		if err != nil {
 			return err
 		}
-		var obj struct {
-			Services [][]any `json:"services"`
-		}
-		if err := json.Unmarshal(body, &obj); err != nil {
-			return fmt.Errorf("parse bootstrap: %w", err)
-		}
-
-		for _, svc := range obj.Services {
-			if len(svc) != 2 {
-				continue
-			}
-			tlds := toStringSlice(svc[0])
-			urls := toStringSlice(svc[1])
-			if len(urls) == 0 {
-				continue
-			}
-			base := strings.TrimRight(urls[0], "/")
-			for _, tl := range tlds {
-				c.rdapBaseCache.Set(strings.ToLower(tl), base)
-			}
+		if err := c.loadDNSBootstrap(body); err != nil {
+			return err
 		}
-		c.respCache.StoreMeta(c.bootstrapURL, resp.Header)
+		c.respCache.Store(c.bootstrapURL, body, resp.Header)
 		return nil
 	default:
 		return fmt.Errorf("bootstrap fetch failed: %s", resp.Status)
 	}
 }
+
+// loadDNSBootstrap caches the registry bases of a DNS bootstrap file. The
+// file has well over a thousand TLDs, so dnsBases grows to hold them all
+// rather than evicting some of them with every load.
+func (c *Client) loadDNSBootstrap(body []byte) error {
+	var obj struct {
+		Services [][]any `json:"services"`
+	}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return fmt.Errorf("parse bootstrap: %w", err)
+	}
+
+	bases := map[string]string{}
+	for _, svc := range obj.Services {
+		if len(svc) != 2 {
+			continue
+		}
+		tlds := toStringSlice(svc[0])
+		base := serviceBase(toStringSlice(svc[1]))
+		if base == "" {
+			continue
+		}
+		for _, tl := range tlds {
+			bases[strings.ToLower(tl)] = base
+		}
+		c.registries.add(toStringSlice(svc[1])...)
+	}
+	c.dnsBases.Reserve(len(bases))
+	for tl, base := range bases {
+		c.dnsBases.Set(tl, base)
+	}
+	return nil
+}
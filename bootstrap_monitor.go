@@ -0,0 +1,246 @@
+package rdapclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BootstrapChangeKind classifies a difference between two bootstrap fetches.
+type BootstrapChangeKind string
+
+const (
+	BootstrapAdded   BootstrapChangeKind = "added"   // a TLD/range gained an RDAP service
+	BootstrapRemoved BootstrapChangeKind = "removed" // a TLD/range lost its RDAP service
+	BootstrapChanged BootstrapChangeKind = "changed" // a TLD/range moved to a different base URL
+)
+
+// BootstrapChange is one entry that differs between successive fetches of an
+// IANA bootstrap file. Key is the TLD, ASN range or CIDR as IANA lists it.
+type BootstrapChange struct {
+	Registry string              `json:"registry"` // bootstrap file URL
+	Kind     BootstrapChangeKind `json:"kind"`
+	Key      string              `json:"key"`
+	OldBase  string              `json:"oldBase,omitempty"`
+	NewBase  string              `json:"newBase,omitempty"`
+}
+
+// BootstrapState maps bootstrap file URL to its entries (key -> base URL).
+// It is what a BootstrapMonitor diffs against and can be persisted between runs.
+type BootstrapState map[string]map[string]string
+
+// BootstrapMonitor re-fetches the IANA bootstrap files and reports entries that
+// were added, removed or re-pointed since the previous fetch. The first Check
+// only records a baseline unless one was supplied with SetState.
+type BootstrapMonitor struct {
+	c        *Client
+	urls     []string
+	onChange func([]BootstrapChange)
+
+	mu    sync.Mutex
+	state BootstrapState
+}
+
+// NewBootstrapMonitor watches the client's DNS, IPv4, IPv6 and ASN bootstrap
+// files. onChange (may be nil) is called with each non-empty batch of changes.
+func (c *Client) NewBootstrapMonitor(onChange func([]BootstrapChange)) *BootstrapMonitor {
+	v6 := strings.TrimSuffix(c.ipBootstrapURL, "ipv4.json") + "ipv6.json"
+	var urls []string
+	for _, u := range []string{c.bootstrapURL, c.ipBootstrapURL, v6, c.asnBootstrapURL} {
+		if u != "" && !containsString(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	return &BootstrapMonitor{
+		c:        c,
+		urls:     urls,
+		onChange: onChange,
+		state:    BootstrapState{},
+	}
+}
+
+// State returns a copy of the last fetched entries.
+func (m *BootstrapMonitor) State() BootstrapState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(BootstrapState, len(m.state))
+	for u, entries := range m.state {
+		cp := make(map[string]string, len(entries))
+		for k, v := range entries {
+			cp[k] = v
+		}
+		out[u] = cp
+	}
+	return out
+}
+
+// SetState seeds the baseline, e.g. with a State saved by a previous run.
+func (m *BootstrapMonitor) SetState(s BootstrapState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s == nil {
+		s = BootstrapState{}
+	}
+	m.state = s
+}
+
+// Check fetches every bootstrap file once and returns the changes against the
+// previous state. A file that fails to fetch keeps its old state and is
+// reported in the error; the other files are still diffed.
+func (m *BootstrapMonitor) Check(ctx context.Context) ([]BootstrapChange, error) {
+	var (
+		changes []BootstrapChange
+		errs    []string
+	)
+	for _, u := range m.urls {
+		entries, err := m.fetch(ctx, u)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", u, err))
+			continue
+		}
+		m.mu.Lock()
+		prev, seen := m.state[u]
+		m.state[u] = entries
+		m.mu.Unlock()
+		if seen {
+			changes = append(changes, diffBootstrap(u, prev, entries)...)
+		}
+	}
+	if len(changes) > 0 && m.onChange != nil {
+		m.onChange(changes)
+	}
+	if len(errs) > 0 {
+		return changes, fmt.Errorf("bootstrap monitor: %s", strings.Join(errs, "; "))
+	}
+	return changes, nil
+}
+
+// Run calls Check every interval until ctx is done. Fetch errors are passed to
+// onErr (may be nil) and do not stop the loop.
+func (m *BootstrapMonitor) Run(ctx context.Context, interval time.Duration, onErr func(error)) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := m.Check(ctx); err != nil && onErr != nil {
+			onErr(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// fetch downloads one bootstrap file unconditionally (the client's validators
+// would turn an unchanged file into a body-less 304).
+func (m *BootstrapMonitor) fetch(ctx context.Context, u string) (map[string]string, error) {
+	c := m.c
+	reqCtx, cancel := context.WithTimeout(ctx, c.baseTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.ua)
+	copyHeaders(req.Header, c.headerExtra)
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bootstrap fetch failed: %s", resp.Status)
+	}
+	var bs bootstrapServices
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 2<<20)).Decode(&bs); err != nil {
+		return nil, fmt.Errorf("parse bootstrap: %w", err)
+	}
+	entries := map[string]string{}
+	for _, svc := range bs.Services {
+		if len(svc) != 2 {
+			continue
+		}
+		base := serviceBase(toStringSlice(svc[1]))
+		if base == "" {
+			continue
+		}
+		for _, k := range toStringSlice(svc[0]) {
+			entries[strings.ToLower(strings.TrimSpace(k))] = base
+		}
+	}
+	return entries, nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func diffBootstrap(registry string, prev, next map[string]string) []BootstrapChange {
+	var out []BootstrapChange
+	for k, nb := range next {
+		switch ob, ok := prev[k]; {
+		case !ok:
+			out = append(out, BootstrapChange{Registry: registry, Kind: BootstrapAdded, Key: k, NewBase: nb})
+		case ob != nb:
+			out = append(out, BootstrapChange{Registry: registry, Kind: BootstrapChanged, Key: k, OldBase: ob, NewBase: nb})
+		}
+	}
+	for k, ob := range prev {
+		if _, ok := next[k]; !ok {
+			out = append(out, BootstrapChange{Registry: registry, Kind: BootstrapRemoved, Key: k, OldBase: ob})
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Key < out[b].Key })
+	return out
+}
+
+// WebhookNotifier returns an onChange callback that POSTs each batch of
+// changes as JSON ({"changes": [...]}) to url using d (nil uses a default
+// HTTP client). Delivery failures are passed to onErr (may be nil).
+func WebhookNotifier(url string, d Doer, onErr func(error)) func([]BootstrapChange) {
+	if d == nil {
+		d = defaultHTTPClient()
+	}
+	return func(changes []BootstrapChange) {
+		if err := postWebhook(d, url, changes); err != nil && onErr != nil {
+			onErr(err)
+		}
+	}
+}
+
+func postWebhook(d Doer, url string, changes []BootstrapChange) error {
+	b, err := json.Marshal(struct {
+		Changes []BootstrapChange `json:"changes"`
+	}{changes})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s: %s", url, resp.Status)
+	}
+	return nil
+}
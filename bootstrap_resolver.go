@@ -21,7 +21,12 @@ func (c *Client) resolveBaseFromBootstrapDNS(ctx context.Context, tld string) (s
 		return "", fmt.Errorf("empty TLD")
 	}
 	tld = strings.ToLower(strings.TrimPrefix(tld, "."))
-	if base, ok := c.rdapBaseCache.Get(tld); ok {
+	if base, ok := c.dnsBases.Get(tld); ok {
+		return base, nil
+	}
+	// Past the soft TTL: serve the stale base and refresh in the background.
+	if base, ok := c.dnsBases.Stale(tld); ok {
+		c.refreshBootstrapAsync()
 		return base, nil
 	}
 	if err := c.fetchBootstrap(ctx, false); err != nil {
@@ -31,12 +36,12 @@ func (c *Client) resolveBaseFromBootstrapDNS(ctx context.Context, tld string) (s
 		}
 		return "", err
 	}
-	if base, ok := c.rdapBaseCache.Get(tld); ok {
+	if base, ok := c.dnsBases.Get(tld); ok {
 		return base, nil
 	}
 	// Try a forced refresh once (handles 304-without-body case or first-run without cache)
 	if err := c.fetchBootstrap(ctx, true); err == nil {
-		if base, ok := c.rdapBaseCache.Get(tld); ok {
+		if base, ok := c.dnsBases.Get(tld); ok {
 			return base, nil
 		}
 	}
@@ -47,8 +52,38 @@ func (c *Client) resolveBaseFromBootstrapDNS(ctx context.Context, tld string) (s
 	return "", fmt.Errorf("no RDAP base for TLD %q", tld)
 }
 
-// fetchBootstrapGeneric fetches a bootstrap json (dns/asn/ipv4/ipv6) and returns parsed services & response meta caching.
+// refreshBootstrapAsync refetches the DNS bootstrap file in the background
+// unless a fetch is already running. It is unconditional: a 304 would leave
+// the stale entries stale.
+func (c *Client) refreshBootstrapAsync() {
+	if c.flights.InFlight(c.bootstrapURL + "#force") {
+		return
+	}
+	c.log.Debug("rdap bootstrap stale; refreshing in background", "url", c.bootstrapURL)
+	go func() { _ = c.fetchBootstrap(context.Background(), true) }()
+}
+
+// fetchBootstrapGeneric fetches a bootstrap json (dns/asn/ipv4/ipv6/object-tags) and returns parsed services & response meta caching.
+// Concurrent calls for the same url share one request.
 func (c *Client) fetchBootstrapGeneric(ctx context.Context, url string) (*bootstrapServices, error) {
+	return c.fetchBootstrapFile(ctx, url, false)
+}
+
+// fetchBootstrapFile is fetchBootstrapGeneric; force sends no validators, so
+// an unchanged file is still returned rather than answered with a 304.
+func (c *Client) fetchBootstrapFile(ctx context.Context, url string, force bool) (*bootstrapServices, error) {
+	key := url
+	if force {
+		key += "#force"
+	}
+	v, err, _ := c.flights.Do(ctx, key, func() (any, error) { return c.doFetchBootstrapGeneric(ctx, url, force) })
+	if err != nil {
+		return nil, err
+	}
+	return v.(*bootstrapServices), nil
+}
+
+func (c *Client) doFetchBootstrapGeneric(ctx context.Context, url string, force bool) (*bootstrapServices, error) {
 	reqCtx, cancel := context.WithTimeout(ctx, c.baseTimeout)
 	defer cancel()
 
@@ -57,7 +92,7 @@ func (c *Client) fetchBootstrapGeneric(ctx context.Context, url string) (*bootst
 	copyHeaders(req.Header, c.headerExtra)
 
 	// Conditional
-	if meta, ok := c.respCache.Meta(url); ok {
+	if meta, ok := c.respCache.Meta(url); ok && !force {
 		if meta.ETag != "" {
 			req.Header.Set("If-None-Match", meta.ETag)
 		}
@@ -71,28 +106,50 @@ func (c *Client) fetchBootstrapGeneric(ctx context.Context, url string) (*bootst
 		return nil, err
 	}
 	defer resp.Body.Close()
+	c.log.DebugContext(ctx, "rdap bootstrap GET", "url", url, "status", resp.StatusCode)
 
 	switch resp.StatusCode {
 	case http.StatusNotModified:
-		// Return cached-but-parsed? We didn’t keep the body; simplest is refetch w/ force when needed.
-		// For our usage (single pass), treat as soft miss and force next time if needed.
+		// Parse the kept body (from disk after a restart); without one, the
+		// caller forces a refetch.
+		if body := c.respCache.FreshBody(url); body != nil {
+			if bs, err := c.parseBootstrap(body); err == nil {
+				c.respCache.UpdateFreshness(url, resp.Header)
+				return bs, nil
+			}
+		}
 		return nil, fmt.Errorf("bootstrap 304 Not Modified (no cached body)")
 	case http.StatusOK:
 		body, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20)) // 2MB cap
 		if err != nil {
 			return nil, err
 		}
-		var bs bootstrapServices
-		if err := json.Unmarshal(body, &bs); err != nil {
-			return nil, fmt.Errorf("parse bootstrap: %w", err)
+		bs, err := c.parseBootstrap(body)
+		if err != nil {
+			return nil, err
 		}
-		c.respCache.StoreMeta(url, resp.Header)
-		return &bs, nil
+		c.respCache.Store(url, body, resp.Header)
+		return bs, nil
 	default:
 		return nil, fmt.Errorf("bootstrap fetch failed: %s", resp.Status)
 	}
 }
 
+// parseBootstrap parses a bootstrap file and trusts its registry hosts.
+func (c *Client) parseBootstrap(body []byte) (*bootstrapServices, error) {
+	var bs bootstrapServices
+	if err := json.Unmarshal(body, &bs); err != nil {
+		return nil, fmt.Errorf("parse bootstrap: %w", err)
+	}
+	for _, svc := range bs.Services {
+		// The URLs come last: object-tags services (RFC 8521) lead with contacts.
+		if n := len(svc); n == 2 || n == 3 {
+			c.registries.add(toStringSlice(svc[n-1])...)
+		}
+	}
+	return &bs, nil
+}
+
 // resolveBaseFromBootstrapASN resolves an RDAP base for a numeric ASN using IANA asn.json.
 // It supports single ASNs and ASN ranges "X-Y".
 func (c *Client) resolveBaseFromBootstrapASN(ctx context.Context, asn uint64) (string, error) {
@@ -113,11 +170,10 @@ func (c *Client) resolveBaseFromBootstrapASN(ctx context.Context, asn uint64) (s
 			continue
 		}
 		ranges := toStringSlice(svc[0])
-		urls := toStringSlice(svc[1])
-		if len(urls) == 0 {
+		base := serviceBase(toStringSlice(svc[1]))
+		if base == "" {
 			continue
 		}
-		base := strings.TrimRight(urls[0], "/")
 		for _, r := range ranges {
 			// r is either a single number "12345" or a range "1-1876"
 			lo, hi, ok := parseASNRange(r)
@@ -127,6 +183,7 @@ func (c *Client) resolveBaseFromBootstrapASN(ctx context.Context, asn uint64) (s
 			if asn >= lo && asn <= hi {
 				// cache a small windowed key to avoid exploding cache
 				c.rdapBaseCache.Set(key, base)
+				c.baseEntries.Set(key, strings.TrimSpace(r))
 				return base, nil
 			}
 		}
@@ -135,6 +192,86 @@ func (c *Client) resolveBaseFromBootstrapASN(ctx context.Context, asn uint64) (s
 	return "https://rdap.org", nil
 }
 
+// handleTag returns the RFC 8521 service provider tag of an entity handle,
+// upper-cased: the part after its last hyphen ("ABC123-ARIN" gives "ARIN").
+// It is "" for handles without one.
+func handleTag(handle string) string {
+	i := strings.LastIndexByte(handle, '-')
+	if i <= 0 || i == len(handle)-1 {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimSpace(handle[i+1:]))
+}
+
+// resolveBaseFromBootstrapObjectTag resolves the base for an entity handle from
+// the IANA object-tags bootstrap (RFC 8521), by the tag its handle ends in. ok
+// is false when the handle has no tag or no service registers it. Like the DNS
+// bootstrap, every tag of the file is cached, and past the TTL the stale base
+// is served while the file is refreshed in the background. Tags the file does
+// not list are cached too, as "".
+func (c *Client) resolveBaseFromBootstrapObjectTag(ctx context.Context, handle string) (string, bool) {
+	tag := handleTag(handle)
+	if tag == "" {
+		return "", false
+	}
+	key := "tag:" + tag
+	if base, ok := c.rdapBaseCache.Get(key); ok {
+		return base, base != ""
+	}
+	if base, ok := c.rdapBaseCache.Stale(key); ok {
+		if !c.flights.InFlight(c.objectTagBootstrapURL + "#force") {
+			go func() { c.loadObjectTags(c.fetchBootstrapFile(context.Background(), c.objectTagBootstrapURL, true)) }()
+		}
+		return base, base != ""
+	}
+	// A 304 leaves nothing to read the tags from; ask again without validators.
+	loaded := c.loadObjectTags(c.fetchBootstrapGeneric(ctx, c.objectTagBootstrapURL))
+	if !loaded {
+		loaded = c.loadObjectTags(c.fetchBootstrapFile(ctx, c.objectTagBootstrapURL, true))
+	}
+	base, ok := c.rdapBaseCache.Get(key)
+	if !ok && loaded {
+		c.rdapBaseCache.Set(key, "")
+	}
+	return base, base != ""
+}
+
+// loadObjectTags caches the base of every tag in an object-tags bootstrap
+// file ([contacts], [tags], [urls] per service), reporting whether there was one.
+func (c *Client) loadObjectTags(bs *bootstrapServices, err error) bool {
+	if err != nil {
+		return false
+	}
+	for _, svc := range bs.Services {
+		if len(svc) != 3 {
+			continue
+		}
+		base := serviceBase(toStringSlice(svc[2]))
+		if base == "" {
+			continue
+		}
+		for _, t := range toStringSlice(svc[1]) {
+			if t = strings.ToUpper(strings.TrimSpace(t)); t != "" {
+				c.rdapBaseCache.Set("tag:"+t, base)
+				c.baseEntries.Set("tag:"+t, t)
+			}
+		}
+	}
+	return true
+}
+
+// serviceBase picks the base URL for a bootstrap service entry: the first
+// non-empty URL, trailing slash removed. Empty entries are skipped rather than
+// producing a host-less base.
+func serviceBase(urls []string) string {
+	for _, u := range urls {
+		if b := strings.TrimRight(strings.TrimSpace(u), "/"); b != "" {
+			return b
+		}
+	}
+	return ""
+}
+
 func parseASNRange(s string) (uint64, uint64, bool) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -156,6 +293,19 @@ func parseASNRange(s string) (uint64, uint64, bool) {
 	return x, x, true
 }
 
+// ipBootstrapFile is the IP bootstrap file for addr's family.
+func (c *Client) ipBootstrapFile(addr netip.Addr) string {
+	bootstrapURL := c.ipBootstrapURL
+	// If the configured ipBootstrapURL is the opposite family, redirect to the right file.
+	if addr.Is6() && strings.HasSuffix(bootstrapURL, "/ipv4.json") {
+		bootstrapURL = "https://data.iana.org/rdap/ipv6.json"
+	}
+	if !addr.Is6() && strings.HasSuffix(bootstrapURL, "/ipv6.json") {
+		bootstrapURL = "https://data.iana.org/rdap/ipv4.json"
+	}
+	return bootstrapURL
+}
+
 // resolveBaseFromBootstrapIP resolves a base for a single IP or CIDR using ipv4/ipv6 bootstrap.
 // We match by CIDR containment.
 func (c *Client) resolveBaseFromBootstrapIP(ctx context.Context, ipOrCIDR string) (string, error) {
@@ -171,16 +321,8 @@ func (c *Client) resolveBaseFromBootstrapIP(ctx context.Context, ipOrCIDR string
 		addr = a
 	}
 
-	// Select file
-	bootstrapURL := c.ipBootstrapURL
+	bootstrapURL := c.ipBootstrapFile(addr)
 	is6 := addr.Is6()
-	// If the configured ipBootstrapURL is the opposite family, redirect to the right file.
-	if is6 && strings.HasSuffix(bootstrapURL, "/ipv4.json") {
-		bootstrapURL = "https://data.iana.org/rdap/ipv6.json"
-	}
-	if !is6 && strings.HasSuffix(bootstrapURL, "/ipv6.json") {
-		bootstrapURL = "https://data.iana.org/rdap/ipv4.json"
-	}
 
 	// Try a tiny LRU key cache
 	key := "ip:" + addr.String()
@@ -193,7 +335,7 @@ func (c *Client) resolveBaseFromBootstrapIP(ctx context.Context, ipOrCIDR string
 		return "https://rdap.org", nil
 	}
 
-	var bestBase string
+	var bestBase, bestEntry string
 	var bestMask int = -1 // longest prefix match
 
 	for _, svc := range bs.Services {
@@ -201,11 +343,10 @@ func (c *Client) resolveBaseFromBootstrapIP(ctx context.Context, ipOrCIDR string
 			continue
 		}
 		cidrs := toStringSlice(svc[0])
-		urls := toStringSlice(svc[1])
-		if len(urls) == 0 {
+		base := serviceBase(toStringSlice(svc[1]))
+		if base == "" {
 			continue
 		}
-		base := strings.TrimRight(urls[0], "/")
 
 		for _, raw := range cidrs {
 			raw = strings.TrimSpace(raw)
@@ -223,12 +364,14 @@ func (c *Client) resolveBaseFromBootstrapIP(ctx context.Context, ipOrCIDR string
 				if ones > bestMask {
 					bestMask = ones
 					bestBase = base
+					bestEntry = pfx.String()
 				}
 			}
 		}
 	}
 	if bestBase != "" {
 		c.rdapBaseCache.Set(key, bestBase)
+		c.baseEntries.Set(key, bestEntry)
 		return bestBase, nil
 	}
 	return "https://rdap.org", nil
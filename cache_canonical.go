@@ -0,0 +1,250 @@
+package rdapclient
+
+import (
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// canonKey identifies an object independently of the URL it was fetched by.
+type canonKey struct {
+	base, class, handle string
+}
+
+type canonEntry struct {
+	raw     map[string]any
+	meta    *Meta
+	expires time.Time
+	prefix  netip.Prefix // ip networks only: the range, when it is a single CIDR
+}
+
+// canonCache is the optional canonical-object cache (see WithCanonicalCache).
+// Objects are stored once per (base, objectClassName, handle) and reached
+// through every URL known to name them: the URL they were fetched by, where
+// it redirected to, their self link and their RFC 9082 lookup URL. IP lookups additionally match any
+// cached network at the same base whose range contains the queried address or
+// prefix, the most specific one winning.
+type canonCache struct {
+	mu      sync.Mutex
+	cap     int
+	ttl     time.Duration
+	now     func() time.Time
+	objs    map[canonKey]*canonEntry
+	aliases map[string]canonKey
+}
+
+func newCanonCache(capacity int, ttl time.Duration, now func() time.Time) *canonCache {
+	return &canonCache{cap: capacity, ttl: ttl, now: now, objs: map[canonKey]*canonEntry{}, aliases: map[string]canonKey{}}
+}
+
+// get returns the cached object answering lookup URL u.
+func (c *canonCache) get(u string) (*canonEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if k, ok := c.aliases[u]; ok {
+		if e, ok := c.objs[k]; ok && now.Before(e.expires) {
+			return e, true
+		}
+	}
+	base, seg, id := splitLookupURL(u)
+	if seg != "ip" {
+		return nil, false
+	}
+	q, err := netip.ParsePrefix(id)
+	if err != nil {
+		a, aerr := netip.ParseAddr(id)
+		if aerr != nil {
+			return nil, false
+		}
+		q = netip.PrefixFrom(a, a.BitLen())
+	}
+	var best *canonEntry
+	for k, e := range c.objs {
+		if k.base != base || !e.prefix.IsValid() || !now.Before(e.expires) {
+			continue
+		}
+		if e.prefix.Bits() <= q.Bits() && e.prefix.Contains(q.Addr()) && (best == nil || e.prefix.Bits() > best.prefix.Bits()) {
+			best = e
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	return best, true
+}
+
+// put stores obj, decoded from raw, as the answer to lookup URL u. extra are
+// further URLs known to return it, such as the end of a redirect chain.
+func (c *canonCache) put(u string, obj Object, raw map[string]any, extra ...string) {
+	base, seg, _ := splitLookupURL(u)
+	if base == "" {
+		return
+	}
+	e := &canonEntry{raw: raw}
+	if top := topObject(obj); top != nil {
+		e.meta = top.Meta
+	}
+	var handle string
+	aliases := append([]string{u}, extra...)
+	switch v := obj.(type) {
+	case *Domain:
+		handle = firstNonEmpty(v.Handle, lower(v.LDHName))
+		aliases = append(aliases, lookupAlias(base, seg, lower(v.LDHName)))
+		aliases = append(aliases, selfHref(v.Links))
+	case *Nameserver:
+		handle = firstNonEmpty(v.Handle, lower(v.LDHName))
+		aliases = append(aliases, lookupAlias(base, seg, lower(v.LDHName)))
+		aliases = append(aliases, selfHref(v.Links))
+	case *Entity:
+		handle = v.Handle
+		aliases = append(aliases, lookupAlias(base, seg, v.Handle))
+		aliases = append(aliases, selfHref(v.Links))
+	case *Autnum:
+		handle = v.Handle
+		if v.StartAutnum != 0 && v.StartAutnum == v.EndAutnum {
+			aliases = append(aliases, lookupAlias(base, seg, strconv.FormatInt(v.StartAutnum, 10)))
+		}
+		aliases = append(aliases, selfHref(v.Links))
+	case *IPNetwork:
+		handle = v.Handle
+		e.prefix = rangePrefix(v.StartAddress, v.EndAddress)
+		if e.prefix.IsValid() {
+			aliases = append(aliases, lookupAlias(base, seg, e.prefix.String()))
+		}
+		aliases = append(aliases, selfHref(v.Links))
+	}
+	if handle == "" {
+		return
+	}
+	k := canonKey{base: base, class: lower(obj.GetObjectClassName()), handle: lower(handle)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	e.expires = now.Add(c.ttl)
+	c.objs[k] = e
+	for _, a := range aliases {
+		if a != "" {
+			c.aliases[a] = k
+		}
+	}
+	if len(c.objs) > c.cap {
+		c.evict(now)
+	}
+}
+
+// drop forgets the object lookup URL u names, under every URL.
+func (c *canonCache) drop(u string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k, ok := c.aliases[u]
+	if !ok {
+		return
+	}
+	delete(c.objs, k)
+	for a, ak := range c.aliases {
+		if ak == k {
+			delete(c.aliases, a)
+		}
+	}
+}
+
+// evict drops expired entries, then the ones closest to expiry, until the
+// cache is within capacity, and forgets aliases to dropped objects.
+func (c *canonCache) evict(now time.Time) {
+	for k, e := range c.objs {
+		if !now.Before(e.expires) {
+			delete(c.objs, k)
+		}
+	}
+	for len(c.objs) > c.cap {
+		var oldest canonKey
+		var at time.Time
+		for k, e := range c.objs {
+			if at.IsZero() || e.expires.Before(at) {
+				oldest, at = k, e.expires
+			}
+		}
+		delete(c.objs, oldest)
+	}
+	for a, k := range c.aliases {
+		if _, ok := c.objs[k]; !ok {
+			delete(c.aliases, a)
+		}
+	}
+}
+
+// splitLookupURL splits an RFC 9082 lookup URL into its base, path segment
+// ("domain", "ip", ...) and object id (a CIDR stays whole). It returns empty
+// strings for URLs that are not lookups.
+func splitLookupURL(u string) (base, seg, id string) {
+	p, err := url.Parse(u)
+	if err != nil || p.Host == "" {
+		return "", "", ""
+	}
+	for _, s := range []string{"domain", "nameserver", "entity", "autnum", "ip"} {
+		i := strings.LastIndex(p.Path, "/"+s+"/")
+		if i < 0 {
+			continue
+		}
+		id = p.Path[i+len(s)+2:]
+		if s != "ip" && strings.Contains(id, "/") {
+			continue
+		}
+		p.Path, p.RawPath, p.RawQuery, p.Fragment = p.Path[:i], "", "", ""
+		return p.String(), s, lower(id)
+	}
+	return "", "", ""
+}
+
+func lookupAlias(base, seg, id string) string {
+	if id == "" {
+		return ""
+	}
+	u, err := BuildObjectURL(base, seg, id)
+	if err != nil {
+		return ""
+	}
+	return u
+}
+
+// rangePrefix returns the CIDR exactly covering start..end, or an invalid Prefix.
+func rangePrefix(start, end string) netip.Prefix {
+	s, err1 := netip.ParseAddr(start)
+	e, err2 := netip.ParseAddr(end)
+	if err1 != nil || err2 != nil || s.BitLen() != e.BitLen() {
+		return netip.Prefix{}
+	}
+	for bits := 0; bits <= s.BitLen(); bits++ {
+		p, err := s.Prefix(bits)
+		if err != nil || p.Addr() != s || !p.Contains(e) {
+			continue
+		}
+		if lastAddr(p) == e {
+			return p
+		}
+	}
+	return netip.Prefix{}
+}
+
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - uint(i%8))
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
+
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,207 @@
+package rdapclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskCache keeps response cache entries on disk, one file per URL under
+// dir, so validators, bodies and remembered errors outlive the process. It
+// sits under the in-memory LRU: entries are read on a memory miss and
+// written through on every change. Past maxBytes the least recently used
+// files are removed. Disk errors are logged and otherwise ignored; the cache
+// is only an optimization.
+type diskCache struct {
+	dir      string
+	maxBytes int64 // 0 = unbounded
+	log      *slog.Logger
+
+	mu    sync.Mutex
+	files map[string]diskFile // file name -> size and last use
+	total int64
+}
+
+type diskFile struct {
+	size int64
+	used time.Time
+}
+
+// diskEntry is the file format of a cachedResponse.
+type diskEntry struct {
+	URL          string      `json:"url"`
+	Body         []byte      `json:"body,omitempty"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified time.Time   `json:"lastModified,omitzero"`
+	Header       http.Header `json:"header,omitempty"`
+	FetchedAt    time.Time   `json:"fetchedAt,omitzero"`
+	ExpiresAt    time.Time   `json:"expiresAt,omitzero"`
+	NegUntil     time.Time   `json:"negUntil,omitzero"`
+	Err          *diskError  `json:"error,omitempty"`
+}
+
+// diskError is a negatively cached registry answer: a 404, 410 or 451.
+type diskError struct {
+	StatusCode int    `json:"statusCode"`
+	Status     string `json:"status,omitempty"`
+	Body       string `json:"body,omitempty"`
+	LegalBlock bool   `json:"legalBlock,omitempty"`
+	BlockedBy  string `json:"blockedBy,omitempty"`
+}
+
+// openDiskCache indexes the entries already in dir, creating it if needed.
+func openDiskCache(dir string, maxBytes int64, log *slog.Logger) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	d := &diskCache{dir: dir, maxBytes: maxBytes, log: log, files: make(map[string]diskFile)}
+	for _, e := range ents {
+		name := e.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			_ = os.Remove(filepath.Join(dir, name)) // left by a crash mid-write
+			continue
+		}
+		if e.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		d.files[name] = diskFile{size: info.Size(), used: info.ModTime()}
+		d.total += info.Size()
+	}
+	d.mu.Lock()
+	d.evict()
+	d.mu.Unlock()
+	return d, nil
+}
+
+// diskName is the file u is kept in.
+func diskName(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return hex.EncodeToString(sum[:]) + ".json"
+}
+
+// load returns u's entry, or false when there is none.
+func (d *diskCache) load(u string) (cachedResponse, bool) {
+	name := diskName(u)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.files[name]; !ok {
+		return cachedResponse{}, false
+	}
+	path := filepath.Join(d.dir, name)
+	b, err := os.ReadFile(path)
+	var e diskEntry
+	if err == nil {
+		err = json.Unmarshal(b, &e)
+	}
+	if err != nil || e.URL != u {
+		if err != nil {
+			d.log.Warn("rdap disk cache entry unreadable; removing it", "url", u, "err", err)
+		}
+		d.remove(name)
+		return cachedResponse{}, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now) // keep the LRU order across restarts
+	d.files[name] = diskFile{size: d.files[name].size, used: now}
+	return e.response(), true
+}
+
+// store writes r's entry, replacing any previous one.
+func (d *diskCache) store(r cachedResponse) {
+	b, err := json.Marshal(newDiskEntry(r))
+	if err != nil {
+		d.log.Warn("rdap disk cache write failed", "url", r.url, "err", err)
+		return
+	}
+	name := diskName(r.url)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	tmp := filepath.Join(d.dir, name+".tmp")
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		d.log.Warn("rdap disk cache write failed", "url", r.url, "err", err)
+		return
+	}
+	if err := os.Rename(tmp, filepath.Join(d.dir, name)); err != nil {
+		_ = os.Remove(tmp)
+		d.log.Warn("rdap disk cache write failed", "url", r.url, "err", err)
+		return
+	}
+	d.total += int64(len(b)) - d.files[name].size
+	d.files[name] = diskFile{size: int64(len(b)), used: time.Now()}
+	d.evict()
+}
+
+// remove deletes the file name. The caller holds mu.
+func (d *diskCache) remove(name string) {
+	_ = os.Remove(filepath.Join(d.dir, name))
+	d.total -= d.files[name].size
+	delete(d.files, name)
+}
+
+// evict removes the least recently used files until the total fits
+// maxBytes. The caller holds mu.
+func (d *diskCache) evict() {
+	if d.maxBytes <= 0 || d.total <= d.maxBytes {
+		return
+	}
+	names := make([]string, 0, len(d.files))
+	for name := range d.files {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return d.files[names[i]].used.Before(d.files[names[j]].used) })
+	for _, name := range names {
+		if d.total <= d.maxBytes {
+			break
+		}
+		d.remove(name)
+	}
+}
+
+func newDiskEntry(r cachedResponse) diskEntry {
+	m := r.meta
+	e := diskEntry{
+		URL: r.url, Body: r.body, ETag: m.ETag, LastModified: m.LastModified, Header: m.header,
+		FetchedAt: m.fetchedAt, ExpiresAt: m.expiresAt, NegUntil: m.negUntil,
+	}
+	var he *HTTPError
+	if errors.As(m.negErr, &he) {
+		e.Err = &diskError{StatusCode: he.StatusCode, Status: he.Status, Body: he.Body}
+		var lb *LegalBlockError
+		if errors.As(m.negErr, &lb) {
+			e.Err.LegalBlock, e.Err.BlockedBy = true, lb.BlockedBy
+		}
+	}
+	return e
+}
+
+// response rebuilds the cachedResponse e was written from.
+func (e diskEntry) response() cachedResponse {
+	m := cachedMeta{
+		ETag: e.ETag, LastModified: e.LastModified, header: e.Header,
+		fetchedAt: e.FetchedAt, expiresAt: e.ExpiresAt, negUntil: e.NegUntil,
+	}
+	if e.Err != nil {
+		m.negErr = statusError(&HTTPError{URL: e.URL, StatusCode: e.Err.StatusCode, Status: e.Err.Status, Body: e.Err.Body})
+		if e.Err.LegalBlock {
+			m.negErr = &LegalBlockError{BlockedBy: e.Err.BlockedBy, Err: m.negErr}
+		}
+	}
+	return cachedResponse{url: e.URL, body: e.Body, meta: m}
+}
@@ -14,8 +14,20 @@ type cachedMeta struct {
 	LastModified time.Time
 	expiresAt    time.Time
 	negUntil     time.Time
+	negErr       error
+	header       http.Header
+	fetchedAt    time.Time
 }
 
+// How long the registry's refusals are remembered: a missing object may be
+// registered soon, a legal block is unlikely to lift within the day, and a
+// removed one (410) is not coming back.
+const (
+	notFoundTTL   = 5 * time.Minute
+	legalBlockTTL = 24 * time.Hour
+	goneTTL       = 7 * 24 * time.Hour
+)
+
 type cachedResponse struct {
 	url  string
 	body []byte
@@ -23,22 +35,109 @@ type cachedResponse struct {
 }
 
 type respCache struct {
-	mu     sync.Mutex
-	cap    int
-	ll     *list.List
-	tab    map[string]*list.Element // key: URL
-	defTTL time.Duration
-	now    func() time.Time
+	mu       sync.Mutex
+	cap      int
+	ll       *list.List
+	tab      map[string]*list.Element // key: URL
+	defTTL   time.Duration
+	now      func() time.Time
+	settling map[string]settling // URL -> shortened TTL after a change; see Settle
+	disk     *diskCache          // entries that outlive the process; nil unless WithDiskCache
+}
+
+// settling caps a URL's cache TTL at ttl until the time until.
+type settling struct {
+	until time.Time
+	ttl   time.Duration
 }
 
 func newRespCache(capacity int, defaultTTL time.Duration) *respCache {
 	return &respCache{
-		cap:    capacity,
-		ll:     list.New(),
-		tab:    make(map[string]*list.Element),
-		defTTL: defaultTTL,
-		now:    time.Now,
+		cap:      capacity,
+		ll:       list.New(),
+		tab:      make(map[string]*list.Element),
+		defTTL:   defaultTTL,
+		now:      time.Now,
+		settling: make(map[string]settling),
+	}
+}
+
+// Settle caps u's cache TTL, positive and negative, at ttl for the next
+// window, starting with the entry cached now. Later responses get their
+// normal TTL again once the window is over.
+func (c *respCache) Settle(u string, window, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for k, s := range c.settling {
+		if !now.Before(s.until) {
+			delete(c.settling, k)
+		}
+	}
+	c.settling[u] = settling{until: now.Add(window), ttl: ttl}
+	if el, ok := c.entry(u); ok {
+		it := el.Value.(cachedResponse)
+		it.meta = c.settle(u, it.meta, now)
+		el.Value = it
+		c.save(it)
+	}
+}
+
+// entry returns u's element, reading it from disk into memory on a miss. The
+// caller holds mu.
+func (c *respCache) entry(u string) (*list.Element, bool) {
+	if el, ok := c.tab[u]; ok {
+		return el, true
+	}
+	if c.disk == nil {
+		return nil, false
+	}
+	it, ok := c.disk.load(u)
+	if !ok {
+		return nil, false
+	}
+	return c.insert(it), true
+}
+
+// insert adds it to the front of the LRU, evicting from memory (not disk)
+// past capacity. The caller holds mu.
+func (c *respCache) insert(it cachedResponse) *list.Element {
+	el := c.ll.PushFront(it)
+	c.tab[it.url] = el
+	for c.ll.Len() > c.cap {
+		back := c.ll.Back()
+		cr := back.Value.(cachedResponse)
+		delete(c.tab, cr.url) // correct key: URL
+		c.ll.Remove(back)
+	}
+	return el
+}
+
+// save writes it through to disk. The caller holds mu.
+func (c *respCache) save(it cachedResponse) {
+	if c.disk != nil {
+		c.disk.store(it)
+	}
+}
+
+// settle shortens m's expiry and negative TTL while u is settling. The caller
+// holds mu.
+func (c *respCache) settle(u string, m cachedMeta, now time.Time) cachedMeta {
+	s, ok := c.settling[u]
+	if !ok {
+		return m
+	}
+	if !now.Before(s.until) {
+		delete(c.settling, u)
+		return m
 	}
+	if limit := now.Add(s.ttl); m.expiresAt.After(limit) {
+		m.expiresAt = limit
+	}
+	if limit := now.Add(s.ttl); m.negUntil.After(limit) {
+		m.negUntil = limit
+	}
+	return m
 }
 
 func (c *respCache) Resize(n int) {
@@ -57,7 +156,7 @@ func (c *respCache) Resize(n int) {
 func (c *respCache) Get(u string) ([]byte, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if el, ok := c.tab[u]; ok {
+	if el, ok := c.entry(u); ok {
 		it := el.Value.(cachedResponse)
 		// Negative cache hit: treat as a miss until negUntil expires.
 		if !it.meta.negUntil.IsZero() && c.now().Before(it.meta.negUntil) {
@@ -72,19 +171,45 @@ func (c *respCache) Get(u string) ([]byte, bool) {
 	return nil, false
 }
 
+// Negative returns the error u was negatively cached with, while that lasts.
+func (c *respCache) Negative(u string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entry(u); ok {
+		if it := el.Value.(cachedResponse); it.meta.negErr != nil && c.now().Before(it.meta.negUntil) {
+			return it.meta.negErr
+		}
+	}
+	return nil
+}
+
 func (c *respCache) FreshBody(u string) []byte {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if el, ok := c.tab[u]; ok {
+	if el, ok := c.entry(u); ok {
 		return el.Value.(cachedResponse).body
 	}
 	return nil
 }
 
+// StaleBody returns u's cached body if it expired no more than maxAge ago
+// (or is still fresh), for answering when the registry cannot.
+func (c *respCache) StaleBody(u string, maxAge time.Duration) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entry(u); ok {
+		it := el.Value.(cachedResponse)
+		if len(it.body) > 0 && c.now().Before(it.meta.expiresAt.Add(maxAge)) {
+			return it.body
+		}
+	}
+	return nil
+}
+
 func (c *respCache) Meta(u string) (cachedMeta, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if el, ok := c.tab[u]; ok {
+	if el, ok := c.entry(u); ok {
 		return el.Value.(cachedResponse).meta, true
 	}
 	return cachedMeta{}, false
@@ -93,70 +218,75 @@ func (c *respCache) Meta(u string) (cachedMeta, bool) {
 func (c *respCache) UpdateFreshness(u string, hdr http.Header) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if el, ok := c.tab[u]; ok {
+	if el, ok := c.entry(u); ok {
 		it := el.Value.(cachedResponse)
-		it.meta = mergeMeta(it.meta, hdr, c.defTTL, c.now())
+		it.meta = c.settle(u, mergeMeta(it.meta, hdr, c.defTTL, c.now()), c.now())
 		// Clear negative state on successful validator refresh.
-		it.meta.negUntil = time.Time{}
+		it.meta.negUntil, it.meta.negErr = time.Time{}, nil
 		el.Value = it
 		c.ll.MoveToFront(el)
+		c.save(it)
 	}
 }
 
 func (c *respCache) Store(u string, body []byte, hdr http.Header) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	meta := makeMeta(hdr, c.defTTL, c.now())
+	meta := c.settle(u, makeMeta(hdr, c.defTTL, c.now()), c.now())
 	cp := append([]byte(nil), body...)
 	resp := cachedResponse{url: u, body: cp, meta: meta}
+	c.save(resp)
 
 	if el, ok := c.tab[u]; ok {
 		el.Value = resp
 		c.ll.MoveToFront(el)
 		return
 	}
-	el := c.ll.PushFront(resp)
-	c.tab[u] = el
-	for c.ll.Len() > c.cap {
-		back := c.ll.Back()
-		cr := back.Value.(cachedResponse)
-		delete(c.tab, cr.url) // correct key: URL
-		c.ll.Remove(back)
-	}
+	c.insert(resp)
 }
 
 func (c *respCache) StoreNegative(u string, d time.Duration) {
+	c.StoreError(u, nil, d)
+}
+
+// StoreError negatively caches u for d, remembering the registry's err so
+// Negative can answer with it without a request.
+func (c *respCache) StoreError(u string, err error, d time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	meta := cachedMeta{negUntil: c.now().Add(d)}
-	if el, ok := c.tab[u]; ok {
+	meta := c.settle(u, cachedMeta{negUntil: c.now().Add(d), negErr: err}, c.now())
+	if el, ok := c.entry(u); ok {
 		it := el.Value.(cachedResponse)
-		it.meta.negUntil = meta.negUntil
+		it.meta.negUntil, it.meta.negErr = meta.negUntil, meta.negErr
 		el.Value = it
 		c.ll.MoveToFront(el)
+		c.save(it)
 		return
 	}
-	el := c.ll.PushFront(cachedResponse{url: u, meta: meta})
-	c.tab[u] = el
+	it := cachedResponse{url: u, meta: meta}
+	c.insert(it)
+	c.save(it)
 }
 
 func (c *respCache) StoreMeta(u string, hdr http.Header) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	meta := makeMeta(hdr, c.defTTL, c.now())
-	if el, ok := c.tab[u]; ok {
+	if el, ok := c.entry(u); ok {
 		it := el.Value.(cachedResponse)
 		it.meta = mergeMeta(it.meta, hdr, c.defTTL, c.now())
 		el.Value = it
 		c.ll.MoveToFront(el)
+		c.save(it)
 		return
 	}
-	el := c.ll.PushFront(cachedResponse{url: u, meta: meta})
-	c.tab[u] = el
+	it := cachedResponse{url: u, meta: meta}
+	c.insert(it)
+	c.save(it)
 }
 
 func makeMeta(h http.Header, defTTL time.Duration, now time.Time) cachedMeta {
-	m := cachedMeta{ETag: h.Get("ETag")}
+	m := cachedMeta{ETag: h.Get("ETag"), header: h.Clone(), fetchedAt: now}
 	if lm := h.Get("Last-Modified"); lm != "" {
 		if t, err := time.Parse(http.TimeFormat, lm); err == nil {
 			m.LastModified = t
@@ -168,6 +298,7 @@ func makeMeta(h http.Header, defTTL time.Duration, now time.Time) cachedMeta {
 
 func mergeMeta(prev cachedMeta, h http.Header, defTTL time.Duration, now time.Time) cachedMeta {
 	m := prev
+	m.fetchedAt = now
 	if et := h.Get("ETag"); et != "" {
 		m.ETag = et
 	}
@@ -197,9 +328,10 @@ func expiryFromHeaders(h http.Header, defTTL time.Duration, now time.Time) time.
 			}
 		}
 	}
+	// Expires is measured against the response's Date, not the local clock (RFC 9111 §4.2.1).
 	if exp := h.Get("Expires"); exp != "" {
 		if t, err := time.Parse(http.TimeFormat, exp); err == nil {
-			if d := t.Sub(now); d > 0 {
+			if d := t.Sub(serverNow(h, now)); d > 0 {
 				return d
 			}
 		}
@@ -2,6 +2,7 @@ package rdapclient
 
 import (
 	"container/list"
+	"math/rand/v2"
 	"sync"
 	"time"
 )
@@ -13,19 +14,80 @@ type ttlItem[T any] struct {
 }
 
 type ttlCache[T any] struct {
-	mu    sync.Mutex
-	ll    *list.List
-	tab   map[string]*list.Element
-	cap   int
-	ttl   time.Duration
-	now   func() time.Time
+	mu  sync.Mutex
+	ll  *list.List
+	tab map[string]*list.Element
+	cap int
+	ttl time.Duration
+	now func() time.Time
+
+	// jitter shortens each entry's TTL by a random fraction up to jitter, so
+	// entries set together (a whole bootstrap file) do not expire together.
+	jitter float64
+	// grace keeps expired entries readable through Stale for this long.
+	grace time.Duration
+
+	hits, misses, evictions uint64
+}
+
+// ttlStats is a snapshot of a ttlCache's size and counters.
+type ttlStats struct {
+	Len, Cap                int
+	Hits, Misses, Evictions uint64
 }
 
 func newTTLCache[T any](ttl time.Duration, capacity int) *ttlCache[T] {
 	return &ttlCache[T]{ll: list.New(), tab: make(map[string]*list.Element), cap: capacity, ttl: ttl, now: time.Now}
 }
 
-func (c *ttlCache[T]) Resize(n int) { c.mu.Lock(); c.cap = n; c.mu.Unlock() }
+// Resize sets the capacity, evicting least recently used entries at once when shrinking.
+func (c *ttlCache[T]) Resize(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cap = n
+	c.evict()
+}
+
+// Reserve raises the capacity to at least n, so a set of n entries fits.
+func (c *ttlCache[T]) Reserve(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cap = max(c.cap, n)
+}
+
+// evict drops least recently used entries down to cap. Callers hold c.mu.
+func (c *ttlCache[T]) evict() {
+	for c.ll.Len() > c.cap {
+		b := c.ll.Back()
+		delete(c.tab, b.Value.(ttlItem[T]).key)
+		c.ll.Remove(b)
+		c.evictions++
+	}
+}
+
+// Delete removes k, e.g. to force a fresh bootstrap lookup for one TLD.
+func (c *ttlCache[T]) Delete(k string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.tab[k]; ok {
+		delete(c.tab, k)
+		c.ll.Remove(el)
+	}
+}
+
+// Len returns the number of entries, including expired ones not yet dropped.
+func (c *ttlCache[T]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Stats returns the cache's size and hit/miss/eviction counters.
+func (c *ttlCache[T]) Stats() ttlStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ttlStats{Len: c.ll.Len(), Cap: c.cap, Hits: c.hits, Misses: c.misses, Evictions: c.evictions}
+}
 
 func (c *ttlCache[T]) Get(k string) (T, bool) {
 	c.mu.Lock()
@@ -33,9 +95,20 @@ func (c *ttlCache[T]) Get(k string) (T, bool) {
 	var zero T
 	if el, ok := c.tab[k]; ok {
 		it := el.Value.(ttlItem[T])
-		if c.now().Before(it.expires) { c.ll.MoveToFront(el); return it.val, true }
-		delete(c.tab, k); c.ll.Remove(el)
+		if c.now().Before(it.expires) {
+			c.ll.MoveToFront(el)
+			c.hits++
+			return it.val, true
+		}
+		c.misses++
+		if c.now().Before(it.expires.Add(c.grace)) {
+			return zero, false
+		}
+		delete(c.tab, k)
+		c.ll.Remove(el)
+		return zero, false
 	}
+	c.misses++
 	return zero, false
 }
 
@@ -43,12 +116,34 @@ func (c *ttlCache[T]) Set(k string, v T) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if el, ok := c.tab[k]; ok {
-		el.Value = ttlItem[T]{key: k, val: v, expires: c.now().Add(c.ttl)}
-		c.ll.MoveToFront(el); return
+		el.Value = ttlItem[T]{key: k, val: v, expires: c.expiry()}
+		c.ll.MoveToFront(el)
+		return
 	}
-	el := c.ll.PushFront(ttlItem[T]{key: k, val: v, expires: c.now().Add(c.ttl)})
+	el := c.ll.PushFront(ttlItem[T]{key: k, val: v, expires: c.expiry()})
 	c.tab[k] = el
-	for c.ll.Len() > c.cap {
-		b := c.ll.Back(); delete(c.tab, b.Value.(ttlItem[T]).key); c.ll.Remove(b)
+	c.evict()
+}
+
+// Stale returns k's value even if it has expired, as long as it is within the
+// grace period.
+func (c *ttlCache[T]) Stale(k string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.tab[k]; ok {
+		it := el.Value.(ttlItem[T])
+		if c.now().Before(it.expires.Add(c.grace)) {
+			return it.val, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+func (c *ttlCache[T]) expiry() time.Time {
+	d := c.ttl
+	if c.jitter > 0 {
+		d -= time.Duration(rand.Float64() * c.jitter * float64(d))
 	}
+	return c.now().Add(d)
 }
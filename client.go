@@ -2,7 +2,10 @@ package rdapclient
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 )
 
@@ -20,46 +23,193 @@ type Client struct {
 	headerExtra http.Header
 
 	// sources
-	bootstrapURL    string // IANA DNS bootstrap
-	ipBootstrapURL  string // IANA IP bootstrap
-	asnBootstrapURL string // IANA ASN bootstrap
+	bootstrapURL          string // IANA DNS bootstrap
+	ipBootstrapURL        string // IANA IP bootstrap
+	asnBootstrapURL       string // IANA ASN bootstrap
+	objectTagBootstrapURL string // IANA object-tags bootstrap (RFC 8521), for entity handles
 
 	// caches
-	rdapBaseCache *ttlCache[string] // tld -> base URL
+	dnsBases      *ttlCache[string] // tld -> base URL, the whole DNS bootstrap file (sized to it)
+	rdapBaseCache *ttlCache[string] // "ip:"/"asn:"/"tag:" key -> base URL
+	baseEntries   *ttlCache[string] // key of rdapBaseCache -> bootstrap entry it matched, for ExplainBase
+	flights       flightGroup       // one bootstrap fetch per URL at a time
 	respCache     *respCache        // url -> cachedResponse
+	canon         *canonCache       // (base, class, handle) -> object; nil unless WithCanonicalCache
+	redirects     sync.Map          // requested URL -> final URL, for redirected responses
+	dataset       *Dataset          // read-only local mirror consulted first; see WithDataset
 
 	// behavior
 	maxRetries int
 	backoff    Backoff
 	now        func() time.Time
 
+	// strictParsing rejects nested objects with a missing or wrong objectClassName
+	// instead of filling it in (see WithLenientParsing)
+	strictParsing bool
+
+	// decodeHooks run after ParseObject, keyed by lower-case objectClassName ("" = every class)
+	decodeHooks map[string][]DecodeHook
+
 	// default/fallbacks
 	defaultRDAPBase string // used when bootstrap lookup fails or TLD missing
+
+	// record/replay (applied around hc once all options are set)
+	recordDir string
+	replayDir string
+
+	// transport shaping (applied around hc once all options are set)
+	rateLimit float64     // requests per second; 0 = unlimited
+	hostRates hostLimiter // per registry host, in getJSON; see WithHostRateLimit
+	proxyURL  *url.URL
+	http3     http.RoundTripper // tried first for https, see WithHTTP3
+	h3        *h3Doer           // set when http3 is in use
+	transport Doer              // hc before rate limiting and recording (Prewarm's HEADs bypass both)
+
+	// skew is the clock skew measured from response Date headers (see Health)
+	skew clockSkew
+
+	// guard limits attempts against failing hosts; see WithDegradedHostConcurrency
+	guard *hostGuard
+
+	// sizes tracks response sizes per registry host; see WithSizeAnomalies
+	sizes *sizeTracker
+
+	// maxNesting bounds response JSON depth; see WithMaxNestingDepth
+	maxNesting int
+
+	// linkPolicy vets links[].href targets; registries are the bootstrap
+	// files' service hosts it trusts. See WithLinkPolicy.
+	linkPolicy LinkPolicy
+	registries registryHosts
+
+	// maxRedirects bounds each request's redirects and maxLinkHops the
+	// cross-host hops of a chain of followed links; see WithMaxRedirects
+	maxRedirects int
+	maxLinkHops  int
+
+	// log receives retries, requests and cache hits; see WithLogger
+	log *slog.Logger
+
+	// faults delays or fails requests in rdapfaults builds; see WithFaultInjector
+	faults FaultInjector
+
+	// synthesizeNS lets Nameserver answer from glue; see WithSynthesizedNameservers
+	synthesizeNS bool
+
+	// bandwidth counts response bytes per registry and job against the
+	// monthly budget; see WithByteBudget
+	bandwidth *bandwidthMeter
+
+	// settleWindow and settleTTL bound the cache TTL of objects NoteChange
+	// reports; see WithSettleWindow.
+	settleWindow, settleTTL time.Duration
+	// diskDir and diskMaxBytes put the response cache on disk as well; see
+	// WithDiskCache
+	diskDir      string
+	diskMaxBytes int64
+
+	// staleIfError is how long past expiry a cached response may answer for
+	// a failing registry; see WithStaleIfError
+	staleIfError time.Duration
+
+	// gateExtensions drops extension members a response did not declare in
+	// rdapConformance; see WithExtensionGating
+	gateExtensions bool
 }
 
 // New returns a ready Client with good defaults.
 func New(opts ...Option) *Client {
 	c := &Client{
-		hc:              defaultHTTPClient(),
-		ua:              "rdapclient/0.1 (+https://example.invalid)",
-		baseTimeout:     10 * time.Second,
-		bootstrapURL:    "https://data.iana.org/rdap/dns.json",
-		ipBootstrapURL:  "https://data.iana.org/rdap/ipv4.json", // covers v4 and v6 via ipv6.json; see options
-		asnBootstrapURL: "https://data.iana.org/rdap/asn.json",
-		headerExtra:     make(http.Header),
+		hc:                    defaultHTTPClient(),
+		ua:                    "rdapclient/0.1 (+https://example.invalid)",
+		baseTimeout:           10 * time.Second,
+		bootstrapURL:          "https://data.iana.org/rdap/dns.json",
+		ipBootstrapURL:        "https://data.iana.org/rdap/ipv4.json", // covers v4 and v6 via ipv6.json; see options
+		asnBootstrapURL:       "https://data.iana.org/rdap/asn.json",
+		objectTagBootstrapURL: "https://data.iana.org/rdap/object-tags.json",
+		headerExtra:           make(http.Header),
 
+		dnsBases:      newTTLCache[string](6*time.Hour, 64),
 		rdapBaseCache: newTTLCache[string](6*time.Hour, 64),
+		baseEntries:   newTTLCache[string](6*time.Hour, 64),
 		respCache:     newRespCache(512, 10*time.Minute),
 
 		maxRetries: 2,
 		backoff:    ExponentialBackoff(200*time.Millisecond, 2.0, 2*time.Second),
 		now:        time.Now,
+		log:        slog.New(slog.DiscardHandler),
+		maxNesting: DefaultMaxNestingDepth,
+		sizes:      newSizeTracker(),
+		bandwidth:  &bandwidthMeter{},
+
+		settleWindow: 30 * time.Minute,
+
+		maxRedirects: DefaultMaxRedirects,
+		maxLinkHops:  DefaultMaxLinkHops,
 
 		defaultRDAPBase: "https://rdap.org",
 	}
+	c.guard = newHostGuard(DefaultDegradedHostConcurrency, func() time.Time { return c.now() })
+	// Spread expirations over the last 10% of the TTL, and keep serving an
+	// expired base (refreshed in the background) up to a week; see WithBootstrapTTL.
+	for _, bc := range []*ttlCache[string]{c.dnsBases, c.rdapBaseCache} {
+		bc.jitter = 0.1
+		bc.grace = 7*24*time.Hour - bc.ttl
+	}
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.registries.add(c.defaultRDAPBase, "https://rdap.org")
+	if c.diskDir != "" {
+		if d, err := openDiskCache(c.diskDir, c.diskMaxBytes, c.log); err != nil {
+			c.log.Warn("rdap disk cache unavailable; caching in memory only", "dir", c.diskDir, "err", err)
+		} else {
+			c.respCache.disk = d
+		}
+	}
+	if c.proxyURL != nil {
+		if hc, ok := c.hc.(*http.Client); ok {
+			tr := http.DefaultTransport.(*http.Transport).Clone()
+			tr.Proxy = http.ProxyURL(c.proxyURL)
+			clone := *hc
+			clone.Transport = tr
+			c.hc = &clone
+		}
+	}
+	if hc, ok := c.hc.(*http.Client); ok && hc.CheckRedirect == nil {
+		clone := *hc
+		clone.CheckRedirect = c.checkRedirect
+		c.hc = &clone
+	}
+	switch {
+	case c.http3 != nil && c.proxyURL != nil:
+		// QUIC cannot go through an HTTP or SOCKS proxy, and going around it
+		// would defeat the proxy.
+		c.log.Warn("rdap HTTP/3 disabled: requests go through the proxy", "proxy", c.proxyURL.Redacted())
+	case c.http3 != nil:
+		// The QUIC transport gets the redirect policy and timeout of the
+		// client it falls back to, so a request behaves the same over either.
+		h3 := &http.Client{Transport: c.http3, CheckRedirect: c.checkRedirect, Timeout: defaultHTTPClient().Timeout}
+		if hc, ok := c.hc.(*http.Client); ok {
+			h3.CheckRedirect, h3.Timeout = hc.CheckRedirect, hc.Timeout
+		}
+		c.h3 = newH3Doer(h3, c.hc, c.now)
+		c.hc = c.h3
+	}
+	if c.faults != nil && faultInjection {
+		c.hc = &faultDoer{next: c.hc, inject: c.faults}
+	}
+	c.transport = c.hc
+	c.hc = &skewDoer{next: c.hc, skew: &c.skew, now: c.now}
+	if c.rateLimit > 0 {
+		c.hc = &rateLimitDoer{next: c.hc, interval: time.Duration(float64(time.Second) / c.rateLimit)}
+	}
+	switch {
+	case c.replayDir != "":
+		c.hc = &replayDoer{dir: c.replayDir, copyTo: c.recordDir}
+	case c.recordDir != "":
+		c.hc = &recordingDoer{next: c.hc, dir: c.recordDir, max: c.sizes.max, secret: c.headerExtra, now: c.now}
+	}
 	return c
 }
 
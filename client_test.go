@@ -1,17 +1,28 @@
 package rdapclient
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/quic-go/quic-go/http3"
 )
 
 // ---------- Backoff ----------
@@ -168,10 +179,13 @@ func TestLastLabelAndJoin(t *testing.T) {
 		t.Fatalf("lastLabel: got %q", got)
 	}
 	base := "https://rdap.example.com/"
-	joined := mustJoin(base, "/domain/", "example.com")
+	joined, err := BuildObjectURL(base, "domain", "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
 	u, err := url.Parse(joined)
 	if err != nil || !strings.HasSuffix(u.String(), "/domain/example.com") {
-		t.Fatalf("mustJoin unexpected: %v %v", u, err)
+		t.Fatalf("BuildObjectURL unexpected: %v %v", u, err)
 	}
 }
 
@@ -244,7 +258,7 @@ func TestRDAPBaseForTLD_BootstrapFetchAndCache(t *testing.T) {
 	)
 	// Freeze cache clocks for determinism
 	c.respCache.now = func() time.Time { return time.Now() }
-	c.rdapBaseCache.now = func() time.Time { return time.Now() }
+	c.dnsBases.now = func() time.Time { return time.Now() }
 
 	// First call -> fetches and caches
 	got, err := c.rdapBaseForTLD(context.Background(), "COM")
@@ -256,7 +270,7 @@ func TestRDAPBaseForTLD_BootstrapFetchAndCache(t *testing.T) {
 		t.Fatalf("base mismatch: %q", got)
 	}
 
-	// A second call should be satisfied from rdapBaseCache without another fetch.
+	// A second call should be satisfied from dnsBases without another fetch.
 	got2, err := c.rdapBaseForTLD(context.Background(), ".net")
 	if err != nil || got2 != "https://rdap.example/v1" {
 		t.Fatalf("cache miss or base mismatch: %v %q", err, got2)
@@ -712,6 +726,64 @@ func TestGetJSON_RetryExhaustsThenError(t *testing.T) {
 	}
 }
 
+func TestGetJSON_RateLimitedReturnsHTTPErrorWithRetryAfter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	c := New()
+	c.maxRetries = 0
+
+	_, _, err := c.getJSON(context.Background(), ts.URL+"/x")
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		t.Fatalf("want *HTTPError, got %T %v", err, err)
+	}
+	// The in-call retry clamps Retry-After, but the error reports it uncapped.
+	if he.StatusCode != http.StatusTooManyRequests || he.RetryAfter != 120*time.Second {
+		t.Fatalf("unexpected HTTPError: %+v", he)
+	}
+}
+
+func TestGetJSON_RDAPErrorBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dns.json" {
+			_, _ = io.WriteString(w, `{"version":"1.0","services":[]}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rdap+json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errorCode":404,"title":"Not Found","description":["The domain is not registered here."],
+			"notices":[{"title":"Terms of Use","description":["See the policy."]}],"rdapConformance":["rdap_level_0"]}`)
+	}))
+	defer ts.Close()
+
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithDefaultRDAPBase(ts.URL), WithMaxRetries(0))
+	_, err := c.Domain(context.Background(), "example.com")
+	var re *RDAPError
+	if !errors.As(err, &re) {
+		t.Fatalf("want *RDAPError, got %T %v", err, err)
+	}
+	if re.ErrorCode != 404 || re.Title != "Not Found" || len(re.Description) != 1 || len(re.Notices) != 1 || re.StatusCode != 404 {
+		t.Fatalf("unexpected RDAPError: %+v", re)
+	}
+	if !strings.Contains(err.Error(), "The domain is not registered here.") {
+		t.Fatalf("message lacks description: %v", err)
+	}
+	var he *HTTPError
+	if !errors.As(err, &he) || ErrorCode(err) != CodeNotFound {
+		t.Fatalf("HTTPError not reachable: %v (%s)", err, ErrorCode(err))
+	}
+
+	// A body that is not an RDAP error stays a plain HTTPError.
+	plain := statusError(&HTTPError{StatusCode: 502, Status: "502 Bad Gateway", Body: "<html>bad gateway</html>"})
+	if _, ok := plain.(*HTTPError); !ok {
+		t.Fatalf("want *HTTPError for non-RDAP body, got %T", plain)
+	}
+}
+
 func TestGetJSON_RetryCanceledContext(t *testing.T) {
 	var hits int
 	firstHit := make(chan struct{}, 1)
@@ -900,3 +972,2884 @@ func TestExpiryFromHeaders_UsesExpiresAndFallsBack(t *testing.T) {
 		t.Fatalf("no-cache must return 0; got %v", d)
 	}
 }
+
+// ---------- Lookup classification chain ----------
+
+func TestLookupWithOptions_RecordsTriedChainAndAllowedKinds(t *testing.T) {
+	var srvURL string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/dns.json"):
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/"]]]}`, srvURL))
+		case strings.HasPrefix(r.URL.Path, "/entity/"):
+			_, _ = io.WriteString(w, `{"objectClassName":"entity","handle":"ns1-x"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	srvURL = ts.URL
+
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithMaxRetries(0))
+	ctx := context.Background()
+
+	_, err := c.Lookup(ctx, "ns1.example.example", "")
+	var lf *ErrLookupFailed
+	if !errors.As(err, &lf) {
+		t.Fatalf("want *ErrLookupFailed, got %T %v", err, err)
+	}
+	if len(lf.Tried) != 2 || lf.Tried[0].Kind != KindNameserver || lf.Tried[1].Kind != KindDomain {
+		t.Fatalf("unexpected chain: %+v", lf.Tried)
+	}
+
+	// Constraining to domain skips the nameserver guess entirely.
+	_, err = c.LookupWithOptions(ctx, "ns1.example.example", LookupOptions{AllowedKinds: []Kind{KindDomain}})
+	if !errors.As(err, &lf) || len(lf.Tried) != 1 || lf.Tried[0].Kind != KindDomain {
+		t.Fatalf("unexpected constrained chain: %v", err)
+	}
+
+	// An explicitly allowed kind the heuristics skipped is still attempted.
+	obj, err := c.LookupWithOptions(ctx, "ns1-x", LookupOptions{TLDHint: "example", AllowedKinds: []Kind{KindEntity}})
+	if err != nil {
+		t.Fatalf("entity lookup: %v", err)
+	}
+	if _, ok := obj.(*Entity); !ok {
+		t.Fatalf("want *Entity, got %T", obj)
+	}
+
+	// Nothing allowed for an ASN query.
+	_, err = c.LookupWithOptions(ctx, "AS15169", LookupOptions{AllowedKinds: []Kind{KindDomain}})
+	if !errors.As(err, &lf) || len(lf.Tried) != 0 {
+		t.Fatalf("want empty chain error, got %v", err)
+	}
+}
+
+// ---------- FQDN validation ----------
+
+func TestNormalizeFQDN(t *testing.T) {
+	ok := map[string]string{
+		"Example.COM.":         "example.com",
+		"bücher.example":       "xn--bcher-kva.example",
+		"münchen.de":           "xn--mnchen-3ya.de",
+		"1.0.192.in-addr.arpa": "1.0.192.in-addr.arpa",
+		// UTS #46 mapping: width and case folding, NFC.
+		"ＥＸＡＭＰＬＥ.com":                 "example.com",
+		"e\u0301xample.com":           "xn--xample-9ua.com",
+		"r3---sn-abc.googlevideo.com": "r3---sn-abc.googlevideo.com",
+	}
+	for in, want := range ok {
+		got, err := NormalizeFQDN(in)
+		if err != nil || got != want {
+			t.Fatalf("NormalizeFQDN(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	bad := []string{
+		"",
+		".",
+		"a..b",
+		"-lead.example",
+		"sp ace.example",
+		"../etc/passwd",
+		"100%.example",
+		"a\u2028b.example", // disallowed code point
+		"\u05d0a.example",  // Bidi rule: RTL label with LTR characters
+		"xn--zz.example",   // invalid punycode
+		strings.Repeat("a", 64) + ".example",
+		strings.Repeat(strings.Repeat("a", 60)+".", 5) + "com",
+	}
+	for _, in := range bad {
+		_, err := NormalizeFQDN(in)
+		var ide *ErrInvalidDomain
+		if !errors.As(err, &ide) {
+			t.Fatalf("NormalizeFQDN(%q): want *ErrInvalidDomain, got %v", in, err)
+		}
+	}
+}
+
+func TestDomain_InvalidNameMakesNoRequest(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithDefaultRDAPBase(ts.URL))
+	if _, err := c.Domain(context.Background(), "bad name.example"); err == nil {
+		t.Fatalf("expected validation error")
+	}
+	if hits != 0 {
+		t.Fatalf("invalid input should not reach the network; hits=%d", hits)
+	}
+}
+
+// ---------- URL building ----------
+
+func TestBuildObjectURL_EscapesSegments(t *testing.T) {
+	cases := []struct{ base, class, id, want string }{
+		{"https://rdap.example/", "domain", "example.com", "https://rdap.example/domain/example.com"},
+		{"https://rdap.example/registry", "ip", "192.0.2.0/24", "https://rdap.example/registry/ip/192.0.2.0/24"},
+		{"https://rdap.example", "entity", "A B%2F", "https://rdap.example/entity/A%20B%252F"},
+		{"https://rdap.example", "entity", "x/../../admin", "https://rdap.example/entity/x%2F..%2F..%2Fadmin"},
+	}
+	for _, tc := range cases {
+		got, err := BuildObjectURL(tc.base, tc.class, tc.id)
+		if err != nil || got != tc.want {
+			t.Fatalf("BuildObjectURL(%q,%q,%q) = %q, %v; want %q", tc.base, tc.class, tc.id, got, err, tc.want)
+		}
+	}
+	for _, id := range []string{"", ".", "..", "192.0.2.0/.."} {
+		class := "entity"
+		if strings.Contains(id, "/") {
+			class = "ip"
+		}
+		if _, err := BuildObjectURL("https://rdap.example", class, id); err == nil {
+			t.Fatalf("BuildObjectURL(%q): expected error", id)
+		}
+	}
+	if _, err := BuildObjectURL("not a url", "domain", "x"); err == nil {
+		t.Fatalf("relative base should be rejected")
+	}
+}
+
+func FuzzBuildObjectURL(f *testing.F) {
+	for _, s := range []string{"example.com", "192.0.2.0/24", "../x", "a b", "%2e%2e", "?q=1#f"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, id string) {
+		got, err := BuildObjectURL("https://rdap.example/base", "entity", id)
+		if err != nil {
+			return
+		}
+		u, err := url.Parse(got)
+		if err != nil {
+			t.Fatalf("unparseable URL %q: %v", got, err)
+		}
+		if u.Host != "rdap.example" || u.RawQuery != "" || u.Fragment != "" {
+			t.Fatalf("id %q escaped its segment: %q", id, got)
+		}
+		if u.Path != "/base/entity/"+id {
+			t.Fatalf("id %q not preserved as one segment: path %q", id, u.Path)
+		}
+	})
+}
+
+// ---------- Record / replay ----------
+
+func TestRecorder_ThenReplayOffline(t *testing.T) {
+	var srvURL string
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/dns.json"):
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/"]]]}`, srvURL))
+		case strings.HasPrefix(r.URL.Path, "/domain/"):
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"rec.example"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	srvURL = ts.URL
+	dir := t.TempDir()
+
+	rec := New(WithBootstrapURL(ts.URL+"/dns.json"), WithRecorder(dir))
+	if _, err := rec.Domain(context.Background(), "rec.example"); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	ts.Close() // network is gone from here on
+	before := hits
+
+	if err := os.WriteFile(filepath.Join(dir, "unused.json"), []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	used := t.TempDir()
+	play := New(WithBootstrapURL(srvURL+"/dns.json"), WithReplay(dir), WithRecorder(used))
+	d, err := play.Domain(context.Background(), "rec.example")
+	if err != nil || d.LDHName != "rec.example" {
+		t.Fatalf("replay: %v %+v", err, d)
+	}
+	if hits != before {
+		t.Fatalf("replay must not hit the network")
+	}
+	// Replaying with a recorder copies the cassettes served, as they are.
+	if copied, _ := os.ReadDir(used); len(copied) != 2 {
+		t.Fatalf("copied %d cassettes, want 2", len(copied))
+	}
+	domainURL := srvURL + "/domain/rec.example"
+	want, _ := os.ReadFile(CassettePath(dir, http.MethodGet, domainURL))
+	if got, err := os.ReadFile(CassettePath(used, http.MethodGet, domainURL)); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("copied cassette differs: %v", err)
+	}
+
+	if _, err := play.Domain(context.Background(), "other.example"); !errors.Is(err, ErrCassetteMiss) {
+		t.Fatalf("want ErrCassetteMiss, got %v", err)
+	}
+}
+
+func TestRecorder_RedactsCredentialsAndSkipsOversized(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=s3cret")
+		if r.URL.Path == "/entity/BIG" {
+			_, _ = io.WriteString(w, `{"objectClassName":"entity","handle":"BIG","remarks":[{"description":["`+strings.Repeat("x", 4<<10)+`"]}]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"objectClassName":"entity","handle":"X"}`)
+	}))
+	defer ts.Close()
+	dir := t.TempDir()
+	c := New(WithRecorder(dir), WithMaxResponseSize(2<<10), WithHeader("X-Api-Key", "k3y"), WithHeader("Authorization", "Bearer t0ken"))
+	ctx := context.Background()
+
+	if _, _, err := c.getJSON(ctx, ts.URL+"/entity/X"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(CassettePath(dir, http.MethodGet, ts.URL+"/entity/X"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"k3y", "t0ken", "s3cret"} {
+		if strings.Contains(string(b), secret) {
+			t.Fatalf("cassette keeps %q: %s", secret, b)
+		}
+	}
+
+	var te *ErrResponseTooLarge
+	if _, _, err := c.getJSON(ctx, ts.URL+"/entity/BIG"); !errors.As(err, &te) {
+		t.Fatalf("want *ErrResponseTooLarge, got %v", err)
+	}
+	if _, err := os.Stat(CassettePath(dir, http.MethodGet, ts.URL+"/entity/BIG")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("oversized response recorded: %v", err)
+	}
+}
+
+func TestWriteCassette_ConcurrentSameURL(t *testing.T) {
+	path := CassettePath(t.TempDir(), http.MethodGet, "https://rdap.example/domain/example.com")
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := bytes.Repeat([]byte{byte('a' + i)}, 4096*(i+1))
+			errs <- writeCassette(path, Cassette{Method: http.MethodGet, Status: 200, Body: body})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cs Cassette
+	if err := json.Unmarshal(b, &cs); err != nil {
+		t.Fatalf("cassette corrupted by concurrent writes: %v", err)
+	}
+	if left, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp")); len(left) != 0 {
+		t.Fatalf("temp files left behind: %v", left)
+	}
+}
+
+func TestBootstrapMonitor_DiffsSuccessiveFetches(t *testing.T) {
+	dns := `{"services":[[["com","net"],["https://a.example/rdap/"]],[["org"],["https://o.example/"]]]}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dns.json" {
+			_, _ = io.WriteString(w, dns)
+			return
+		}
+		_, _ = io.WriteString(w, `{"services":[]}`)
+	}))
+	defer ts.Close()
+
+	var hooked []BootstrapChange
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Changes []BootstrapChange }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		hooked = append(hooked, body.Changes...)
+	}))
+	defer hook.Close()
+
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithIPBootstrapURL(ts.URL+"/ipv4.json"), WithASNBootstrapURL(ts.URL+"/asn.json"))
+	m := c.NewBootstrapMonitor(WebhookNotifier(hook.URL, nil, func(err error) { t.Error(err) }))
+	if got, err := m.Check(context.Background()); err != nil || len(got) != 0 {
+		t.Fatalf("baseline: %v %v", got, err)
+	}
+
+	dns = `{"services":[[["com"],["https://b.example/rdap/"]],[["org","dev"],["https://o.example/"]]]}`
+	got, err := m.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BootstrapChange{
+		{Registry: ts.URL + "/dns.json", Kind: BootstrapChanged, Key: "com", OldBase: "https://a.example/rdap", NewBase: "https://b.example/rdap"},
+		{Registry: ts.URL + "/dns.json", Kind: BootstrapAdded, Key: "dev", NewBase: "https://o.example"},
+		{Registry: ts.URL + "/dns.json", Kind: BootstrapRemoved, Key: "net", OldBase: "https://a.example/rdap"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes:\n got %+v\nwant %+v", got, want)
+	}
+	if !reflect.DeepEqual(hooked, want) {
+		t.Fatalf("webhook got %+v", hooked)
+	}
+
+	// A restored state diffs against the saved baseline instead of starting over.
+	m2 := c.NewBootstrapMonitor(nil)
+	m2.SetState(BootstrapState{ts.URL + "/dns.json": {"com": "https://b.example/rdap"}})
+	if got, _ := m2.Check(context.Background()); len(got) != 2 {
+		t.Fatalf("restored baseline: %+v", got)
+	}
+}
+
+func TestLoadConfig_NewFromConfig(t *testing.T) {
+	cfg, err := LoadConfig(strings.NewReader(`{"userAgent":"ua/1","timeout":"3s","maxRetries":0,"headers":{"X-Team":"abuse"},"defaultRDAPBase":"https://fallback.example"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewFromConfig(cfg)
+	if c.ua != "ua/1" || c.baseTimeout != 3*time.Second || c.maxRetries != 0 || c.headerExtra.Get("X-Team") != "abuse" || c.defaultRDAPBase != "https://fallback.example" {
+		t.Fatalf("config not applied: ua=%q timeout=%v retries=%d", c.ua, c.baseTimeout, c.maxRetries)
+	}
+	// Unset fields keep New's defaults.
+	if d := NewFromConfig(Config{}); d.maxRetries != 2 || d.bootstrapURL != New().bootstrapURL {
+		t.Fatalf("defaults changed: %+v", d)
+	}
+	if _, err := LoadConfig(strings.NewReader(`{"userAgnet":"typo"}`)); err == nil {
+		t.Fatal("want error for unknown field")
+	}
+	b, _ := json.Marshal(Config{Timeout: Duration(1500 * time.Millisecond)})
+	if string(b) != `{"timeout":"1.5s"}` {
+		t.Fatalf("marshal: %s", b)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("MYAPP_UA", "env/1")
+	t.Setenv("MYAPP_TIMEOUT", "4s")
+	t.Setenv("MYAPP_MAX_RETRIES", "0")
+	t.Setenv("MYAPP_RESPONSE_CACHE_SIZE", "64")
+	t.Setenv("MYAPP_RATE_LIMIT", "5")
+	t.Setenv("MYAPP_HTTP_PROXY", "http://proxy.example:3128")
+	cfg, err := ConfigFromEnv("MYAPP_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.UserAgent != "env/1" || time.Duration(cfg.Timeout) != 4*time.Second || cfg.MaxRetries == nil || *cfg.MaxRetries != 0 ||
+		cfg.ResponseCacheSize != 64 || cfg.RateLimit != 5 || cfg.HTTPProxy != "http://proxy.example:3128" {
+		t.Fatalf("cfg: %+v", cfg)
+	}
+	c := New(FromEnv("MYAPP")...)
+	if c.ua != "env/1" || c.maxRetries != 0 {
+		t.Fatalf("FromEnv not applied: ua=%q retries=%d", c.ua, c.maxRetries)
+	}
+	if _, ok := c.hc.(*rateLimitDoer); !ok {
+		t.Fatalf("rate limit not applied: %T", c.hc)
+	}
+
+	t.Setenv("MYAPP_TIMEOUT", "soon")
+	t.Setenv("MYAPP_HTTP_PROXY", "proxy")
+	cfg, err = ConfigFromEnv("MYAPP")
+	if err == nil || !strings.Contains(err.Error(), "MYAPP_TIMEOUT") || !strings.Contains(err.Error(), "MYAPP_HTTP_PROXY") {
+		t.Fatalf("want both malformed vars reported, got %v", err)
+	}
+	if cfg.UserAgent != "env/1" {
+		t.Fatalf("valid vars should still load: %+v", cfg)
+	}
+}
+
+func TestRateLimitDoer_SpacesRequests(t *testing.T) {
+	var n int
+	d := &rateLimitDoer{interval: 20 * time.Millisecond, next: doerFunc(func(*http.Request) (*http.Response, error) {
+		n++
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "https://x.example/", nil)
+		if _, err := d.Do(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if el := time.Since(start); el < 60*time.Millisecond || n != 4 {
+		t.Fatalf("4 requests at 50/s took %v (n=%d)", el, n)
+	}
+}
+
+func TestDecodeHook_NormalizesAfterDecoding(t *testing.T) {
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/ipv4.json"):
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["192.0.2.0/24"],["%s/"]]]}`, srvURL))
+		case strings.HasPrefix(r.URL.Path, "/ip/"):
+			_, _ = io.WriteString(w, `{"objectClassName":"ip network","handle":"NET-1","ipVersion":"IPv4","x_org":"Example"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+
+	var order []string
+	c := New(WithIPBootstrapURL(ts.URL+"/ipv4.json"),
+		WithDecodeHook("", func(json.RawMessage, Object) error { order = append(order, "any"); return nil }),
+		WithDecodeHook("IP Network", func(raw json.RawMessage, obj Object) error {
+			order = append(order, "ip")
+			n := obj.(*IPNetwork)
+			n.IPVersion = strings.TrimPrefix(strings.ToLower(n.IPVersion), "ip")
+			var ext struct {
+				Org string `json:"x_org"`
+			}
+			if err := json.Unmarshal(raw, &ext); err != nil {
+				return err
+			}
+			n.Name = ext.Org
+			return nil
+		}),
+		WithDecodeHook("domain", func(json.RawMessage, Object) error { t.Error("domain hook ran for a network"); return nil }),
+	)
+	n, err := c.IP(context.Background(), "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.IPVersion != "v4" || n.Name != "Example" || !reflect.DeepEqual(order, []string{"any", "ip"}) {
+		t.Fatalf("hooks: version=%q name=%q order=%v", n.IPVersion, n.Name, order)
+	}
+
+	c = New(WithIPBootstrapURL(ts.URL+"/ipv4.json"), WithDecodeHook("ip network", func(json.RawMessage, Object) error { return errors.New("nope") }))
+	if _, err := c.IP(context.Background(), "192.0.2.1"); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Fatalf("want hook error, got %v", err)
+	}
+}
+
+func TestParseObject_NestedClassNamesLenientAndStrict(t *testing.T) {
+	body := []byte(`{"objectClassName":"domain","ldhName":"a.example",
+		"entities":[{"handle":"R1","roles":["registrar"],"entities":[{"handle":"ABUSE"}],"networks":[{"handle":"NET"}]}],
+		"nameservers":[{"ldhName":"ns1.a.example"},{"objectClassName":"nameserver","ldhName":"ns2.a.example"}]}`)
+
+	c := New(WithHTTPDoer(staticDoer(http.StatusOK, body)))
+	d, err := c.Domain(context.Background(), "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := d.Entities[0]
+	if !e.Validate() || !e.Entities[0].Validate() || !e.Networks[0].Validate() || !d.Nameservers[0].Validate() {
+		t.Fatalf("lenient: class names not filled: %+v", d)
+	}
+
+	c = New(WithHTTPDoer(staticDoer(http.StatusOK, body)), WithLenientParsing(false))
+	_, err = c.Domain(context.Background(), "a.example")
+	if err == nil {
+		t.Fatal("strict: want error")
+	}
+	for _, p := range []string{"entities[0]=", "entities[0].entities[0]=", "entities[0].networks[0]=", "nameservers[0]="} {
+		if !strings.Contains(err.Error(), p) {
+			t.Fatalf("strict error missing %q: %v", p, err)
+		}
+	}
+	if strings.Contains(err.Error(), "nameservers[1]") {
+		t.Fatalf("strict flagged a valid nameserver: %v", err)
+	}
+}
+
+func TestHydrate_StubViaSelfLink(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/self/entity/S1":
+			_, _ = io.WriteString(w, `{"objectClassName":"entity","handle":"S1","vcardArray":["vcard",[["fn",{},"text","Self"]]]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	c := New(WithLinkPolicy(LinkPolicy{Hosts: []string{"127.0.0.1"}}))
+
+	full := &Entity{CommonObject: CommonObject{ObjectClassName: "entity", Handle: "F", Events: []Event{{EventAction: "x"}}}}
+	if full.IsStub() {
+		t.Fatal("entity with events is not a stub")
+	}
+	if err := c.Hydrate(context.Background(), full); err != nil || full.Handle != "F" {
+		t.Fatalf("non-stub should be untouched: %v", err)
+	}
+
+	s1 := &Entity{CommonObject: CommonObject{Handle: "S1", Links: []Link{{Rel: "self", Href: ts.URL + "/self/entity/S1"}}}, Roles: []string{"technical"}}
+	if !s1.IsStub() {
+		t.Fatal("handle+roles+links should be a stub")
+	}
+	if err := c.Hydrate(context.Background(), s1); err != nil {
+		t.Fatal(err)
+	}
+	if s1.IsStub() || !reflect.DeepEqual(s1.Roles, []string{"technical"}) {
+		t.Fatalf("self-link hydrate: %+v", s1)
+	}
+}
+
+func TestHydrate_BlockedSelfLinkWithoutHandle(t *testing.T) {
+	fetched := 0
+	c := New(WithHTTPDoer(doerFunc(func(r *http.Request) (*http.Response, error) {
+		fetched++
+		return staticResponse(`{"objectClassName":"entity","handle":"X"}`), nil
+	})))
+	for _, href := range []string{"https://169.254.169.254/latest/meta-data/entity/X", "https://10.0.0.1/entity/X"} {
+		stub := &Entity{CommonObject: CommonObject{Links: []Link{{Rel: "self", Href: href}}}, Roles: []string{"registrant"}}
+		err := c.Hydrate(context.Background(), stub)
+		var lb *ErrLinkBlocked
+		if !errors.As(err, &lb) || lb.Href != href {
+			t.Fatalf("%s: want *ErrLinkBlocked, got %v", href, err)
+		}
+	}
+	if fetched != 0 {
+		t.Fatalf("%d requests to blocked self links", fetched)
+	}
+}
+
+func TestDomain_ArrayAndUnexpectedClass(t *testing.T) {
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/"]]]}`, srvURL))
+		case "/domain/arr.example":
+			_, _ = io.WriteString(w, `[{"objectClassName":"domain","ldhName":"arr.example"},{"objectClassName":"domain","ldhName":"other.example"}]`)
+		case "/domain/ent.example":
+			_, _ = io.WriteString(w, `{"objectClassName":"entity","handle":"E1"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithBootstrapURL(ts.URL + "/dns.json"))
+
+	d, err := c.Domain(context.Background(), "arr.example")
+	if err != nil || d.LDHName != "arr.example" {
+		t.Fatalf("array response: %v %+v", err, d)
+	}
+
+	_, err = c.Domain(context.Background(), "ent.example")
+	var ue ErrUnexpectedObject
+	if !errors.As(err, &ue) {
+		t.Fatalf("want ErrUnexpectedObject, got %v", err)
+	}
+	if ue.Want != "domain" || ue.Got != "entity" || ue.URL != ts.URL+"/domain/ent.example" || !strings.Contains(string(ue.Raw), `"E1"`) {
+		t.Fatalf("error = %+v", ue)
+	}
+}
+
+func TestDomain_SearchResultsWrapper(t *testing.T) {
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/"]]]}`, srvURL))
+		case "/domain/one.example":
+			_, _ = io.WriteString(w, `{"rdapConformance":["rdap_level_0"],"domainSearchResults":[{"objectClassName":"domain","ldhName":"one.example"}]}`)
+		case "/domain/two.example":
+			_, _ = io.WriteString(w, `{"domainSearchResults":[{"objectClassName":"domain","ldhName":"two.example"},{"objectClassName":"domain","handle":"D-2"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithBootstrapURL(ts.URL + "/dns.json"))
+
+	d, err := c.Domain(context.Background(), "one.example")
+	if err != nil || d.LDHName != "one.example" {
+		t.Fatalf("single result: %v %+v", err, d)
+	}
+
+	_, err = c.Domain(context.Background(), "two.example")
+	var ae *ErrAmbiguousObject
+	if !errors.As(err, &ae) {
+		t.Fatalf("want *ErrAmbiguousObject, got %v", err)
+	}
+	if ae.Want != "domain" || !reflect.DeepEqual(ae.Candidates, []string{"two.example", "D-2"}) {
+		t.Fatalf("error = %+v", ae)
+	}
+}
+
+func TestEntityNetworks_InlineOrLinkedPaged(t *testing.T) {
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/ips" && r.URL.Query().Get("page") == "":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"ipSearchResults":[{"objectClassName":"ip network","handle":"N1"}],
+				"paging_metadata":{"totalCount":2,"links":[{"rel":"next","href":"%s/ips?handle=H&page=2"}]}}`, srvURL))
+		case r.URL.Path == "/ips":
+			_, _ = io.WriteString(w, `{"ipSearchResults":[{"objectClassName":"ip network","handle":"N2"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithLinkPolicy(LinkPolicy{Hosts: []string{"127.0.0.1"}}))
+	ctx := context.Background()
+
+	inline := &Entity{Networks: []IPNetwork{{CommonObject: CommonObject{Handle: "I1"}}}}
+	if nets, err := c.EntityNetworks(ctx, inline); err != nil || len(nets) != 1 || nets[0].Handle != "I1" {
+		t.Fatalf("inline: %v %+v", err, nets)
+	}
+
+	linked := &Entity{CommonObject: CommonObject{Links: []Link{
+		{Rel: "self", Href: ts.URL + "/entity/H"},
+		{Rel: "related", Href: ts.URL + "/ips?handle=H"},
+	}}}
+	nets, err := c.EntityNetworks(ctx, linked)
+	if err != nil || len(nets) != 2 || nets[0].Handle != "N1" || nets[1].Handle != "N2" {
+		t.Fatalf("linked: %v %+v", err, nets)
+	}
+	if as, err := c.EntityAutnums(ctx, linked); err != nil || len(as) != 0 {
+		t.Fatalf("no autnums link: %v %+v", err, as)
+	}
+}
+
+func TestClockSkew_MeasuredAndFreshnessUsesDate(t *testing.T) {
+	local := time.Now()
+	server := local.Add(time.Hour)
+	h := http.Header{}
+	h.Set("Date", server.UTC().Format(http.TimeFormat))
+	h.Set("Expires", server.Add(time.Minute).UTC().Format(http.TimeFormat))
+	if d := expiryFromHeaders(h, 10*time.Minute, local); d < 59*time.Second || d > 61*time.Second {
+		t.Fatalf("Expires should be relative to Date, got %v", d)
+	}
+
+	d := doerFunc(func(r *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Date", server.UTC().Format(http.TimeFormat))
+		_, _ = io.WriteString(rec, `{"objectClassName":"domain","ldhName":"x.example"}`)
+		return rec.Result(), nil
+	})
+	c := New(WithHTTPDoer(d))
+	if _, _, err := c.getJSON(context.Background(), "https://rdap.example/domain/x.example"); err != nil {
+		t.Fatal(err)
+	}
+	hl := c.Health()
+	if hl.ClockSkew < 59*time.Minute || hl.ClockSkew > 61*time.Minute || hl.ClockSkewHost != "rdap.example" {
+		t.Fatalf("health = %+v", hl)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// withHTTP3Transport is WithHTTP3 with rt in place of quic-go's transport.
+func withHTTP3Transport(rt http.RoundTripper) Option { return func(c *Client) { c.http3 = rt } }
+
+func TestHTTP3_QUIC(t *testing.T) {
+	if _, ok := New(WithHTTP3(true)).http3.(*http3.Transport); !ok {
+		t.Fatal("WithHTTP3(true): want quic-go's transport")
+	}
+	if c := New(WithHTTP3(true), WithHTTP3(false)); c.h3 != nil {
+		t.Fatal("WithHTTP3(false) leaves HTTP/3 on")
+	}
+
+	// A registry over QUIC on loopback, with httptest's certificate.
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+	body := `{"objectClassName":"domain","ldhName":"x.example"}`
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no UDP: %v", err)
+	}
+	srv := &http3.Server{TLSConfig: http3.ConfigureTLSConfig(ts.TLS.Clone()), Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, body)
+	})}
+	go func() { _ = srv.Serve(pc) }()
+	defer srv.Close()
+
+	tcp := doerFunc(func(r *http.Request) (*http.Response, error) { return nil, errors.New("unexpected fallback") })
+	tr := &http3.Transport{TLSClientConfig: ts.Client().Transport.(*http.Transport).TLSClientConfig}
+	defer tr.Close()
+	c := New(WithHTTPDoer(tcp), withHTTP3Transport(tr), WithMaxRetries(0))
+	u := "https://" + pc.LocalAddr().String() + "/domain/x.example"
+	f, err := c.getResponse(context.Background(), u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.meta(u).Proto != "HTTP/3.0" || c.Health().HTTP3Fallbacks != 0 {
+		t.Fatalf("proto = %q, health = %+v", f.proto, c.Health())
+	}
+}
+
+func TestHTTP3_FallbackAndProtocols(t *testing.T) {
+	body := `{"objectClassName":"domain","ldhName":"x.example"}`
+	h3Calls := 0
+	h3 := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		h3Calls++
+		if r.URL.Host == "quic.example" {
+			resp := staticResponse(body)
+			resp.Proto = "HTTP/3.0"
+			return resp, nil
+		}
+		return nil, errors.New("no recent network activity")
+	})
+	tcp := doerFunc(func(r *http.Request) (*http.Response, error) {
+		resp := staticResponse(body)
+		resp.Proto = "HTTP/2.0"
+		return resp, nil
+	})
+	c := New(WithHTTPDoer(tcp), withHTTP3Transport(h3))
+	ctx := context.Background()
+	for _, u := range []string{"https://quic.example/domain/x.example", "https://tcp.example/domain/x.example", "https://tcp.example/domain/y.example"} {
+		if _, _, err := c.getJSON(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if h3Calls != 2 {
+		t.Fatalf("broken host should skip QUIC after a failure: %d h3 calls", h3Calls)
+	}
+	h := c.Health()
+	want := map[string]string{"quic.example": "HTTP/3.0", "tcp.example": "HTTP/2.0"}
+	if !reflect.DeepEqual(h.Protocols, want) || h.HTTP3Fallbacks != 1 {
+		t.Fatalf("health = %+v", h)
+	}
+	if f, err := c.getResponse(WithFreshData(ctx), "https://quic.example/domain/x.example"); err != nil || f.meta("").Proto != "HTTP/3.0" {
+		t.Fatalf("meta proto = %q %v", f.proto, err)
+	}
+}
+
+func TestHTTP3_FollowsRedirectPolicyAndSkipsProxy(t *testing.T) {
+	hops := 0
+	h3 := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hops++
+		resp := staticResponse("")
+		resp.StatusCode, resp.Proto = http.StatusFound, "HTTP/3.0"
+		resp.Header.Set("Location", fmt.Sprintf("/domain/x%d.example", hops))
+		return resp, nil
+	})
+	tcp := doerFunc(func(r *http.Request) (*http.Response, error) { return nil, errors.New("unexpected fallback") })
+	c := New(WithHTTPDoer(tcp), withHTTP3Transport(h3), WithMaxRedirects(2), WithMaxRetries(0))
+	_, _, err := c.getJSON(context.Background(), "https://quic.example/domain/x.example")
+	var hl *ErrHopLimit
+	if !errors.As(err, &hl) || hops != 3 {
+		t.Fatalf("want the redirect limit over HTTP/3 after 3 requests, got %d: %v", hops, err)
+	}
+
+	proxy, _ := url.Parse("http://proxy.example:3128")
+	if c := New(WithHTTPProxy(proxy), withHTTP3Transport(h3)); c.h3 != nil {
+		t.Fatal("HTTP/3 must not bypass WithHTTPProxy")
+	}
+}
+
+func staticResponse(body string) *http.Response {
+	rec := httptest.NewRecorder()
+	_, _ = io.WriteString(rec, body)
+	return rec.Result()
+}
+
+func TestPrewarm_GroupsQueriesByRegistryHost(t *testing.T) {
+	var (
+		srvURL string
+		heads  int
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/rdap/"]]]}`, srvURL))
+		case r.Method == http.MethodHead:
+			heads++
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithBootstrapURL(ts.URL + "/dns.json"))
+
+	got := c.Prewarm(context.Background(), []string{"a.example", "b.example"})
+	if len(got) != 1 || got[0].Host != strings.TrimPrefix(ts.URL, "http://") || got[0].Queries != 2 || got[0].Err != "" {
+		t.Fatalf("prewarm = %+v", got)
+	}
+	if heads != 1 {
+		t.Fatalf("want one HEAD per host, got %d", heads)
+	}
+	if r := New(WithReplay(t.TempDir())).Prewarm(context.Background(), []string{"a.example"}); r != nil {
+		t.Fatalf("replay should not prewarm: %+v", r)
+	}
+}
+
+func TestCanonicalCache_EquivalentIPQueries(t *testing.T) {
+	var srvURL string
+	hits := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ipv4.json" {
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["192.0.2.0/24","198.51.100.0/24"],["%s/"]]]}`, srvURL))
+			return
+		}
+		hits[r.URL.Path]++
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/ip/192.0.2."):
+			_, _ = io.WriteString(w, `{"objectClassName":"ip network","handle":"NET-1","startAddress":"192.0.2.0","endAddress":"192.0.2.255"}`)
+		case strings.HasPrefix(r.URL.Path, "/ip/198.51.100."):
+			_, _ = io.WriteString(w, `{"objectClassName":"ip network","handle":"NET-2","startAddress":"198.51.100.0","endAddress":"198.51.100.255"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	ctx := context.Background()
+
+	c := New(WithIPBootstrapURL(ts.URL+"/ipv4.json"), WithCanonicalCache(16))
+	if _, err := c.IP(ctx, "192.0.2.0/24"); err != nil {
+		t.Fatal(err)
+	}
+	n, err := c.IP(ctx, "192.0.2.1")
+	if err != nil || n.Handle != "NET-1" {
+		t.Fatalf("canonical hit: %v %+v", err, n)
+	}
+	if hits["/ip/192.0.2.1"] != 0 {
+		t.Fatalf("address inside a cached network should not be fetched")
+	}
+
+	// The reverse direction: an address lookup caches the network under its CIDR URL.
+	if _, err := c.IP(ctx, "198.51.100.7"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.IP(ctx, "198.51.100.0/24"); err != nil {
+		t.Fatal(err)
+	}
+	if hits["/ip/198.51.100.0/24"] != 0 {
+		t.Fatalf("CIDR of a cached network should not be fetched")
+	}
+
+	plain := New(WithIPBootstrapURL(ts.URL + "/ipv4.json"))
+	_, _ = plain.IP(ctx, "192.0.2.0/24")
+	_, _ = plain.IP(ctx, "192.0.2.1")
+	if hits["/ip/192.0.2.1"] != 1 {
+		t.Fatalf("without the canonical cache each URL is fetched")
+	}
+}
+
+func TestRedirect_AliasesOriginalAndFinalURL(t *testing.T) {
+	hits := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/agg/domain/x.example":
+			http.Redirect(w, r, "/auth/domain/x.example", http.StatusFound)
+		case "/auth/domain/x.example":
+			_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"x.example","links":[{"rel":"self","href":"https://self.example/domain/x.example"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	ctx := context.Background()
+
+	c := New(WithCanonicalCache(8))
+	if _, err := c.fetchObject(ctx, ts.URL+"/agg/domain/x.example", "domain"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.getJSON(ctx, ts.URL+"/auth/domain/x.example"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.getJSON(ctx, ts.URL+"/agg/domain/x.example"); err != nil {
+		t.Fatal(err)
+	}
+	if hits["/agg/domain/x.example"] != 1 || hits["/auth/domain/x.example"] != 1 {
+		t.Fatalf("original and final URL should both be cached: %v", hits)
+	}
+	for _, u := range []string{ts.URL + "/auth/domain/x.example", "https://self.example/domain/x.example"} {
+		if _, ok := c.canon.get(u); !ok {
+			t.Fatalf("canonical cache should resolve %s", u)
+		}
+	}
+}
+
+func TestDataset_AnsweredBeforeNetwork(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mirror.jsonl")
+	lines := []string{
+		`{"objectClassName":"domain","ldhName":"xn--bcher-kva.example","unicodeName":"bücher.example"}`,
+		`{"query":"ORG-1","object":{"objectClassName":"entity","handle":"ORG-1"},"attempts":1}`,
+		`not json`,
+		`{"objectClassName":"ip network","handle":"NET-16","startAddress":"10.0.0.0","endAddress":"10.0.255.255"}`,
+		`{"objectClassName":"ip network","handle":"NET-24","startAddress":"10.0.1.0","endAddress":"10.0.1.255"}`,
+		`{"objectClassName":"autnum","handle":"AS64496","startAutnum":64496,"endAutnum":64511}`,
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := OpenDataset(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+	if ds.Len() != 5 {
+		t.Fatalf("Len = %d", ds.Len())
+	}
+
+	offline := doerFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("network used for %s", r.URL)
+	})
+	c := New(WithHTTPDoer(offline), WithMaxRetries(0), WithDataset(ds))
+	ctx := context.Background()
+	if d, err := c.Domain(ctx, "Bücher.example"); err != nil || d.LDHName != "xn--bcher-kva.example" {
+		t.Fatalf("domain: %v %+v", err, d)
+	}
+	if e, err := c.Entity(ctx, "org-1", ""); err != nil || e.Handle != "ORG-1" {
+		t.Fatalf("entity from batch line: %v %+v", err, e)
+	}
+	if n, err := c.IP(ctx, "10.0.1.9"); err != nil || n.Handle != "NET-24" {
+		t.Fatalf("narrowest network: %v %+v", err, n)
+	}
+	if n, err := c.IP(ctx, "10.0.0.0/23"); err != nil || n.Handle != "NET-16" {
+		t.Fatalf("prefix spanning both: %v %+v", err, n)
+	}
+	if a, err := c.Autnum(ctx, "AS64500"); err != nil || a.Handle != "AS64496" {
+		t.Fatalf("autnum range: %v %+v", err, a)
+	}
+	if _, err := c.Domain(ctx, "missing.example"); err == nil {
+		t.Fatalf("objects outside the dataset go to the network")
+	}
+}
+
+func TestDataset_NestedRangesAndLen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mirror.jsonl")
+	lines := []string{
+		// Two keys, one object.
+		`{"objectClassName":"domain","handle":"D1","ldhName":"a.example","unicodeName":"alias.example"}`,
+		`{"objectClassName":"ip network","handle":"V4-8","startAddress":"10.0.0.0","endAddress":"10.255.255.255"}`,
+		`{"objectClassName":"ip network","handle":"V6","startAddress":"2001:db8::","endAddress":"2001:db8::ffff"}`,
+		`{"objectClassName":"autnum","handle":"AS-OLD","startAutnum":64500,"endAutnum":64500}`,
+		`{"objectClassName":"autnum","handle":"AS-NEW","startAutnum":64500,"endAutnum":64500}`,
+		`{"objectClassName":"autnum","handle":"AS-BLOCK","startAutnum":64496,"endAutnum":64511}`,
+	}
+	// Sibling /24s under the /8, and a /28 inside the first.
+	for i := 0; i < 200; i++ {
+		lines = append(lines, fmt.Sprintf(`{"objectClassName":"ip network","handle":"V4-%d","startAddress":"10.0.%d.0","endAddress":"10.0.%d.255"}`, i, i, i))
+	}
+	lines = append(lines, `{"objectClassName":"ip network","handle":"V4-28","startAddress":"10.0.0.16","endAddress":"10.0.0.31"}`)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := OpenDataset(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+	if want := 1 + 2 + 2 + 200 + 1; ds.Len() != want {
+		t.Fatalf("Len = %d, want %d", ds.Len(), want)
+	}
+
+	for _, tc := range []struct {
+		kind     Kind
+		id, want string
+	}{
+		{KindIP, "10.0.0.20", "V4-28"},
+		{KindIP, "10.0.0.40", "V4-0"},
+		{KindIP, "10.0.199.1", "V4-199"},
+		{KindIP, "10.0.0.0/23", "V4-8"},
+		{KindIP, "10.0.200.1", "V4-8"},
+		{KindIP, "2001:db8::1", "V6"},
+		{KindAutnum, "AS64500", "AS-NEW"}, // a later line replaces an earlier one
+		{KindAutnum, "64501", "AS-BLOCK"},
+		{KindIP, "11.0.0.1", ""},
+		{KindIP, "2001:db8::1:0", ""},
+		{KindAutnum, "AS64512", ""},
+	} {
+		obj, err := ds.Get(tc.kind, tc.id)
+		if tc.want == "" {
+			if !errors.Is(err, ErrNotInDataset) {
+				t.Errorf("%s: want ErrNotInDataset, got %v", tc.id, err)
+			}
+			continue
+		}
+		handle := ""
+		switch v := obj.(type) {
+		case *IPNetwork:
+			handle = v.Handle
+		case *Autnum:
+			handle = v.Handle
+		}
+		if err != nil || handle != tc.want {
+			t.Errorf("%s: got %q %v, want %s", tc.id, handle, err, tc.want)
+		}
+	}
+}
+
+func TestParseAllocationType(t *testing.T) {
+	for raw, want := range map[string]AllocationType{
+		"":                       AllocationNone,
+		"DIRECT ALLOCATION":      AllocationAllocated,
+		"ALLOCATED PA":           AllocationAllocated,
+		"ALLOCATED PORTABLE":     AllocationAllocated,
+		"REALLOCATION":           AllocationSubAllocated,
+		"SUB-ALLOCATED PA":       AllocationSubAllocated,
+		"ALLOCATED NON-PORTABLE": AllocationSubAllocated,
+		"DIRECT ASSIGNMENT":      AllocationAssigned,
+		"assigned_pi":            AllocationAssigned,
+		"ASSIGNED ANYCAST":       AllocationAssigned,
+		"LEGACY":                 AllocationLegacy,
+		"IANA Reserved":          AllocationReserved,
+		"AVAILABLE":              AllocationAvailable,
+		"EXPERIMENTAL":           AllocationOther,
+	} {
+		if got := ParseAllocationType(raw); got != want {
+			t.Errorf("ParseAllocationType(%q) = %q, want %q", raw, got, want)
+		}
+	}
+	n := &IPNetwork{Type: "ASSIGNED PA", Country: " uk"}
+	if n.AllocationType() != AllocationAssigned || n.Type != "ASSIGNED PA" || n.CountryCode() != "GB" {
+		t.Fatalf("network: %q %q %q", n.AllocationType(), n.Type, n.CountryCode())
+	}
+	if got := NormalizeCountry("Germany"); got != "" {
+		t.Fatalf("NormalizeCountry(Germany) = %q", got)
+	}
+}
+
+func TestDomain_RegistrarAndEventTime(t *testing.T) {
+	var d Domain
+	if err := json.Unmarshal([]byte(`{
+		"objectClassName": "domain", "ldhName": "example.com",
+		"events": [
+			{"eventAction": "registration", "eventDate": "1995-08-14T04:00:00Z"},
+			{"eventAction": "Expiration", "eventDate": "2030-08-13"}
+		],
+		"entities": [
+			{"objectClassName": "entity", "handle": "T1", "roles": ["technical"]},
+			{"objectClassName": "entity", "handle": "376", "roles": ["registrar"],
+			 "publicIds": [{"type": "IANA Registrar ID", "identifier": "376"}],
+			 "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "RESERVED-Internet Assigned Numbers Authority"]]]}
+		]
+	}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	r := d.Registrar()
+	if r == nil || r.RegistrarName() != "RESERVED-Internet Assigned Numbers Authority" || r.IANARegistrarID() != "376" {
+		t.Fatalf("registrar: %+v", r)
+	}
+	exp, ok := d.EventTime(EventExpiration)
+	if !ok || !exp.Equal(time.Date(2030, 8, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expiration: %v %v", exp, ok)
+	}
+	if _, ok := d.EventTime(EventTransfer); ok {
+		t.Fatal("transfer: want no event")
+	}
+	if !d.Expiration().Equal(exp) || d.Registration().Year() != 1995 || !d.LastChanged().IsZero() {
+		t.Fatalf("event shorthands: %v %v %v", d.Registration(), d.Expiration(), d.LastChanged())
+	}
+	if reg, ok := d.RegistrationDate(); !ok || !reg.Equal(time.Date(1995, 8, 14, 4, 0, 0, 0, time.UTC)) {
+		t.Fatalf("RegistrationDate: %v %v", reg, ok)
+	}
+	if _, ok := d.LastChangedDate(); ok {
+		t.Fatal("LastChangedDate: want no event")
+	}
+	if et, ok := d.Events[0].Time(); !ok || et.Year() != 1995 {
+		t.Fatalf("Event.Time: %v %v", et, ok)
+	}
+
+	// Registrars report their own expiration when the registry has none.
+	d.Events = []Event{{EventAction: "registrar expiration", EventDate: "2031-01-02T00:00:00Z"}, {EventAction: "last changed", EventDate: "garbage"}}
+	if e, ok := d.ExpirationDate(); !ok || e.Year() != 2031 {
+		t.Fatalf("ExpirationDate fallback: %v %v", e, ok)
+	}
+	if _, ok := d.LastChangedDate(); ok {
+		t.Fatal("LastChangedDate: unparseable date should report false")
+	}
+}
+
+func TestBootstrapRefresh_SingleFlightServesStale(t *testing.T) {
+	var hits atomic.Int32
+	entered := make(chan struct{}, 4)
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) > 1 {
+			entered <- struct{}{}
+			<-release
+		}
+		_, _ = io.WriteString(w, `{"services":[[["com"],["https://rdap.example/v1/"]]]}`)
+	}))
+	defer s.Close()
+
+	c := New(WithBootstrapURL(s.URL))
+	now := time.Now()
+	c.dnsBases.now = func() time.Time { return now }
+	ctx := context.Background()
+	if _, err := c.rdapBaseForTLD(ctx, "com"); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(6*time.Hour + 30*time.Minute) // past the 6h TTL, within the grace period
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.rdapBaseForTLD(ctx, "com")
+		done <- err
+	}()
+	<-entered
+	for i := 0; i < 5; i++ {
+		if got, err := c.rdapBaseForTLD(ctx, "com"); err != nil || got != "https://rdap.example/v1" {
+			t.Fatalf("stale read during refresh: %q %v", got, err)
+		}
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Fatalf("bootstrap fetched %d times, want 2", n)
+	}
+}
+
+func TestBootstrap_KeepsEveryTLDPastCacheCap(t *testing.T) {
+	var services []string
+	for i := 0; i < 300; i++ {
+		services = append(services, fmt.Sprintf(`[["t%d"],["https://rdap%d.example/"]]`, i, i%7))
+	}
+	var dnsHits, ipHits atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ipv4.json" {
+			ipHits.Add(1)
+			_, _ = io.WriteString(w, `{"services":[[["192.0.2.0/24"],["https://rdap-ip.example/"]]]}`)
+			return
+		}
+		dnsHits.Add(1)
+		_, _ = io.WriteString(w, `{"services":[`+strings.Join(services, ",")+`]}`)
+	}))
+	defer s.Close()
+
+	// The base cache for IP, ASN and tag keys holds 8; the 300 TLDs must not
+	// compete with those keys or with each other.
+	c := New(WithBootstrapURL(s.URL+"/dns.json"), WithIPBootstrapURL(s.URL+"/ipv4.json"), WithCacheSizes(8, 0))
+	ctx := context.Background()
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 300; i++ {
+			got, err := c.rdapBaseForTLD(ctx, fmt.Sprintf("t%d", i))
+			if want := fmt.Sprintf("https://rdap%d.example", i%7); err != nil || got != want {
+				t.Fatalf("t%d: %q %v, want %q", i, got, err, want)
+			}
+			if i%10 == 0 {
+				if _, err := c.resolveBaseFromBootstrapIP(ctx, fmt.Sprintf("192.0.2.%d", i%256)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	if n := dnsHits.Load(); n != 1 {
+		t.Fatalf("DNS bootstrap fetched %d times, want 1", n)
+	}
+	if c.dnsBases.Len() != 300 || c.rdapBaseCache.Len() > 8 {
+		t.Fatalf("dns bases %d, base cache %d", c.dnsBases.Len(), c.rdapBaseCache.Len())
+	}
+}
+
+func TestTTLCache_JitterShortensOnly(t *testing.T) {
+	c := newTTLCache[int](time.Hour, 100)
+	c.jitter = 0.1
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	for i := 0; i < 50; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	for el := c.ll.Front(); el != nil; el = el.Next() {
+		d := el.Value.(ttlItem[int]).expires.Sub(now)
+		if d > time.Hour || d < 54*time.Minute {
+			t.Fatalf("ttl %v outside [54m, 1h]", d)
+		}
+	}
+}
+
+func TestBootstrap_SoftTTLServesStaleWhileIANADown(t *testing.T) {
+	var hits atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) > 1 {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, `{"services":[[["com"],["https://rdap.example/v1/"]]]}`)
+	}))
+	defer s.Close()
+
+	c := New(WithBootstrapURL(s.URL), WithBootstrapTTL(time.Hour, 24*time.Hour), WithDefaultRDAPBase("https://fallback.example"), WithMaxRetries(0))
+	var mu sync.Mutex
+	now := time.Now()
+	c.dnsBases.now = func() time.Time { mu.Lock(); defer mu.Unlock(); return now }
+	advance := func(d time.Duration) { mu.Lock(); now = now.Add(d); mu.Unlock() }
+	ctx := context.Background()
+	if _, err := c.rdapBaseForTLD(ctx, "com"); err != nil {
+		t.Fatal(err)
+	}
+
+	advance(2 * time.Hour)
+	if got, err := c.rdapBaseForTLD(ctx, "com"); err != nil || got != "https://rdap.example/v1" {
+		t.Fatalf("past soft TTL: %q %v", got, err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for hits.Load() < 2 || c.flights.InFlight(c.bootstrapURL+"#force") {
+		if time.Now().After(deadline) {
+			t.Fatal("no background refresh")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// The failed refresh keeps the stale base.
+	if got, _ := c.rdapBaseForTLD(ctx, "com"); got != "https://rdap.example/v1" {
+		t.Fatalf("after failed refresh: %q", got)
+	}
+
+	advance(24 * time.Hour)
+	if got, _ := c.rdapBaseForTLD(ctx, "com"); got != "https://fallback.example" {
+		t.Fatalf("past hard TTL: %q", got)
+	}
+}
+
+func TestTTLCache_ResizeEvictsAndStats(t *testing.T) {
+	c := newTTLCache[int](time.Hour, 10)
+	for i := 0; i < 10; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	c.Get("0") // most recently used survives the shrink
+	c.Resize(3)
+	if c.Len() != 3 {
+		t.Fatalf("len after resize = %d", c.Len())
+	}
+	if _, ok := c.Get("0"); !ok {
+		t.Fatal("recently used entry evicted")
+	}
+	if _, ok := c.Get("1"); ok {
+		t.Fatal("LRU entry kept")
+	}
+	c.Delete("0")
+	if _, ok := c.Get("0"); ok || c.Len() != 2 {
+		t.Fatalf("delete: len=%d", c.Len())
+	}
+	st := c.Stats()
+	if st.Cap != 3 || st.Len != 2 || st.Hits != 2 || st.Misses != 2 || st.Evictions != 7 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestWithFreshData_RevalidatesCachedResponse(t *testing.T) {
+	var hits, conditional atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"example.com"}`)
+	}))
+	defer s.Close()
+
+	c := New()
+	ctx := context.Background()
+	u := s.URL + "/domain/example.com"
+	for i := 0; i < 2; i++ {
+		if _, _, err := c.getJSON(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("cached lookups hit the server %d times", hits.Load())
+	}
+	m, _, err := c.getJSON(WithFreshData(ctx), u)
+	if err != nil || m["ldhName"] != "example.com" {
+		t.Fatalf("fresh lookup: %v %v", m, err)
+	}
+	if hits.Load() != 2 || conditional.Load() != 1 {
+		t.Fatalf("fresh lookup: hits=%d conditional=%d", hits.Load(), conditional.Load())
+	}
+}
+
+func TestGetJSON_ConcurrentCallersShareRetries(t *testing.T) {
+	var hits atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= 2 {
+			time.Sleep(20 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"example.com"}`)
+	}))
+	defer s.Close()
+
+	c := New(WithMaxRetries(3), WithBackoff(func(int) time.Duration { return 10 * time.Millisecond }))
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m, _, err := c.getJSON(context.Background(), s.URL+"/domain/example.com")
+			if err == nil && m["ldhName"] != "example.com" {
+				err = fmt.Errorf("got %v", m)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := hits.Load(); n != 3 {
+		t.Fatalf("server saw %d requests, want 3 (one retry sequence)", n)
+	}
+}
+
+func TestDegradedHost_LimitsConcurrentAttempts(t *testing.T) {
+	var inflight, peak atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/domain/down.example" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		_, _ = io.WriteString(w, `{"objectClassName":"domain"}`)
+	}))
+	defer s.Close()
+
+	c := New(WithMaxRetries(0), WithDegradedHostConcurrency(2))
+	ctx := context.Background()
+	if _, _, err := c.getJSON(ctx, s.URL+"/domain/down.example"); err == nil {
+		t.Fatal("want 503 error")
+	}
+	host := strings.TrimPrefix(s.URL, "http://")
+	if h := c.Health().DegradedHosts; len(h) != 1 || h[0] != host {
+		t.Fatalf("degraded hosts = %v", h)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, _ = c.getJSON(ctx, fmt.Sprintf("%s/domain/d%d.example", s.URL, i))
+		}(i)
+	}
+	wg.Wait()
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("peak concurrency against degraded host = %d, want <= 2", p)
+	}
+	if h := c.Health().DegradedHosts; len(h) != 0 {
+		t.Fatalf("still degraded after successes: %v", h)
+	}
+}
+
+func TestBatchError_JSONAndUnwrap(t *testing.T) {
+	var b BatchError
+	if b.Err() != nil {
+		t.Fatal("empty batch should be nil")
+	}
+	b.Add("example.com", "", 3, &HTTPError{URL: "https://rdap.example/domain/example.com", StatusCode: 429, Status: "429 Too Many Requests", RetryAfter: 90 * time.Second})
+	b.Add("AS64496", "rdap.arin.example", 1, &ErrLookupFailed{Query: "AS64496"})
+	b.Add("ignored", "", 1, nil)
+
+	var he *HTTPError
+	if err := b.Err(); err == nil || !errors.As(err, &he) || he.StatusCode != 429 {
+		t.Fatalf("errors.As through BatchError: %v", err)
+	}
+	out, err := json.Marshal(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Errors []map[string]any `json:"errors"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil || len(got.Errors) != 2 {
+		t.Fatalf("json: %s %v", out, err)
+	}
+	first := got.Errors[0]
+	if first["code"] != CodeRateLimited || first["registry"] != "rdap.example" || first["retryAfter"] != 90.0 || first["attempts"] != 3.0 || first["status"] != 429.0 {
+		t.Fatalf("first item: %v", first)
+	}
+	if got.Errors[1]["code"] != CodeLookupFailed {
+		t.Fatalf("second item: %v", got.Errors[1])
+	}
+	if !strings.Contains(b.Error(), "2 items failed (1 rate_limited, 1 lookup_failed)") {
+		t.Fatalf("message: %s", b.Error())
+	}
+}
+
+func TestWithLogger_RetriesAtInfoCacheHitsAtDebug(t *testing.T) {
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits++; hits == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"ok.example"}`)
+	}))
+	defer ts.Close()
+
+	logs := func(level slog.Level) string {
+		var buf bytes.Buffer
+		c := New(WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level}))))
+		c.backoff = func(int) time.Duration { return 0 }
+		hits = 0
+		for range 2 {
+			if _, _, err := c.getJSON(context.Background(), ts.URL+"/x"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return buf.String()
+	}
+
+	info := logs(slog.LevelInfo)
+	if !strings.Contains(info, `msg="rdap retry"`) || !strings.Contains(info, "status=502") {
+		t.Fatalf("info log missing retry: %s", info)
+	}
+	if strings.Contains(info, "cache hit") {
+		t.Fatalf("debug record at info level: %s", info)
+	}
+	debug := logs(slog.LevelDebug)
+	if !strings.Contains(debug, `msg="rdap cache hit"`) || !strings.Contains(debug, "status=200") {
+		t.Fatalf("debug log missing request or cache hit: %s", debug)
+	}
+	if New().log == nil {
+		t.Fatal("default logger is nil")
+	}
+}
+
+func TestAutnum_Organization(t *testing.T) {
+	var a Autnum
+	if err := json.Unmarshal([]byte(`{
+		"objectClassName": "autnum", "handle": "AS15169", "startAutnum": 15169, "endAutnum": 15169,
+		"entities": [
+			{"objectClassName": "entity", "handle": "ABUSE5250-ARIN", "roles": ["abuse"]},
+			{"objectClassName": "entity", "handle": "GOGL", "roles": ["registrant"],
+			 "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Google LLC"], ["kind", {}, "text", "org"]]]}
+		]
+	}`), &a); err != nil {
+		t.Fatal(err)
+	}
+	org := a.Organization()
+	if org == nil || org.Handle != "GOGL" || org.OrgName() != "Google LLC" {
+		t.Fatalf("organization: %+v", org)
+	}
+	a.Entities[1].Roles = []string{"administrative"}
+	if org := a.Organization(); org == nil || org.Handle != "GOGL" {
+		t.Fatalf("organization by vCard kind: %+v", org)
+	}
+	a.Entities = a.Entities[:1]
+	if org := a.Organization(); org != nil {
+		t.Fatalf("want no organization, got %+v", org)
+	}
+}
+
+func TestSiblingAutnums_PivotThroughRegistrant(t *testing.T) {
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/asn.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["1-400000"],["%s/"]]]}`, srvURL))
+		case "/autnum/15169":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"objectClassName":"autnum","handle":"AS15169","startAutnum":15169,"endAutnum":15169,
+				"entities":[{"objectClassName":"entity","handle":"ZG39-ARIN","roles":["technical"]},
+				{"objectClassName":"entity","handle":"GOGL","roles":["registrant"],"links":[{"rel":"self","href":"%s/entity/GOGL"}]}]}`, srvURL))
+		case "/entity/GOGL":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"objectClassName":"entity","handle":"GOGL",
+				"links":[{"rel":"self","href":"%[1]s/entity/GOGL"},{"rel":"related","href":"%[1]s/autnums?handle=GOGL"}]}`, srvURL))
+		case "/autnums":
+			_, _ = io.WriteString(w, `{"autnumSearchResults":[
+				{"objectClassName":"autnum","handle":"AS15169","startAutnum":15169,"endAutnum":15169},
+				{"objectClassName":"autnum","handle":"AS36040","startAutnum":36040,"endAutnum":36040},
+				{"objectClassName":"autnum","handle":"AS396982","startAutnum":396982,"endAutnum":396982}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithASNBootstrapURL(ts.URL + "/asn.json"))
+
+	got, err := c.SiblingAutnums(context.Background(), "AS15169")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Autnum.Handle != "AS36040" || got[1].Autnum.Handle != "AS396982" {
+		t.Fatalf("siblings: %+v", got)
+	}
+	if s := got[0]; s.Via != "GOGL" || s.Role != "registrant" || s.Source != ts.URL+"/autnums?handle=GOGL" {
+		t.Fatalf("provenance: %+v", s)
+	}
+}
+
+func TestMaxNestingDepth_RejectsDeepEntities(t *testing.T) {
+	deep := `{"objectClassName":"entity","handle":"E0"}`
+	for i := 1; i <= 200; i++ {
+		deep = fmt.Sprintf(`{"objectClassName":"entity","handle":"E%d","entities":[%s]}`, i, deep)
+	}
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, deep)
+	}))
+	defer ts.Close()
+
+	c := New()
+	for range 2 {
+		_, err := c.fetchObject(context.Background(), ts.URL+"/entity/E200", "entity")
+		var de *ErrNestingTooDeep
+		if !errors.As(err, &de) || de.Limit != DefaultMaxNestingDepth || ErrorCode(err) != CodeNestingTooDeep {
+			t.Fatalf("want *ErrNestingTooDeep, got %v", err)
+		}
+	}
+	if hits != 2 {
+		t.Fatalf("a rejected response must not be cached: %d hits", hits)
+	}
+
+	obj, err := New(WithMaxNestingDepth(0)).fetchObject(context.Background(), ts.URL+"/entity/E200", "entity")
+	if err != nil || obj.(*Entity).Handle != "E200" {
+		t.Fatalf("unlimited: %v", err)
+	}
+}
+
+func TestNestedDeeper_IgnoresBracketsInStrings(t *testing.T) {
+	for _, tc := range []struct {
+		in    string
+		limit int
+		want  bool
+	}{
+		{`{"a":[1,{"b":[]}]}`, 4, false},
+		{`{"a":[1,{"b":[]}]}`, 3, true},
+		{`{"a":"[[[[{{{{"}`, 1, false},
+		{`{"a":"\"[[[","b":"\\"}`, 1, false},
+		{`[[]]`, 1, true},
+	} {
+		if got := nestedDeeper([]byte(tc.in), tc.limit); got != tc.want {
+			t.Errorf("nestedDeeper(%s, %d) = %v, want %v", tc.in, tc.limit, got, tc.want)
+		}
+	}
+}
+
+func TestResponseSize_AnomalyFlaggedAndCapRejected(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remarks := ""
+		if strings.HasPrefix(r.URL.Path, "/big/") {
+			remarks = `,"remarks":[{"description":["` + strings.Repeat("x", 200<<10) + `"]}]`
+		}
+		_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"a.example"`+remarks+`}`)
+	}))
+	defer ts.Close()
+	ctx := context.Background()
+
+	warm := func(c *Client) {
+		for i := range sizeMinSamples {
+			if _, _, err := c.getJSON(ctx, fmt.Sprintf("%s/small/%d", ts.URL, i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	c := New()
+	warm(c)
+	if _, _, err := c.getJSON(ctx, ts.URL+"/big/1"); err != nil {
+		t.Fatalf("flag only: %v", err)
+	}
+	an := c.Health().SizeAnomalies
+	if len(an) != 1 || an[0].URL != ts.URL+"/big/1" || an[0].Rejected || an[0].Typical == 0 || an[0].Bytes < 200<<10 {
+		t.Fatalf("anomalies: %+v", an)
+	}
+
+	c = New(WithSizeAnomalies(DefaultSizeAnomalyFactor, true))
+	warm(c)
+	_, _, err := c.getJSON(ctx, ts.URL+"/big/2")
+	var te *ErrResponseTooLarge
+	if !errors.As(err, &te) || te.Typical == 0 || ErrorCode(err) != CodeTooLarge {
+		t.Fatalf("reject: want *ErrResponseTooLarge, got %v", err)
+	}
+
+	c = New(WithMaxResponseSize(100 << 10))
+	_, _, err = c.getJSON(ctx, ts.URL+"/big/3")
+	if !errors.As(err, &te) || te.Limit != 100<<10 || te.Typical != 0 {
+		t.Fatalf("cap: want *ErrResponseTooLarge, got %v", err)
+	}
+	if an := c.Health().SizeAnomalies; len(an) != 1 || !an[0].Rejected {
+		t.Fatalf("cap anomaly: %+v", an)
+	}
+}
+
+func TestLinkPolicy_BlocksSSRFTargets(t *testing.T) {
+	c := New()
+	c.registries.add("https://rdap.verisign.com/com/v1/")
+	from := "https://rdap.nic.example.co.uk/domain/a.example.co.uk"
+
+	for href, ok := range map[string]bool{
+		"https://rdap.verisign.com/com/v1/domain/a.com":     true,  // bootstrap registry
+		"https://rdap.org/entity/X":                         true,  // default base
+		"https://whois.example.co.uk/entity/X":              true,  // same registrable domain
+		"https://rdap.other.co.uk/entity/X":                 false, // unknown host
+		"http://rdap.nic.example.co.uk/entity/X":            false, // http
+		"https://127.0.0.1/entity/X":                        false,
+		"https://10.1.2.3/entity/X":                         false,
+		"https://[fe80::1]/entity/X":                        false,
+		"https://localhost/entity/X":                        false,
+		"file:///etc/passwd":                                false,
+		"gopher://rdap.nic.example.co.uk/entity/X":          false,
+		"https://169.254.169.254/latest/meta-data/entity/X": false,
+	} {
+		if err := c.CheckLink(from, href); (err == nil) != ok {
+			t.Errorf("CheckLink(%s) = %v, want allowed=%v", href, err, ok)
+		}
+	}
+
+	_, err := c.FollowLinkFrom(context.Background(), from, Link{Href: "http://10.0.0.1/entity/X"})
+	var lb *ErrLinkBlocked
+	if !errors.As(err, &lb) || lb.From != from || ErrorCode(err) != CodeLinkBlocked {
+		t.Fatalf("FollowLinkFrom: want *ErrLinkBlocked, got %v", err)
+	}
+	// The link's own value is the server's word, not where it was fetched.
+	_, err = c.FollowLink(context.Background(), Link{Value: from, Href: "https://whois.example.co.uk/entity/X"})
+	if !errors.As(err, &lb) || lb.From != "" {
+		t.Fatalf("FollowLink by value: want *ErrLinkBlocked, got %v", err)
+	}
+
+	c = New(WithLinkPolicy(LinkPolicy{AllowHTTP: true, AllowPrivateIPs: true, Hosts: []string{".internal.example"}}))
+	if err := c.CheckLink("", "http://rdap.internal.example/entity/X"); err != nil {
+		t.Fatalf("listed host: %v", err)
+	}
+	if err := c.CheckLink("", "http://10.0.0.1/entity/X"); err == nil {
+		t.Fatal("private IP allowed but host not listed: want blocked")
+	}
+}
+
+func TestSearchDomains_BootstrapBaseAndParams(t *testing.T) {
+	var srvURL string
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/reg/"]]]}`, srvURL))
+		case "/reg/domains", "/fallback/domains":
+			queries = append(queries, r.URL.Path+"?"+r.URL.RawQuery)
+			_, _ = io.WriteString(w, `{"rdapConformance":["rdap_level_0"],"notices":[{"title":"Search Policy","description":["truncated"]}],
+				"domainSearchResults":[{"objectClassName":"domain","ldhName":"one.example"},{"objectClassName":"domain","ldhName":"two.example"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithDefaultRDAPBase(ts.URL+"/fallback"))
+	ctx := context.Background()
+
+	res, err := c.SearchDomains(ctx, DomainSearchParams{Name: "O*.Example."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Domains) != 2 || res.Domains[1].LDHName != "two.example" || len(res.Notices) != 1 || res.Notices[0].Title != "Search Policy" {
+		t.Fatalf("results: %+v", res)
+	}
+	if _, err := c.SearchDomains(ctx, DomainSearchParams{NSLDHName: "ns1.*.example"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SearchDomains(ctx, DomainSearchParams{NSIP: "2001:DB8::1"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/reg/domains?name=o%2A.example", "/reg/domains?nsLdhName=ns1.%2A.example", "/fallback/domains?nsIp=2001%3Adb8%3A%3A1"}
+	if !reflect.DeepEqual(queries, want) {
+		t.Fatalf("queries = %q, want %q", queries, want)
+	}
+
+	for _, p := range []DomainSearchParams{{}, {Name: "a.example", NSIP: "192.0.2.1"}, {NSIP: "not-an-ip"}} {
+		if _, err := c.SearchDomains(ctx, p); err == nil {
+			t.Errorf("SearchDomains(%+v): want error", p)
+		}
+	}
+}
+
+func TestLinkHops_RedirectAndCrossHostLimits(t *testing.T) {
+	redirects := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/r/"))
+		http.Redirect(w, r, fmt.Sprintf("/r/%d", n+1), http.StatusFound)
+	}))
+	defer redirects.Close()
+	entity := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"objectClassName":"entity","handle":"`+filepath.Base(r.URL.Path)+`"}`)
+	})
+	a, b := httptest.NewServer(entity), httptest.NewServer(entity)
+	defer a.Close()
+	defer b.Close()
+
+	c := New(WithMaxRedirects(2), WithMaxLinkHops(2), WithMaxRetries(0), WithLinkPolicy(LinkPolicy{Hosts: []string{"127.0.0.1"}}))
+	ctx, trail := WithHopTrail(context.Background())
+
+	_, _, err := c.getJSON(ctx, redirects.URL+"/r/0")
+	var hl *ErrHopLimit
+	if !errors.As(err, &hl) || !hl.Redirect || len(hl.Hops) != 3 || ErrorCode(err) != CodeHopLimit {
+		t.Fatalf("redirects: want *ErrHopLimit, got %v", err)
+	}
+	if hops := trail.Hops(); len(hops) != 3 || hops[0].To != redirects.URL+"/r/1" || !hops[0].Redirect {
+		t.Fatalf("redirect trail: %+v", hops)
+	}
+
+	// Each hop starts where its document was fetched. A link's value is the
+	// server's word and does not make a cross-host hop a same-host one.
+	ctx, trail = WithHopTrail(context.Background())
+	from := a.URL + "/entity/A"
+	for _, l := range []Link{
+		{Value: b.URL + "/entity/A", Href: b.URL + "/entity/B"},
+		{Href: b.URL + "/entity/B2"}, // same host: free
+		{Href: a.URL + "/entity/A"},
+	} {
+		obj, err := c.FollowLinkFrom(ctx, from, l)
+		if err != nil {
+			t.Fatalf("follow %s: %v", l.Href, err)
+		}
+		from = obj.(*Entity).Meta.FinalURL
+	}
+	_, err = c.FollowLinkFrom(ctx, from, Link{Value: b.URL + "/entity/A", Href: b.URL + "/entity/B"})
+	if !errors.As(err, &hl) || hl.Redirect || hl.Limit != 2 || len(hl.Hops) != 4 {
+		t.Fatalf("third cross-host hop: want *ErrHopLimit, got %v", err)
+	}
+	if hops := trail.Hops(); len(hops) != 3 || hops[0].From != a.URL+"/entity/A" || hops[1].From != b.URL+"/entity/B" {
+		t.Fatalf("link trail: %+v", hops)
+	}
+}
+
+func TestLinkPolicy_BlocksRedirects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved/entity/X":
+			http.Redirect(w, r, "/entity/X", http.StatusFound)
+		case "/metadata/entity/X":
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		case "/internal/entity/X":
+			http.Redirect(w, r, "https://10.0.0.1/entity/X", http.StatusMovedPermanently)
+		default:
+			_, _ = io.WriteString(w, `{"objectClassName":"entity","handle":"X"}`)
+		}
+	}))
+	defer ts.Close()
+	c := New(WithMaxRetries(0), WithLinkPolicy(LinkPolicy{AllowHTTP: true, Hosts: []string{"127.0.0.1"}}))
+	ctx := context.Background()
+
+	if _, _, err := c.getJSON(ctx, ts.URL+"/moved/entity/X"); err != nil {
+		t.Fatalf("same-origin redirect: %v", err)
+	}
+	for _, p := range []string{"/metadata/entity/X", "/internal/entity/X"} {
+		_, _, err := c.getJSON(ctx, ts.URL+p)
+		var lb *ErrLinkBlocked
+		if !errors.As(err, &lb) || lb.From != ts.URL+p || ErrorCode(err) != CodeLinkBlocked {
+			t.Fatalf("%s: want *ErrLinkBlocked, got %v", p, err)
+		}
+	}
+}
+
+func TestSearchEntities_ResultsAndTruncation(t *testing.T) {
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/entities" {
+			http.NotFound(w, r)
+			return
+		}
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("fn") != "" {
+			_, _ = io.WriteString(w, `{"notices":[{"title":"Truncated","type":"result set truncated due to excessive load"}],
+				"entitySearchResults":[{"objectClassName":"entity","handle":"E1","roles":["registrant"]},{"objectClassName":"entity","handle":"E2"}]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"entitySearchResults":[{"objectClassName":"entity","handle":"ABC-1"}],
+			"paging_metadata":{"totalCount":1,"pageNumber":1}}`)
+	}))
+	defer ts.Close()
+	c := New(WithDefaultRDAPBase(ts.URL))
+	ctx := context.Background()
+
+	res, err := c.SearchEntities(ctx, EntitySearchQuery{FN: "Example Corp*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Entities) != 2 || res.Entities[0].Handle != "E1" || !res.Truncated() {
+		t.Fatalf("fn search: %+v", res)
+	}
+	res, err = c.SearchEntities(ctx, EntitySearchQuery{Handle: "ABC-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Entities) != 1 || res.Truncated() || res.Paging == nil || res.Paging.TotalCount != 1 {
+		t.Fatalf("handle search: %+v", res)
+	}
+	if want := []string{"fn=Example+Corp%2A", "handle=ABC-%2A"}; !reflect.DeepEqual(queries, want) {
+		t.Fatalf("queries = %q, want %q", queries, want)
+	}
+	if _, err := c.SearchEntities(ctx, EntitySearchQuery{FN: "x", Handle: "y"}); err == nil {
+		t.Fatal("fn and handle: want error")
+	}
+}
+
+func TestNormalize_SortsUnorderedArraysOnACopy(t *testing.T) {
+	mk := func(status []string, ents []string, ns []string, links []Link) *Domain {
+		d := &Domain{CommonObject: CommonObject{ObjectClassName: "domain", Status: status, Links: links}, LDHName: "a.example"}
+		for _, h := range ents {
+			d.Entities = append(d.Entities, Entity{CommonObject: CommonObject{Handle: h}, Roles: []string{"technical", "administrative"}})
+		}
+		for _, n := range ns {
+			d.Nameservers = append(d.Nameservers, Nameserver{LDHName: n, IPAddresses: &IPAddresses{V4: []string{"192.0.2.2", "192.0.2.1"}}})
+		}
+		return d
+	}
+	a := mk([]string{"active", "client transfer prohibited"}, []string{"B", "A"}, []string{"ns2.a.example", "NS1.a.example"},
+		[]Link{{Rel: "self", Href: "https://x/2"}, {Rel: "related", Href: "https://x/1"}, {Rel: "self", Href: "https://x/1"}})
+	b := mk([]string{"client transfer prohibited", "active"}, []string{"A", "B"}, []string{"NS1.a.example", "ns2.a.example"},
+		[]Link{{Rel: "self", Href: "https://x/1"}, {Rel: "self", Href: "https://x/2"}, {Rel: "related", Href: "https://x/1"}})
+
+	na, nb := Normalize(a).(*Domain), Normalize(b).(*Domain)
+	ja, _ := json.Marshal(na)
+	jb, _ := json.Marshal(nb)
+	if !bytes.Equal(ja, jb) {
+		t.Fatalf("normalized forms differ:\n%s\n%s", ja, jb)
+	}
+	if na.Entities[0].Handle != "A" || na.Nameservers[0].LDHName != "NS1.a.example" || na.Links[0].Rel != "related" ||
+		!reflect.DeepEqual(na.Entities[0].Roles, []string{"administrative", "technical"}) || na.Nameservers[0].IPAddresses.V4[0] != "192.0.2.1" {
+		t.Fatalf("normalized: %s", ja)
+	}
+	if a.Status[0] != "active" || a.Entities[0].Handle != "B" || a.Nameservers[0].IPAddresses.V4[0] != "192.0.2.2" {
+		t.Fatalf("Normalize modified its argument: %+v", a)
+	}
+}
+
+func TestSearchNameservers_ByPatternTLD(t *testing.T) {
+	var srvURL string
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["com"],["%s/com/"]]]}`, srvURL))
+		case "/com/nameservers", "/fallback/nameservers":
+			queries = append(queries, r.URL.Path+"?"+r.URL.RawQuery)
+			_, _ = io.WriteString(w, `{"nameserverSearchResults":[{"objectClassName":"nameserver","ldhName":"ns1.example.com"},
+				{"objectClassName":"nameserver","ldhName":"ns2.example.com","ipAddresses":{"v4":["192.0.2.2"]}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithDefaultRDAPBase(ts.URL+"/fallback"))
+	ctx := context.Background()
+
+	res, err := c.SearchNameservers(ctx, "NS*.example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Nameservers) != 2 || res.Nameservers[1].IPAddresses == nil || res.Truncated() {
+		t.Fatalf("results: %+v", res)
+	}
+	if _, err := c.SearchNameservers(ctx, "ns1.example.*"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/com/nameservers?name=ns%2A.example.com", "/fallback/nameservers?name=ns1.example.%2A"}; !reflect.DeepEqual(queries, want) {
+		t.Fatalf("queries = %q, want %q", queries, want)
+	}
+	if _, err := c.SearchNameservers(ctx, ""); err == nil {
+		t.Fatal("empty pattern: want error")
+	}
+}
+
+func TestSearchNameserversByIP(t *testing.T) {
+	var query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Path + "?" + r.URL.RawQuery
+		_, _ = io.WriteString(w, `{"nameserverSearchResults":[{"objectClassName":"nameserver","ldhName":"ns1.example.com","ipAddresses":{"v6":["2001:db8::53"]}}]}`)
+	}))
+	defer ts.Close()
+	c := New(WithDefaultRDAPBase(ts.URL))
+
+	res, err := c.SearchNameserversByIP(context.Background(), "2001:DB8:0::53")
+	if err != nil || len(res.Nameservers) != 1 || res.Nameservers[0].LDHName != "ns1.example.com" {
+		t.Fatalf("search: %v %+v", err, res)
+	}
+	if query != "/nameservers?ip=2001%3Adb8%3A%3A53" {
+		t.Fatalf("query = %q", query)
+	}
+	if _, err := c.SearchNameserversByIP(context.Background(), "ns1.example.com"); err == nil {
+		t.Fatal("non-IP: want error")
+	}
+}
+
+func TestBaseFor_BootstrapWithoutObjectFetches(t *testing.T) {
+	var srvURL string
+	var objectHits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/dns/"]]]}`, srvURL))
+		case "/ipv4.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["192.0.2.0/24"],["%s/ip/"]]]}`, srvURL))
+		case "/asn.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["64496-64511"],["%s/asn/"]]]}`, srvURL))
+		default:
+			objectHits.Add(1)
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithIPBootstrapURL(ts.URL+"/ipv4.json"), WithASNBootstrapURL(ts.URL+"/asn.json"),
+		WithDefaultRDAPBase("https://fallback.example"))
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		fn   func(context.Context, string) (string, error)
+		q    string
+		want string
+	}{
+		{c.BaseForDomain, "WWW.A.Example.", ts.URL + "/dns/"},
+		{c.BaseForDomain, "a.invalid", "https://fallback.example"},
+		{c.BaseForIP, "192.0.2.7", ts.URL + "/ip/"},
+		{c.BaseForIP, "192.0.2.0/25", ts.URL + "/ip/"},
+		{c.BaseForASN, "AS64500", ts.URL + "/asn/"},
+	} {
+		if got, err := tc.fn(ctx, tc.q); err != nil || strings.TrimSuffix(got, "/") != strings.TrimSuffix(tc.want, "/") {
+			t.Errorf("base for %s = %q, %v; want %q", tc.q, got, err, tc.want)
+		}
+	}
+	if _, err := c.BaseForASN(ctx, "ASX"); err == nil {
+		t.Error("bad ASN: want error")
+	}
+	if n := objectHits.Load(); n != 0 {
+		t.Fatalf("%d object requests, want none", n)
+	}
+}
+
+func TestDomainsByNameserver(t *testing.T) {
+	var srvURL, query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["net"],["%s/net/"]]]}`, srvURL))
+		case "/net/domains":
+			query = r.URL.RawQuery
+			_, _ = io.WriteString(w, `{"domainSearchResults":[{"objectClassName":"domain","ldhName":"a.net"},{"objectClassName":"domain","ldhName":"b.net"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithBootstrapURL(ts.URL + "/dns.json"))
+
+	res, err := c.DomainsByNameserver(context.Background(), "NS1.Hoster.net.")
+	if err != nil || len(res.Domains) != 2 || res.Domains[0].LDHName != "a.net" {
+		t.Fatalf("domains: %v %+v", err, res)
+	}
+	if query != "nsLdhName=ns1.hoster.net" {
+		t.Fatalf("query = %q", query)
+	}
+	if _, err := c.DomainsByNameserver(context.Background(), "not a host"); err == nil {
+		t.Fatal("invalid name: want error")
+	}
+}
+
+func TestExplainBase_EntryOverrideFallback(t *testing.T) {
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/dns/"]]]}`, srvURL))
+		case "/ipv4.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["192.0.0.0/8","192.0.2.0/24"],["%s/ip/"]]]}`, srvURL))
+		case "/asn.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["64496-64511"],["%s/asn/"]]]}`, srvURL))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithIPBootstrapURL(ts.URL+"/ipv4.json"), WithASNBootstrapURL(ts.URL+"/asn.json"),
+		WithDefaultRDAPBase("https://fallback.example"))
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		q, kind, source, entry string
+	}{
+		{"a.example", "domain", BaseFromBootstrap, "example"},
+		{"ns1.a.example", "nameserver", BaseFromBootstrap, "example"},
+		{"a.invalid", "domain", BaseFromFallback, ""},
+		{"192.0.2.9", "ip", BaseFromBootstrap, "192.0.2.0/24"},
+		{"198.51.100.1", "ip", BaseFromFallback, ""},
+		{"AS64500", "autnum", BaseFromBootstrap, "64496-64511"},
+		{"AS64500", "autnum", BaseFromBootstrap, "64496-64511"}, // from the cache
+	} {
+		r, err := c.ExplainBase(ctx, tc.q)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.q, err)
+		}
+		if string(r.Kind) != tc.kind || r.Source != tc.source || r.Entry != tc.entry || !r.Override || r.Base == "" {
+			t.Errorf("%s: %+v", tc.q, r)
+		}
+	}
+	offline := doerFunc(func(*http.Request) (*http.Response, error) { return nil, errors.New("offline") })
+	if r, _ := New(WithHTTPDoer(offline)).ExplainBase(ctx, "192.0.2.1"); r == nil || r.Override || r.Bootstrap != "https://data.iana.org/rdap/ipv4.json" {
+		t.Errorf("default bootstrap: %+v", r)
+	}
+}
+
+func TestDomainsByNameserverIP_TLDOrDefaultBase(t *testing.T) {
+	var srvURL string
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["com"],["%s/com/"]]]}`, srvURL))
+		case "/com/domains", "/agg/domains":
+			queries = append(queries, r.URL.Path+"?"+r.URL.RawQuery)
+			_, _ = io.WriteString(w, `{"domainSearchResults":[{"objectClassName":"domain","ldhName":"a.com"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithDefaultRDAPBase(ts.URL+"/agg"))
+	ctx := context.Background()
+
+	for _, tld := range []string{".COM", ""} {
+		res, err := c.DomainsByNameserverIP(ctx, "192.0.2.53", tld)
+		if err != nil || len(res.Domains) != 1 {
+			t.Fatalf("tld %q: %v %+v", tld, err, res)
+		}
+	}
+	if want := []string{"/com/domains?nsIp=192.0.2.53", "/agg/domains?nsIp=192.0.2.53"}; !reflect.DeepEqual(queries, want) {
+		t.Fatalf("queries = %q, want %q", queries, want)
+	}
+	if _, err := c.DomainsByNameserverIP(ctx, "ns1.a.com", ""); err == nil {
+		t.Fatal("non-IP: want error")
+	}
+}
+
+func TestHelp_ByBaseOrTLD(t *testing.T) {
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/reg/"]]]}`, srvURL))
+		case "/reg/help":
+			_, _ = io.WriteString(w, `{"rdapConformance":["rdap_level_0","icann_rdap_response_profile_1"],
+				"notices":[{"title":"Terms of Service","description":["Use is limited."],"links":[{"rel":"terms-of-service","href":"https://reg.example/tos"}]},
+					{"title":"Rate Limits","description":["10 queries per second"]}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	c := New(WithBootstrapURL(ts.URL + "/dns.json"))
+	ctx := context.Background()
+
+	for _, q := range []string{"EXAMPLE", ts.URL + "/reg/"} {
+		h, err := c.Help(ctx, q)
+		if err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+		if len(h.Notices) != 2 || h.Notices[1].Title != "Rate Limits" || len(h.RDAPConformance) != 2 ||
+			h.Notices[0].Links[0].Href != "https://reg.example/tos" {
+			t.Fatalf("%s: %+v", q, h)
+		}
+	}
+}
+
+func TestFaultInjector_DelayFailStatus(t *testing.T) {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"a.example"}`)
+	}))
+	defer ts.Close()
+	ctx := context.Background()
+
+	calls := 0
+	script := func(*http.Request) InjectedFault {
+		calls++
+		switch calls {
+		case 1:
+			return InjectedFault{Err: &ErrInjected{URL: "x"}}
+		case 2:
+			return InjectedFault{Status: 503}
+		}
+		return InjectedFault{Delay: 10 * time.Millisecond}
+	}
+	c := New(WithFaultInjector(script), WithMaxRetries(2), WithBackoff(ExponentialBackoff(time.Millisecond, 2, time.Millisecond)))
+	start := time.Now()
+	_, _, err := c.getJSON(ctx, ts.URL+"/domain/a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !faultInjection {
+		if calls != 0 || hits.Load() != 1 {
+			t.Fatalf("without the rdapfaults tag the injector must not run: %d calls", calls)
+		}
+		return
+	}
+	if calls != 3 || hits.Load() != 1 || time.Since(start) < 10*time.Millisecond {
+		t.Fatalf("calls = %d, hits = %d", calls, hits.Load())
+	}
+
+	c = New(WithFaultInjector(RandomFaults(0, 0, 1)), WithMaxRetries(0))
+	_, _, err = c.getJSON(ctx, ts.URL+"/domain/b.example")
+	var ie *ErrInjected
+	if !errors.As(err, &ie) || ErrorCode(err) != CodeNetwork {
+		t.Fatalf("RandomFaults(fail=1): want *ErrInjected, got %v", err)
+	}
+}
+
+func TestNameserverWithFallback_RegistrarThenGlue(t *testing.T) {
+	var srvURL string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			fmt.Fprintf(w, `{"services":[[["example"],["%s/"]]]}`, srvURL)
+		case "/nameserver/ns0.a.example":
+			_, _ = io.WriteString(w, `{"objectClassName":"nameserver","ldhName":"ns0.a.example"}`)
+		case "/domain/a.example":
+			fmt.Fprintf(w, `{"objectClassName":"domain","ldhName":"a.example",
+				"links":[{"rel":"related","type":"application/rdap+json","href":"%[1]s/registrar/domain/a.example"}],
+				"nameservers":[{"objectClassName":"nameserver","ldhName":"NS1.A.EXAMPLE"},
+					{"ldhName":"ns2.a.example","ipAddresses":{"v4":["192.0.2.2"]}}]}`, srvURL)
+		case "/registrar/nameserver/ns1.a.example":
+			_, _ = io.WriteString(w, `{"objectClassName":"nameserver","ldhName":"ns1.a.example","handle":"NS1-REG"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"errorCode":404,"title":"Not Found"}`)
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	srvURL = ts.URL
+
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithMaxRetries(0))
+	ctx := context.Background()
+
+	r, err := c.NameserverWithFallback(ctx, "ns0.a.example")
+	if err != nil || r.Source != NameserverFromRegistry || len(r.Tried) != 0 {
+		t.Fatalf("registry: %+v %v", r, err)
+	}
+	r, err = c.NameserverWithFallback(ctx, "ns1.a.example")
+	if err != nil || r.Source != NameserverFromRegistrar || r.Nameserver.Handle != "NS1-REG" ||
+		r.URL != ts.URL+"/registrar/nameserver/ns1.a.example" || r.Parent != "a.example" || len(r.Tried) != 1 {
+		t.Fatalf("registrar: %+v %v", r, err)
+	}
+	r, err = c.NameserverWithFallback(ctx, "ns2.a.example")
+	if err != nil || r.Source != NameserverFromGlue || r.Nameserver.ObjectClassName != "nameserver" ||
+		r.Nameserver.IPAddresses == nil || r.Nameserver.IPAddresses.V4[0] != "192.0.2.2" || len(r.Tried) != 2 {
+		t.Fatalf("glue: %+v %v", r, err)
+	}
+
+	_, err = c.NameserverWithFallback(ctx, "ns3.a.example")
+	var nu *ErrNameserverUnavailable
+	var re *RDAPError
+	if !errors.As(err, &nu) || len(nu.Tried) != 3 || !errors.As(err, &re) {
+		t.Fatalf("want *ErrNameserverUnavailable over three steps, got %T %v", err, err)
+	}
+}
+
+func TestNameserver_SynthesizedFromGlue(t *testing.T) {
+	var srvURL string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			fmt.Fprintf(w, `{"services":[[["example"],["%s/"]]]}`, srvURL)
+		case "/domain/a.example":
+			_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"a.example",
+				"nameservers":[{"ldhName":"ns1.a.example","ipAddresses":{"v6":["2001:db8::1"]}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"errorCode":404,"title":"Not Found"}`)
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	srvURL = ts.URL
+	ctx := context.Background()
+
+	// Off by default: the registry's 404 is the answer.
+	plain := New(WithBootstrapURL(ts.URL+"/dns.json"), WithMaxRetries(0))
+	if _, err := plain.Nameserver(ctx, "ns1.a.example"); ErrorCode(err) != CodeNotFound {
+		t.Fatalf("want not_found without synthesis, got %v", err)
+	}
+
+	c := New(append((&Config{SynthesizeNameservers: true}).Options(), WithBootstrapURL(ts.URL+"/dns.json"), WithMaxRetries(0))...)
+	ns, err := c.Nameserver(ctx, "ns1.a.example")
+	if err != nil || !ns.Synthetic || ns.LDHName != "ns1.a.example" || ns.IPAddresses.V6[0] != "2001:db8::1" {
+		t.Fatalf("synthesized: %+v %v", ns, err)
+	}
+	if b, _ := json.Marshal(ns); !strings.Contains(string(b), `"synthetic":true`) {
+		t.Fatalf("Synthetic not marshaled: %s", b)
+	}
+	// A host the parent does not list keeps the registry's error.
+	if _, err := c.Nameserver(ctx, "ns9.a.example"); ErrorCode(err) != CodeNotFound {
+		t.Fatalf("want not_found for unlisted host, got %v", err)
+	}
+}
+
+func TestEntityContact_JCard(t *testing.T) {
+	decode := func(s string) *Entity {
+		t.Helper()
+		var e Entity
+		if err := json.Unmarshal([]byte(`{"objectClassName":"entity","vcardArray":`+s+`}`), &e); err != nil {
+			t.Fatal(err)
+		}
+		return &e
+	}
+	c := decode(`["vcard",[["version",{},"text","4.0"],["fn",{},"text","Joe User"],["kind",{},"text","Individual"],
+		["org",{"type":"work"},"text",["Example","Ops"]],["email",{"type":"work"},"text","joe@example.com"],
+		["tel",{"type":["work","voice"]},"uri","tel:+1-555-555-1234;ext=102"],["tel",{"type":"fax"},"text","+1.5555550000"],
+		["adr",{"type":"work","cc":"us"},"text",["","Suite 1234",["4321 Rue Somewhere","Bldg 2"],"Quebec","QC","G1V 2M2","Canada"]],
+		["adr",{"label":"123 Maple Ave\nAnytown"},"text",["","","","","","",""]]]]`).Contact()
+	if c == nil || c.FullName != "Joe User" || c.Kind != "individual" || c.Org != "Example, Ops" ||
+		!reflect.DeepEqual(c.Emails, []string{"joe@example.com"}) {
+		t.Fatalf("contact = %+v", c)
+	}
+	if len(c.Phones) != 2 || c.Phones[0].Number != "+1-555-555-1234;ext=102" || !reflect.DeepEqual(c.Phones[0].Types, []string{"work", "voice"}) ||
+		!reflect.DeepEqual(c.Phones[1].Types, []string{"fax"}) {
+		t.Fatalf("phones = %+v", c.Phones)
+	}
+	if len(c.Addresses) != 2 || c.Addresses[0].Locality != "Quebec" || c.Addresses[0].CountryCode != "US" ||
+		!reflect.DeepEqual(c.Addresses[0].Street, []string{"4321 Rue Somewhere", "Bldg 2"}) || c.Addresses[1].Label != "123 Maple Ave\nAnytown" {
+		t.Fatalf("addresses = %+v", c.Addresses)
+	}
+
+	// Malformed cards registrars send.
+	for _, tc := range []struct{ card, fn, email string }{
+		{`[["fn",{},"text","No Wrapper"],["email",{},"text","a@example.com"]]`, "No Wrapper", "a@example.com"},
+		{`[["vcard",[["FN",{},"text","Extra Array"]]]]`, "Extra Array", ""},
+		{`["vcard",[["fn",{},"Missing Type"],["email",{},"text"],["tel",{}],["email",{},"uri","b@example.com"],"junk",[42]]]`, "Missing Type", "b@example.com"},
+		{`["vcard",[["n",{},"text",["User","Joe","",["Dr."],""]]]]`, "Dr. Joe User", ""},
+	} {
+		c := decode(tc.card).Contact()
+		if c == nil || c.FullName != tc.fn || (tc.email != "" && (len(c.Emails) != 1 || c.Emails[0] != tc.email)) {
+			t.Errorf("%s: contact = %+v", tc.card, c)
+		}
+	}
+	for _, card := range []string{`null`, `"vcard"`, `["vcard"]`, `{}`} {
+		if c := decode(card).Contact(); c != nil {
+			t.Errorf("%s: want nil, got %+v", card, c)
+		}
+	}
+}
+
+func TestNormalizeStatus(t *testing.T) {
+	for in, want := range map[string]Status{
+		"client transfer prohibited":                                StatusClientTransferProhibited,
+		"clientTransferProhibited":                                  StatusClientTransferProhibited,
+		"CLIENT_TRANSFER_PROHIBITED":                                StatusClientTransferProhibited,
+		"serverHold https://icann.org/epp#serverHold":               StatusServerHold,
+		"redemptionPeriod (https://icann.org/epp#redemptionPeriod)": StatusRedemptionPeriod,
+		"ok":                        StatusActive,
+		"linked":                    StatusAssociated,
+		" Auto-Renew Grace Period ": StatusAutoRenewPeriod,
+		"Registry Special Hold":     Status("registry special hold"),
+	} {
+		if got := NormalizeStatus(in); got != want {
+			t.Errorf("NormalizeStatus(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if Status("registry special hold").Known() || !StatusActive.Known() {
+		t.Error("Known")
+	}
+	if StatusClientTransferProhibited.EPP() != "clientTransferProhibited" || StatusActive.EPP() != "ok" || StatusProxy.EPP() != "" {
+		t.Error("EPP spellings")
+	}
+
+	d := &Domain{CommonObject: CommonObject{Status: []string{"clientTransferProhibited", "client transfer prohibited", "pendingDelete"}}}
+	if !d.IsLocked() || !d.IsPendingDelete() || d.IsOnHold() {
+		t.Fatalf("predicates: locked=%v pendingDelete=%v hold=%v", d.IsLocked(), d.IsPendingDelete(), d.IsOnHold())
+	}
+	if got := d.Statuses(); !reflect.DeepEqual(got, []Status{StatusClientTransferProhibited, StatusPendingDelete}) {
+		t.Fatalf("Statuses() = %q", got)
+	}
+	if (&Domain{CommonObject: CommonObject{Status: []string{"active"}}}).IsLocked() {
+		t.Fatal("active domain reported locked")
+	}
+}
+
+func TestByteBudget(t *testing.T) {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dns.json" {
+			_, _ = io.WriteString(w, `{"version":"1.0","services":[]}`)
+			return
+		}
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/rdap+json")
+		fmt.Fprintf(w, `{"objectClassName":"domain","ldhName":%q}`, strings.TrimPrefix(r.URL.Path, "/domain/"))
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithDefaultRDAPBase(ts.URL), WithMaxRetries(0),
+		WithByteBudget(ByteBudget{Jobs: map[string]int64{"sweep": 1}, Registries: map[string]int64{host: 200}}))
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+	sweep := WithJob(ctx, "sweep")
+
+	if _, err := c.Domain(sweep, "a.example"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := c.Domain(sweep, "b.example")
+	var be *ErrBudgetExceeded
+	if !errors.As(err, &be) || be.Scope != BudgetJob || be.Name != "sweep" || ErrorCode(err) != CodeBudgetExceeded {
+		t.Fatalf("want job budget error, got %v", err)
+	}
+	if !be.ResetAt.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("ResetAt = %v", be.ResetAt)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("over-budget request was sent: %d hits", hits.Load())
+	}
+	// Other work goes on until the registry's own budget runs out.
+	for i := 0; err == nil || !errors.As(err, &be) || be.Scope != BudgetRegistry; i++ {
+		if i > 20 {
+			t.Fatalf("registry budget never hit: %v", err)
+		}
+		_, err = c.Domain(ctx, fmt.Sprintf("c%d.example", i))
+	}
+	u := c.BandwidthUsage()
+	if u.Month != "2026-03" || u.Total < 200 || u.Registries[host] != u.Total || u.Jobs["sweep"] == 0 || u.Jobs["sweep"] >= u.Total {
+		t.Fatalf("usage = %+v", u)
+	}
+
+	// Saved usage restores into a new process; a new month starts from zero.
+	c2 := New(WithByteBudget(ByteBudget{Monthly: 100}))
+	c2.now = c.now
+	c2.SetBandwidthUsage(u)
+	if got := c2.BandwidthUsage(); got.Total != u.Total {
+		t.Fatalf("restored usage = %+v", got)
+	}
+	now = now.Add(2 * time.Hour)
+	if got := c.BandwidthUsage(); got.Month != "2026-04" || got.Total != 0 {
+		t.Fatalf("after rollover = %+v", got)
+	}
+	if _, err := c.Domain(sweep, "d.example"); err != nil {
+		t.Fatalf("new month still over budget: %v", err)
+	}
+}
+
+func TestRedactedFields(t *testing.T) {
+	var m map[string]any
+	if err := json.Unmarshal([]byte(`{"objectClassName":"domain","ldhName":"example.com",
+		"rdapConformance":["rdap_level_0","redacted"],
+		"redacted":[
+			{"name":{"type":"Registrant Name"},"prePath":"$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='fn')][3]","method":"removal","reason":{"description":"Server policy"}},
+			{"name":{"type":"Registrant Email"},"postPath":"$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='email')][3]","method":"emptyValue"},
+			{"name":{"description":"Administrative Contact"},"prePath":"$.entities[?(@.roles[0]=='administrative')]"},
+			{"name":{"type":"registrant name"}}]}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := ParseObject(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := obj.(*Domain)
+	if got := d.RedactedFields(); !reflect.DeepEqual(got, []string{"Registrant Name", "Registrant Email", "Administrative Contact"}) {
+		t.Fatalf("RedactedFields() = %q", got)
+	}
+	if !d.IsRedacted("registrant email") || d.IsRedacted(RedactedTechEmail) {
+		t.Fatal("IsRedacted")
+	}
+	r, ok := d.RedactionFor("Administrative Contact")
+	if !ok || r.EffectiveMethod() != RedactionRemoval || r.PrePath == "" {
+		t.Fatalf("RedactionFor = %+v, %v", r, ok)
+	}
+	if r, _ := d.RedactionFor(RedactedRegistrantName); r.Reason == nil || r.Reason.Description != "Server policy" {
+		t.Fatalf("reason = %+v", r.Reason)
+	}
+}
+
+func TestStaleIfErrorAndCompleteness(t *testing.T) {
+	var down atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dns.json" {
+			_, _ = io.WriteString(w, `{"version":"1.0","services":[]}`)
+			return
+		}
+		if down.Load() {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprint(w, `{"objectClassName":"domain","ldhName":"a.example"}`)
+	}))
+	defer ts.Close()
+
+	now := time.Now()
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithDefaultRDAPBase(ts.URL), WithMaxRetries(0), WithStaleIfError(time.Hour))
+	c.respCache.now = func() time.Time { return now }
+	ctx, rep := WithCompleteness(context.Background())
+	item, itemRep := WithCompleteness(ctx)
+
+	if _, err := c.Domain(item, "a.example"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Domain(ctx, "a.example"); err != nil { // fresh cache hit
+		t.Fatal(err)
+	}
+	down.Store(true)
+	now = now.Add(10 * time.Minute)
+	d, err := c.Domain(ctx, "a.example")
+	if err != nil || d.LDHName != "a.example" {
+		t.Fatalf("want stale answer, got %v %v", d, err)
+	}
+	if _, err := c.Domain(ctx, "b.example"); err == nil {
+		t.Fatal("no cached copy: want the registry's error")
+	}
+
+	got := rep.Report()
+	if got.Fetched != 3 || got.FromCache != 1 || len(got.Stale) != 1 || len(got.Failed) != 1 || got.Complete() {
+		t.Fatalf("report = %+v", got)
+	}
+	if s := got.Stale[0]; !strings.HasSuffix(s.URL, "/domain/a.example") || !strings.Contains(s.Cause, "503") {
+		t.Fatalf("stale = %+v", s)
+	}
+	if ir := itemRep.Report(); ir.Fetched != 1 || !ir.Complete() {
+		t.Fatalf("nested report = %+v", ir)
+	}
+
+	// Past the stale window the error comes through.
+	now = now.Add(2 * time.Hour)
+	if _, err := c.Domain(context.Background(), "a.example"); err == nil {
+		t.Fatal("stale beyond WithStaleIfError window was served")
+	}
+}
+
+func TestCapabilitiesAndExtensionGating(t *testing.T) {
+	caps := ParseCapabilities([]string{"rdap_level_0", "CIDR0", "arin_originas0", " "})
+	for name, want := range map[string]bool{
+		"rdap_level_0": true, "rdap_level": true, "cidr0": true, "cidr": true,
+		"arin_originas": true, "redacted": false, "rdap": false, "": false,
+	} {
+		if got := caps.Supports(name); got != want {
+			t.Errorf("Supports(%q) = %v", name, got)
+		}
+	}
+	if got := caps.Tokens(); !reflect.DeepEqual(got, []string{"arin_originas0", "cidr0", "rdap_level_0"}) {
+		t.Fatalf("Tokens() = %q", got)
+	}
+	profiles := ParseCapabilities([]string{"icann_rdap_response_profile_0", "icann_rdap_response_profile_1", "rdap_level_0", "subsetting"})
+	if v, ok := profiles.ProfileVersion(ProfileICANNResponse); !ok || v != 1 {
+		t.Fatalf("ProfileVersion(response profile) = %d, %v", v, ok)
+	}
+	if v, ok := profiles.ProfileVersion(ProfileRDAPLevel); !ok || v != 0 {
+		t.Fatalf("ProfileVersion(rdap_level) = %d, %v", v, ok)
+	}
+	if _, ok := profiles.ProfileVersion(ProfileICANNTechnicalGuide); ok {
+		t.Fatal("undeclared profile has a version")
+	}
+	if !profiles.Supports(ConformanceSubsetting) || profiles.Supports(ConformanceSorting) {
+		t.Fatal("Supports(subsetting/sorting)")
+	}
+
+	body := `{"objectClassName":"ip network","handle":"NET-1","rdapConformance":["rdap_level_0","cidr0"],
+		"cidr0_cidrs":[{"v4prefix":"192.0.2.0","length":25},{"v4prefix":"192.0.2.128","length":26}],
+		"arin_originas0_originautnums":[64500],
+		"redacted":[{"name":{"type":"Registrant Email"}}]}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, body)
+	}))
+	defer ts.Close()
+	for _, gate := range []bool{false, true} {
+		c := New(WithExtensionGating(gate))
+		obj, err := c.fetchObject(context.Background(), ts.URL+"/ip/192.0.2.0/24", "ip network")
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := obj.(*IPNetwork)
+		if !n.SupportsExtension(ConformanceCIDR0) || n.SupportsExtension(ConformanceRedacted) {
+			t.Fatalf("SupportsExtension: %q", n.RDAPConformance)
+		}
+		if p := n.Prefixes(); len(p) != 2 || p[1].String() != "192.0.2.128/26" {
+			t.Fatalf("gate=%v: prefixes = %v", gate, p)
+		}
+		// Undeclared extensions are only decoded without gating.
+		if got := len(n.OriginAutnums) == 1 && len(n.Redacted) == 1; got == gate {
+			t.Fatalf("gate=%v: originautnums=%v redacted=%v", gate, n.OriginAutnums, n.Redacted)
+		}
+	}
+}
+
+func TestClientErrorTaxonomy(t *testing.T) {
+	var hits atomic.Int32
+	status := map[string]int{"/domain/a.example": 401, "/domain/b.example": 400, "/domain/c.example": 422, "/domain/d.example": 451, "/domain/e.example": 404, "/domain/f.example": 410}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dns.json" {
+			_, _ = io.WriteString(w, `{"version":"1.0","services":[]}`)
+			return
+		}
+		hits.Add(1)
+		if status[r.URL.Path] == 451 {
+			w.Header().Set("Link", `<https://authority.example/>; rel="blocked-by"`)
+		}
+		w.WriteHeader(status[r.URL.Path])
+	}))
+	defer ts.Close()
+
+	c := New(WithBootstrapURL(ts.URL+"/dns.json"), WithDefaultRDAPBase(ts.URL), WithMaxRetries(2))
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name     string
+		sentinel error
+		code     string
+		cached   bool
+	}{
+		{"a.example", ErrUnauthorized, CodeUnauthorized, false},
+		{"b.example", ErrBadRequest, CodeBadRequest, false},
+		{"c.example", ErrUnprocessable, CodeUnprocessable, false},
+		{"d.example", ErrLegallyBlocked, CodeLegalBlock, true},
+		{"e.example", ErrNotFound, CodeNotFound, true},
+		{"f.example", ErrGone, CodeGone, true},
+	} {
+		hits.Store(0)
+		_, err := c.Domain(ctx, tc.name)
+		if !errors.Is(err, tc.sentinel) || ErrorCode(err) != tc.code {
+			t.Fatalf("%s: err = %v (%s)", tc.name, err, ErrorCode(err))
+		}
+		if n := hits.Load(); n != 1 {
+			t.Fatalf("%s: %d requests, want 1 (no retries)", tc.name, n)
+		}
+		_, err2 := c.Domain(ctx, tc.name)
+		if !errors.Is(err2, tc.sentinel) {
+			t.Fatalf("%s: second err = %v", tc.name, err2)
+		}
+		if cached := hits.Load() == 1; cached != tc.cached {
+			t.Fatalf("%s: negatively cached = %v, want %v", tc.name, cached, tc.cached)
+		}
+	}
+
+	_, err := c.Domain(ctx, "d.example")
+	var lb *LegalBlockError
+	var he *HTTPError
+	if !errors.As(err, &lb) || lb.BlockedBy != "https://authority.example/" || !errors.As(err, &he) || he.StatusCode != 451 {
+		t.Fatalf("legal block = %#v", err)
+	}
+	var gone *HTTPError
+	if _, err := c.Domain(ctx, "f.example"); !errors.Is(err, ErrNotFound) || !errors.As(err, &gone) || !gone.Gone() {
+		t.Fatalf("gone = %v", err)
+	}
+	if _, err := c.Domain(ctx, "e.example"); errors.Is(err, ErrGone) {
+		t.Fatalf("404 matched ErrGone: %v", err)
+	}
+	// A 404 is forgotten after minutes, a 410 is not.
+	c.respCache.now = func() time.Time { return time.Now().Add(time.Hour) }
+	hits.Store(0)
+	_, _ = c.Domain(ctx, "e.example")
+	_, _ = c.Domain(ctx, "f.example")
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("%d requests an hour later, want 1", n)
+	}
+	// WithFreshData asks the registry again.
+	hits.Store(0)
+	if _, err := c.Domain(WithFreshData(ctx), "e.example"); !errors.Is(err, ErrNotFound) || hits.Load() != 1 {
+		t.Fatalf("fresh lookup: %v after %d requests", err, hits.Load())
+	}
+}
+
+func TestObjectMeta(t *testing.T) {
+	body := `{"objectClassName":"domain","ldhName":"x.example"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old/domain/x.example" {
+			http.Redirect(w, r, "/domain/x.example", http.StatusFound)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("X-Registry", "test")
+		_, _ = io.WriteString(w, body)
+	}))
+	defer ts.Close()
+
+	c := New()
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return at }
+	c.respCache.now = c.now
+	ctx := context.Background()
+	u := ts.URL + "/old/domain/x.example"
+
+	obj, err := c.fetchObject(ctx, u, "domain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := obj.(*Domain).Meta
+	if m == nil || string(m.Raw) != body || m.Header.Get("X-Registry") != "test" || !m.FetchedAt.Equal(at) ||
+		m.URL != u || m.FinalURL != ts.URL+"/domain/x.example" || m.CacheHit {
+		t.Fatalf("meta = %+v", m)
+	}
+
+	// A cache hit keeps the original response's headers and fetch time.
+	c.respCache.now = func() time.Time { return at.Add(time.Second) }
+	obj, err = c.fetchObject(ctx, u, "domain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = obj.(*Domain).Meta
+	if m == nil || !m.CacheHit || string(m.Raw) != body || m.Header.Get("X-Registry") != "test" || !m.FetchedAt.Equal(at) ||
+		m.FinalURL != ts.URL+"/domain/x.example" {
+		t.Fatalf("cached meta = %+v", m)
+	}
+	if b, _ := json.Marshal(obj); strings.Contains(string(b), "X-Registry") {
+		t.Fatalf("Meta leaked into JSON: %s", b)
+	}
+}
+
+func TestObjectTagBootstrap(t *testing.T) {
+	var tagFetches atomic.Int32
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/object-tags.json":
+			tagFetches.Add(1)
+			fmt.Fprintf(w, `{"version":"1.0","services":[
+				[["rdap@arin.example"],["ARIN"],["%[1]s/arin/"]],
+				[["rdap@ripe.example"],["RIPE"],["","%[1]s/ripe"]]]}`, srvURL)
+		case strings.HasPrefix(r.URL.Path, "/arin/entity/"), strings.HasPrefix(r.URL.Path, "/ripe/entity/"):
+			fmt.Fprintf(w, `{"objectClassName":"entity","handle":%q}`, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+
+	c := New(WithObjectTagBootstrapURL(ts.URL + "/object-tags.json"))
+	ctx := context.Background()
+	e, err := c.Entity(ctx, "ABC123-ARIN", "")
+	if err != nil || e.Handle != "ABC123-ARIN" || !strings.HasPrefix(e.Meta.URL, ts.URL+"/arin/entity/") {
+		t.Fatalf("entity = %+v, %v", e, err)
+	}
+	// Other tags come from the cached file; tags match in any case.
+	for handle, want := range map[string]string{"X-ripe": ts.URL + "/ripe", "NOTAG": "https://rdap.org", "ABC-LACNIC": "https://rdap.org"} {
+		if got, _ := c.BaseForEntity(ctx, handle); got != want {
+			t.Errorf("BaseForEntity(%q) = %q, want %q", handle, got, want)
+		}
+	}
+	// An unknown tag is remembered as such.
+	_, _ = c.BaseForEntity(ctx, "ABC-LACNIC")
+	if n := tagFetches.Load(); n > 3 {
+		t.Fatalf("object-tags fetched %d times", n)
+	}
+	before := tagFetches.Load()
+	if _, err := c.Entity(ctx, "X-RIPE", ""); err != nil || tagFetches.Load() != before {
+		t.Fatalf("cached tag refetched: %v, %d fetches", err, tagFetches.Load())
+	}
+
+	r, err := c.ExplainBase(ctx, "ABC123-ARIN")
+	if err != nil || r.Kind != KindEntity || r.Base != ts.URL+"/arin" || r.Source != BaseFromBootstrap || r.Entry != "ARIN" {
+		t.Fatalf("ExplainBase = %+v, %v", r, err)
+	}
+	obj, err := c.Lookup(ctx, "ABC123-ARIN", "")
+	if _, ok := obj.(*Entity); !ok || err != nil {
+		t.Fatalf("Lookup = %T, %v", obj, err)
+	}
+}
+
+func TestNoteChangeSettleWindow(t *testing.T) {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"x.example"}`)
+	}))
+	defer ts.Close()
+
+	c := New(WithSettleWindow(time.Hour, time.Minute))
+	now := time.Now()
+	c.respCache.now = func() time.Time { return now }
+	ctx := context.Background()
+	u := ts.URL + "/domain/x.example"
+	fetch := func() Object {
+		t.Helper()
+		obj, err := c.fetchObject(ctx, u, "domain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	fetch()
+	obj := fetch()
+	if hits.Load() != 1 {
+		t.Fatalf("hits = %d, want a cache hit", hits.Load())
+	}
+
+	c.NoteChange(obj, nil)
+	now = now.Add(2 * time.Minute)
+	fetch()
+	if hits.Load() != 2 {
+		t.Fatalf("hits = %d, want a refetch after the change", hits.Load())
+	}
+	now = now.Add(30 * time.Second)
+	fetch()
+	if hits.Load() != 2 {
+		t.Fatalf("hits = %d, want the settle TTL to hold", hits.Load())
+	}
+	now = now.Add(40 * time.Second)
+	fetch()
+	if hits.Load() != 3 {
+		t.Fatalf("hits = %d, want a refetch past the settle TTL", hits.Load())
+	}
+
+	// After the window the response's own TTL applies again.
+	now = now.Add(2 * time.Hour)
+	fetch()
+	now = now.Add(10 * time.Minute)
+	fetch()
+	if hits.Load() != 4 {
+		t.Fatalf("hits = %d, want normal caching after the window", hits.Load())
+	}
+}
+
+func TestLinksBest(t *testing.T) {
+	links := Links{
+		{Rel: "related", Href: "https://registrar.example/whois?d=example.com", Type: "text/html"},
+		{Rel: "related", Href: "https://rdap.registrar.example/fr/domain/example.com", Type: "application/rdap+json", HrefLang: "fr"},
+		{Rel: "related", Href: "https://rdap.registrar.example/domain/example.com", Type: "application/json"},
+		{Rel: "related", Href: "https://rdap.registrar.example/en/domain/example.com", Type: "application/rdap+json; charset=utf-8", HrefLang: "en-GB"},
+		{Rel: "self alternate", Href: "https://rdap.example/domain/example.com"},
+		{Rel: "related", Href: "/domain/example.com", Type: "application/rdap+json"},
+	}
+	best := func(rel string, langs ...string) string {
+		l, ok := links.Best(rel, langs...)
+		if !ok {
+			return ""
+		}
+		return l.Href
+	}
+	if got := best("related"); got != "https://rdap.registrar.example/fr/domain/example.com" {
+		t.Fatalf("Best(related) = %q, want the first rdap+json link", got)
+	}
+	if got := best("related", "en"); got != "https://rdap.registrar.example/en/domain/example.com" {
+		t.Fatalf("Best(related, en) = %q, want the en-GB link", got)
+	}
+	if got := best("alternate"); got != "https://rdap.example/domain/example.com" {
+		t.Fatalf("Best(alternate) = %q, want the multi-rel link", got)
+	}
+	if got := best("next"); got != "" {
+		t.Fatalf("Best(next) = %q, want none", got)
+	}
+	r := links.Ranked("RELATED", "de")
+	if len(r) != 4 || r[3].Type != "text/html" || r[2].Type != "application/json" {
+		t.Fatalf("Ranked = %+v", r)
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	var hits, notModified atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "max-age=0")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		switch r.URL.Path {
+		case "/dns.json":
+			_, _ = io.WriteString(w, `{"version":"1.0","services":[[["test"],["`+"http://"+r.Host+`/"]]]}`)
+		case "/domain/a.test":
+			_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"a.test"}`)
+		case "/domain/b.test":
+			w.WriteHeader(http.StatusUnavailableForLegalReasons)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	open := func() *Client {
+		return New(WithBootstrapURL(ts.URL+"/dns.json"), WithDefaultRDAPBase(ts.URL), WithDiskCache(dir, 0))
+	}
+	c := open()
+	if _, err := c.Domain(ctx, "a.test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Domain(ctx, "b.test"); !errors.Is(err, ErrLegallyBlocked) {
+		t.Fatalf("err = %v, want a legal block", err)
+	}
+
+	// A new process: the bootstrap file and the domain revalidate with 304s,
+	// and the 451 is answered from disk.
+	hits.Store(0)
+	c = open()
+	d, err := c.Domain(ctx, "a.test")
+	if err != nil || d.LDHName != "a.test" {
+		t.Fatalf("Domain = %+v, %v", d, err)
+	}
+	if h, n := hits.Load(), notModified.Load(); h != 2 || n != 2 {
+		t.Fatalf("%d requests, %d 304s; want the bootstrap and domain revalidated", h, n)
+	}
+	_, err = c.Domain(ctx, "b.test")
+	var lb *LegalBlockError
+	if !errors.As(err, &lb) || ErrorCode(err) != CodeLegalBlock {
+		t.Fatalf("err = %v, want the remembered legal block", err)
+	}
+	if h := hits.Load(); h != 2 {
+		t.Fatalf("%d requests, want the 451 from disk", h)
+	}
+
+	// Past maxBytes the least recently used entries go.
+	c = New(WithBootstrapURL(ts.URL+"/dns.json"), WithDefaultRDAPBase(ts.URL), WithDiskCache(dir, 1))
+	if ents, _ := os.ReadDir(dir); len(ents) != 0 {
+		t.Fatalf("%d files left under a 1-byte limit", len(ents))
+	}
+}
+
+func TestHostRateLimit(t *testing.T) {
+	var l hostLimiter
+	l.set("slow.example", 10)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 12; i++ {
+		if err := l.wait(ctx, "SLOW.example"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if el := time.Since(start); el < 150*time.Millisecond {
+		t.Fatalf("12 requests at 10/s with a burst of 10 took %v", el)
+	}
+	start = time.Now()
+	for i := 0; i < 50; i++ {
+		_ = l.wait(ctx, "fast.example")
+	}
+	if el := time.Since(start); el > 50*time.Millisecond {
+		t.Fatalf("an unlimited host waited %v", el)
+	}
+
+	// The default applies to hosts without their own rate, and a context
+	// ending while waiting fails the request.
+	l.set("", 1)
+	if err := l.wait(ctx, "other.example"); err != nil {
+		t.Fatal(err)
+	}
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := l.wait(short, "other.example"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the deadline", err)
+	}
+
+	cfg := Config{HostRateLimits: map[string]float64{"rdap.example": 2}}
+	c := New(cfg.Options()...)
+	if c.hostRates.rates["rdap.example"] != 2 {
+		t.Fatalf("config not applied: %+v", c.hostRates.rates)
+	}
+}
@@ -0,0 +1,88 @@
+package rdapclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Health is a point-in-time view of client diagnostics.
+type Health struct {
+	// ClockSkew is the last registry Date header minus the local clock at the
+	// time of the response; positive means the local clock is behind. Date has
+	// one-second resolution, so values under a second or two are noise.
+	ClockSkew time.Duration `json:"clockSkew"`
+	// ClockSkewHost is the registry host the skew was measured against.
+	ClockSkewHost string `json:"clockSkewHost,omitempty"`
+	// ClockSkewAt is when the skew was measured (local clock); zero if never.
+	ClockSkewAt time.Time `json:"clockSkewAt,omitempty"`
+	// Protocols maps registry host to the protocol of its last response
+	// ("HTTP/3.0", "HTTP/2.0", ...); only tracked with WithHTTP3.
+	Protocols map[string]string `json:"protocols,omitempty"`
+	// HTTP3Fallbacks counts requests that failed over QUIC and were resent
+	// over HTTP/2 or 1.1.
+	HTTP3Fallbacks int `json:"http3Fallbacks,omitempty"`
+	// DegradedHosts are registry hosts failing with retryable errors, to which
+	// concurrent attempts are currently limited (WithDegradedHostConcurrency).
+	DegradedHosts []string `json:"degradedHosts,omitempty"`
+	// SizeAnomalies are the most recent responses over the body cap or far
+	// larger than their registry's typical size (WithSizeAnomalies), oldest first.
+	SizeAnomalies []SizeAnomaly `json:"sizeAnomalies,omitempty"`
+}
+
+// Health reports the client's diagnostics. Skew is only measured on live
+// traffic: replayed cassettes carry their recording-time Date headers.
+func (c *Client) Health() Health {
+	c.skew.mu.Lock()
+	h := Health{ClockSkew: c.skew.skew, ClockSkewHost: c.skew.host, ClockSkewAt: c.skew.at}
+	c.skew.mu.Unlock()
+	if c.h3 != nil {
+		h.Protocols, h.HTTP3Fallbacks = c.h3.stats()
+	}
+	h.DegradedHosts = c.guard.degradedHosts()
+	h.SizeAnomalies = c.sizes.anomalies()
+	return h
+}
+
+// clockSkew holds the most recent skew measurement.
+type clockSkew struct {
+	mu   sync.Mutex
+	skew time.Duration
+	host string
+	at   time.Time
+}
+
+// skewDoer measures clock skew from the Date header of every response.
+type skewDoer struct {
+	next Doer
+	skew *clockSkew
+	now  func() time.Time
+}
+
+func (d *skewDoer) Do(req *http.Request) (*http.Response, error) {
+	start := d.now()
+	resp, err := d.next.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	date, derr := http.ParseTime(resp.Header.Get("Date"))
+	if derr != nil {
+		return resp, nil
+	}
+	end := d.now()
+	mid := start.Add(end.Sub(start) / 2)
+	d.skew.mu.Lock()
+	d.skew.skew, d.skew.host, d.skew.at = date.Sub(mid).Round(time.Second), req.URL.Host, end
+	d.skew.mu.Unlock()
+	return resp, nil
+}
+
+// serverNow is the server's notion of "now" for a response: its Date header
+// when valid, otherwise the local clock. Relative times (Expires, Retry-After
+// dates) measured against it are immune to local clock skew.
+func serverNow(h http.Header, local time.Time) time.Time {
+	if t, err := http.ParseTime(h.Get("Date")); err == nil {
+		return t
+	}
+	return local
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	rc "github.com/datum-labs/rdap"
+)
+
+// ---- ASN-BULK (autnums of a list, optionally grouped by organization) ------
+
+// asnRow is one autnum of `rdapctl asn-bulk`.
+type asnRow struct {
+	Query     string `json:"query"`
+	ASN       int64  `json:"asn,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Country   string `json:"country,omitempty"`
+	Org       string `json:"org,omitempty"`
+	OrgHandle string `json:"orgHandle,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// orgRollup is one organization of `rdapctl asn-bulk --rollup-org`.
+type orgRollup struct {
+	Org    string  `json:"org"`
+	Handle string  `json:"handle,omitempty"`
+	ASNs   []int64 `json:"asns"`
+}
+
+func cmdASNBulk() *cobra.Command {
+	var (
+		input   string
+		workers int
+		rollup  bool
+	)
+	cmd := &cobra.Command{
+		Use:     "asn-bulk [-i asns.txt]",
+		Aliases: []string{"bulk-asn"},
+		Short:   "Look up many autnums and resolve each one's organization (--rollup-org groups ASNs by org)",
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			queries, err := readQueries(input)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			rows := lookupASNs(ctx, newClient(), queries, workers)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			for _, r := range rows {
+				if r.Error != "" {
+					warnf("%s: %s\n", r.Query, r.Error)
+				}
+			}
+			if rollup {
+				orgs := rollupOrgs(rows)
+				if flagJSON {
+					return printJSON(orgs)
+				}
+				for _, o := range orgs {
+					asns := make([]string, len(o.ASNs))
+					for i, n := range o.ASNs {
+						asns[i] = fmt.Sprintf("AS%d", n)
+					}
+					fmt.Printf("%s (%d): %s\n", o.Org, len(o.ASNs), strings.Join(asns, " "))
+				}
+				return nil
+			}
+			if flagJSON {
+				return printJSON(rows)
+			}
+			for _, r := range rows {
+				if r.Error == "" {
+					fmt.Printf("AS%-10d %-24s %-3s %s\n", r.ASN, r.Name, r.Country, r.Org)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&input, "input", "i", "", "file of ASNs (AS15169 or 15169), one per line (- or empty reads stdin)")
+	cmd.Flags().IntVar(&workers, "concurrency", 4, "lookups in flight at once")
+	cmd.Flags().BoolVar(&rollup, "rollup-org", false, "group ASNs by organization: org -> ASN list")
+	return cmd
+}
+
+// lookupASNs looks up queries with n lookups in flight, hydrating stub
+// organization entities, and returns the rows in input order.
+func lookupASNs(ctx context.Context, c *rc.Client, queries []string, n int) []asnRow {
+	out := make([]asnRow, len(queries))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(n, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				out[i] = lookupASN(ctx, c, queries[i])
+			}
+		}()
+	}
+	for i := range queries {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return out
+}
+
+func lookupASN(ctx context.Context, c *rc.Client, q string) asnRow {
+	row := asnRow{Query: q}
+	a, err := c.Autnum(ctx, q)
+	if err != nil {
+		row.Error = err.Error()
+		return row
+	}
+	row.ASN, row.Name, row.Country = a.StartAutnum, a.Name, a.CountryCode()
+	if e := a.Organization(); e != nil {
+		// Hydrate a copy: a may be shared through the client's caches. After a
+		// failed hydrate the name falls back to the handle.
+		org := *e
+		if err := c.Hydrate(ctx, &org); err != nil {
+			warnf("%s: organization %s: %v\n", q, org.Handle, err)
+		}
+		row.Org, row.OrgHandle = org.OrgName(), org.Handle
+	}
+	return row
+}
+
+// rollupOrgs groups rows by organization handle (name when there is none),
+// largest first; ASNs without an organization go under "unknown".
+func rollupOrgs(rows []asnRow) []orgRollup {
+	byKey := map[string]*orgRollup{}
+	for _, r := range rows {
+		if r.Error != "" {
+			continue
+		}
+		key, org := r.OrgHandle, r.Org
+		if key == "" {
+			key = org
+		}
+		if org == "" {
+			key, org = "", "unknown"
+		}
+		o := byKey[key]
+		if o == nil {
+			o = &orgRollup{Org: org, Handle: r.OrgHandle}
+			byKey[key] = o
+		}
+		o.ASNs = append(o.ASNs, r.ASN)
+	}
+	out := make([]orgRollup, 0, len(byKey))
+	for _, o := range byKey {
+		sort.Slice(o.ASNs, func(a, b int) bool { return o.ASNs[a] < o.ASNs[b] })
+		out = append(out, *o)
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if len(out[a].ASNs) != len(out[b].ASNs) {
+			return len(out[a].ASNs) > len(out[b].ASNs)
+		}
+		return out[a].Org < out[b].Org
+	})
+	return out
+}
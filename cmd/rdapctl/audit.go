@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	rc "github.com/datum-labs/rdap"
+	"github.com/datum-labs/rdap/x/annotate"
+)
+
+// ---- AUDIT (portfolio reports over many domains) ---------------------------
+
+func cmdAudit() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Security reports over a list of domains",
+	}
+	cmd.AddCommand(cmdAuditTransferLock())
+	return cmd
+}
+
+// transferLockLine is one line of the transfer-lock report: a verdict, or the
+// lookup error for that domain.
+type transferLockLine struct {
+	*annotate.TransferLockReport
+	Query string `json:"query,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// domainResult is the outcome of looking up one domain of a list.
+type domainResult struct {
+	Domain *rc.Domain
+	Err    error
+}
+
+// lookupDomains looks up names with n lookups in flight and returns the
+// results in input order.
+func lookupDomains(ctx context.Context, c *rc.Client, names []string, n int) []domainResult {
+	out := make([]domainResult, len(names))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(n, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				d, err := c.Domain(ctx, names[i])
+				out[i] = domainResult{Domain: d, Err: err}
+			}
+		}()
+	}
+	for i := range names {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return out
+}
+
+func cmdAuditTransferLock() *cobra.Command {
+	var (
+		input   string
+		workers int
+	)
+	cmd := &cobra.Command{
+		Use:   "transfer-lock [-i domains.txt]",
+		Short: "Report each domain's transfer lock (registry, registrar, remark-only, unlocked)",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			names, err := readQueries(input)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			c := newClient()
+
+			lines := make([]transferLockLine, len(names))
+			for i, res := range lookupDomains(ctx, c, names, workers) {
+				if res.Err != nil {
+					lines[i] = transferLockLine{Query: names[i], Error: res.Err.Error()}
+					continue
+				}
+				r := annotate.TransferLock(res.Domain)
+				lines[i] = transferLockLine{TransferLockReport: &r}
+			}
+
+			counts := map[string]int{}
+			for _, l := range lines {
+				if l.Error != "" {
+					counts["error"]++
+				} else {
+					counts[string(l.Verdict)]++
+				}
+				if flagJSON {
+					if err := printJSONLine(l); err != nil {
+						return err
+					}
+					continue
+				}
+				switch {
+				case l.Error != "":
+					fmt.Printf("%-40s error: %s\n", l.Query, l.Error)
+				case len(l.LockRemarks) > 0:
+					fmt.Printf("%-40s %s (remarks: %s)\n", l.Domain, l.Verdict, strings.Join(l.LockRemarks, "; "))
+				default:
+					fmt.Printf("%-40s %s\n", l.Domain, l.Verdict)
+				}
+			}
+			keys := make([]string, 0, len(counts))
+			for k := range counts {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			summary := make([]string, len(keys))
+			for i, k := range keys {
+				summary[i] = fmt.Sprintf("%d %s", counts[k], k)
+			}
+			progressf("transfer-lock: %d domains: %s\n", len(names), strings.Join(summary, ", "))
+			return ctx.Err()
+		},
+	}
+	cmd.Flags().StringVarP(&input, "input", "i", "", "file of domains, one per line (- or empty reads stdin)")
+	cmd.Flags().IntVar(&workers, "concurrency", 4, "lookups in flight at once")
+	return cmd
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	rc "github.com/datum-labs/rdap"
+)
+
+// ---- BASE (which registry a query is routed to, and why) -------------------
+
+func cmdBase() *cobra.Command {
+	return &cobra.Command{
+		Use:     "base <domain|ip|cidr|asn|handle>",
+		Aliases: []string{"resolve-base"},
+		Short:   "Print the RDAP base URL a query is sent to and why (bootstrap entry, override file, fallback)",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			r, err := newClient().ExplainBase(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+			if flagJSON {
+				return printJSON(r)
+			}
+			fmt.Printf("%s (%s) -> %s\n", r.Query, r.Kind, r.Base)
+			file := r.Bootstrap
+			if r.Override {
+				file += " (override)"
+			}
+			if r.Source == rc.BaseFromBootstrap {
+				fmt.Printf("  bootstrap entry %q in %s\n", r.Entry, file)
+			} else {
+				fmt.Printf("  fallback: no bootstrap entry matched in %s (or it could not be fetched)\n", file)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	rc "github.com/datum-labs/rdap"
+	"github.com/datum-labs/rdap/jobs"
+	"github.com/datum-labs/rdap/x/export"
+)
+
+// ---- BATCH (long sweeps over a persistent job queue) -----------------------
+
+// batchResult is one NDJSON line of batch output.
+type batchResult struct {
+	Query    string `json:"query"`
+	Object   any    `json:"object,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Attempts int    `json:"attempts,omitempty"`
+	// Stale is set when a registry failure was answered from an expired
+	// cache entry (staleIfError in --config).
+	Stale bool `json:"stale,omitempty"`
+	// Failure is Error in structured form (code, url, registry, retryAfter).
+	Failure *rc.ItemError `json:"failure,omitempty"`
+}
+
+func cmdBatch() *cobra.Command {
+	var (
+		input       string
+		resume      string
+		workers     int
+		maxAttempts int
+		prewarm     bool
+		job         string
+		outDir      string
+	)
+	cmd := &cobra.Command{
+		Use:   "batch [-i queries.txt] [--resume jobdir]",
+		Short: "Look up many queries (one per line); rate-limited items wait out Retry-After",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			format, err := outputFormat("csv", "table")
+			if err != nil {
+				return err
+			}
+			if format == "text" {
+				return errors.New("batch writes json, csv or table, not text")
+			}
+			dir := resume
+			if dir == "" {
+				tmp, err := os.MkdirTemp("", "rdapctl-batch-")
+				if err != nil {
+					return err
+				}
+				defer os.RemoveAll(tmp)
+				dir = tmp
+			}
+			q, err := jobs.Open(dir)
+			if err != nil {
+				return err
+			}
+			defer q.Close()
+
+			// On resume, input is optional: the queue already holds the sweep.
+			if input != "" || resume == "" {
+				queries, err := readQueries(input)
+				if err != nil {
+					return err
+				}
+				if _, err := q.Add(queries...); err != nil {
+					return err
+				}
+			}
+
+			// Results go to the job dir when resuming, so nothing is lost across runs.
+			var out io.Writer = os.Stdout
+			if resume != "" && format != "csv" {
+				f, err := os.OpenFile(filepath.Join(dir, "results.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+			enc := json.NewEncoder(out)
+			write := func(r batchResult) error { return enc.Encode(r) }
+			switch format {
+			case "csv":
+				if outDir == "" {
+					outDir = resume
+				}
+				if outDir == "" {
+					outDir = "."
+				}
+				tables := &csvResults{dir: outDir}
+				defer func() {
+					if err := tables.close(); err != nil {
+						warnf("batch: %v\n", err)
+					}
+				}()
+				write = tables.write
+			case "table":
+				// One line per result on stdout; a resumed sweep still keeps
+				// results.jsonl.
+				lines := export.NewLines(os.Stdout, "query")
+				keep := write
+				write = func(r batchResult) error {
+					if resume != "" {
+						if err := keep(r); err != nil {
+							return err
+						}
+					}
+					if r.Object == nil {
+						return lines.WriteError(errors.New(r.Error), r.Query)
+					}
+					return lines.Write(r.Object, r.Query)
+				}
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			if job != "" {
+				ctx = rc.WithJob(ctx, job)
+			}
+			c := newClient()
+			// A resumed sweep keeps counting against the month's byte budget.
+			if resume != "" {
+				usage := filepath.Join(dir, "bandwidth.json")
+				loadBandwidth(c, usage)
+				defer saveBandwidth(c, usage)
+			}
+			if prewarm {
+				var pending []string
+				for _, j := range q.Jobs() {
+					if j.State == jobs.StatePending {
+						pending = append(pending, j.Query)
+					}
+				}
+				start := time.Now()
+				warmed := c.Prewarm(ctx, pending)
+				for _, w := range warmed {
+					if w.Err != "" {
+						warnf("prewarm %s: %s\n", w.Host, w.Err)
+					}
+				}
+				progressf("prewarm: %d registry hosts in %v\n", len(warmed), time.Since(start).Round(time.Millisecond))
+			}
+			completeness, err := drainBatch(ctx, c, q, write, workers, maxAttempts)
+			counts := q.Counts()
+			progressf("batch: %d done, %d failed, %d pending\n", counts[jobs.StateDone], counts[jobs.StateFailed], counts[jobs.StatePending])
+			if !completeness.Complete() {
+				progressf("batch: %s\n", completenessLine(completeness))
+			}
+			if errors.Is(err, context.Canceled) && resume != "" {
+				fmt.Fprintf(os.Stderr, "interrupted; continue with: rdapctl batch --resume %s\n", resume)
+				return nil
+			}
+			var be *rc.ErrBudgetExceeded
+			if errors.As(err, &be) && resume != "" {
+				fmt.Fprintf(os.Stderr, "paused: %v; continue after %s with: rdapctl batch --resume %s\n", be, be.ResetAt.Format("2006-01-02"), resume)
+				return nil
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&input, "input", "i", "", "file of queries, one per line (- or empty reads stdin; optional with --resume)")
+	cmd.Flags().StringVar(&resume, "resume", "", "job directory that persists queue state and results.jsonl across restarts")
+	cmd.Flags().IntVar(&workers, "concurrency", 4, "lookups in flight at once")
+	cmd.Flags().IntVar(&maxAttempts, "max-attempts", 5, "give up on a query after this many rate-limited or transient failures")
+	cmd.Flags().StringVar(&job, "job", "", "name to account the sweep's bytes under (byteBudget.jobs in --config)")
+	cmd.Flags().StringVarP(&outDir, "out", "o", "", "directory for the --format csv tables (default: the --resume job dir, else the current directory)")
+	cmd.Flags().BoolVar(&prewarm, "prewarm", true, "resolve and connect to every registry host the queries map to before starting")
+	return cmd
+}
+
+// csvResults writes batch results as CSV tables in dir: <kind>.csv per object
+// class (export.Columns) after a query column, and failed.csv for the queries
+// that failed. Tables already there, from a sweep being resumed, are
+// appended to.
+type csvResults struct {
+	dir    string
+	files  []*os.File
+	tables *export.CSV
+	failed *csv.Writer
+}
+
+func (t *csvResults) write(r batchResult) error {
+	if r.Object != nil {
+		if t.tables == nil {
+			t.tables = export.NewCSV(func(class string) (io.Writer, bool, error) { return t.open(class) }, "query")
+		}
+		return t.tables.Write(r.Object, r.Query)
+	}
+	if t.failed == nil {
+		f, header, err := t.open("failed")
+		if err != nil {
+			return err
+		}
+		t.failed = csv.NewWriter(f)
+		if header {
+			if err := t.failed.Write([]string{"query", "attempts", "code", "error"}); err != nil {
+				return err
+			}
+		}
+	}
+	var code string
+	if r.Failure != nil {
+		code = rc.ErrorCode(r.Failure.Err)
+	}
+	return t.failed.Write(export.Escape([]string{r.Query, strconv.Itoa(r.Attempts), code, r.Error}))
+}
+
+// open opens dir/name.csv for appending, reporting whether it is new.
+func (t *csvResults) open(name string) (*os.File, bool, error) {
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return nil, false, err
+	}
+	f, err := os.OpenFile(filepath.Join(t.dir, name+".csv"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, false, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, false, err
+	}
+	t.files = append(t.files, f)
+	return f, st.Size() == 0, nil
+}
+
+// close flushes the tables and closes their files.
+func (t *csvResults) close() error {
+	var first error
+	keep := func(err error) {
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	if t.tables != nil {
+		keep(t.tables.Flush())
+	}
+	if t.failed != nil {
+		t.failed.Flush()
+		keep(t.failed.Error())
+	}
+	for _, f := range t.files {
+		keep(f.Close())
+	}
+	return first
+}
+
+// loadBandwidth restores the month's byte usage saved at path, if any.
+func loadBandwidth(c *rc.Client, path string) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var u rc.BandwidthUsage
+	if err == nil {
+		err = json.Unmarshal(b, &u)
+	}
+	if err != nil {
+		warnf("load bandwidth usage: %v\n", err)
+		return
+	}
+	c.SetBandwidthUsage(u)
+}
+
+func saveBandwidth(c *rc.Client, path string) {
+	b, err := json.Marshal(c.BandwidthUsage())
+	if err == nil {
+		err = os.WriteFile(path, b, 0o644)
+	}
+	if err != nil {
+		warnf("save bandwidth usage: %v\n", err)
+	}
+}
+
+func readQueries(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var out []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out, sc.Err()
+}
+
+// runBatch drains q with n workers, writing one result line per settled job.
+func runBatch(ctx context.Context, c *rc.Client, q *jobs.Queue, out io.Writer, n, maxAttempts int) (rc.Completeness, error) {
+	enc := json.NewEncoder(out)
+	return drainBatch(ctx, c, q, func(r batchResult) error { return enc.Encode(r) }, n, maxAttempts)
+}
+
+// drainBatch drains q with n workers, passing write each settled job's
+// result; write is never called concurrently. It reports the completeness of
+// the jobs it settled; attempts that were retried later do not count.
+func drainBatch(ctx context.Context, c *rc.Client, q *jobs.Queue, write func(batchResult) error, n, maxAttempts int) (rc.Completeness, error) {
+	if n < 1 {
+		n = 1
+	}
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		run      rc.CompletenessReport
+	)
+	emit := func(r batchResult) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return write(r)
+	}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				j, err := q.Next(ctx)
+				if err != nil {
+					if !errors.Is(err, jobs.ErrDrained) {
+						mu.Lock()
+						if firstErr == nil {
+							firstErr = err
+						}
+						mu.Unlock()
+					}
+					return
+				}
+				lctx, item := rc.WithCompleteness(ctx)
+				obj, err := c.Lookup(lctx, j.Query, flagTLD)
+				settled := func(r batchResult) error {
+					ic := item.Report()
+					if r.Failure != nil && len(ic.Failed) == 0 {
+						ic.Failed = []*rc.ItemError{r.Failure} // failed before any fetch
+					}
+					run.Merge(ic)
+					r.Stale = len(ic.Stale) > 0
+					return emit(r)
+				}
+				if err := settleJob(ctx, q, j, obj, err, maxAttempts, settled); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return run.Report(), firstErr
+}
+
+// settleJob records the outcome of one lookup: done, rescheduled, or failed.
+// A used-up byte budget pauses the run: the job goes back untried and the
+// budget error stops the worker.
+func settleJob(ctx context.Context, q *jobs.Queue, j jobs.Job, obj any, err error, maxAttempts int, emit func(batchResult) error) error {
+	var be *rc.ErrBudgetExceeded
+	switch {
+	case err == nil:
+		if err := q.Complete(j.ID); err != nil {
+			return err
+		}
+		return emit(batchResult{Query: j.Query, Object: obj, Attempts: j.Attempts + 1})
+	case ctx.Err() != nil:
+		// Interrupted mid-flight: leave the job pending for the next run.
+		return ctx.Err()
+	case errors.As(err, &be):
+		if rerr := q.Release(j.ID); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+	if wait, ok := retryDelay(err, j.Attempts+1); ok && j.Attempts+1 < maxAttempts {
+		return q.Retry(j.ID, wait, err)
+	}
+	if ferr := q.Fail(j.ID, err); ferr != nil {
+		return ferr
+	}
+	return emit(batchResult{Query: j.Query, Error: err.Error(), Attempts: j.Attempts + 1,
+		Failure: &rc.ItemError{ID: j.Query, Attempts: j.Attempts + 1, Err: err}})
+}
+
+// retryDelay reports whether err is worth retrying later and how long to wait:
+// the registry's Retry-After when it sent one, else an escalating backoff.
+func retryDelay(err error, attempt int) (time.Duration, bool) {
+	backoff := rc.ExponentialBackoff(30*time.Second, 2, 30*time.Minute)(attempt)
+	var he *rc.HTTPError
+	if errors.As(err, &he) {
+		switch he.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusInternalServerError:
+			if he.RetryAfter > 0 {
+				return he.RetryAfter, true
+			}
+			return backoff, true
+		}
+		return 0, false
+	}
+	var ne interface{ Timeout() bool }
+	if errors.As(err, &ne) && ne.Timeout() {
+		return backoff, true
+	}
+	return 0, false
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	rc "github.com/datum-labs/rdap"
+)
+
+// ---- BOOTSTRAP (IANA registry change monitoring) ---------------------------
+
+func cmdBootstrap() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bootstrap",
+		Short: "Inspect the IANA RDAP bootstrap registries",
+	}
+	cmd.AddCommand(cmdBootstrapWatch())
+	return cmd
+}
+
+func cmdBootstrapWatch() *cobra.Command {
+	var (
+		webhook  string
+		state    string
+		interval time.Duration
+		once     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "watch [--webhook URL] [--state file.json]",
+		Short: "Report TLDs/ranges whose RDAP service was added, removed or moved",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			var notify []func([]rc.BootstrapChange)
+			notify = append(notify, func(cs []rc.BootstrapChange) {
+				for _, ch := range cs {
+					_ = printJSONLine(ch)
+				}
+			})
+			if webhook != "" {
+				notify = append(notify, rc.WebhookNotifier(webhook, nil, func(err error) {
+					fmt.Fprintln(os.Stderr, "webhook:", err)
+				}))
+			}
+			var m *rc.BootstrapMonitor
+			m = newClient().NewBootstrapMonitor(func(cs []rc.BootstrapChange) {
+				for _, n := range notify {
+					n(cs)
+				}
+				if state != "" {
+					saveBootstrapState(m, state)
+				}
+			})
+
+			// A saved state lets a cron-driven --once run diff against the previous run.
+			if state != "" {
+				b, err := os.ReadFile(state)
+				switch {
+				case err == nil:
+					var s rc.BootstrapState
+					if err := json.Unmarshal(b, &s); err != nil {
+						return fmt.Errorf("%s: %w", state, err)
+					}
+					m.SetState(s)
+				case !errors.Is(err, os.ErrNotExist):
+					return err
+				}
+				defer saveBootstrapState(m, state)
+			}
+
+			if once {
+				_, err := m.Check(context.Background())
+				return err
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			err := m.Run(ctx, interval, func(err error) { fmt.Fprintln(os.Stderr, err) })
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&webhook, "webhook", "", "POST each batch of changes as JSON to this URL")
+	cmd.Flags().StringVar(&state, "state", "", "load the baseline from and save it to this file (diffs across runs)")
+	cmd.Flags().DurationVar(&interval, "interval", 6*time.Hour, "how often to re-fetch the bootstrap files")
+	cmd.Flags().BoolVar(&once, "once", false, "check once and exit (pair with --state for cron)")
+	return cmd
+}
+
+func saveBootstrapState(m *rc.BootstrapMonitor, path string) {
+	b, err := json.Marshal(m.State())
+	if err == nil {
+		err = os.WriteFile(path, b, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "save state:", err)
+	}
+}
+
+func printJSONLine(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datum-labs/rdap/dnscheck"
+)
+
+// ---- CHECK (RDAP delegation vs live DNS, for mail deliverability) ----------
+
+func cmdCheck() *cobra.Command {
+	var (
+		mx      bool
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "check <domain>...",
+		Short: "Check that RDAP-listed nameservers serve the zone and its MX hosts resolve",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			c := newClient()
+			ctx := context.Background()
+			dc := dnscheck.New()
+			dc.Timeout = timeout
+			failed := 0
+			for _, name := range args {
+				d, err := c.Domain(ctx, name)
+				if err != nil {
+					return err
+				}
+				r, err := dc.Check(ctx, d, mx)
+				if err != nil {
+					return err
+				}
+				if !r.OK() {
+					failed++
+				}
+				if flagJSON {
+					if err := printJSONLine(r); err != nil {
+						return err
+					}
+					continue
+				}
+				printCheck(r)
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d domains have delegation or mail problems", failed, len(args))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&mx, "mx", true, "also check the zone's MX records and that each MX host resolves")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "timeout for each query sent to a nameserver")
+	return cmd
+}
+
+func printCheck(r *dnscheck.Report) {
+	fmt.Printf("%s\n", r.Domain)
+	for _, ns := range r.Nameservers {
+		state := "ok"
+		if !ns.ServesApex {
+			state = "LAME"
+		}
+		fmt.Printf("  ns %-30s %-5s %v\n", ns.Host, state, ns.Addrs)
+	}
+	if r.NullMX {
+		fmt.Println("  mx (null MX: accepts no mail)")
+	}
+	for _, m := range r.MX {
+		fmt.Printf("  mx %-30s %-5d %v\n", m.Host, m.Pref, m.Addrs)
+	}
+	for _, p := range r.Problems {
+		fmt.Printf("  ! %s\n", p)
+	}
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	rc "github.com/datum-labs/rdap"
+	"github.com/datum-labs/rdap/jobs"
+	"github.com/datum-labs/rdap/snapshot"
+)
+
+// ---- DAEMON (scheduled batches, watches and bootstrap checks) --------------
+
+// daemonConfig is the jobs file of `rdapctl daemon`, YAML or (for a .json
+// file) JSON.
+type daemonConfig struct {
+	// Dir holds each job's state under Dir/<name>: the batch queue and
+	// results.jsonl, the watch history, the bootstrap baseline, changes.jsonl;
+	// Dir/bandwidth.json keeps the month's byte usage across restarts.
+	Dir string `json:"dir" yaml:"dir"`
+	// Listen is the status endpoint's address; "" disables it.
+	Listen string      `json:"listen,omitempty" yaml:"listen,omitempty"`
+	Jobs   []daemonJob `json:"jobs" yaml:"jobs"`
+	// Client configures the daemon's client, since its --config names this
+	// file rather than a client config.
+	Client *rc.Config `json:"client,omitempty" yaml:"client,omitempty"`
+}
+
+// Job types of a daemonJob.
+const (
+	jobBatch     = "batch"     // look up Input's queries, like rdapctl batch --resume
+	jobWatch     = "watch"     // diff Input's objects against the stored history, like snapshot history
+	jobBootstrap = "bootstrap" // refresh the client's bootstrap and report moved registries, like bootstrap watch
+)
+
+type daemonJob struct {
+	Name  string      `json:"name" yaml:"name"`
+	Type  string      `json:"type" yaml:"type"`
+	Every rc.Duration `json:"every" yaml:"every"`
+	// Input is the queries file of batch and watch jobs, re-read every run.
+	Input string `json:"input,omitempty" yaml:"input,omitempty"`
+	// Webhook receives watch and bootstrap changes as {"job", "changes"}.
+	Webhook     string `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+	Concurrency int    `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	MaxAttempts int    `json:"maxAttempts,omitempty" yaml:"maxAttempts,omitempty"`
+}
+
+func loadDaemonConfig(path string) (*daemonConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var cfg daemonConfig
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+	} else {
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		err = dec.Decode(&cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.Client != nil {
+		if err := cfg.Client.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: client: %w", path, err)
+		}
+	}
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("%s: dir is required", path)
+	}
+	seen := map[string]bool{}
+	for i, j := range cfg.Jobs {
+		switch {
+		case j.Name == "" || strings.ContainsAny(j.Name, `/\`) || j.Name == "." || j.Name == "..":
+			return nil, fmt.Errorf("%s: job %d: name must be a plain file name", path, i)
+		case seen[j.Name]:
+			return nil, fmt.Errorf("%s: job %q: duplicate name", path, j.Name)
+		case j.Type != jobBatch && j.Type != jobWatch && j.Type != jobBootstrap:
+			return nil, fmt.Errorf("%s: job %q: type must be batch, watch or bootstrap", path, j.Name)
+		case time.Duration(j.Every) <= 0:
+			return nil, fmt.Errorf("%s: job %q: every must be a positive duration", path, j.Name)
+		case j.Type != jobBootstrap && j.Input == "":
+			return nil, fmt.Errorf("%s: job %q: input is required", path, j.Name)
+		}
+		seen[j.Name] = true
+	}
+	if len(cfg.Jobs) == 0 {
+		return nil, fmt.Errorf("%s: no jobs", path)
+	}
+	return &cfg, nil
+}
+
+// jobStatus is what the status endpoint reports for one job.
+type jobStatus struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Every     string    `json:"every"`
+	Running   bool      `json:"running"`
+	Runs      int       `json:"runs"`
+	LastStart time.Time `json:"lastStart,omitempty"`
+	LastEnd   time.Time `json:"lastEnd,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+	Next      time.Time `json:"next,omitempty"`
+	// PausedUntil is set while the job's or the month's byte budget is used
+	// up: the next run waits for the new month.
+	PausedUntil time.Time `json:"pausedUntil,omitempty"`
+	// Summary is the last run's outcome: queue counts for batches, the
+	// number of changes for watches and bootstrap checks.
+	Summary any `json:"summary,omitempty"`
+	// Completeness is the last batch or watch run's: what was fetched,
+	// served stale, failed or skipped.
+	Completeness *rc.Completeness `json:"completeness,omitempty"`
+}
+
+type daemon struct {
+	cfg     *daemonConfig
+	c       *rc.Client
+	started time.Time
+
+	mu     sync.Mutex
+	status map[string]*jobStatus
+	boot   map[string]*rc.BootstrapMonitor
+}
+
+func cmdDaemon() *cobra.Command {
+	var jobsPath string
+	cmd := &cobra.Command{
+		Use:   "daemon --config jobs.yaml",
+		Short: "Run scheduled batch lookups, watches and bootstrap checks, with a status endpoint",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err := loadDaemonConfig(jobsPath)
+			if err != nil {
+				return err
+			}
+			var opts []rc.Option
+			if cfg.Client != nil {
+				opts = cfg.Client.Options()
+			}
+			d := &daemon{cfg: cfg, c: newClient(opts...), started: time.Now(), status: map[string]*jobStatus{}, boot: map[string]*rc.BootstrapMonitor{}}
+			if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+				return err
+			}
+			loadBandwidth(d.c, d.bandwidthPath())
+			for _, j := range cfg.Jobs {
+				d.status[j.Name] = &jobStatus{Name: j.Name, Type: j.Type, Every: time.Duration(j.Every).String()}
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			var srv *http.Server
+			if cfg.Listen != "" {
+				srv = &http.Server{Addr: cfg.Listen, Handler: d.handler(), ReadHeaderTimeout: 10 * time.Second}
+				go func() {
+					if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+						warnf("status endpoint: %v\n", err)
+					}
+				}()
+				progressf("daemon: status on http://%s/status\n", cfg.Listen)
+			}
+			var wg sync.WaitGroup
+			for _, j := range cfg.Jobs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					d.schedule(ctx, j)
+				}()
+			}
+			wg.Wait()
+			d.saveBandwidth()
+			if srv != nil {
+				shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = srv.Shutdown(shutdown)
+			}
+			return nil
+		},
+	}
+	// A local --config: the daemon's client is configured in the jobs file.
+	cmd.Flags().StringVar(&jobsPath, "config", "", "jobs file, YAML (JSON if it ends in .json): dir, listen, jobs (name, type, every, input, ...) and client (an rdap.Config)")
+	_ = cmd.MarkFlagRequired("config")
+	return cmd
+}
+
+// schedule runs j now and then every j.Every until ctx ends. Runs never
+// overlap: a run that takes longer than the interval delays the next one.
+func (d *daemon) schedule(ctx context.Context, j daemonJob) {
+	every := time.Duration(j.Every)
+	for {
+		paused := d.run(ctx, j)
+		next := time.Now().Add(every)
+		if paused.After(next) {
+			next = paused
+		}
+		d.update(j.Name, func(s *jobStatus) { s.Next = next })
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+// run runs j once, with its bytes accounted to it by name, and returns when
+// a used-up byte budget pauses it until (zero if it is not paused).
+func (d *daemon) run(ctx context.Context, j daemonJob) (paused time.Time) {
+	ctx = rc.WithJob(ctx, j.Name)
+	d.update(j.Name, func(s *jobStatus) { s.Running, s.LastStart, s.Next = true, time.Now(), time.Time{} })
+	dir := filepath.Join(d.cfg.Dir, j.Name)
+	var (
+		summary any
+		err     = os.MkdirAll(dir, 0o755)
+	)
+	if err == nil {
+		switch j.Type {
+		case jobBatch:
+			summary, err = d.runBatch(ctx, j, dir)
+		case jobWatch:
+			summary, err = d.runWatch(ctx, j, dir)
+		case jobBootstrap:
+			summary, err = d.runBootstrap(ctx, j, dir)
+		}
+	}
+	if ctx.Err() != nil {
+		err = nil // shutting down; the next start resumes
+	}
+	if err != nil {
+		warnf("daemon: %s: %v\n", j.Name, err)
+	}
+	// A used-up registry budget only pauses that registry's lookups; the
+	// job's own or the monthly one pauses the job.
+	var be *rc.ErrBudgetExceeded
+	if errors.As(err, &be) && be.Scope != rc.BudgetRegistry {
+		paused = be.ResetAt
+	}
+	d.update(j.Name, func(s *jobStatus) {
+		s.Running, s.LastEnd, s.Summary, s.LastError = false, time.Now(), summary, ""
+		s.Runs++
+		s.PausedUntil = paused
+		if err != nil {
+			s.LastError = err.Error()
+		}
+	})
+	d.saveBandwidth()
+	return paused
+}
+
+func (d *daemon) bandwidthPath() string { return filepath.Join(d.cfg.Dir, "bandwidth.json") }
+
+// saveBandwidth persists the month's byte usage, so budgets hold across restarts.
+func (d *daemon) saveBandwidth() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	saveBandwidth(d.c, d.bandwidthPath())
+}
+
+func (d *daemon) update(name string, fn func(*jobStatus)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(d.status[name])
+}
+
+// runBatch adds the input's queries to the job's queue (requeueing those a
+// previous run finished) and drains it into results.jsonl.
+func (d *daemon) runBatch(ctx context.Context, j daemonJob, dir string) (any, error) {
+	queries, err := readQueries(j.Input)
+	if err != nil {
+		return nil, err
+	}
+	q, err := jobs.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+	if _, err := q.Add(queries...); err != nil {
+		return nil, err
+	}
+	if _, err := q.Requeue(0, queries...); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "results.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	workers, attempts := j.Concurrency, j.MaxAttempts
+	if workers <= 0 {
+		workers = 4
+	}
+	if attempts <= 0 {
+		attempts = 5
+	}
+	completeness, err := runBatch(ctx, d.c, q, f, workers, attempts)
+	d.update(j.Name, func(s *jobStatus) { s.Completeness = &completeness })
+	return q.Counts(), err
+}
+
+// runWatch re-looks up the input's queries against the registry (not the
+// caches) and records created/updated/deleted/restored changes.
+func (d *daemon) runWatch(ctx context.Context, j daemonJob, dir string) (any, error) {
+	queries, err := readQueries(j.Input)
+	if err != nil {
+		return nil, err
+	}
+	h, err := snapshot.OpenHistory(filepath.Join(dir, "history.json"))
+	if err != nil {
+		return nil, err
+	}
+	fresh, watched := rc.WithCompleteness(rc.WithFreshData(ctx))
+	now := time.Now()
+	var (
+		changes []snapshot.Change
+		paused  error
+	)
+	for _, q := range queries {
+		if ctx.Err() != nil {
+			break
+		}
+		obj, lerr := d.c.Lookup(fresh, q, flagTLD)
+		var be *rc.ErrBudgetExceeded
+		if errors.As(lerr, &be) {
+			paused = lerr
+			if be.Scope != rc.BudgetRegistry {
+				break
+			}
+			continue
+		}
+		ch, err := h.Observe(strings.ToLower(q), obj, lerr, now)
+		if err != nil {
+			return nil, err
+		}
+		if ch != nil {
+			// Other jobs on the client poll it through the cache; let them
+			// see it settle.
+			d.c.NoteChange(obj, lerr)
+			changes = append(changes, *ch)
+		}
+	}
+	if err := h.Save(); err != nil {
+		return nil, err
+	}
+	completeness := watched.Report()
+	d.update(j.Name, func(s *jobStatus) { s.Completeness = &completeness })
+	return map[string]int{"queries": len(queries), "changes": len(changes)}, errors.Join(paused, report(j, dir, changes))
+}
+
+// runBootstrap re-fetches the client's DNS bootstrap and diffs every
+// bootstrap file against the job's saved baseline.
+func (d *daemon) runBootstrap(ctx context.Context, j daemonJob, dir string) (any, error) {
+	if err := d.c.RefreshBootstrap(ctx); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "bootstrap.json")
+	d.mu.Lock()
+	m := d.boot[j.Name]
+	d.mu.Unlock()
+	if m == nil {
+		m = d.c.NewBootstrapMonitor(nil)
+		b, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			var s rc.BootstrapState
+			if err := json.Unmarshal(b, &s); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			m.SetState(s)
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		}
+		d.mu.Lock()
+		d.boot[j.Name] = m
+		d.mu.Unlock()
+	}
+	// A file that failed to fetch is in err; the others were still diffed.
+	changes, err := m.Check(ctx)
+	saveBootstrapState(m, path)
+	return map[string]int{"changes": len(changes)}, errors.Join(err, report(j, dir, changes))
+}
+
+var webhookClient = &http.Client{Timeout: 15 * time.Second}
+
+// report appends changes to the job's changes.jsonl and posts them to its webhook.
+func report[T any](j daemonJob, dir string, changes []T) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, ch := range changes {
+		if err := enc.Encode(ch); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "changes.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf.Bytes())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil || j.Webhook == "" {
+		return err
+	}
+	b, err := json.Marshal(struct {
+		Job     string `json:"job"`
+		Changes []T    `json:"changes"`
+	}{j.Name, changes})
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(j.Webhook, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s: %s", j.Webhook, resp.Status)
+	}
+	return nil
+}
+
+// handler serves /status (every job's schedule and last run, plus the
+// client's health and the month's byte usage) and /healthz.
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, _ *http.Request) {
+		d.mu.Lock()
+		out := struct {
+			Started   time.Time         `json:"started"`
+			Jobs      []jobStatus       `json:"jobs"`
+			Health    rc.Health         `json:"health"`
+			Bandwidth rc.BandwidthUsage `json:"bandwidth"`
+		}{Started: d.started, Health: d.c.Health(), Bandwidth: d.c.BandwidthUsage()}
+		for _, j := range d.cfg.Jobs {
+			out.Jobs = append(out.Jobs, *d.status[j.Name])
+		}
+		d.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	})
+	return mux
+}
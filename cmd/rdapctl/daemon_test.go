@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadDaemonConfig(t *testing.T) {
+	write := func(t *testing.T, name, body string) string {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg, err := loadDaemonConfig(write(t, "jobs.json", `{"dir": "/var/lib/rdapctl", "listen": "127.0.0.1:8081", "jobs": [
+		{"name": "enrich", "type": "batch", "every": "24h", "input": "queries.txt", "concurrency": 4},
+		{"name": "iana", "type": "bootstrap", "every": "6h"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Dir != "/var/lib/rdapctl" || len(cfg.Jobs) != 2 || time.Duration(cfg.Jobs[0].Every) != 24*time.Hour || cfg.Jobs[0].Concurrency != 4 {
+		t.Fatalf("config = %+v", cfg)
+	}
+
+	cfg, err = loadDaemonConfig(write(t, "jobs.yaml", `dir: /var/lib/rdapctl
+listen: 127.0.0.1:8081
+client:
+  timeout: 30s
+  maxRetries: 2
+jobs:
+  - name: enrich
+    type: batch
+    every: 24h
+    input: queries.txt
+    maxAttempts: 3
+  - {name: iana, type: bootstrap, every: 6h}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != "127.0.0.1:8081" || len(cfg.Jobs) != 2 || time.Duration(cfg.Jobs[1].Every) != 6*time.Hour || cfg.Jobs[0].MaxAttempts != 3 ||
+		cfg.Client == nil || time.Duration(cfg.Client.Timeout) != 30*time.Second {
+		t.Fatalf("yaml config = %+v", cfg)
+	}
+
+	for _, tc := range []struct{ name, body, want string }{
+		{"yaml unknown field", "dir: d\njobs:\n  - {name: a, type: bootstrap, every: 1h, cron: '* * * * *'}\n", "not found"},
+		{"json file holding yaml", "dir: d\njobs:\n  - {name: a, type: bootstrap, every: 1h}\n", "invalid character"},
+		{"unknown field", `{"dir": "d", "jobs": [{"name": "a", "type": "bootstrap", "every": "1h", "cron": "* * * * *"}]}`, "unknown field"},
+		{"no dir", `{"jobs": [{"name": "a", "type": "bootstrap", "every": "1h"}]}`, "dir is required"},
+		{"no jobs", `{"dir": "d", "jobs": []}`, "no jobs"},
+		{"path name", `{"dir": "d", "jobs": [{"name": "../a", "type": "bootstrap", "every": "1h"}]}`, "plain file name"},
+		{"duplicate", `{"dir": "d", "jobs": [{"name": "a", "type": "bootstrap", "every": "1h"}, {"name": "a", "type": "bootstrap", "every": "2h"}]}`, "duplicate name"},
+		{"type", `{"dir": "d", "jobs": [{"name": "a", "type": "cron", "every": "1h"}]}`, "type must be"},
+		{"every", `{"dir": "d", "jobs": [{"name": "a", "type": "bootstrap", "every": "0s"}]}`, "positive duration"},
+		{"input", `{"dir": "d", "jobs": [{"name": "a", "type": "watch", "every": "1h"}]}`, "input is required"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			name := "jobs.json"
+			if strings.HasPrefix(tc.name, "yaml") {
+				name = "jobs.yaml"
+			}
+			if _, err := loadDaemonConfig(write(t, name, tc.body)); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	rc "github.com/datum-labs/rdap"
+)
+
+// ---- DOCTOR (client diagnostics) --------------------------------------------
+
+// maxClockSkew is the skew past which doctor warns: beyond it, cache lifetimes
+// and Retry-After dates computed from the local clock would be noticeably off.
+const maxClockSkew = 30 * time.Second
+
+type doctorReport struct {
+	Query   string        `json:"query"`
+	Elapsed time.Duration `json:"elapsed"`
+	Error   string        `json:"error,omitempty"`
+	rc.Health
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func cmdDoctor() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor [query]",
+		Short: "Run a test lookup and report client health (e.g. local clock skew)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			q := "example.com"
+			if len(args) == 1 {
+				q = args[0]
+			}
+			c := newClient()
+			start := time.Now()
+			_, err := c.Lookup(context.Background(), q, flagTLD)
+			r := doctorReport{Query: q, Elapsed: time.Since(start).Round(time.Millisecond), Health: c.Health()}
+			if err != nil {
+				r.Error = err.Error()
+			}
+			switch {
+			case flagReplay != "":
+				r.Warnings = append(r.Warnings, "replaying cassettes: clock skew is not measured")
+			case r.ClockSkewAt.IsZero():
+				r.Warnings = append(r.Warnings, "no response carried a Date header: clock skew unknown")
+			case r.ClockSkew > maxClockSkew || r.ClockSkew < -maxClockSkew:
+				r.Warnings = append(r.Warnings, fmt.Sprintf("local clock is off by %v from %s; sync it (NTP)", r.ClockSkew, r.ClockSkewHost))
+			}
+			for _, h := range r.DegradedHosts {
+				r.Warnings = append(r.Warnings, fmt.Sprintf("%s is failing with retryable errors; concurrent requests to it are limited", h))
+			}
+			for _, a := range r.SizeAnomalies {
+				if a.Typical == 0 {
+					r.Warnings = append(r.Warnings, fmt.Sprintf("%s: response over the size cap (rejected)", a.URL))
+					continue
+				}
+				r.Warnings = append(r.Warnings, fmt.Sprintf("%s: %d-byte response, typical for %s is %d", a.URL, a.Bytes, a.Host, a.Typical))
+			}
+			if flagJSON {
+				return printJSON(r)
+			}
+			fmt.Printf("lookup %s: %v", q, r.Elapsed)
+			if r.Error != "" {
+				fmt.Printf(" (error: %s)", r.Error)
+			}
+			fmt.Println()
+			if !r.ClockSkewAt.IsZero() {
+				fmt.Printf("clock skew: %v (vs %s)\n", r.ClockSkew, r.ClockSkewHost)
+			}
+			for _, w := range r.Warnings {
+				fmt.Println("warning:", w)
+			}
+			return nil
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	rc "github.com/datum-labs/rdap"
+)
+
+// ---- ERRORS (how a failed command is reported) -----------------------------
+
+// failedQuery is what the failing command was asked about (its arguments),
+// reported as the error's id.
+var failedQuery string
+
+// noteQuery, run before every command, remembers the command's
+// arguments for fatal.
+func noteQuery(_ *cobra.Command, args []string) {
+	failedQuery = strings.Join(args, " ")
+}
+
+// cliError is the JSON error rdapctl writes to stderr in JSON mode:
+// {"error": {"id", "code", "message", "registry", "url", "status", "retryAfter"}},
+// or {"errors": [...]} when several items of a walk or batch failed.
+type cliError struct {
+	Error *rc.ItemError `json:"error"`
+}
+
+// fatal reports err and exits with status 1. With --json the error is a
+// single JSON line on stderr, classified by rc.ErrorCode; otherwise it is the
+// plain message.
+func fatal(err error) {
+	if !flagJSON {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	var v any = cliError{&rc.ItemError{ID: failedQuery, Err: err}}
+	var be *rc.BatchError
+	if errors.As(err, &be) && be.Len() > 0 {
+		v = be
+	}
+	b, jerr := json.Marshal(v)
+	if jerr != nil {
+		b, _ = json.Marshal(map[string]any{"error": map[string]string{"code": rc.CodeOther, "message": err.Error()}})
+	}
+	os.Stderr.Write(append(b, '\n'))
+	os.Exit(1)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datum-labs/rdap/graph"
+)
+
+// ---- GRAPH (offline tools over exported graphs) ----------------------------
+
+func cmdGraph() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Work with graphs exported by `tree` (JSON)",
+	}
+	cmd.AddCommand(cmdGraphQuery())
+	return cmd
+}
+
+func cmdGraphQuery() *cobra.Command {
+	var input string
+	cmd := &cobra.Command{
+		Use:   "query '<MATCH ... RETURN ...>'",
+		Short: "Run a path query, e.g. MATCH e:entity-[REGISTRANT_OF]->domain RETURN e.handle",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			var r io.Reader = os.Stdin
+			if input != "" && input != "-" {
+				f, err := os.Open(input)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+			g, err := graph.Load(r)
+			if err != nil {
+				return fmt.Errorf("load graph: %w", err)
+			}
+			res, err := g.Query(args[0])
+			if err != nil {
+				return err
+			}
+			if flagJSON {
+				return printJSON(res)
+			}
+			fmt.Println(strings.Join(res.Columns, "\t"))
+			for _, row := range res.Rows {
+				fmt.Println(strings.Join(row, "\t"))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&input, "input", "i", "-", "graph JSON file (from `rdapctl tree`); - reads stdin")
+	return cmd
+}
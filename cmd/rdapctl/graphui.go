@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"time"
+)
+
+// ---- TREE --open (local graph viewer) ---------------------------------------
+
+// graphUIPage is the viewer: one self-contained page that fetches graph.json
+// and lays it out with a force-directed simulation, no external assets.
+//
+//go:embed graphui.html
+var graphUIPage []byte
+
+// openGraph serves g to a browser on a loopback port and opens it, serving
+// until interrupted.
+func openGraph(g *Graph, seed string) error {
+	data, err := json.Marshal(struct {
+		Seed string `json:"seed"`
+		*Graph
+	}{seed, g})
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(graphUIPage)
+	})
+	mux.HandleFunc("/graph.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+	u := "http://" + ln.Addr().String() + "/"
+	progressf("graph of %s at %s (Ctrl-C to stop)\n", seed, u)
+	if err := openBrowser(u); err != nil {
+		warnf("could not open a browser (%v); visit %s\n", err, u)
+	}
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// openBrowser opens u in the desktop's default browser.
+func openBrowser(u string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	return cmd.Start()
+}
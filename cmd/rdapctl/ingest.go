@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datum-labs/rdap/ingest"
+	"github.com/datum-labs/rdap/jobs"
+)
+
+// ---- INGEST (zone-file / escrow name lists into the job queue) -------------
+
+// newNamePriority is the queue priority of names that appeared since the last
+// import; they are looked up before the rest of the zone.
+const newNamePriority = 10
+
+func cmdIngest() *cobra.Command {
+	var (
+		input       string
+		zone        string
+		dir         string
+		interval    time.Duration
+		once        bool
+		refresh     bool
+		workers     int
+		maxAttempts int
+	)
+	cmd := &cobra.Command{
+		Use:   "ingest -i com.zone --zone com --dir ./ingest",
+		Short: "Import a zone file or domain list and enrich it over RDAP, newly added names first",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			q, err := jobs.Open(dir)
+			if err != nil {
+				return err
+			}
+			defer q.Close()
+			f, err := os.OpenFile(filepath.Join(dir, "results.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			c := newClient()
+			for {
+				if err := importNames(q, input, zone, filepath.Join(dir, "names.txt"), refresh); err != nil {
+					return err
+				}
+				completeness, err := runBatch(ctx, c, q, f, workers, maxAttempts)
+				counts := q.Counts()
+				progressf("ingest: %d done, %d failed, %d pending\n", counts[jobs.StateDone], counts[jobs.StateFailed], counts[jobs.StatePending])
+				if !completeness.Complete() {
+					progressf("ingest: %s\n", completenessLine(completeness))
+				}
+				if errors.Is(err, context.Canceled) {
+					fmt.Fprintf(os.Stderr, "interrupted; rerun with --dir %s to continue\n", dir)
+					return nil
+				}
+				if err != nil || once {
+					return err
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(interval):
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&input, "input", "i", "", "zone file or list of names, one per line (- or empty reads stdin)")
+	cmd.Flags().StringVar(&zone, "zone", "", "zone origin (e.g. com); qualifies relative names and drops names outside it")
+	cmd.Flags().StringVar(&dir, "dir", "ingest", "directory holding the job queue, names.txt (last import) and results.jsonl")
+	cmd.Flags().DurationVar(&interval, "interval", 24*time.Hour, "how often to re-read the input and enrich again")
+	cmd.Flags().BoolVar(&once, "once", false, "import and enrich once, then exit (for cron)")
+	cmd.Flags().BoolVar(&refresh, "refresh", false, "also look up names already enriched on an earlier run, after the new ones")
+	cmd.Flags().IntVar(&workers, "concurrency", 4, "lookups in flight at once")
+	cmd.Flags().IntVar(&maxAttempts, "max-attempts", 5, "give up on a name after this many rate-limited or transient failures")
+	return cmd
+}
+
+// importNames reads the input, diffs it against the previous import saved in
+// namesFile and queues it: names added since then at newNamePriority, the rest
+// (the whole zone on a first import) at normal priority.
+func importNames(q *jobs.Queue, input, zone, namesFile string, refresh bool) error {
+	var r io.Reader = os.Stdin
+	if input != "" && input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	names, err := ingest.ReadNames(r, zone)
+	if err != nil {
+		return fmt.Errorf("%s: %w", input, err)
+	}
+	prev, seen, err := ingest.LoadNames(namesFile)
+	if err != nil {
+		return err
+	}
+
+	var added, removed []string
+	if seen {
+		added, removed = ingest.Diff(prev, names)
+	}
+	isNew := make(map[string]bool, len(added))
+	for _, n := range added {
+		isNew[n] = true
+	}
+	var rest []string
+	for _, n := range names {
+		if !isNew[n] {
+			rest = append(rest, n)
+		}
+	}
+	if _, err := q.AddPriority(newNamePriority, added...); err != nil {
+		return err
+	}
+	// A name deleted and registered again is new too, though the queue knows it.
+	if _, err := q.Requeue(newNamePriority, added...); err != nil {
+		return err
+	}
+	if _, err := q.Add(rest...); err != nil {
+		return err
+	}
+	if refresh {
+		if _, err := q.Requeue(0, rest...); err != nil {
+			return err
+		}
+	}
+	if err := ingest.SaveNames(namesFile, names); err != nil {
+		return err
+	}
+	progressf("ingest: %d names, %d added, %d removed\n", len(names), len(added), len(removed))
+	return nil
+}
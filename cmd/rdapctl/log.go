@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// ---- DIAGNOSTICS (stderr only; -q, -v, -vv) --------------------------------
+
+// Data (objects, reports, NDJSON) goes to stdout; everything else — progress,
+// summaries, warnings, the client's retries and cache activity — goes to
+// stderr, so `rdapctl ... > out.json` never mixes the two.
+
+var (
+	flagVerbose int  // -v: client retries; -vv: every request and cache hit
+	flagQuiet   bool // -q: errors only
+)
+
+// logger is the client's logger for the -v level: retries at -v, requests and
+// cache hits at -vv. Without -v only client warnings show.
+func logger() *slog.Logger {
+	level := slog.LevelWarn
+	switch {
+	case flagQuiet:
+		level = slog.LevelError
+	case flagVerbose == 1:
+		level = slog.LevelInfo
+	case flagVerbose >= 2:
+		level = slog.LevelDebug
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+}
+
+// progressf prints a progress or summary line to stderr unless -q.
+func progressf(format string, args ...any) {
+	if !flagQuiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// warnf prints a non-fatal problem to stderr, -q or not.
+func warnf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format, args...)
+}
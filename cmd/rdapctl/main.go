@@ -4,17 +4,49 @@
 //
 // Subcommands
 //   domain, ip, asn, ns, entity, lookup   – fetch a single object
+//   asn --siblings                         – other ASNs of the same org, via its registrant entity's autnum search
+//   asn-bulk                               – look up many ASNs concurrently; --rollup-org groups them as org -> ASNs
 //   tree                                   – recursively flush the entire related graph
+//   snapshot export|import                 – package a walk (graph + raw responses) for offline use
+//   snapshot history                       – re-look up queries and report changes, incl. deletions (404/410 tombstones)
+//   graph query                            – MATCH/RETURN path queries over an exported graph
+//   batch                                  – look up many queries over a resumable, Retry-After-aware job queue
+//   ingest                                 – import a zone file / escrow name list and enrich it, new names first
+//   audit transfer-lock                    – per-domain transfer-lock verdict (registry/registrar lock, remarks)
+//   portfolio                              – aggregate report: registrars, expiry, DNSSEC, locks, NS providers
+//   check                                  – RDAP nameservers vs live DNS: lame delegations, glue, MX resolution
+//   serve                                  – RDAP gateway (RFC 9082 paths) with per-API-key quotas
+//   base                                   – which RDAP base a query is routed to, and why (bootstrap entry, override, fallback)
+//   bootstrap watch                        – report IANA bootstrap services added/removed/moved (stdout + webhook)
+//   daemon                                 – scheduled batch/watch/bootstrap jobs with a persistent store and /status endpoint
+//   schema                                 – JSON Schema of an object class or the graph format (-o DIR: all of them)
+//   doctor                                 – test lookup plus client health (local clock skew vs registry Date)
 //
 // Flags
 //   --json (default true)     – JSON output for single objects; for tree, outputs a graph {nodes,edges}
+//   --format FMT              – json|text, csv|table (single objects, batch), dot|graphml|mermaid (tree)
 //   --walk                    – for single-object commands: print related, one level deep (text mode only)
 //   --max-depth               – for `tree` recursion depth (default 5)
 //   --follow-links            – for `tree`, chase rdap.Links[] (best-effort)
 //   --tld                     – hint for entity/lookup resolution
+//   --record DIR              – record every HTTP exchange as a cassette under DIR
+//   --replay DIR              – serve responses from cassettes in DIR (offline, no network)
+//   --config FILE             – JSON client config (rdap.Config); env vars and flags override it
+//   --ns-providers FILE       – nameserver provider patterns (JSON) tried before the built-in table
+//   --dataset FILE            – NDJSON mirror of RDAP objects (e.g. batch output), memory-mapped and consulted first
+//   --template TMPL           – single-object commands: Go template over the typed object ('{{.LDHName}} {{date .Expiration}}')
+//   --color auto|always|never – ANSI color in text output (auto: terminal and NO_COLOR unset)
+//   -v, -vv                   – log client retries (-v), and every request and cache hit (-vv), to stderr
+//   -q, --quiet               – no progress/summary lines; stdout carries data, stderr only errors and warnings
+//
+// Errors
+//   exit status 1; with --json a single JSON line on stderr, {"error":{"code","message","url","registry","status","retryAfter"}}
+//   ({"errors":[...]} for walk/batch failures); codes are rdap.ErrorCode's (not_found, rate_limited, timeout, ...)
 //
 // Env options for client:
-//   RDAPCTL_UA, RDAPCTL_TIMEOUT, RDAPCTL_DNS_BOOTSTRAP, RDAPCTL_IP_BOOTSTRAP, RDAPCTL_ASN_BOOTSTRAP
+//   RDAPCTL_UA, RDAPCTL_TIMEOUT, RDAPCTL_DNS_BOOTSTRAP, RDAPCTL_IP_BOOTSTRAP, RDAPCTL_ASN_BOOTSTRAP,
+//   RDAPCTL_OBJECT_TAG_BOOTSTRAP, RDAPCTL_DEFAULT_BASE, RDAPCTL_MAX_RETRIES, RDAPCTL_TLD_CACHE_SIZE, RDAPCTL_RESPONSE_CACHE_SIZE,
+//   RDAPCTL_RATE_LIMIT, RDAPCTL_HTTP_PROXY (read by rdap.ConfigFromEnv("RDAPCTL"))
 //
 // Build
 //   go mod init example.com/rdapctl
@@ -28,6 +60,11 @@
 //   ./rdapctl tree 8.8.8.0/24 --follow-links
 //   ./rdapctl lookup ns1.google.com --json=false
 //   ./rdapctl entity ORG-GOGL-1 --tld com
+//   ./rdapctl snapshot export --seed example.com -o bundle.tar.zst
+//   ./rdapctl tree example.com > g.json && ./rdapctl graph query -i g.json 'MATCH entity-[REGISTRAR_OF]->domain RETURN entity.handle'
+//   ./rdapctl snapshot import bundle.tar.zst -d ./case42 && ./rdapctl --replay ./case42/cassettes tree example.com
+//   ./rdapctl batch -i domains.txt --resume ./sweep   (re-run with just --resume ./sweep after a restart)
+//   ./rdapctl serve --addr :8080 --keys keys.json && curl -H 'X-API-Key: k1' localhost:8080/domain/example.com
 
 package main
 
@@ -36,68 +73,149 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
-	"net/url"
+	"io"
 	"os"
-	"regexp"
+	"sort"
 	"strings"
-	"time"
 
 	"github.com/spf13/cobra"
 
 	rc "github.com/datum-labs/rdap"
+	"github.com/datum-labs/rdap/graph"
+	"github.com/datum-labs/rdap/x/annotate"
+	"github.com/datum-labs/rdap/x/export"
 )
 
 var (
-	flagJSON        = true // default to JSON output
-	flagWalk        bool
-	flagTLD         string
-	flagMaxDepth    int
-	flagFollowLinks bool
+	flagJSON          = true // default to JSON output
+	flagFormat        string
+	flagWalk          bool
+	flagTLD           string
+	flagMaxDepth      int
+	flagFollowLinks   bool
+	flagPivotGlue     bool
+	flagReverseSearch bool
+	flagMaxRequests   int
+	flagConcurrency   int
+	flagHostConc      int
+	flagStream        bool
+	flagSeenDir       string
+	flagTruncation    string
+	flagQuota         map[string]int
+	flagSample        float64
+	flagRecord        string
+	flagReplay        string
+	flagConfig        string
+	flagDataset       string
+	flagHTTP3         bool
+	flagNSProviders   string
 )
 
 func main() {
 	root := &cobra.Command{
 		Use:   "rdapctl",
 		Short: "RDAP CLI",
+		// Errors (and, in text mode, usage) are reported by fatal, as JSON under --json.
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			noteQuery(cmd, args)
+			switch flagColor {
+			case "auto", "always", "never":
+				return nil
+			}
+			return fmt.Errorf("--color: want auto, always or never, got %q", flagColor)
+		},
 	}
 
 	// Global flags
 	root.PersistentFlags().BoolVar(&flagJSON, "json", true, "emit JSON; set --json=false for text output")
+	root.PersistentFlags().StringVar(&flagFormat, "format", "", "output format: json or text (default json, or text with --json=false); csv or table for lookups and batch; dot, graphml or mermaid for tree")
 	root.PersistentFlags().BoolVar(&flagWalk, "walk", false, "for single-object commands: resolve immediate related objects (ignored in --json)")
 	root.PersistentFlags().StringVar(&flagTLD, "tld", "", "TLD hint for entity lookups (e.g., 'com')")
+	root.PersistentFlags().StringVar(&flagRecord, "record", "", "record HTTP exchanges as cassettes into this directory")
+	root.PersistentFlags().StringVar(&flagReplay, "replay", "", "replay HTTP exchanges from cassettes in this directory (no network)")
+	root.PersistentFlags().StringVar(&flagConfig, "config", "", "JSON client config file (rdap.Config); env vars and flags override it")
+	root.PersistentFlags().StringVar(&flagNSProviders, "ns-providers", "", "JSON table of nameserver provider patterns, tried before the built-in one")
+	root.PersistentFlags().BoolVar(&flagHTTP3, "http3", false, "try HTTP/3 (QUIC) first for https registries, falling back to HTTP/2/1.1 (not through a proxy)")
+	root.PersistentFlags().StringVar(&flagDataset, "dataset", "", "NDJSON dataset of RDAP objects (e.g. batch output) consulted before the network")
+	root.PersistentFlags().StringVar(&flagTemplate, "template", "", "Go text/template over the fetched object, e.g. '{{.LDHName}} {{.Expiration}}' (replaces JSON/text output)")
+	root.PersistentFlags().StringVar(&flagColor, "color", "auto", "color text output: auto (terminal, NO_COLOR unset), always or never")
+	root.PersistentFlags().CountVarP(&flagVerbose, "verbose", "v", "log client retries to stderr; -vv also logs every request and cache hit")
+	root.PersistentFlags().BoolVarP(&flagQuiet, "quiet", "q", false, "print only data and errors: no progress or summary lines on stderr")
 
 	// Subcommands
-	root.AddCommand(cmdDomain(), cmdIP(), cmdASN(), cmdNS(), cmdEntity(), cmdLookup(), cmdTree())
+	root.AddCommand(cmdDomain(), cmdIP(), cmdASN(), cmdNS(), cmdEntity(), cmdLookup(), cmdASNBulk(), cmdTree(), cmdSnapshot(), cmdGraph(), cmdBatch(), cmdIngest(), cmdAudit(), cmdPortfolio(), cmdCheck(), cmdServe(), cmdBase(), cmdBootstrap(), cmdDaemon(), cmdSchema(), cmdDoctor())
 
-	if err := root.Execute(); err != nil {
-		log.Fatal(err)
+	if cmd, err := root.ExecuteC(); err != nil {
+		if !flagJSON {
+			cmd.PrintErr(cmd.UsageString())
+		}
+		fatal(err)
 	}
 }
 
-// newClient constructs the rdap.Client with env-configured options.
-func newClient() *rc.Client {
-	opts := []rc.Option{}
-	if ua := os.Getenv("RDAPCTL_UA"); ua != "" {
-		opts = append(opts, rc.WithUserAgent(ua))
-	}
-	if to := os.Getenv("RDAPCTL_TIMEOUT"); to != "" {
-		if d, err := time.ParseDuration(to); err == nil {
-			opts = append(opts, rc.WithTimeout(d))
+// newClient constructs the rdap.Client from --config, then env, then flags.
+// cfgOpts stand in for the --config file of commands whose own --config
+// names something else (daemon).
+func newClient(cfgOpts ...rc.Option) *rc.Client {
+	opts := append([]rc.Option{}, cfgOpts...)
+	if flagConfig != "" {
+		f, err := os.Open(flagConfig)
+		if err != nil {
+			fatal(err)
 		}
+		cfg, err := rc.LoadConfig(f)
+		f.Close()
+		if err != nil {
+			fatal(err)
+		}
+		opts = append(opts, cfg.Options()...)
+	}
+	cfg, err := rc.ConfigFromEnv("RDAPCTL")
+	if err != nil {
+		warnf("warning: %v\n", err)
 	}
-	if u := os.Getenv("RDAPCTL_DNS_BOOTSTRAP"); u != "" {
-		opts = append(opts, rc.WithBootstrapURL(u))
+	opts = append(opts, cfg.Options()...)
+	opts = append(opts, rc.WithLogger(logger()))
+	if flagRecord != "" {
+		opts = append(opts, rc.WithRecorder(flagRecord))
 	}
-	if u := os.Getenv("RDAPCTL_IP_BOOTSTRAP"); u != "" {
-		opts = append(opts, rc.WithIPBootstrapURL(u))
+	if flagReplay != "" {
+		opts = append(opts, rc.WithReplay(flagReplay))
 	}
-	if u := os.Getenv("RDAPCTL_ASN_BOOTSTRAP"); u != "" {
-		opts = append(opts, rc.WithASNBootstrapURL(u))
+	if flagHTTP3 {
+		opts = append(opts, rc.WithHTTP3(true))
+	}
+	if flagDataset != "" {
+		// Mapped for the life of the process.
+		ds, err := rc.OpenDataset(flagDataset)
+		if err != nil {
+			fatal(err)
+		}
+		opts = append(opts, rc.WithDataset(ds))
 	}
 	return rc.New(opts...)
 }
 
+// nsClassifier returns the nameserver provider classifier, with --ns-providers
+// rules ahead of the built-in table.
+func nsClassifier() *annotate.NSClassifier {
+	if flagNSProviders == "" {
+		return annotate.DefaultNSClassifier()
+	}
+	f, err := os.Open(flagNSProviders)
+	if err != nil {
+		fatal(err)
+	}
+	defer f.Close()
+	pc, err := annotate.LoadNSClassifier(f, nil)
+	if err != nil {
+		fatal(err)
+	}
+	return pc
+}
+
 func cmdDomain() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "domain <fqdn>",
@@ -135,6 +253,7 @@ func cmdIP() *cobra.Command {
 }
 
 func cmdASN() *cobra.Command {
+	var siblings bool
 	cmd := &cobra.Command{
 		Use:   "asn <AS12345|12345>",
 		Short: "Fetch autnum RDAP",
@@ -142,6 +261,9 @@ func cmdASN() *cobra.Command {
 		RunE: func(_ *cobra.Command, args []string) error {
 			c := newClient()
 			ctx := context.Background()
+			if siblings {
+				return printSiblings(c.SiblingAutnums(ctx, args[0]))
+			}
 			a, err := c.Autnum(ctx, args[0])
 			if err != nil {
 				return err
@@ -149,9 +271,30 @@ func cmdASN() *cobra.Command {
 			return renderObject(c, ctx, a)
 		},
 	}
+	cmd.Flags().BoolVar(&siblings, "siblings", false, "list the other ASNs of the same organization, via its registrant/maintainer entity")
 	return cmd
 }
 
+// printSiblings prints SiblingAutnums' results; a partial result is printed
+// before its error is returned.
+func printSiblings(sibs []rc.SiblingAutnum, err error) error {
+	if len(sibs) > 0 || err == nil {
+		if flagJSON {
+			if sibs == nil {
+				sibs = []rc.SiblingAutnum{}
+			}
+			if perr := printJSON(sibs); perr != nil {
+				return perr
+			}
+		} else {
+			for _, s := range sibs {
+				fmt.Printf("AS%-10d %-24s via %s (%s) %s\n", s.Autnum.StartAutnum, s.Autnum.Name, s.Via, s.Role, s.Source)
+			}
+		}
+	}
+	return err
+}
+
 func cmdNS() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "ns <hostname>",
@@ -209,28 +352,46 @@ func cmdLookup() *cobra.Command {
 // ---- TREE (flush entire graph) ---------------------------------------------
 
 func cmdTree() *cobra.Command {
+	var open bool
 	cmd := &cobra.Command{
 		Use:   "tree <seed>",
 		Short: "Flush the entire RDAP graph reachable from a seed (domain/ip/asn/ns/entity)",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
+			format, err := outputFormat("dot", "graphml", "mermaid")
+			if err != nil {
+				return err
+			}
 			c := newClient()
 			ctx := context.Background()
 
 			seed := args[0]
-			obj, err := c.Lookup(ctx, seed, flagTLD)
+			graph, err := walkSeed(ctx, c, seed)
 			if err != nil {
 				return err
 			}
 
-			seen := newSeenSet()
-			graph := &Graph{Nodes: map[string]GraphNode{}, Edges: []GraphEdge{}}
-
-			if err := walkAny(ctx, c, obj, 0, flagMaxDepth, flagFollowLinks, seen, graph); err != nil {
-				return err
+			if flagStream {
+				return nil // the patches are the output
 			}
-
-			if flagJSON {
+			if open {
+				return openGraph(graph, seed)
+			}
+			switch format {
+			case "dot":
+				return export.DOT(os.Stdout, graph)
+			case "graphml":
+				return export.GraphML(os.Stdout, graph)
+			case "mermaid":
+				// Fenced, ready to paste into Markdown.
+				fmt.Println("```mermaid")
+				if err := export.Mermaid(os.Stdout, graph); err != nil {
+					return err
+				}
+				fmt.Println("```")
+				return nil
+			}
+			if format == "json" {
 				// Emit consolidated graph (nodes keyed by id, edges with from->to)
 				return printJSON(graph)
 			}
@@ -241,33 +402,156 @@ func cmdTree() *cobra.Command {
 			return nil
 		},
 	}
-	cmd.Flags().IntVar(&flagMaxDepth, "max-depth", 5, "maximum recursion depth when walking the graph")
-	cmd.Flags().BoolVar(&flagFollowLinks, "follow-links", false, "follow RDAP links[] to fetch additional objects (best-effort)")
+	addWalkFlags(cmd)
+	cmd.Flags().BoolVar(&flagStream, "stream", false, "write the graph as NDJSON patches ({\"op\":\"addNode\",...}) while it grows, ending with a summary record")
+	cmd.Flags().BoolVar(&open, "open", false, "view the graph in the browser: serve an interactive page on a local port until Ctrl-C")
 	return cmd
 }
 
-// Graph types for JSON output
-type Graph struct {
-	Nodes map[string]GraphNode `json:"nodes"`
-	Edges []GraphEdge          `json:"edges"`
+// addWalkFlags registers the graph-walk flags shared by tree and snapshot export.
+func addWalkFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&flagMaxDepth, "max-depth", 5, "maximum recursion depth when walking the graph")
+	cmd.Flags().BoolVar(&flagFollowLinks, "follow-links", false, "follow RDAP links[] to fetch additional objects (best-effort)")
+	cmd.Flags().BoolVar(&flagPivotGlue, "pivot-glue", false, "look up nameserver glue IPs at their RIR and add network/ASN nodes")
+	cmd.Flags().BoolVar(&flagReverseSearch, "reverse-search", false, "expand entities into their registered domains where the registry supports RFC 9536")
+	cmd.Flags().IntVar(&flagMaxRequests, "max-requests", 0, "cap on RDAP requests per walk (0 = unlimited)")
+	cmd.Flags().IntVar(&flagConcurrency, "concurrency", graph.DefaultWalkConcurrency, "RDAP requests in flight at once during a walk, across registries")
+	cmd.Flags().IntVar(&flagHostConc, "host-concurrency", 1, "RDAP requests in flight at once to one registry during a walk (pace them with hostRateLimits in --config)")
+	cmd.Flags().StringVar(&flagSeenDir, "seen-dir", "", "keep the visited-node set on disk in this directory (bounded memory for huge crawls; reused across runs)")
+	cmd.Flags().StringVar(&flagTruncation, "truncation", graph.TruncateDiscovery, "which fetches --max-requests leaves out: discovery (first found first) or breadth-first (a layer of every branch)")
+	cmd.Flags().StringToIntVar(&flagQuota, "quota", nil, "follow at most N children of a kind per object, e.g. entity=3,nameserver=4")
+	cmd.Flags().Float64Var(&flagSample, "sample", 0, "follow a deterministic fraction (0-1) of each object's children (0 = all)")
+}
+
+// walkSeed resolves seed and walks the graph reachable from it using the tree flags.
+func walkSeed(ctx context.Context, c *rc.Client, seed string) (*Graph, error) {
+	obj, err := c.Lookup(ctx, seed, flagTLD)
+	if err != nil {
+		return nil, err
+	}
+	opts := graph.WalkOptions{
+		MaxDepth:        flagMaxDepth,
+		FollowLinks:     flagFollowLinks,
+		PivotGlue:       flagPivotGlue,
+		ReverseSearch:   flagReverseSearch,
+		MaxRequests:     flagMaxRequests,
+		Concurrency:     flagConcurrency,
+		HostConcurrency: flagHostConc,
+		Truncation:      flagTruncation,
+		KindQuota:       flagQuota,
+		SampleRate:      flagSample,
+	}
+	if flagTruncation != graph.TruncateDiscovery && flagTruncation != graph.TruncateBreadthFirst {
+		return nil, fmt.Errorf("--truncation must be %s or %s", graph.TruncateDiscovery, graph.TruncateBreadthFirst)
+	}
+	if flagStream {
+		opts.OnPatch = func(p graph.Patch) { _ = printJSONLine(p) }
+	}
+	if flagSeenDir != "" {
+		seen, err := graph.OpenDiskSeen(flagSeenDir, 1<<20)
+		if err != nil {
+			return nil, err
+		}
+		opts.Seen = seen
+	}
+	w := graph.NewWalker(c, opts)
+	g, err := w.Walk(ctx, obj)
+	if completeness := w.Completeness(); err == nil && !completeness.Complete() {
+		progressf("walk: %s\n", completenessLine(completeness))
+	}
+	if l := w.Summary().Limits; err == nil && l != nil {
+		progressf("walk: %s\n", limitsLine(l))
+	}
+	if be := w.Errors(); be != nil {
+		// Skipped fetches go to stderr so stdout stays a clean graph.
+		if flagJSON {
+			b, _ := json.Marshal(be)
+			fmt.Fprintln(os.Stderr, string(b))
+		} else {
+			fmt.Fprintln(os.Stderr, be)
+		}
+	}
+	return g, err
 }
 
-type GraphNode struct {
-	ID   string      `json:"id"`
-	Kind string      `json:"kind"` // domain | nameserver | entity | ip-network | autnum | link
-	Data interface{} `json:"data"` // the typed RDAP object (Domain, Nameserver, Entity, IPNetwork, Autnum) or link URL
+// completenessLine summarizes a Completeness for progress output:
+// "40 fetched (12 cached), 2 stale, 1 failed, skipped rdap.example, truncated".
+func completenessLine(r rc.Completeness) string {
+	parts := []string{fmt.Sprintf("%d fetched (%d cached)", r.Fetched, r.FromCache)}
+	if len(r.Stale) > 0 {
+		parts = append(parts, fmt.Sprintf("%d stale", len(r.Stale)))
+	}
+	if len(r.Failed) > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", len(r.Failed)))
+	}
+	if len(r.SkippedRegistries) > 0 {
+		parts = append(parts, "skipped "+strings.Join(r.SkippedRegistries, ", "))
+	}
+	if r.Truncated {
+		parts = append(parts, "truncated at --max-requests")
+	}
+	return strings.Join(parts, ", ")
 }
 
-type GraphEdge struct {
-	From string `json:"from"`
-	To   string `json:"to"`
-	Rel  string `json:"rel"` // e.g., nameserver, entity, parent, link, contact, etc.
+// limitsLine summarizes what a walk's limits left out:
+// "breadth-first truncation dropped entity=4, over quota entity=2".
+func limitsLine(l *graph.Limits) string {
+	counts := func(m map[string]int) string {
+		kinds := make([]string, 0, len(m))
+		for k := range m {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		for i, k := range kinds {
+			kinds[i] = fmt.Sprintf("%s=%d", k, m[k])
+		}
+		return strings.Join(kinds, " ")
+	}
+	var parts []string
+	if l.Truncation != "" {
+		p := l.Truncation + " truncation"
+		if len(l.Dropped) > 0 {
+			p += " dropped " + counts(l.Dropped)
+		}
+		parts = append(parts, p)
+	}
+	if len(l.OverQuota) > 0 {
+		parts = append(parts, "over quota "+counts(l.OverQuota))
+	}
+	if len(l.SampledOut) > 0 {
+		parts = append(parts, fmt.Sprintf("sampled at %g, left out %s", l.SampleRate, counts(l.SampledOut)))
+	}
+	return strings.Join(parts, ", ")
 }
 
+// Graph types for JSON output (defined in the library so exported graphs can be queried offline)
+type (
+	Graph     = graph.Graph
+	GraphNode = graph.Node
+)
+
 // ---- Rendering for single objects -----------------------------------------
 
 func renderObject(c *rc.Client, ctx context.Context, obj any) error {
-	if flagJSON {
+	format, err := outputFormat("csv", "table")
+	if err != nil {
+		return err
+	}
+	if flagTemplate != "" {
+		if err := renderTemplate(flagTemplate, obj); err != nil {
+			return err
+		}
+		if !flagWalk {
+			return nil
+		}
+	} else if format == "csv" {
+		// A header and the object's row, in its class's schema (--walk is ignored).
+		w := export.NewCSV(func(string) (io.Writer, bool, error) { return os.Stdout, true, nil })
+		if err := w.Write(obj); err != nil {
+			return err
+		}
+		return w.Flush()
+	} else if format == "json" {
 		// In JSON mode, output only the primary typed object.
 		// (Note: --walk is ignored in JSON mode to keep output single-object.)
 		return printJSON(obj)
@@ -313,69 +597,6 @@ func printJSON(v any) error {
 	return nil
 }
 
-func printHeader(kind, handle, extra string) {
-	fmt.Printf("\n=== %s: %s %s===\n", strings.ToUpper(kind), handle, extra)
-}
-
-func printDomain(d *rc.Domain) {
-	printHeader("domain", d.LDHName, "")
-	fmt.Printf("handle: %s\n", d.Handle)
-	if len(d.Status) > 0 {
-		fmt.Printf("status: %v\n", d.Status)
-	}
-	if d.SecureDNS != nil {
-		fmt.Printf("dnssec: zoneSigned=%v delegationSigned=%v\n", d.SecureDNS.ZoneSigned, d.SecureDNS.DelegationSigned)
-	}
-	if len(d.Nameservers) > 0 {
-		fmt.Println("nameservers:")
-		for _, ns := range d.Nameservers {
-			fmt.Printf("  - %s\n", ns.LDHName)
-		}
-	}
-	if len(d.Entities) > 0 {
-		fmt.Println("entities:")
-		for _, e := range d.Entities {
-			fmt.Printf("  - %s (%v)\n", e.Handle, e.Roles)
-		}
-	}
-}
-
-func printNameserver(n *rc.Nameserver) {
-	printHeader("nameserver", n.LDHName, "")
-	fmt.Printf("handle: %s\n", n.Handle)
-	if n.IPAddresses != nil {
-		if len(n.IPAddresses.V4) > 0 {
-			fmt.Printf("v4: %v\n", n.IPAddresses.V4)
-		}
-		if len(n.IPAddresses.V6) > 0 {
-			fmt.Printf("v6: %v\n", n.IPAddresses.V6)
-		}
-	}
-	if len(n.Entities) > 0 {
-		fmt.Println("entities:")
-		for _, e := range n.Entities {
-			fmt.Printf("  - %s (%v)\n", e.Handle, e.Roles)
-		}
-	}
-}
-
-func printIPNet(n *rc.IPNetwork) {
-	printHeader("ip network", n.Handle, fmt.Sprintf("(%s %s-%s) ", n.IPVersion, n.StartAddress, n.EndAddress))
-	fmt.Printf("name: %s country: %s parent: %s\n", n.Name, n.Country, n.ParentHandle)
-}
-
-func printAutnum(a *rc.Autnum) {
-	printHeader("autnum", a.Handle, fmt.Sprintf("(%d-%d) ", a.StartAutnum, a.EndAutnum))
-	fmt.Printf("name: %s country: %s type: %s\n", a.Name, a.Country, a.Type)
-}
-
-func printEntity(e *rc.Entity) {
-	printHeader("entity", e.Handle, "")
-	if len(e.Roles) > 0 {
-		fmt.Printf("roles: %v\n", e.Roles)
-	}
-}
-
 // ---- One-level walks for single-object commands ---------------------------
 
 func walkDomainOnce(c *rc.Client, ctx context.Context, d *rc.Domain) error {
@@ -383,7 +604,7 @@ func walkDomainOnce(c *rc.Client, ctx context.Context, d *rc.Domain) error {
 		fmt.Printf("\n> resolving nameserver %s...\n", ns.LDHName)
 		full, err := c.Nameserver(ctx, ns.LDHName)
 		if err != nil {
-			fmt.Printf("  (error: %v)\n", err)
+			warnf("%s: %v\n", ns.LDHName, err)
 			continue
 		}
 		printNameserver(full)
@@ -391,7 +612,7 @@ func walkDomainOnce(c *rc.Client, ctx context.Context, d *rc.Domain) error {
 	for _, e := range d.Entities {
 		fmt.Printf("\n> resolving entity %s...\n", e.Handle)
 		if err := walkEntityOnce(c, ctx, &e, make(map[string]struct{})); err != nil {
-			fmt.Printf("  (error: %v)\n", err)
+			warnf("%s: %v\n", e.Handle, err)
 		}
 	}
 	return nil
@@ -401,7 +622,7 @@ func walkNameserverOnce(c *rc.Client, ctx context.Context, n *rc.Nameserver) err
 	for _, e := range n.Entities {
 		fmt.Printf("\n> resolving entity %s...\n", e.Handle)
 		if err := walkEntityOnce(c, ctx, &e, make(map[string]struct{})); err != nil {
-			fmt.Printf("  (error: %v)\n", err)
+			warnf("%s: %v\n", e.Handle, err)
 		}
 	}
 	return nil
@@ -414,7 +635,7 @@ func walkIPNetOnce(c *rc.Client, ctx context.Context, n *rc.IPNetwork) error {
 	for _, e := range n.Entities {
 		fmt.Printf("\n> resolving entity %s...\n", e.Handle)
 		if err := walkEntityOnce(c, ctx, &e, make(map[string]struct{})); err != nil {
-			fmt.Printf("  (error: %v)\n", err)
+			warnf("%s: %v\n", e.Handle, err)
 		}
 	}
 	return nil
@@ -424,7 +645,7 @@ func walkAutnumOnce(c *rc.Client, ctx context.Context, a *rc.Autnum) error {
 	for _, e := range a.Entities {
 		fmt.Printf("\n> resolving entity %s...\n", e.Handle)
 		if err := walkEntityOnce(c, ctx, &e, make(map[string]struct{})); err != nil {
-			fmt.Printf("  (error: %v)\n", err)
+			warnf("%s: %v\n", e.Handle, err)
 		}
 	}
 	return nil
@@ -452,232 +673,6 @@ func walkEntityOnce(c *rc.Client, ctx context.Context, e *rc.Entity, seen map[st
 	return nil
 }
 
-// ---- Full graph walk (tree) -----------------------------------------------
-
-type seenSet struct {
-	ids map[string]struct{}
-}
-
-func newSeenSet() *seenSet { return &seenSet{ids: map[string]struct{}{}} }
-
-func (s *seenSet) add(id string) bool {
-	if _, ok := s.ids[id]; ok {
-		return false
-	}
-	s.ids[id] = struct{}{}
-	return true
-}
-
-func makeNodeID(kind, key string) string { return kind + ":" + strings.ToLower(key) }
-
-func walkAny(ctx context.Context, c *rc.Client, obj any, depth, maxDepth int, followLinks bool, seen *seenSet, g *Graph) error {
-	if obj == nil || depth > maxDepth {
-		return nil
-	}
-	switch v := obj.(type) {
-	case *rc.Domain:
-		id := makeNodeID("domain", v.LDHName)
-		if seen.add(id) {
-			addNode(g, id, "domain", v)
-			// Nameservers
-			for _, ns := range v.Nameservers {
-				nsObj, err := c.Nameserver(ctx, ns.LDHName)
-				if err == nil && nsObj != nil {
-					nsID := makeNodeID("nameserver", nsObj.LDHName)
-					addEdge(g, id, nsID, "nameserver")
-					_ = walkAny(ctx, c, nsObj, depth+1, maxDepth, followLinks, seen, g)
-				}
-			}
-			// Entities
-			for _, e := range v.Entities {
-				ent, err := c.Entity(ctx, e.Handle, "")
-				if err == nil && ent != nil {
-					entID := makeNodeID("entity", ent.Handle)
-					addEdge(g, id, entID, "entity")
-					_ = walkAny(ctx, c, ent, depth+1, maxDepth, followLinks, seen, g)
-				}
-			}
-			// Links (optional)
-			if followLinks {
-				walkLinks(ctx, c, id, v.Links, depth, maxDepth, seen, g)
-			}
-		}
-	case *rc.Nameserver:
-		id := makeNodeID("nameserver", v.LDHName)
-		if seen.add(id) {
-			addNode(g, id, "nameserver", v)
-			for _, e := range v.Entities {
-				ent, err := c.Entity(ctx, e.Handle, "")
-				if err == nil && ent != nil {
-					entID := makeNodeID("entity", ent.Handle)
-					addEdge(g, id, entID, "entity")
-					_ = walkAny(ctx, c, ent, depth+1, maxDepth, followLinks, seen, g)
-				}
-			}
-			if followLinks {
-				walkLinks(ctx, c, id, v.Links, depth, maxDepth, seen, g)
-			}
-		}
-	case *rc.IPNetwork:
-		id := makeNodeID("ip-network", v.Handle)
-		if seen.add(id) {
-			addNode(g, id, "ip-network", v)
-			for _, e := range v.Entities {
-				ent, err := c.Entity(ctx, e.Handle, "")
-				if err == nil && ent != nil {
-					entID := makeNodeID("entity", ent.Handle)
-					addEdge(g, id, entID, "entity")
-					_ = walkAny(ctx, c, ent, depth+1, maxDepth, followLinks, seen, g)
-				}
-			}
-			if followLinks {
-				walkLinks(ctx, c, id, v.Links, depth, maxDepth, seen, g)
-			}
-		}
-	case *rc.Autnum:
-		id := makeNodeID("autnum", v.Handle)
-		if seen.add(id) {
-			addNode(g, id, "autnum", v)
-			for _, e := range v.Entities {
-				ent, err := c.Entity(ctx, e.Handle, "")
-				if err == nil && ent != nil {
-					entID := makeNodeID("entity", ent.Handle)
-					addEdge(g, id, entID, "entity")
-					_ = walkAny(ctx, c, ent, depth+1, maxDepth, followLinks, seen, g)
-				}
-			}
-			if followLinks {
-				walkLinks(ctx, c, id, v.Links, depth, maxDepth, seen, g)
-			}
-		}
-	case *rc.Entity:
-		id := makeNodeID("entity", v.Handle)
-		if seen.add(id) {
-			addNode(g, id, "entity", v)
-			for _, a := range v.Autnums {
-				full, err := c.Autnum(ctx, a.Handle)
-				if err == nil && full != nil {
-					to := makeNodeID("autnum", full.Handle)
-					addEdge(g, id, to, "autnum")
-					_ = walkAny(ctx, c, full, depth+1, maxDepth, followLinks, seen, g)
-				}
-			}
-			for _, n := range v.Networks {
-				full, err := c.IP(ctx, n.Handle)
-				if err == nil && full != nil {
-					to := makeNodeID("ip-network", full.Handle)
-					addEdge(g, id, to, "network")
-					_ = walkAny(ctx, c, full, depth+1, maxDepth, followLinks, seen, g)
-				}
-			}
-			if followLinks {
-				walkLinks(ctx, c, id, v.Links, depth, maxDepth, seen, g)
-			}
-		}
-	default:
-		return errors.New("unknown seed type")
-	}
-	return nil
-}
-
-// walkLinks tries to follow RDAP link relations that look like domain/entity/ns/autnum/ip.
-// This is best-effort and safe-guards with parsing & small pattern matches.
-func walkLinks(ctx context.Context, c *rc.Client, fromID string, links []rc.Link, depth, maxDepth int, seen *seenSet, g *Graph) {
-	for _, l := range links {
-		if l.Href == "" {
-			continue
-		}
-		u, err := url.Parse(l.Href)
-		if err != nil || u.Path == "" {
-			continue
-		}
-		// Common RDAP paths: /domain/<name> /entity/<handle> /nameserver/<name> /autnum/<n> /ip/<cidr>
-		path := strings.ToLower(u.Path)
-		switch {
-		case strings.Contains(path, "/domain/"):
-			name := tail(path)
-			if name == "" {
-				break
-			}
-			if dom, err := c.Domain(ctx, name); err == nil && dom != nil {
-				to := makeNodeID("domain", dom.LDHName)
-				addEdge(g, fromID, to, "link:"+relOr("domain", l.Rel))
-				_ = walkAny(ctx, c, dom, depth+1, maxDepth, true, seen, g)
-			}
-		case strings.Contains(path, "/nameserver/"):
-			name := tail(path)
-			if name == "" {
-				break
-			}
-			if ns, err := c.Nameserver(ctx, name); err == nil && ns != nil {
-				to := makeNodeID("nameserver", ns.LDHName)
-				addEdge(g, fromID, to, "link:"+relOr("nameserver", l.Rel))
-				_ = walkAny(ctx, c, ns, depth+1, maxDepth, true, seen, g)
-			}
-		case strings.Contains(path, "/entity/"):
-			h := tail(path)
-			if h == "" {
-				break
-			}
-			if ent, err := c.Entity(ctx, h, ""); err == nil && ent != nil {
-				to := makeNodeID("entity", ent.Handle)
-				addEdge(g, fromID, to, "link:"+relOr("entity", l.Rel))
-				_ = walkAny(ctx, c, ent, depth+1, maxDepth, true, seen, g)
-			}
-		case strings.Contains(path, "/autnum/"):
-			h := tail(path)
-			if h == "" {
-				break
-			}
-			if a, err := c.Autnum(ctx, h); err == nil && a != nil {
-				to := makeNodeID("autnum", a.Handle)
-				addEdge(g, fromID, to, "link:"+relOr("autnum", l.Rel))
-				_ = walkAny(ctx, c, a, depth+1, maxDepth, true, seen, g)
-			}
-		case strings.Contains(path, "/ip/"):
-			h := tail(path)
-			if h == "" {
-				break
-			}
-			if n, err := c.IP(ctx, h); err == nil && n != nil {
-				to := makeNodeID("ip-network", n.Handle)
-				addEdge(g, fromID, to, "link:"+relOr("ip", l.Rel))
-				_ = walkAny(ctx, c, n, depth+1, maxDepth, true, seen, g)
-			}
-		default:
-			// Ignore other link types quietly
-		}
-	}
-}
-
-var slashTail = regexp.MustCompile(`/([^/]+)$`)
-
-func tail(p string) string {
-	m := slashTail.FindStringSubmatch(p)
-	if len(m) == 2 {
-		return m[1]
-	}
-	return ""
-}
-
-func relOr(def, rel string) string {
-	if rel == "" {
-		return def
-	}
-	return rel
-}
-
-func addNode(g *Graph, id, kind string, data interface{}) {
-	if _, ok := g.Nodes[id]; ok {
-		return
-	}
-	g.Nodes[id] = GraphNode{ID: id, Kind: kind, Data: data}
-}
-
-func addEdge(g *Graph, from, to, rel string) {
-	g.Edges = append(g.Edges, GraphEdge{From: from, To: to, Rel: rel})
-}
-
 // Text presentation of the graph (simple fan-out by kind, then ID)
 func printGraphText(g *Graph) {
 	// Group by kind
@@ -695,7 +690,7 @@ func printGraphText(g *Graph) {
 		fmt.Printf("\n[%s]\n", strings.ToUpper(k))
 		for _, n := range nodes {
 			fmt.Printf("- %s\n", n.ID)
-			// show outward edges
+			// show outward edges ("<this> is <rel> <to>")
 			for _, e := range g.Edges {
 				if e.From == n.ID {
 					fmt.Printf("    -> %s (%s)\n", e.To, e.Rel)
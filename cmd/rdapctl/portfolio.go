@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	rc "github.com/datum-labs/rdap"
+	"github.com/datum-labs/rdap/x/annotate"
+)
+
+// ---- PORTFOLIO (aggregate report over a list of domains) -------------------
+
+// expiryBuckets are the expiry histogram's buckets, in display order.
+var expiryBuckets = []string{"expired", "<30d", "30-90d", "90d-1y", "1-2y", ">2y", "unknown"}
+
+// portfolioReport is the aggregate `rdapctl portfolio` prints.
+type portfolioReport struct {
+	Domains             int               `json:"domains"`
+	Errors              map[string]string `json:"errors,omitempty"` // query -> lookup error
+	Registrars          map[string]int    `json:"registrars"`
+	Expiry              map[string]int    `json:"expiry"`
+	DNSSECSigned        int               `json:"dnssecSigned"`
+	DNSSECRate          float64           `json:"dnssecRate"`
+	TransferLocks       map[string]int    `json:"transferLocks"`
+	LockCoverage        float64           `json:"lockCoverage"` // registry- or registrar-locked share
+	NameserverProviders map[string]int    `json:"nameserverProviders"`
+	RegistrarDefaultDNS int               `json:"registrarDefaultDns"` // domains on a registrar's bundled DNS
+}
+
+func cmdPortfolio() *cobra.Command {
+	var (
+		input   string
+		workers int
+	)
+	cmd := &cobra.Command{
+		Use:   "portfolio [-i domains.txt]",
+		Short: "Summarize a domain portfolio: registrars, expiry, DNSSEC, transfer locks, DNS providers",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			names, err := readQueries(input)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			rep := buildPortfolio(names, lookupDomains(ctx, newClient(), names, workers), nsClassifier(), time.Now())
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if flagJSON {
+				return printJSON(rep)
+			}
+			printPortfolio(rep)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&input, "input", "i", "", "file of domains, one per line (- or empty reads stdin)")
+	cmd.Flags().IntVar(&workers, "concurrency", 4, "lookups in flight at once")
+	return cmd
+}
+
+func buildPortfolio(names []string, results []domainResult, pc *annotate.NSClassifier, now time.Time) portfolioReport {
+	rep := portfolioReport{
+		Domains:             len(names),
+		Errors:              map[string]string{},
+		Registrars:          map[string]int{},
+		Expiry:              map[string]int{},
+		TransferLocks:       map[string]int{},
+		NameserverProviders: map[string]int{},
+	}
+	ok := 0
+	for i, res := range results {
+		if res.Err != nil {
+			rep.Errors[names[i]] = res.Err.Error()
+			continue
+		}
+		d := res.Domain
+		ok++
+
+		registrar := "unknown"
+		if e := d.Registrar(); e != nil && e.RegistrarName() != "" {
+			registrar = e.RegistrarName()
+		}
+		rep.Registrars[registrar]++
+
+		rep.Expiry[expiryBucket(d, now)]++
+
+		if d.SecureDNS != nil && d.SecureDNS.DelegationSigned {
+			rep.DNSSECSigned++
+		}
+
+		rep.TransferLocks[string(annotate.TransferLock(d).Verdict)]++
+
+		seen := map[string]bool{}
+		registrarDNS := false
+		for _, ns := range d.Nameservers {
+			name := nsProvider(ns.LDHName)
+			if p, ok := pc.Classify(ns.LDHName); ok {
+				name = p.Name
+				registrarDNS = registrarDNS || p.RegistrarDefault
+			}
+			if name != "" && !seen[name] {
+				seen[name] = true
+				rep.NameserverProviders[name]++
+			}
+		}
+		if registrarDNS {
+			rep.RegistrarDefaultDNS++
+		}
+	}
+	if ok > 0 {
+		rep.DNSSECRate = float64(rep.DNSSECSigned) / float64(ok)
+		locked := rep.TransferLocks[string(annotate.TransferRegistryLocked)] + rep.TransferLocks[string(annotate.TransferRegistrarLocked)]
+		rep.LockCoverage = float64(locked) / float64(ok)
+	}
+	return rep
+}
+
+func expiryBucket(d *rc.Domain, now time.Time) string {
+	t, ok := d.ExpirationDate()
+	if !ok {
+		return "unknown"
+	}
+	day := 24 * time.Hour
+	switch left := t.Sub(now); {
+	case left < 0:
+		return "expired"
+	case left < 30*day:
+		return "<30d"
+	case left < 90*day:
+		return "30-90d"
+	case left < 365*day:
+		return "90d-1y"
+	case left < 2*365*day:
+		return "1-2y"
+	}
+	return ">2y"
+}
+
+// nsProvider groups a nameserver host no classifier rule knows by the domain it
+// is served under ("ns1.example-dns.net" -> "example-dns.net").
+func nsProvider(host string) string {
+	labels := strings.Split(strings.Trim(strings.ToLower(host), "."), ".")
+	if len(labels) < 2 {
+		return strings.Join(labels, ".")
+	}
+	return strings.Join(labels[len(labels)-2:], ".")
+}
+
+func printPortfolio(r portfolioReport) {
+	fmt.Printf("domains: %d (%d lookup errors)\n", r.Domains, len(r.Errors))
+	fmt.Println("registrars:")
+	printCounts(r.Registrars)
+	fmt.Println("expiry:")
+	for _, b := range expiryBuckets {
+		if n := r.Expiry[b]; n > 0 {
+			fmt.Printf("  %-8s %d\n", b, n)
+		}
+	}
+	fmt.Printf("dnssec: %d signed (%.0f%%)\n", r.DNSSECSigned, 100*r.DNSSECRate)
+	fmt.Printf("transfer locks: %.0f%% locked\n", 100*r.LockCoverage)
+	printCounts(r.TransferLocks)
+	fmt.Printf("nameserver providers (%d domains on registrar default DNS):\n", r.RegistrarDefaultDNS)
+	printCounts(r.NameserverProviders)
+	for q, err := range r.Errors {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", q, err)
+	}
+}
+
+// printCounts prints m largest first (ties by name).
+func printCounts(m map[string]int) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if m[keys[a]] != m[keys[b]] {
+			return m[keys[a]] > m[keys[b]]
+		}
+		return keys[a] < keys[b]
+	})
+	for _, k := range keys {
+		fmt.Printf("  %-40s %d\n", k, m[k])
+	}
+}
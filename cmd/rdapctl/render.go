@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	rc "github.com/datum-labs/rdap"
+	"github.com/datum-labs/rdap/x/export"
+)
+
+// ---- TEXT RENDERING (templates, --template, --color) -----------------------
+
+var (
+	flagTemplate string
+	flagColor    = "auto" // auto|always|never
+)
+
+// outputFormat is the --format in force: the flag, else json or text by
+// --json. Besides those two, a command accepts only the formats it lists.
+func outputFormat(extra ...string) (string, error) {
+	f := flagFormat
+	if f == "" {
+		if flagJSON {
+			return "json", nil
+		}
+		return "text", nil
+	}
+	allowed := append([]string{"json", "text"}, extra...)
+	for _, a := range allowed {
+		if f == a {
+			return f, nil
+		}
+	}
+	n := len(allowed) - 1
+	return "", fmt.Errorf("unknown --format %q (want %s or %s)", f, strings.Join(allowed[:n], ", "), allowed[n])
+}
+
+// textTemplates are the built-in text layouts, one per object kind. --template
+// replaces them for the fetched object; the same functions are available.
+var textTemplates = map[string]string{
+	"domain": `{{header "domain" .LDHName ""}}
+{{label "handle:"}} {{.Handle}}
+{{if .Status}}{{label "status:"}} {{.Status}}
+{{end}}{{with .SecureDNS}}{{label "dnssec:"}} zoneSigned={{.ZoneSigned}} delegationSigned={{.DelegationSigned}}
+{{end}}{{if .Nameservers}}{{label "nameservers:"}}
+{{range .Nameservers}}  - {{.LDHName}}{{with provider .LDHName}} {{dim (printf "(%s)" .)}}{{end}}
+{{end}}{{end}}{{if .Entities}}{{label "entities:"}}
+{{range .Entities}}  - {{.Handle}} ({{.Roles}})
+{{end}}{{end}}`,
+
+	"nameserver": `{{header "nameserver" .LDHName ""}}
+{{label "handle:"}} {{.Handle}}
+{{with .IPAddresses}}{{if .V4}}{{label "v4:"}} {{.V4}}
+{{end}}{{if .V6}}{{label "v6:"}} {{.V6}}
+{{end}}{{end}}{{if .Entities}}{{label "entities:"}}
+{{range .Entities}}  - {{.Handle}} ({{.Roles}})
+{{end}}{{end}}`,
+
+	"ip network": `{{header "ip network" .Handle (printf "(%s %s-%s) " .IPVersion .StartAddress .EndAddress)}}
+{{label "name:"}} {{.Name}} {{label "country:"}} {{.Country}} {{label "parent:"}} {{.ParentHandle}}
+{{if .Type}}{{label "type:"}} {{.Type}} ({{.AllocationType}})
+{{end}}`,
+
+	"autnum": `{{header "autnum" .Handle (printf "(%d-%d) " .StartAutnum .EndAutnum)}}
+{{label "name:"}} {{.Name}} {{label "country:"}} {{.Country}} {{label "type:"}} {{.Type}} ({{.AllocationType}})
+`,
+
+	"entity": `{{header "entity" .Handle ""}}
+{{if .Roles}}{{label "roles:"}} {{.Roles}}
+{{end}}`,
+}
+
+// ANSI styles used by the template color functions.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// useColor reports whether text output is colored: --color=always, or auto on
+// a terminal with NO_COLOR unset.
+var useColor = sync.OnceValue(func() bool {
+	switch flagColor {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+})
+
+func style(code string) func(any) string {
+	return func(v any) string {
+		if !useColor() {
+			return fmt.Sprint(v)
+		}
+		return code + fmt.Sprint(v) + ansiReset
+	}
+}
+
+// templateFuncs are available to built-in and --template templates.
+func templateFuncs() template.FuncMap {
+	provider := sync.OnceValue(nsClassifier)
+	return template.FuncMap{
+		"header": header,
+		"label":  style(ansiBold),
+		"bold":   style(ansiBold),
+		"dim":    style(ansiDim),
+		"red":    style(ansiRed),
+		"green":  style(ansiGreen),
+		"yellow": style(ansiYellow),
+		"cyan":   style(ansiCyan),
+		"join":   strings.Join,
+		"upper":  strings.ToUpper,
+		"lower":  strings.ToLower,
+		// date formats t with layout (default 2006-01-02), "" when t is zero.
+		"date": func(t time.Time, layout ...string) string {
+			if t.IsZero() {
+				return ""
+			}
+			if len(layout) > 0 {
+				return t.Format(layout[0])
+			}
+			return t.Format(time.DateOnly)
+		},
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"provider": func(host string) string {
+			if p, ok := provider().Classify(host); ok {
+				return p.Name
+			}
+			return ""
+		},
+	}
+}
+
+var (
+	builtinOnce sync.Once
+	builtin     map[string]*template.Template
+)
+
+// renderText writes obj to stdout with the built-in template for kind, or
+// under --format table as aligned fields under its header.
+func renderText(kind, name string, obj any) {
+	if flagFormat == "table" {
+		printHeader(kind, name, "")
+		if err := export.Record(os.Stdout, obj); err != nil {
+			warnf("render %s: %v\n", kind, err)
+		}
+		return
+	}
+	builtinOnce.Do(func() {
+		builtin = map[string]*template.Template{}
+		funcs := templateFuncs()
+		for k, src := range textTemplates {
+			builtin[k] = template.Must(template.New(k).Funcs(funcs).Parse(src))
+		}
+	})
+	if err := builtin[kind].Execute(os.Stdout, obj); err != nil {
+		warnf("render %s: %v\n", kind, err)
+	}
+}
+
+// renderTemplate writes obj to stdout with the --template text, adding a
+// final newline when the template has none.
+func renderTemplate(src string, obj any) error {
+	t, err := template.New("template").Funcs(templateFuncs()).Parse(src)
+	if err != nil {
+		return fmt.Errorf("--template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, obj); err != nil {
+		return fmt.Errorf("--template: %w", err)
+	}
+	out := b.String()
+	if !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	_, err = os.Stdout.WriteString(out)
+	return err
+}
+
+// header is the "=== KIND: handle ===" line that opens each object, after a
+// blank line.
+func header(kind, handle, extra string) string {
+	return "\n" + style(ansiBold+ansiCyan)(fmt.Sprintf("=== %s: %s %s===", strings.ToUpper(kind), handle, extra))
+}
+
+func printHeader(kind, handle, extra string) { fmt.Println(header(kind, handle, extra)) }
+
+func printDomain(d *rc.Domain)         { renderText("domain", d.LDHName, d) }
+func printNameserver(n *rc.Nameserver) { renderText("nameserver", n.LDHName, n) }
+func printIPNet(n *rc.IPNetwork)       { renderText("ip network", n.Handle, n) }
+func printAutnum(a *rc.Autnum)         { renderText("autnum", a.Handle, a) }
+func printEntity(e *rc.Entity)         { renderText("entity", e.Handle, e) }
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datum-labs/rdap/schema"
+)
+
+// ---- SCHEMA (JSON Schemas for rdapctl's JSON output) -----------------------
+
+func cmdSchema() *cobra.Command {
+	var outDir string
+	cmd := &cobra.Command{
+		Use:     "schema <" + strings.Join(schema.Names(), "|") + ">",
+		Aliases: []string{"json-schema"},
+		Short:   "Print the JSON Schema of an object class or the tree graph (-o DIR writes them all)",
+		Args: func(_ *cobra.Command, args []string) error {
+			if outDir != "" {
+				return cobra.NoArgs(nil, args)
+			}
+			return cobra.ExactArgs(1)(nil, args)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			if outDir != "" {
+				if err := schema.WriteAll(outDir); err != nil {
+					return err
+				}
+				if !flagQuiet {
+					fmt.Fprintf(os.Stderr, "wrote %d schemas to %s\n", len(schema.Names()), outDir)
+				}
+				return nil
+			}
+			b, err := schema.Marshal(args[0])
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(b)
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&outDir, "out", "o", "", "write every schema to DIR as <name>.schema.json")
+	return cmd
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datum-labs/rdap/proxy"
+)
+
+// ---- SERVE (shared RDAP gateway) -------------------------------------------
+
+func cmdServe() *cobra.Command {
+	var (
+		addr        string
+		keysPath    string
+		daily       int
+		perRegistry int
+		filters     []string
+	)
+	cmd := &cobra.Command{
+		Use:   "serve [--addr :8080] [--keys keys.json]",
+		Short: "Run an RDAP gateway (RFC 9082 paths) with per-key quotas",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			opts := proxy.Options{Filters: filters}
+			if _, err := proxy.FilterChain(filters); err != nil {
+				return err
+			}
+			if keysPath != "" || daily > 0 || perRegistry > 0 {
+				q := &proxy.Quotas{Default: proxy.Limits{Daily: daily, PerRegistry: perRegistry}}
+				if keysPath != "" {
+					f, err := os.Open(keysPath)
+					if err != nil {
+						return err
+					}
+					q.Keys, err = proxy.LoadKeys(f)
+					f.Close()
+					if err != nil {
+						return err
+					}
+					for k, l := range q.Keys {
+						if _, err := proxy.FilterChain(l.Filters); err != nil {
+							return fmt.Errorf("key %q: %w", k, err)
+						}
+					}
+				}
+				opts.Quotas = q
+			}
+
+			srv := &http.Server{Addr: addr, Handler: proxy.New(newClient(), opts), ReadHeaderTimeout: 10 * time.Second}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = srv.Shutdown(shutdown)
+			}()
+			progressf("serving RDAP on %s\n", addr)
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			if opts.Quotas != nil {
+				return printJSON(opts.Quotas.Report())
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&addr, "addr", ":8080", "listen address")
+	cmd.Flags().StringVar(&keysPath, "keys", "", `JSON file of API keys: {"<key>": {"name": "team-a", "daily": 10000, "perRegistry": 2000, "filters": ["strip-vcard"]}}`)
+	cmd.Flags().IntVar(&daily, "daily", 0, "default requests/day per key (0 = unlimited; keys file entries override)")
+	cmd.Flags().IntVar(&perRegistry, "per-registry", 0, "default requests/day per key to any one registry (0 = unlimited)")
+	cmd.Flags().StringSliceVar(&filters, "filter", nil, "response filters for keys without their own list: strip-vcard, redact-contact, strip-remarks")
+	return cmd
+}
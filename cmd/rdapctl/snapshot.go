@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	rc "github.com/datum-labs/rdap"
+	"github.com/datum-labs/rdap/snapshot"
+)
+
+// ---- SNAPSHOT (portable bundles for offline analysis) ----------------------
+
+func cmdSnapshot() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Export or import portable RDAP bundles (graph + raw responses)",
+	}
+	cmd.AddCommand(cmdSnapshotExport(), cmdSnapshotImport(), cmdSnapshotVerify(), cmdSnapshotHistory())
+	return cmd
+}
+
+func cmdSnapshotExport() *cobra.Command {
+	var seed, out, signKey string
+	cmd := &cobra.Command{
+		Use:   "export --seed <query> -o <bundle.tar.zst>",
+		Short: "Walk from a seed while recording every response, then write a bundle",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if seed == "" || out == "" {
+				return errors.New("--seed and -o are required")
+			}
+			if flagRecord != "" {
+				return errors.New("--record cannot be used with snapshot export: the bundle holds the recordings")
+			}
+			tmp, err := os.MkdirTemp("", "rdapctl-snapshot-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(tmp)
+
+			// Record into the temp dir; under --replay (re-bundling offline
+			// data) that collects a copy of each cassette the walk used.
+			flagRecord = tmp
+			c := newClient()
+			graph, err := walkSeed(context.Background(), c, seed)
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Create(out)
+			if err != nil {
+				return err
+			}
+			m := snapshot.Manifest{Seed: seed, CreatedAt: time.Now().UTC(), Generator: "rdapctl"}
+			if err := snapshot.Export(f, m, graph, tmp); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			progressf("wrote %s (%d nodes, %d edges)\n", out, len(graph.Nodes), len(graph.Edges))
+			if signKey != "" {
+				if err := signBundle(out, signKey); err != nil {
+					return err
+				}
+				progressf("wrote %s.sig\n", out)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&seed, "seed", "", "seed query (domain/ip/asn/ns/entity)")
+	cmd.Flags().StringVarP(&out, "output", "o", "", "bundle file to write (zstd-compressed tar)")
+	cmd.Flags().StringVar(&signKey, "sign-key", "", "PEM private key (ed25519/ecdsa/rsa); writes a detached <output>.sig")
+	addWalkFlags(cmd)
+	return cmd
+}
+
+func cmdSnapshotImport() *cobra.Command {
+	var dir string
+	cmd := &cobra.Command{
+		Use:   "import <bundle.tar.zst>",
+		Short: "Unpack a bundle so it can be replayed offline with --replay",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			m, err := snapshot.Import(f, dir)
+			if err != nil {
+				return err
+			}
+			if flagJSON {
+				return printJSON(m)
+			}
+			fmt.Printf("seed: %s\ncreated: %s\ncassettes: %d\n", m.Seed, m.CreatedAt.Format(time.RFC3339), m.Cassettes)
+			fmt.Printf("replay with: rdapctl --replay %s tree %s\n", filepath.Join(dir, snapshot.CassetteDir), m.Seed)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&dir, "dir", "d", ".", "directory to unpack into")
+	return cmd
+}
+
+func cmdSnapshotVerify() *cobra.Command {
+	var pubPath, sigPath string
+	cmd := &cobra.Command{
+		Use:   "verify <bundle.tar.zst> --pub <key.pem>",
+		Short: "Check a bundle's detached signature and per-file digests",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			bundle := args[0]
+			if sigPath == "" {
+				sigPath = bundle + ".sig"
+			}
+			pemBytes, err := os.ReadFile(pubPath)
+			if err != nil {
+				return err
+			}
+			pub, err := snapshot.ParsePublicKeyPEM(pemBytes)
+			if err != nil {
+				return err
+			}
+			sig, err := os.ReadFile(sigPath)
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(bundle)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if err := snapshot.Verify(f, sig, pub); err != nil {
+				return err
+			}
+			// Digests are checked by Import; unpack to a scratch dir and discard.
+			if _, err := f.Seek(0, 0); err != nil {
+				return err
+			}
+			tmp, err := os.MkdirTemp("", "rdapctl-verify-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(tmp)
+			m, err := snapshot.Import(f, tmp)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("OK: %s (seed %s, %d cassettes, created %s)\n", bundle, m.Seed, m.Cassettes, m.CreatedAt.Format(time.RFC3339))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&pubPath, "pub", "", "PEM public key (PKIX) of the signer")
+	cmd.Flags().StringVar(&sigPath, "sig", "", "detached signature file (default <bundle>.sig)")
+	_ = cmd.MarkFlagRequired("pub")
+	return cmd
+}
+
+func cmdSnapshotHistory() *cobra.Command {
+	var in, path string
+	cmd := &cobra.Command{
+		Use:   "history -i <queries.txt> --history <file.json>",
+		Short: "Re-look up queries and print created/updated/deleted/restored events",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			queries, err := readQueries(in)
+			if err != nil {
+				return err
+			}
+			h, err := snapshot.OpenHistory(path)
+			if err != nil {
+				return err
+			}
+			c := newClient()
+			// A history entry must reflect the registry now, not a cached copy.
+			ctx := rc.WithFreshData(context.Background())
+			now := time.Now()
+			for _, q := range queries {
+				obj, lerr := c.Lookup(ctx, q, flagTLD)
+				ch, err := h.Observe(strings.ToLower(q), obj, lerr, now)
+				if err != nil {
+					return err
+				}
+				if lerr != nil && ch == nil {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", q, lerr)
+				}
+				if ch != nil {
+					c.NoteChange(obj, lerr)
+					_ = printJSONLine(ch)
+				}
+			}
+			return h.Save()
+		},
+	}
+	cmd.Flags().StringVarP(&in, "input", "i", "-", "file with one query per line (- for stdin)")
+	cmd.Flags().StringVar(&path, "history", "", "history file to diff against and update")
+	_ = cmd.MarkFlagRequired("history")
+	return cmd
+}
+
+func signBundle(bundle, keyPath string) error {
+	pemBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+	key, err := snapshot.ParsePrivateKeyPEM(pemBytes)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(bundle)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sig, err := snapshot.Sign(f, key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(bundle+".sig", sig, 0o644)
+}
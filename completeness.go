@@ -0,0 +1,196 @@
+package rdapclient
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// Completeness says how much of a composite result (a graph walk, a batch,
+// several lookups under one WithCompleteness context) is backed by current
+// registry data, so consumers can weigh it.
+type Completeness struct {
+	// Fetched counts the objects that were answered, from the registry or
+	// from a local copy.
+	Fetched int `json:"fetched"`
+	// FromCache counts those answered by a fresh cache entry or the dataset.
+	FromCache int `json:"fromCache,omitempty"`
+	// Stale are answers served from an expired cache entry because the
+	// registry failed (WithStaleIfError); they are counted in Fetched too.
+	Stale []StaleAnswer `json:"stale,omitempty"`
+	// Failed are the objects that could not be fetched.
+	Failed []*ItemError `json:"failed,omitempty"`
+	// SkippedRegistries are the registry hosts requests were not sent to:
+	// a used-up byte budget, a refused link, a chain of links too long.
+	SkippedRegistries []string `json:"skippedRegistries,omitempty"`
+	// Truncated is set when the operation stopped at a request cap (see
+	// graph.WalkOptions.MaxRequests) with work left.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// StaleAnswer is an object served from an expired cache entry. Cause is the
+// registry failure that made it necessary.
+type StaleAnswer struct {
+	URL      string `json:"url"`
+	Registry string `json:"registry,omitempty"`
+	Cause    string `json:"cause"`
+}
+
+// Complete reports whether nothing failed, was skipped or is stale.
+func (c Completeness) Complete() bool {
+	return len(c.Failed) == 0 && len(c.SkippedRegistries) == 0 && len(c.Stale) == 0 && !c.Truncated
+}
+
+// CompletenessReport collects a Completeness under a context from
+// WithCompleteness. It is safe for concurrent use; the zero value is a
+// report of its own, for merging others into.
+type CompletenessReport struct {
+	mu      sync.Mutex
+	c       Completeness
+	skipped map[string]bool
+	parent  *CompletenessReport
+}
+
+type completenessKey struct{}
+
+// WithCompleteness returns a context under which the client's lookups record
+// what they fetched, from where, and what failed, in the returned report. A
+// report nested in another one records into both, so a batch can keep one
+// per item and one for the run.
+func WithCompleteness(ctx context.Context) (context.Context, *CompletenessReport) {
+	r := &CompletenessReport{parent: CompletenessFrom(ctx)}
+	return context.WithValue(ctx, completenessKey{}, r), r
+}
+
+// CompletenessFrom returns the report of a context from WithCompleteness, or nil.
+func CompletenessFrom(ctx context.Context) *CompletenessReport {
+	r, _ := ctx.Value(completenessKey{}).(*CompletenessReport)
+	return r
+}
+
+// Report returns a copy of what has been recorded so far.
+func (r *CompletenessReport) Report() Completeness {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.c
+	c.Stale = append([]StaleAnswer(nil), r.c.Stale...)
+	c.Failed = append([]*ItemError(nil), r.c.Failed...)
+	c.SkippedRegistries = append([]string(nil), r.c.SkippedRegistries...)
+	sort.Strings(c.SkippedRegistries)
+	return c
+}
+
+// each runs fn on r and every report r is nested in. A nil r is a no-op.
+func (r *CompletenessReport) each(fn func(*CompletenessReport)) {
+	for ; r != nil; r = r.parent {
+		r.mu.Lock()
+		fn(r)
+		r.mu.Unlock()
+	}
+}
+
+// Fetched records an answered object; cached is set when a fresh local copy
+// answered it.
+func (r *CompletenessReport) Fetched(cached bool) {
+	r.each(func(r *CompletenessReport) {
+		r.c.Fetched++
+		if cached {
+			r.c.FromCache++
+		}
+	})
+}
+
+// Failed records an object that could not be fetched. Errors that mean no
+// request was sent (a used-up byte budget, a refused link, too many hops)
+// also list the registry as skipped.
+func (r *CompletenessReport) Failed(id, registry string, attempts int, err error) {
+	if err == nil {
+		return
+	}
+	var (
+		be *ErrBudgetExceeded
+		lb *ErrLinkBlocked
+		hl *ErrHopLimit
+	)
+	skipped := registry != "" && (errors.As(err, &be) || errors.As(err, &lb) || errors.As(err, &hl) && !hl.Redirect)
+	r.each(func(r *CompletenessReport) {
+		r.c.Failed = append(r.c.Failed, &ItemError{ID: id, Registry: registry, Attempts: attempts, Err: err})
+		if skipped {
+			r.skip(registry)
+		}
+	})
+}
+
+// Merge adds another report's findings, such as one item's of a batch.
+func (r *CompletenessReport) Merge(c Completeness) {
+	r.each(func(r *CompletenessReport) {
+		r.c.Fetched += c.Fetched
+		r.c.FromCache += c.FromCache
+		r.c.Stale = append(r.c.Stale, c.Stale...)
+		r.c.Failed = append(r.c.Failed, c.Failed...)
+		for _, reg := range c.SkippedRegistries {
+			r.skip(reg)
+		}
+		r.c.Truncated = r.c.Truncated || c.Truncated
+	})
+}
+
+// Truncate records that the operation stopped with work left.
+func (r *CompletenessReport) Truncate() {
+	r.each(func(r *CompletenessReport) { r.c.Truncated = true })
+}
+
+func (r *CompletenessReport) stale(a StaleAnswer) {
+	r.each(func(r *CompletenessReport) { r.c.Stale = append(r.c.Stale, a) })
+}
+
+// skip lists registry once. The caller holds r.mu.
+func (r *CompletenessReport) skip(registry string) {
+	if r.skipped == nil {
+		r.skipped = map[string]bool{}
+	}
+	if !r.skipped[registry] {
+		r.skipped[registry] = true
+		r.c.SkippedRegistries = append(r.c.SkippedRegistries, registry)
+	}
+}
+
+// staleWarning marks the header getJSON returns with a stale answer, as an
+// HTTP cache would (RFC 7234 section 5.5.1).
+const staleWarning = `110 - "Response is Stale"`
+
+// staleAnswer returns u's expired cached body in place of err when
+// WithStaleIfError allows it and err is a registry failure rather than an
+// answer: a network error or timeout, a 5xx or 429, a used-up byte budget.
+func (c *Client) staleAnswer(ctx context.Context, u string, err error) ([]byte, bool) {
+	if c.staleIfError <= 0 || ctx.Err() != nil {
+		return nil, false
+	}
+	var he *HTTPError
+	switch ErrorCode(err) {
+	case CodeNetwork, CodeTimeout, CodeRateLimited, CodeBudgetExceeded:
+	case CodeHTTP:
+		if !errors.As(err, &he) || he.StatusCode < 500 {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	body := c.respCache.StaleBody(u, c.staleIfError)
+	if body == nil {
+		return nil, false
+	}
+	c.log.WarnContext(ctx, "rdap serving stale response", "url", u, "err", err)
+	CompletenessFrom(ctx).stale(StaleAnswer{URL: u, Registry: urlHost(u), Cause: err.Error()})
+	return body, true
+}
+
+// urlHost is u's host, or "" when u does not parse.
+func urlHost(u string) string {
+	if pu, err := url.Parse(u); err == nil {
+		return pu.Host
+	}
+	return ""
+}
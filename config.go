@@ -0,0 +1,244 @@
+package rdapclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// Config is a declarative alternative to functional options: a plain struct
+// that can be unmarshaled from JSON or YAML (field names match in both) and
+// turned into the same Options New accepts. Zero values keep New's defaults.
+type Config struct {
+	UserAgent       string   `json:"userAgent,omitempty" yaml:"userAgent,omitempty"`
+	Timeout         Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	BootstrapURL    string   `json:"bootstrapURL,omitempty" yaml:"bootstrapURL,omitempty"`
+	IPBootstrapURL  string   `json:"ipBootstrapURL,omitempty" yaml:"ipBootstrapURL,omitempty"`
+	ASNBootstrapURL string   `json:"asnBootstrapURL,omitempty" yaml:"asnBootstrapURL,omitempty"`
+	// ObjectTagBootstrapURL maps to WithObjectTagBootstrapURL.
+	ObjectTagBootstrapURL string `json:"objectTagBootstrapURL,omitempty" yaml:"objectTagBootstrapURL,omitempty"`
+	DefaultRDAPBase       string `json:"defaultRDAPBase,omitempty" yaml:"defaultRDAPBase,omitempty"`
+	// MaxRetries is a pointer so an explicit 0 (no retries) differs from unset.
+	MaxRetries *int              `json:"maxRetries,omitempty" yaml:"maxRetries,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// TLDCacheSize and ResponseCacheSize map to WithCacheSizes. The former
+	// caps the IP, ASN and entity bases; every TLD of the DNS bootstrap is kept.
+	TLDCacheSize      int    `json:"tldCacheSize,omitempty" yaml:"tldCacheSize,omitempty"`
+	ResponseCacheSize int    `json:"responseCacheSize,omitempty" yaml:"responseCacheSize,omitempty"`
+	RecordDir         string `json:"recordDir,omitempty" yaml:"recordDir,omitempty"`
+	ReplayDir         string `json:"replayDir,omitempty" yaml:"replayDir,omitempty"`
+	// RateLimit is requests per second across the client (WithRateLimit).
+	RateLimit float64 `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+	// HTTPProxy is a proxy URL for outgoing requests (WithHTTPProxy).
+	HTTPProxy string `json:"httpProxy,omitempty" yaml:"httpProxy,omitempty"`
+	// HTTP3 maps to WithHTTP3.
+	HTTP3 bool `json:"http3,omitempty" yaml:"http3,omitempty"`
+	// StrictParsing turns off lenient nested objectClassName handling (WithLenientParsing).
+	StrictParsing bool `json:"strictParsing,omitempty" yaml:"strictParsing,omitempty"`
+	// CanonicalCacheSize enables WithCanonicalCache with that many objects.
+	CanonicalCacheSize int `json:"canonicalCacheSize,omitempty" yaml:"canonicalCacheSize,omitempty"`
+	// BootstrapSoftTTL and BootstrapHardTTL map to WithBootstrapTTL.
+	BootstrapSoftTTL Duration `json:"bootstrapSoftTTL,omitempty" yaml:"bootstrapSoftTTL,omitempty"`
+	BootstrapHardTTL Duration `json:"bootstrapHardTTL,omitempty" yaml:"bootstrapHardTTL,omitempty"`
+	// DegradedHostConcurrency maps to WithDegradedHostConcurrency.
+	DegradedHostConcurrency int `json:"degradedHostConcurrency,omitempty" yaml:"degradedHostConcurrency,omitempty"`
+	// MaxNestingDepth maps to WithMaxNestingDepth.
+	MaxNestingDepth int `json:"maxNestingDepth,omitempty" yaml:"maxNestingDepth,omitempty"`
+	// MaxResponseSize maps to WithMaxResponseSize (bytes).
+	MaxResponseSize int64 `json:"maxResponseSize,omitempty" yaml:"maxResponseSize,omitempty"`
+	// SizeAnomalyFactor and RejectSizeAnomalies map to WithSizeAnomalies.
+	SizeAnomalyFactor   float64 `json:"sizeAnomalyFactor,omitempty" yaml:"sizeAnomalyFactor,omitempty"`
+	RejectSizeAnomalies bool    `json:"rejectSizeAnomalies,omitempty" yaml:"rejectSizeAnomalies,omitempty"`
+	// LinkPolicy maps to WithLinkPolicy.
+	LinkPolicy *LinkPolicy `json:"linkPolicy,omitempty" yaml:"linkPolicy,omitempty"`
+	// MaxRedirects maps to WithMaxRedirects.
+	MaxRedirects int `json:"maxRedirects,omitempty" yaml:"maxRedirects,omitempty"`
+	// MaxLinkHops maps to WithMaxLinkHops.
+	MaxLinkHops int `json:"maxLinkHops,omitempty" yaml:"maxLinkHops,omitempty"`
+	// SynthesizeNameservers maps to WithSynthesizedNameservers.
+	SynthesizeNameservers bool `json:"synthesizeNameservers,omitempty" yaml:"synthesizeNameservers,omitempty"`
+	// ByteBudget maps to WithByteBudget.
+	ByteBudget *ByteBudget `json:"byteBudget,omitempty" yaml:"byteBudget,omitempty"`
+	// StaleIfError maps to WithStaleIfError.
+	StaleIfError Duration `json:"staleIfError,omitempty" yaml:"staleIfError,omitempty"`
+	// ExtensionGating maps to WithExtensionGating.
+	ExtensionGating bool `json:"extensionGating,omitempty" yaml:"extensionGating,omitempty"`
+	// SettleWindow and SettleTTL map to WithSettleWindow.
+	SettleWindow Duration `json:"settleWindow,omitempty" yaml:"settleWindow,omitempty"`
+	SettleTTL    Duration `json:"settleTTL,omitempty" yaml:"settleTTL,omitempty"`
+	// DiskCacheDir and DiskCacheMaxBytes map to WithDiskCache.
+	DiskCacheDir      string `json:"diskCacheDir,omitempty" yaml:"diskCacheDir,omitempty"`
+	DiskCacheMaxBytes int64  `json:"diskCacheMaxBytes,omitempty" yaml:"diskCacheMaxBytes,omitempty"`
+	// HostRateLimit (every host) and HostRateLimits (by host) map to
+	// WithHostRateLimit, in requests per second.
+	HostRateLimit  float64            `json:"hostRateLimit,omitempty" yaml:"hostRateLimit,omitempty"`
+	HostRateLimits map[string]float64 `json:"hostRateLimits,omitempty" yaml:"hostRateLimits,omitempty"`
+}
+
+// Duration is a time.Duration that (un)marshals as a Go duration string ("10s").
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) { return []byte(time.Duration(d).String()), nil }
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Options converts cfg to functional options, for callers that mix both styles.
+func (cfg Config) Options() []Option {
+	var opts []Option
+	if cfg.UserAgent != "" {
+		opts = append(opts, WithUserAgent(cfg.UserAgent))
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, WithTimeout(time.Duration(cfg.Timeout)))
+	}
+	if cfg.BootstrapURL != "" {
+		opts = append(opts, WithBootstrapURL(cfg.BootstrapURL))
+	}
+	if cfg.IPBootstrapURL != "" {
+		opts = append(opts, WithIPBootstrapURL(cfg.IPBootstrapURL))
+	}
+	if cfg.ASNBootstrapURL != "" {
+		opts = append(opts, WithASNBootstrapURL(cfg.ASNBootstrapURL))
+	}
+	if cfg.ObjectTagBootstrapURL != "" {
+		opts = append(opts, WithObjectTagBootstrapURL(cfg.ObjectTagBootstrapURL))
+	}
+	if cfg.DefaultRDAPBase != "" {
+		opts = append(opts, WithDefaultRDAPBase(cfg.DefaultRDAPBase))
+	}
+	if cfg.MaxRetries != nil {
+		opts = append(opts, WithMaxRetries(*cfg.MaxRetries))
+	}
+	for k, v := range cfg.Headers {
+		opts = append(opts, WithHeader(k, v))
+	}
+	if cfg.TLDCacheSize > 0 || cfg.ResponseCacheSize > 0 {
+		opts = append(opts, WithCacheSizes(cfg.TLDCacheSize, cfg.ResponseCacheSize))
+	}
+	if cfg.BootstrapSoftTTL > 0 || cfg.BootstrapHardTTL > 0 {
+		soft, hard := time.Duration(cfg.BootstrapSoftTTL), time.Duration(cfg.BootstrapHardTTL)
+		if soft == 0 {
+			soft = 6 * time.Hour
+		}
+		if hard == 0 {
+			hard = 7 * 24 * time.Hour
+		}
+		opts = append(opts, WithBootstrapTTL(soft, hard))
+	}
+	if cfg.DegradedHostConcurrency > 0 {
+		opts = append(opts, WithDegradedHostConcurrency(cfg.DegradedHostConcurrency))
+	}
+	if cfg.MaxNestingDepth > 0 {
+		opts = append(opts, WithMaxNestingDepth(cfg.MaxNestingDepth))
+	}
+	if cfg.MaxResponseSize > 0 {
+		opts = append(opts, WithMaxResponseSize(cfg.MaxResponseSize))
+	}
+	if cfg.SizeAnomalyFactor > 0 || cfg.RejectSizeAnomalies {
+		factor := cfg.SizeAnomalyFactor
+		if factor <= 0 {
+			factor = DefaultSizeAnomalyFactor
+		}
+		opts = append(opts, WithSizeAnomalies(factor, cfg.RejectSizeAnomalies))
+	}
+	if cfg.LinkPolicy != nil {
+		opts = append(opts, WithLinkPolicy(*cfg.LinkPolicy))
+	}
+	if cfg.MaxRedirects > 0 {
+		opts = append(opts, WithMaxRedirects(cfg.MaxRedirects))
+	}
+	if cfg.MaxLinkHops > 0 {
+		opts = append(opts, WithMaxLinkHops(cfg.MaxLinkHops))
+	}
+	if cfg.SynthesizeNameservers {
+		opts = append(opts, WithSynthesizedNameservers(true))
+	}
+	if cfg.ByteBudget != nil {
+		opts = append(opts, WithByteBudget(*cfg.ByteBudget))
+	}
+	if cfg.StaleIfError > 0 {
+		opts = append(opts, WithStaleIfError(time.Duration(cfg.StaleIfError)))
+	}
+	if cfg.ExtensionGating {
+		opts = append(opts, WithExtensionGating(true))
+	}
+	if cfg.SettleWindow > 0 {
+		opts = append(opts, WithSettleWindow(time.Duration(cfg.SettleWindow), time.Duration(cfg.SettleTTL)))
+	}
+	if cfg.HostRateLimit > 0 {
+		opts = append(opts, WithHostRateLimit("", cfg.HostRateLimit))
+	}
+	for host, rps := range cfg.HostRateLimits {
+		opts = append(opts, WithHostRateLimit(host, rps))
+	}
+	if cfg.DiskCacheDir != "" {
+		opts = append(opts, WithDiskCache(cfg.DiskCacheDir, cfg.DiskCacheMaxBytes))
+	}
+	if cfg.CanonicalCacheSize > 0 {
+		opts = append(opts, WithCanonicalCache(cfg.CanonicalCacheSize))
+	}
+	if cfg.RecordDir != "" {
+		opts = append(opts, WithRecorder(cfg.RecordDir))
+	}
+	if cfg.ReplayDir != "" {
+		opts = append(opts, WithReplay(cfg.ReplayDir))
+	}
+	if cfg.RateLimit > 0 {
+		opts = append(opts, WithRateLimit(cfg.RateLimit))
+	}
+	if u, err := parseProxyURL(cfg.HTTPProxy); err == nil && u != nil {
+		opts = append(opts, WithHTTPProxy(u))
+	}
+	if cfg.HTTP3 {
+		opts = append(opts, WithHTTP3(true))
+	}
+	if cfg.StrictParsing {
+		opts = append(opts, WithLenientParsing(false))
+	}
+	return opts
+}
+
+// Validate reports settings Options would have to drop.
+func (cfg Config) Validate() error {
+	if _, err := parseProxyURL(cfg.HTTPProxy); err != nil {
+		return fmt.Errorf("rdap config: httpProxy: %w", err)
+	}
+	return nil
+}
+
+func parseProxyURL(s string) (*url.URL, error) {
+	if s == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an absolute URL", s)
+	}
+	return u, nil
+}
+
+// NewFromConfig returns a Client configured from cfg.
+func NewFromConfig(cfg Config) *Client { return New(cfg.Options()...) }
+
+// LoadConfig decodes a JSON Config, rejecting unknown fields so typos surface.
+func LoadConfig(r io.Reader) (Config, error) {
+	var cfg Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("rdap config: %w", err)
+	}
+	return cfg, cfg.Validate()
+}
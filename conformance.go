@@ -0,0 +1,209 @@
+package rdapclient
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rdapConformance tokens of the specifications and extensions the client
+// decodes or acts on (ConformanceReverseSearch is with the search endpoints).
+const (
+	ConformanceLevel0        = "rdap_level_0"
+	ConformanceCIDR0         = "cidr0"
+	ConformanceRedacted      = "redacted"
+	ConformanceArinOriginAS0 = "arin_originas0"
+	ConformancePaging        = "paging"
+	ConformanceSorting       = "sorting"
+	ConformanceICANNProfile  = "icann_rdap_response_profile_0"
+	ConformanceSubsetting    = "subsetting"
+)
+
+// Versioned profiles, for Capabilities.ProfileVersion.
+const (
+	ProfileRDAPLevel           = "rdap_level"
+	ProfileICANNResponse       = "icann_rdap_response_profile"
+	ProfileICANNTechnicalGuide = "icann_rdap_technical_implementation_guide"
+)
+
+// Capabilities is a response's rdapConformance as a set of lower-cased tokens.
+type Capabilities map[string]struct{}
+
+// ParseCapabilities builds the set of an rdapConformance array.
+func ParseCapabilities(tokens []string) Capabilities {
+	c := make(Capabilities, len(tokens))
+	for _, t := range tokens {
+		if t = lower(strings.TrimSpace(t)); t != "" {
+			c[t] = struct{}{}
+		}
+	}
+	return c
+}
+
+// Supports reports whether the server declared name ("redacted",
+// "subsetting"). A name without a version also matches its versioned tokens:
+// "arin_originas" matches "arin_originas0", "rdap_level" matches "rdap_level_0".
+func (c Capabilities) Supports(name string) bool {
+	name = lower(strings.TrimSpace(name))
+	if name == "" {
+		return false
+	}
+	if _, ok := c[name]; ok {
+		return true
+	}
+	for t := range c {
+		if v, ok := strings.CutPrefix(t, name); ok && isVersion(strings.TrimPrefix(v, "_")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProfileVersion returns the highest version of a versioned specification
+// the server declared: 1 for "icann_rdap_response_profile_1" given
+// ProfileICANNResponse. ok is false when no version of it was declared.
+func (c Capabilities) ProfileVersion(name string) (version int, ok bool) {
+	name = lower(strings.TrimSpace(name))
+	if name == "" {
+		return 0, false
+	}
+	for t := range c {
+		v, found := strings.CutPrefix(t, name)
+		if !found {
+			continue
+		}
+		v = strings.TrimPrefix(v, "_")
+		if !isVersion(v) {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err == nil && (!ok || n > version) {
+			version, ok = n, true
+		}
+	}
+	return version, ok
+}
+
+func isVersion(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Tokens returns the declared tokens, sorted.
+func (c Capabilities) Tokens() []string {
+	out := make([]string, 0, len(c))
+	for t := range c {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Response is a top-level RDAP response: an object, search results, a help
+// response or an error, whichever declares rdapConformance.
+type Response interface {
+	Capabilities() Capabilities
+	SupportsExtension(name string) bool
+}
+
+var (
+	_ Response = CommonObject{}
+	_ Response = SearchMetadata{}
+	_ Response = (*HelpResponse)(nil)
+	_ Response = (*RDAPError)(nil)
+)
+
+// Capabilities returns o's rdapConformance as a set. Only top-level objects
+// carry one; nested objects answer for their response through it.
+func (o CommonObject) Capabilities() Capabilities { return ParseCapabilities(o.RDAPConformance) }
+
+// SupportsExtension reports whether o's server declared name (see Capabilities.Supports).
+func (o CommonObject) SupportsExtension(name string) bool { return o.Capabilities().Supports(name) }
+
+// Capabilities returns the search response's rdapConformance as a set.
+func (m SearchMetadata) Capabilities() Capabilities { return ParseCapabilities(m.RDAPConformance) }
+
+// SupportsExtension reports whether the search server declared name.
+func (m SearchMetadata) SupportsExtension(name string) bool { return m.Capabilities().Supports(name) }
+
+// Capabilities returns the help response's rdapConformance as a set.
+func (h *HelpResponse) Capabilities() Capabilities { return ParseCapabilities(h.RDAPConformance) }
+
+// SupportsExtension reports whether the server declared name in its help response.
+func (h *HelpResponse) SupportsExtension(name string) bool { return h.Capabilities().Supports(name) }
+
+// Capabilities returns the error response's rdapConformance as a set.
+func (e *RDAPError) Capabilities() Capabilities { return ParseCapabilities(e.RDAPConformance) }
+
+// SupportsExtension reports whether the error response declared name.
+func (e *RDAPError) SupportsExtension(name string) bool { return e.Capabilities().Supports(name) }
+
+// CIDR0 is one block of the cidr0 extension's "cidr0_cidrs": an IP network
+// given as prefixes, which ranges that are not one CIDR block need.
+type CIDR0 struct {
+	V4Prefix string `json:"v4prefix,omitempty"`
+	V6Prefix string `json:"v6prefix,omitempty"`
+	Length   int    `json:"length"`
+}
+
+// Prefix parses c as a netip.Prefix.
+func (c CIDR0) Prefix() (netip.Prefix, error) {
+	addr := c.V4Prefix
+	if addr == "" {
+		addr = c.V6Prefix
+	}
+	return netip.ParsePrefix(fmt.Sprintf("%s/%d", addr, c.Length))
+}
+
+// Prefixes returns n's cidr0 blocks that parse, in order.
+func (n *IPNetwork) Prefixes() []netip.Prefix {
+	var out []netip.Prefix
+	for _, c := range n.CIDRs {
+		if p, err := c.Prefix(); err == nil {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// gateExtensions clears the extension members obj's response did not declare
+// in rdapConformance, for WithExtensionGating.
+func gateExtensions(obj Object) {
+	top := topObject(obj)
+	if top == nil {
+		return
+	}
+	caps := top.Capabilities()
+	if !caps.Supports(ConformanceRedacted) {
+		top.Redacted = nil
+	}
+	network := func(n *IPNetwork) {
+		if !caps.Supports(ConformanceCIDR0) {
+			n.CIDRs = nil
+		}
+		if !caps.Supports(ConformanceArinOriginAS0) {
+			n.OriginAutnums = nil
+		}
+	}
+	switch v := obj.(type) {
+	case *IPNetwork:
+		network(v)
+	case *Domain:
+		if v.Network != nil {
+			network(v.Network)
+		}
+	case *Entity:
+		for i := range v.Networks {
+			network(&v.Networks[i])
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package rdapclient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Contact is the contact data of an entity's jCard (RFC 7095), decoded into
+// plain fields.
+type Contact struct {
+	// Kind is the vCard kind: "individual", "org", "group" or "location".
+	Kind     string `json:"kind,omitempty"`
+	FullName string `json:"fullName,omitempty"`
+	// Org joins the organization name and its units with ", ".
+	Org       string    `json:"org,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Role      string    `json:"role,omitempty"`
+	Emails    []string  `json:"emails,omitempty"`
+	Phones    []Phone   `json:"phones,omitempty"`
+	Addresses []Address `json:"addresses,omitempty"`
+	URLs      []string  `json:"urls,omitempty"`
+	Lang      string    `json:"lang,omitempty"`
+}
+
+// Phone is a jCard "tel" property. Number has any "tel:" URI scheme removed;
+// Types are its type parameters ("voice", "fax", "work", ...).
+type Phone struct {
+	Number string   `json:"number"`
+	Types  []string `json:"types,omitempty"`
+}
+
+// Address is a jCard "adr" property. Label is the formatted address from the
+// "label" parameter, which some servers send instead of the components.
+type Address struct {
+	Label       string   `json:"label,omitempty"`
+	POBox       string   `json:"poBox,omitempty"`
+	Extended    string   `json:"extended,omitempty"`
+	Street      []string `json:"street,omitempty"`
+	Locality    string   `json:"locality,omitempty"`
+	Region      string   `json:"region,omitempty"`
+	PostalCode  string   `json:"postalCode,omitempty"`
+	Country     string   `json:"country,omitempty"`
+	CountryCode string   `json:"countryCode,omitempty"`
+	Types       []string `json:"types,omitempty"`
+}
+
+// Contact decodes e's jCard, or returns nil when e has none. It tolerates the
+// shapes registrars get wrong: a property list without the "vcard" wrapper
+// or with an extra array around it, property names in any case, parameters
+// with a single type as a string, properties missing their value type, and
+// text where a structured value belongs. Properties it cannot read are
+// skipped. When "fn" is missing, FullName is built from "n".
+func (e *Entity) Contact() *Contact {
+	props := jcardProps(e.VCardArray)
+	if props == nil {
+		return nil
+	}
+	c := &Contact{}
+	var name string
+	for _, p := range props {
+		params, values := p.params, p.values
+		switch p.name {
+		case "kind":
+			c.Kind = strings.ToLower(firstText(values))
+		case "fn":
+			c.FullName = firstText(values)
+		case "n":
+			name = structuredName(values)
+		case "org":
+			c.Org = strings.Join(components(values), ", ")
+		case "title":
+			c.Title = firstText(values)
+		case "role":
+			c.Role = firstText(values)
+		case "email":
+			c.Emails = append(c.Emails, texts(values)...)
+		case "tel":
+			for _, v := range texts(values) {
+				c.Phones = append(c.Phones, Phone{Number: strings.TrimPrefix(v, "tel:"), Types: paramList(params, "type")})
+			}
+		case "adr":
+			c.Addresses = append(c.Addresses, address(params, values))
+		case "url":
+			c.URLs = append(c.URLs, texts(values)...)
+		case "lang":
+			if c.Lang == "" {
+				c.Lang = firstText(values)
+			}
+		}
+	}
+	if c.FullName == "" {
+		c.FullName = name
+	}
+	return c
+}
+
+// jcardProp is one property of a jCard: its lower-cased name, parameters and
+// values (everything after the value type).
+type jcardProp struct {
+	name   string
+	params map[string]any
+	values []any
+}
+
+// jcardProps returns the properties of a vcardArray, or nil when v is not one.
+func jcardProps(v any) []jcardProp {
+	card, ok := v.([]any)
+	// [["vcard", [...]]]: one array too many.
+	for ok && len(card) == 1 {
+		inner, isArr := card[0].([]any)
+		if !isArr {
+			break
+		}
+		card = inner
+	}
+	if !ok || len(card) == 0 {
+		return nil
+	}
+	var list []any
+	if s, isStr := card[0].(string); isStr && strings.EqualFold(s, "vcard") {
+		if len(card) < 2 {
+			return nil
+		}
+		list, _ = card[1].([]any)
+	} else if _, isArr := card[0].([]any); isArr {
+		// The property list without its "vcard" wrapper.
+		list = card
+	}
+	if list == nil {
+		return nil
+	}
+	props := make([]jcardProp, 0, len(list))
+	for _, p := range list {
+		prop, ok := p.([]any)
+		if !ok || len(prop) < 2 {
+			continue
+		}
+		name, ok := prop[0].(string)
+		if !ok || name == "" {
+			continue
+		}
+		jp := jcardProp{name: strings.ToLower(name)}
+		rest := prop[1:]
+		if m, ok := rest[0].(map[string]any); ok {
+			jp.params, rest = m, rest[1:]
+		}
+		// The value type, when present, is a string followed by the value;
+		// a lone string is the value itself unless it names a type.
+		if len(rest) == 0 {
+			continue
+		}
+		if t, ok := rest[0].(string); ok && (len(rest) >= 2 || jcardValueTypes[strings.ToLower(t)]) {
+			rest = rest[1:]
+		}
+		jp.values = rest
+		if len(jp.values) > 0 {
+			props = append(props, jp)
+		}
+	}
+	return props
+}
+
+// jcardValueTypes are the RFC 7095 value types.
+var jcardValueTypes = map[string]bool{
+	"text": true, "uri": true, "date": true, "time": true, "date-time": true, "date-and-or-time": true,
+	"timestamp": true, "boolean": true, "integer": true, "float": true, "utc-offset": true,
+	"language-tag": true, "unknown": true,
+}
+
+// text renders a scalar jCard value; arrays and objects give "".
+func text(v any) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case float64, bool:
+		return fmt.Sprint(t)
+	}
+	return ""
+}
+
+func firstText(values []any) string {
+	for _, v := range values {
+		if s := text(v); s != "" {
+			return s
+		}
+		if arr, ok := v.([]any); ok {
+			if s := firstText(arr); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// texts returns every non-empty scalar among values (multi-valued properties
+// put extra values after the first).
+func texts(values []any) []string {
+	var out []string
+	for _, v := range values {
+		if s := text(v); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// components flattens a structured value (or a plain text one) into its
+// non-empty parts.
+func components(values []any) []string {
+	var out []string
+	for _, v := range values {
+		if arr, ok := v.([]any); ok {
+			out = append(out, components(arr)...)
+		} else if s := text(v); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// structuredName renders an "n" value (family; given; additional; prefixes;
+// suffixes) as "prefixes given additional family suffixes".
+func structuredName(values []any) string {
+	if len(values) == 0 {
+		return ""
+	}
+	parts, ok := values[0].([]any)
+	if !ok {
+		return firstText(values)
+	}
+	at := func(i int) string {
+		if i < len(parts) {
+			return strings.Join(components(parts[i:i+1]), " ")
+		}
+		return ""
+	}
+	var out []string
+	for _, s := range []string{at(3), at(1), at(2), at(0), at(4)} {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// address decodes an "adr" property; its seven components are pobox,
+// extended, street, locality, region, postal code and country.
+func address(params map[string]any, values []any) Address {
+	a := Address{Types: paramList(params, "type")}
+	if l := paramList(params, "label"); len(l) > 0 {
+		a.Label = strings.Join(l, "\n")
+	}
+	if cc := paramList(params, "cc"); len(cc) > 0 {
+		a.CountryCode = strings.ToUpper(cc[0])
+	}
+	parts, ok := values[0].([]any)
+	if !ok {
+		// Plain text where the components belong: keep it as the label.
+		if a.Label == "" {
+			a.Label = strings.Join(texts(values), "\n")
+		}
+		return a
+	}
+	at := func(i int) []string {
+		if i < len(parts) {
+			return components(parts[i : i+1])
+		}
+		return nil
+	}
+	one := func(i int) string { return strings.Join(at(i), " ") }
+	a.POBox, a.Extended, a.Street = one(0), one(1), at(2)
+	a.Locality, a.Region, a.PostalCode, a.Country = one(3), one(4), one(5), one(6)
+	return a
+}
+
+// paramList returns the values of a jCard parameter (name matched without
+// case), which may be a string or an array of strings.
+func paramList(params map[string]any, name string) []string {
+	for k, v := range params {
+		if !strings.EqualFold(k, name) {
+			continue
+		}
+		switch t := v.(type) {
+		case string:
+			if t != "" {
+				return []string{t}
+			}
+		case []any:
+			return texts(t)
+		}
+	}
+	return nil
+}
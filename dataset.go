@@ -0,0 +1,276 @@
+package rdapclient
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dataset is a read-only, memory-mapped collection of RDAP objects, e.g. the
+// output of a nightly bulk crawl mirrored internally. A client given one with
+// WithDataset answers lookups from it before going to the network.
+//
+// The file is NDJSON: one RDAP object per line, or one `rdapctl batch` result
+// line ({"query": ..., "object": {...}}). Only the index (keys and offsets)
+// lives on the heap; object bodies stay in the mapping and are decoded on use.
+type Dataset struct {
+	data  []byte
+	close func() error
+
+	byKey   map[string]span // "domain/example.com", "nameserver/…", "entity/…" (lower-case)
+	autnums rangeIndex[int64]
+	nets    rangeIndex[netip.Addr]
+	n       int // objects indexed; a domain may have two keys
+}
+
+type span struct{ off, n int }
+
+// rangeIndex finds the narrowest of a set of ranges that contains a query.
+// Ranges are sorted by start, wider first, and each knows the narrowest range
+// before it that contains it; registries allocate nested ranges, so the
+// answer is the last range starting at or before the query, or one of its
+// enclosing ranges.
+type rangeIndex[T any] struct {
+	cmp    func(a, b T) int
+	ranges []rangeSpan[T]
+}
+
+type rangeSpan[T any] struct {
+	lo, hi T
+	span
+	parent int // index of the enclosing range, or -1
+}
+
+func (x *rangeIndex[T]) add(lo, hi T, sp span) {
+	x.ranges = append(x.ranges, rangeSpan[T]{lo: lo, hi: hi, span: sp})
+}
+
+// build sorts the ranges, keeps the last line of each duplicate and links
+// every range to the one enclosing it.
+func (x *rangeIndex[T]) build() {
+	r := x.ranges
+	sort.SliceStable(r, func(i, j int) bool {
+		if c := x.cmp(r[i].lo, r[j].lo); c != 0 {
+			return c < 0
+		}
+		return x.cmp(r[i].hi, r[j].hi) > 0
+	})
+	out := r[:0]
+	for _, s := range r {
+		if n := len(out); n > 0 && x.cmp(out[n-1].lo, s.lo) == 0 && x.cmp(out[n-1].hi, s.hi) == 0 {
+			out[n-1] = s
+			continue
+		}
+		out = append(out, s)
+	}
+	var open []int // ranges that may still enclose later ones, outermost first
+	for i := range out {
+		for len(open) > 0 && x.cmp(out[open[len(open)-1]].hi, out[i].lo) < 0 {
+			open = open[:len(open)-1]
+		}
+		out[i].parent = -1
+		for j := len(open) - 1; j >= 0; j-- {
+			if x.cmp(out[open[j]].hi, out[i].hi) >= 0 {
+				out[i].parent = open[j]
+				break
+			}
+		}
+		open = append(open, i)
+	}
+	x.ranges = out
+}
+
+// find returns the narrowest range containing [lo, hi].
+func (x *rangeIndex[T]) find(lo, hi T) (span, bool) {
+	i := sort.Search(len(x.ranges), func(i int) bool { return x.cmp(x.ranges[i].lo, lo) > 0 }) - 1
+	for ; i >= 0; i = x.ranges[i].parent {
+		if x.cmp(x.ranges[i].hi, hi) >= 0 {
+			return x.ranges[i].span, true
+		}
+	}
+	return span{}, false
+}
+
+// datasetKeys is the part of a line needed to index it.
+type datasetKeys struct {
+	ObjectClassName string          `json:"objectClassName"`
+	Handle          string          `json:"handle"`
+	LDHName         string          `json:"ldhName"`
+	UnicodeName     string          `json:"unicodeName"`
+	StartAutnum     int64           `json:"startAutnum"`
+	EndAutnum       int64           `json:"endAutnum"`
+	StartAddress    string          `json:"startAddress"`
+	EndAddress      string          `json:"endAddress"`
+	Object          json.RawMessage `json:"object"`
+}
+
+// OpenDataset maps the file at path and indexes it. Lines that are not RDAP
+// objects are skipped; a later line for the same key replaces an earlier one.
+func OpenDataset(path string) (*Dataset, error) {
+	data, closeFn, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ds := &Dataset{data: data, close: closeFn, byKey: map[string]span{}}
+	ds.autnums.cmp = cmp.Compare[int64]
+	ds.nets.cmp = netip.Addr.Compare
+	for off := 0; off < len(data); {
+		end := bytes.IndexByte(data[off:], '\n')
+		if end < 0 {
+			end = len(data) - off
+		}
+		ds.index(data[off:off+end], off)
+		off += end + 1
+	}
+	ds.autnums.build()
+	ds.nets.build()
+	objects := map[int]bool{}
+	for _, sp := range ds.byKey {
+		objects[sp.off] = true
+	}
+	ds.n = len(objects) + len(ds.autnums.ranges) + len(ds.nets.ranges)
+	return ds, nil
+}
+
+func (ds *Dataset) index(line []byte, off int) {
+	line = bytes.TrimRight(line, "\r")
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	var k datasetKeys
+	if json.Unmarshal(line, &k) != nil {
+		return
+	}
+	sp := span{off, len(line)}
+	if k.ObjectClassName == "" && len(k.Object) > 0 {
+		i := bytes.Index(line, k.Object)
+		if i < 0 {
+			return
+		}
+		sp = span{off + i, len(k.Object)}
+		k = datasetKeys{}
+		if json.Unmarshal(ds.data[sp.off:sp.off+sp.n], &k) != nil {
+			return
+		}
+	}
+	switch class := lower(k.ObjectClassName); class {
+	case "domain", "nameserver":
+		for _, name := range []string{k.LDHName, k.UnicodeName} {
+			if a, err := NormalizeFQDN(name); err == nil {
+				ds.byKey[class+"/"+a] = sp
+			}
+		}
+	case "entity":
+		if k.Handle != "" {
+			ds.byKey["entity/"+lower(k.Handle)] = sp
+		}
+	case "autnum":
+		if k.StartAutnum > 0 {
+			hi := k.EndAutnum
+			if hi < k.StartAutnum {
+				hi = k.StartAutnum
+			}
+			ds.autnums.add(k.StartAutnum, hi, sp)
+		}
+	case "ip network":
+		lo, err1 := netip.ParseAddr(k.StartAddress)
+		hi, err2 := netip.ParseAddr(k.EndAddress)
+		if err1 == nil && err2 == nil && lo.BitLen() == hi.BitLen() && lo.Compare(hi) <= 0 {
+			ds.nets.add(lo, hi, sp)
+		}
+	}
+}
+
+// Len reports how many objects are indexed.
+func (ds *Dataset) Len() int { return ds.n }
+
+// Close unmaps the file. The Dataset must not be used afterwards.
+func (ds *Dataset) Close() error {
+	if ds.close == nil {
+		return nil
+	}
+	err := ds.close()
+	ds.close, ds.data = nil, nil
+	return err
+}
+
+// Get returns the object of kind k identified by id (a name, handle, ASN, IP
+// address or CIDR), decoded from the mapping. Autnum and IP queries match the
+// narrowest range that contains them.
+func (ds *Dataset) Get(k Kind, id string) (Object, error) {
+	m, err := ds.raw(k, id)
+	if err != nil {
+		return nil, err
+	}
+	return ParseObject(m)
+}
+
+// ErrNotInDataset is returned by Dataset.Get for objects the dataset lacks.
+var ErrNotInDataset = errors.New("rdap dataset: object not found")
+
+func (ds *Dataset) raw(k Kind, id string) (map[string]any, error) {
+	b, ok := ds.line(k, id)
+	if !ok {
+		return nil, ErrNotInDataset
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("rdap dataset: %w", err)
+	}
+	return m, nil
+}
+
+// line returns the undecoded object of kind k identified by id.
+func (ds *Dataset) line(k Kind, id string) ([]byte, bool) {
+	sp, ok := ds.find(k, strings.TrimSpace(id))
+	if !ok {
+		return nil, false
+	}
+	return ds.data[sp.off : sp.off+sp.n], true
+}
+
+func (ds *Dataset) find(k Kind, id string) (span, bool) {
+	switch k {
+	case KindDomain, KindNameserver:
+		a, err := NormalizeFQDN(id)
+		if err != nil {
+			return span{}, false
+		}
+		sp, ok := ds.byKey[string(k)+"/"+a]
+		return sp, ok
+	case KindEntity:
+		sp, ok := ds.byKey["entity/"+lower(id)]
+		return sp, ok
+	case KindAutnum:
+		n, err := strconv.ParseInt(strings.TrimPrefix(strings.ToUpper(id), "AS"), 10, 64)
+		if err != nil {
+			return span{}, false
+		}
+		return ds.autnums.find(n, n)
+	case KindIP:
+		lo, hi, ok := queryRange(id)
+		if !ok {
+			return span{}, false
+		}
+		return ds.nets.find(lo, hi)
+	}
+	return span{}, false
+}
+
+// queryRange returns the first and last address of an IP or CIDR query.
+func queryRange(id string) (netip.Addr, netip.Addr, bool) {
+	if p, err := netip.ParsePrefix(id); err == nil {
+		p = p.Masked()
+		return p.Addr(), lastAddr(p), true
+	}
+	if a, err := netip.ParseAddr(id); err == nil {
+		return a, a, true
+	}
+	return netip.Addr{}, netip.Addr{}, false
+}
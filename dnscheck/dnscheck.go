@@ -0,0 +1,244 @@
+// Package dnscheck cross-checks a domain's RDAP delegation against live DNS
+// with an eye on mail deliverability: do the nameservers the registry lists
+// actually answer for the zone, does their glue match what they resolve to,
+// and does the zone publish MX hosts that resolve.
+//
+// RDAP only says what the registry believes; a lame delegation or a dangling
+// MX shows up here before it shows up as bounced mail.
+package dnscheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// Resolver is the subset of *net.Resolver the checker uses.
+type Resolver interface {
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Checker runs the cross-check. The zero value is not usable; use New.
+type Checker struct {
+	// Resolver is the recursive resolver for nameserver and MX host addresses.
+	Resolver Resolver
+	// At returns a resolver that queries the server at addr ("192.0.2.1") directly.
+	At func(addr string) Resolver
+	// Timeout bounds each query to a single nameserver.
+	Timeout time.Duration
+}
+
+// New returns a Checker using the system resolver, and the pure-Go resolver
+// over UDP/TCP port 53 to query listed nameservers directly.
+func New() *Checker {
+	return &Checker{Resolver: net.DefaultResolver, At: serverResolver, Timeout: 5 * time.Second}
+}
+
+func serverResolver(addr string) Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, net.JoinHostPort(addr, "53"))
+		},
+	}
+}
+
+// Nameserver is the outcome for one RDAP-listed nameserver.
+type Nameserver struct {
+	Host string `json:"host"`
+	// Addrs are the addresses the nameserver was queried at: its RDAP glue,
+	// or what the resolver returned for out-of-zone hosts.
+	Addrs []string `json:"addrs,omitempty"`
+	// GlueMissing lists RDAP glue addresses DNS does not return for the host.
+	GlueMissing []string `json:"glueMissing,omitempty"`
+	// ServesApex reports whether some address answered NS for the zone apex.
+	ServesApex bool `json:"servesApex"`
+	// NS is the apex NS set the nameserver returned.
+	NS    []string `json:"ns,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// MX is one mail exchanger of the zone.
+type MX struct {
+	Host  string   `json:"host"`
+	Pref  uint16   `json:"pref"`
+	Addrs []string `json:"addrs,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// Report is the result of Check.
+type Report struct {
+	Domain      string       `json:"domain"`
+	Nameservers []Nameserver `json:"nameservers"`
+	MX          []MX         `json:"mx,omitempty"`
+	// NullMX is set when the zone publishes RFC 7505 "MX 0 ." (accepts no mail).
+	NullMX bool `json:"nullMx,omitempty"`
+	// Problems are human-readable findings; empty means the delegation and
+	// mail setup look healthy.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// OK reports whether the check found no problems.
+func (r *Report) OK() bool { return len(r.Problems) == 0 }
+
+// Check queries every nameserver d lists for the zone apex and, when mx is
+// set, the zone's MX hosts. Failures are findings in the report, not errors;
+// only a domain without a name is an error.
+func (c *Checker) Check(ctx context.Context, d *rdap.Domain, mx bool) (*Report, error) {
+	apex := strings.TrimSuffix(strings.ToLower(d.LDHName), ".")
+	if apex == "" {
+		return nil, fmt.Errorf("dnscheck: domain has no ldhName")
+	}
+	r := &Report{Domain: apex}
+	if len(d.Nameservers) == 0 {
+		r.problem("registry lists no nameservers")
+	}
+
+	var answering []Resolver
+	for _, ns := range d.Nameservers {
+		res, at := c.checkNameserver(ctx, apex, ns)
+		r.Nameservers = append(r.Nameservers, res)
+		switch {
+		case res.Error != "" && len(res.Addrs) == 0:
+			r.problem("nameserver %s: %s", res.Host, res.Error)
+		case !res.ServesApex:
+			r.problem("nameserver %s does not answer for %s (lame delegation)", res.Host, apex)
+		case !sameSet(res.NS, hostsOf(d.Nameservers)):
+			r.problem("nameserver %s returns NS %v, registry lists %v", res.Host, res.NS, hostsOf(d.Nameservers))
+		}
+		if len(res.GlueMissing) > 0 {
+			r.problem("nameserver %s: RDAP glue %v not in DNS", res.Host, res.GlueMissing)
+		}
+		if at != nil {
+			answering = append(answering, at)
+		}
+	}
+
+	if mx {
+		if len(answering) == 0 {
+			r.problem("MX not checked: no nameserver answered")
+		} else {
+			c.checkMX(ctx, apex, answering[0], r)
+		}
+	}
+	return r, nil
+}
+
+// checkNameserver queries ns at each of its addresses until one answers NS for
+// apex, and returns that server's resolver.
+func (c *Checker) checkNameserver(ctx context.Context, apex string, ns rdap.Nameserver) (Nameserver, Resolver) {
+	res := Nameserver{Host: strings.TrimSuffix(strings.ToLower(ns.LDHName), ".")}
+	var glue []string
+	if ns.IPAddresses != nil {
+		glue = append(append(glue, ns.IPAddresses.V4...), ns.IPAddresses.V6...)
+	}
+	resolved, err := c.Resolver.LookupHost(ctx, res.Host)
+	if err != nil && len(glue) == 0 {
+		res.Error = err.Error()
+		return res, nil
+	}
+	if err == nil {
+		for _, g := range glue {
+			if !containsIP(resolved, g) {
+				res.GlueMissing = append(res.GlueMissing, g)
+			}
+		}
+	}
+	res.Addrs = glue
+	if len(res.Addrs) == 0 {
+		res.Addrs = resolved
+	}
+
+	for _, addr := range res.Addrs {
+		at := c.At(addr)
+		qctx, cancel := context.WithTimeout(ctx, c.Timeout)
+		nss, err := at.LookupNS(qctx, apex)
+		cancel()
+		if err != nil {
+			res.Error = err.Error()
+			continue
+		}
+		res.ServesApex, res.Error = true, ""
+		for _, n := range nss {
+			res.NS = append(res.NS, strings.TrimSuffix(strings.ToLower(n.Host), "."))
+		}
+		sort.Strings(res.NS)
+		return res, at
+	}
+	return res, nil
+}
+
+// checkMX asks an answering nameserver for apex's MX set and resolves each host.
+func (c *Checker) checkMX(ctx context.Context, apex string, at Resolver, r *Report) {
+	qctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	mxs, err := at.LookupMX(qctx, apex)
+	cancel()
+	if err != nil || len(mxs) == 0 {
+		// RFC 5321 falls back to the apex address, which rarely runs a mail server.
+		r.problem("no MX records for %s", apex)
+		return
+	}
+	for _, m := range mxs {
+		host := strings.TrimSuffix(strings.ToLower(m.Host), ".")
+		if host == "" && m.Pref == 0 && len(mxs) == 1 {
+			r.NullMX = true
+			return
+		}
+		res := MX{Host: host, Pref: m.Pref}
+		addrs, err := c.Resolver.LookupHost(ctx, host)
+		switch {
+		case err != nil:
+			res.Error = err.Error()
+			r.problem("MX %s does not resolve: %v", host, err)
+		case len(addrs) == 0:
+			r.problem("MX %s has no addresses", host)
+		}
+		res.Addrs = addrs
+		r.MX = append(r.MX, res)
+	}
+}
+
+func (r *Report) problem(format string, args ...any) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+func hostsOf(nss []rdap.Nameserver) []string {
+	out := make([]string, 0, len(nss))
+	for _, ns := range nss {
+		out = append(out, strings.TrimSuffix(strings.ToLower(ns.LDHName), "."))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// containsIP reports whether addrs holds ip, comparing parsed addresses so
+// "2001:db8::1" matches "2001:0db8:0:0::1".
+func containsIP(addrs []string, ip string) bool {
+	want := net.ParseIP(ip)
+	for _, a := range addrs {
+		if got := net.ParseIP(a); got != nil && want != nil && got.Equal(want) || a == ip {
+			return true
+		}
+	}
+	return false
+}
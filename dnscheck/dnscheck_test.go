@@ -0,0 +1,108 @@
+package dnscheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// fakeDNS answers from maps; the zero value knows nothing.
+type fakeDNS struct {
+	ns    map[string][]*net.NS
+	mx    map[string][]*net.MX
+	hosts map[string][]string
+}
+
+var errNoAnswer = errors.New("no answer")
+
+func (f *fakeDNS) LookupNS(_ context.Context, name string) ([]*net.NS, error) {
+	if v, ok := f.ns[name]; ok {
+		return v, nil
+	}
+	return nil, errNoAnswer
+}
+
+func (f *fakeDNS) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	if v, ok := f.mx[name]; ok {
+		return v, nil
+	}
+	return nil, errNoAnswer
+}
+
+func (f *fakeDNS) LookupHost(_ context.Context, host string) ([]string, error) {
+	if v, ok := f.hosts[host]; ok {
+		return v, nil
+	}
+	return nil, errNoAnswer
+}
+
+func TestCheck(t *testing.T) {
+	system := &fakeDNS{hosts: map[string][]string{
+		"ns1.example.net": {"192.0.2.1"},
+		"ns2.example.net": {"192.0.2.2"},
+		"mx1.example.com": {"198.51.100.25"},
+	}}
+	authoritative := &fakeDNS{
+		ns: map[string][]*net.NS{"example.com": {{Host: "ns2.example.net."}, {Host: "ns1.example.net."}}},
+		mx: map[string][]*net.MX{"example.com": {{Host: "mx1.example.com.", Pref: 10}, {Host: "mx2.example.com.", Pref: 20}}},
+	}
+	c := &Checker{
+		Resolver: system,
+		At: func(addr string) Resolver {
+			if addr == "192.0.2.1" {
+				return authoritative
+			}
+			return &fakeDNS{} // 192.0.2.2 is lame
+		},
+		Timeout: time.Second,
+	}
+	d := &rdap.Domain{LDHName: "EXAMPLE.COM", Nameservers: []rdap.Nameserver{
+		{LDHName: "ns1.example.net"},
+		{LDHName: "ns2.example.net", IPAddresses: &rdap.IPAddresses{V4: []string{"192.0.2.2", "192.0.2.99"}}},
+	}}
+	r, err := c.Check(context.Background(), d, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Nameservers[0].ServesApex || r.Nameservers[1].ServesApex {
+		t.Fatalf("serves apex: %+v", r.Nameservers)
+	}
+	if got := r.Nameservers[1].GlueMissing; len(got) != 1 || got[0] != "192.0.2.99" {
+		t.Fatalf("glue missing: %v", got)
+	}
+	if len(r.MX) != 2 || r.MX[0].Addrs[0] != "198.51.100.25" || r.MX[1].Error == "" {
+		t.Fatalf("mx: %+v", r.MX)
+	}
+	joined := strings.Join(r.Problems, "\n")
+	for _, want := range []string{"ns2.example.net does not answer", "192.0.2.99", "MX mx2.example.com does not resolve"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("problems missing %q:\n%s", want, joined)
+		}
+	}
+	if len(r.Problems) != 3 {
+		t.Errorf("problems: %q", r.Problems)
+	}
+}
+
+func TestCheck_NullMX(t *testing.T) {
+	auth := &fakeDNS{
+		ns: map[string][]*net.NS{"example.org": {{Host: "ns1.example.org"}}},
+		mx: map[string][]*net.MX{"example.org": {{Host: ".", Pref: 0}}},
+	}
+	c := &Checker{Resolver: &fakeDNS{}, At: func(string) Resolver { return auth }, Timeout: time.Second}
+	d := &rdap.Domain{LDHName: "example.org", Nameservers: []rdap.Nameserver{
+		{LDHName: "ns1.example.org", IPAddresses: &rdap.IPAddresses{V6: []string{"2001:db8::53"}}},
+	}}
+	r, err := c.Check(context.Background(), d, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.NullMX || !r.OK() {
+		t.Fatalf("report: %+v", r)
+	}
+}
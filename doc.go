@@ -0,0 +1,21 @@
+// Package rdapclient is an RDAP client (RFC 9082/9083): bootstrap-driven
+// lookups of domains, nameservers, IP networks, autnums and entities, with
+// caching, retries and rate limits, decoding into typed models.
+//
+// # Stability
+//
+// The module's v1 API is the client (New, Client and its lookup, search and
+// link-following methods), its options and Config, the RDAP models, the
+// errors and error codes, and in package graph the Walker, WalkOptions and
+// the Graph they build. api/v1.txt lists every declaration covered, and a
+// test keeps it from changing: until a v2 module path, these are only added
+// to, never renamed, removed or given a different signature.
+//
+// Everything else is experimental and may change in a minor release: the
+// exported identifiers of these packages that api/v1.txt leaves out (such
+// as WithDataset, WithSettleWindow, WithSizeAnomalies, graph queries and
+// DiskSeen), packages under x/ (x/annotate for transfer-lock verdicts and
+// DNS provider attribution, x/export for graph file formats, CSV and text
+// tables), and the other subpackages (jobs, proxy, snapshot, ingest,
+// dnscheck, rdapeasy, rdaptest, schema) until they are promoted.
+package rdapclient
@@ -21,22 +21,20 @@ func (c *Client) Autnum(ctx context.Context, asn string) (*Autnum, error) {
 	if _, err := strconv.ParseUint(trimmed, 10, 64); err != nil {
 		return nil, err
 	}
+	if obj, ok := c.fromDataset(ctx, KindAutnum, trimmed, "autnum"); ok {
+		return obj.(*Autnum), nil
+	}
 	base, err := c.rdapBaseForASN(ctx, trimmed)
 	if err != nil {
 		return nil, err
 	}
-	u := mustJoin(base, "/autnum/", trimmed)
-	m, _, err := c.getJSON(ctx, u)
+	u, err := BuildObjectURL(base, "autnum", trimmed)
 	if err != nil {
 		return nil, err
 	}
-	obj, err := ParseObject(m)
+	obj, err := c.fetchObject(ctx, u, "autnum")
 	if err != nil {
 		return nil, err
 	}
-	a, ok := obj.(*Autnum)
-	if !ok {
-		return nil, ErrUnexpectedObject("autnum")
-	}
-	return a, nil
+	return obj.(*Autnum), nil
 }
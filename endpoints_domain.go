@@ -3,23 +3,26 @@ package rdapclient
 import "context"
 
 // Domain returns a typed RDAP Domain per RFC 9083.
+// The name is validated and mapped to its A-label form before any request is made.
 func (c *Client) Domain(ctx context.Context, fqdn string) (*Domain, error) {
-	base, err := c.rdapBaseForDomain(ctx, fqdn)
+	fqdn, err := NormalizeFQDN(fqdn)
 	if err != nil {
 		return nil, err
 	}
-	u := mustJoin(base, "/domain/", fqdn)
-	raw, _, err := c.getJSON(ctx, u)
+	if obj, ok := c.fromDataset(ctx, KindDomain, fqdn, "domain"); ok {
+		return obj.(*Domain), nil
+	}
+	base, err := c.rdapBaseForDomain(ctx, fqdn)
 	if err != nil {
 		return nil, err
 	}
-	obj, err := ParseObject(raw)
+	u, err := BuildObjectURL(base, "domain", fqdn)
 	if err != nil {
 		return nil, err
 	}
-	d, ok := obj.(*Domain)
-	if !ok {
-		return nil, ErrUnexpectedObject("domain")
+	obj, err := c.fetchObject(ctx, u, "domain")
+	if err != nil {
+		return nil, err
 	}
-	return d, nil
+	return obj.(*Domain), nil
 }
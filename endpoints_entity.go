@@ -1,29 +1,89 @@
 package rdapclient
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
 // Entity queries an entity handle and returns a typed Entity; tldHint helps pick the right registry base.
+// Without a hint, a handle ending in a tag ("ABC123-ARIN") goes to the registry
+// the object-tags bootstrap (RFC 8521) names for it, and any other to rdap.org.
 func (c *Client) Entity(ctx context.Context, handle, tldHint string) (*Entity, error) {
-	var base string
-	var err error
-	if tl := trimDotLower(tldHint); tl != "" {
-		base, err = c.rdapBaseForTLD(ctx, tl)
-	}
-	if base == "" || err != nil {
-		base = "https://rdap.org"
+	if obj, ok := c.fromDataset(ctx, KindEntity, handle, "entity"); ok {
+		return obj.(*Entity), nil
 	}
-	u := mustJoin(base, "/entity/", handle)
-	m, _, err := c.getJSON(ctx, u)
+	u, err := BuildObjectURL(c.rdapBaseForEntity(ctx, handle, tldHint), "entity", handle)
 	if err != nil {
 		return nil, err
 	}
-	obj, err := ParseObject(m)
+	obj, err := c.fetchObject(ctx, u, "entity")
 	if err != nil {
 		return nil, err
 	}
-	e, ok := obj.(*Entity)
-	if !ok {
-		return nil, ErrUnexpectedObject("entity")
+	return obj.(*Entity), nil
+}
+
+// rdapBaseForEntity picks the base an entity lookup is sent to: tldHint's
+// registry, else the one handle's object tag names, else rdap.org.
+func (c *Client) rdapBaseForEntity(ctx context.Context, handle, tldHint string) string {
+	if tl := trimDotLower(tldHint); tl != "" {
+		if base, err := c.rdapBaseForTLD(ctx, tl); err == nil && base != "" {
+			return base
+		}
+	}
+	if base, ok := c.resolveBaseFromBootstrapObjectTag(ctx, handle); ok {
+		return base
+	}
+	return "https://rdap.org"
+}
+
+// Hydrate replaces a stub entity (see IsStub) with its full record, fetched from
+// its "self" link when it has one and by handle lookup otherwise. Roles are
+// relative to the object the stub was embedded in, so the stub's roles are kept
+// when the full record has none. Entities that are not stubs are left alone.
+func (c *Client) Hydrate(ctx context.Context, e *Entity) error {
+	if e == nil || !e.IsStub() {
+		return nil
+	}
+	full, err := c.entityRecord(ctx, e)
+	if err != nil {
+		return err
+	}
+	if len(full.Roles) == 0 {
+		full.Roles = e.Roles
+	}
+	*e = *full
+	return nil
+}
+
+// entityRecord fetches e's own record: from its "self" link when it has one
+// the LinkPolicy allows, by handle otherwise. A self link the policy blocks is
+// never fetched: without a handle to look up instead, its *ErrLinkBlocked is
+// returned.
+func (c *Client) entityRecord(ctx context.Context, e *Entity) (*Entity, error) {
+	if href := selfHref(e.Links); href != "" {
+		err := c.CheckLink("", href)
+		if err == nil {
+			obj, err := c.fetchObject(ctx, href, "entity")
+			if err != nil {
+				return nil, err
+			}
+			return obj.(*Entity), nil
+		}
+		if e.Handle == "" {
+			return nil, err
+		}
+	}
+	if e.Handle == "" {
+		return nil, errors.New("rdap: cannot fetch entity without handle or self link")
+	}
+	return c.Entity(ctx, e.Handle, "")
+}
+
+// selfHref returns the absolute http(s) URL of the "self" link in links, or "".
+func selfHref(links []Link) string {
+	if l, ok := Links(links).Best("self"); ok {
+		return l.Href
 	}
-	return e, nil
+	return ""
 }
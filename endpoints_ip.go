@@ -8,22 +8,20 @@ func (c *Client) rdapBaseForIP(ctx context.Context, ipOrCIDR string) (string, er
 }
 
 func (c *Client) IP(ctx context.Context, ipOrCIDR string) (*IPNetwork, error) {
+	if obj, ok := c.fromDataset(ctx, KindIP, ipOrCIDR, "ip network"); ok {
+		return obj.(*IPNetwork), nil
+	}
 	base, err := c.rdapBaseForIP(ctx, ipOrCIDR)
 	if err != nil {
 		return nil, err
 	}
-	u := mustJoin(base, "/ip/", ipOrCIDR)
-	m, _, err := c.getJSON(ctx, u)
+	u, err := BuildObjectURL(base, "ip", ipOrCIDR)
 	if err != nil {
 		return nil, err
 	}
-	obj, err := ParseObject(m)
+	obj, err := c.fetchObject(ctx, u, "ip network")
 	if err != nil {
 		return nil, err
 	}
-	ipn, ok := obj.(*IPNetwork)
-	if !ok {
-		return nil, ErrUnexpectedObject("ip network")
-	}
-	return ipn, nil
+	return obj.(*IPNetwork), nil
 }
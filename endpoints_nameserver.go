@@ -1,24 +1,45 @@
 package rdapclient
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
+// Nameserver returns a typed RDAP Nameserver from the registry for host's
+// TLD. With WithSynthesizedNameservers, a host no server knows (404 or 410)
+// comes back as the parent domain's entry for it, marked Synthetic, when the
+// rest of the NameserverWithFallback chain finds nothing better.
 func (c *Client) Nameserver(ctx context.Context, host string) (*Nameserver, error) {
+	host, err := NormalizeFQDN(host)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := c.registryNameserver(ctx, host)
+	if err == nil || !c.synthesizeNS || !errors.Is(err, ErrNotFound) {
+		return ns, err
+	}
+	if r, ferr := c.nameserverFallback(ctx, host, err); ferr == nil {
+		return r.Nameserver, nil
+	}
+	return nil, err
+}
+
+// registryNameserver looks up the normalized host at its TLD's registry.
+func (c *Client) registryNameserver(ctx context.Context, host string) (*Nameserver, error) {
+	if obj, ok := c.fromDataset(ctx, KindNameserver, host, "nameserver"); ok {
+		return obj.(*Nameserver), nil
+	}
 	base, err := c.rdapBaseForDomain(ctx, host)
 	if err != nil || base == "" {
 		base = "https://rdap.org"
 	}
-	u := mustJoin(base, "/nameserver/", host)
-	m, _, err := c.getJSON(ctx, u)
+	u, err := BuildObjectURL(base, "nameserver", host)
 	if err != nil {
 		return nil, err
 	}
-	obj, err := ParseObject(m)
+	obj, err := c.fetchObject(ctx, u, "nameserver")
 	if err != nil {
 		return nil, err
 	}
-	ns, ok := obj.(*Nameserver)
-	if !ok {
-		return nil, ErrUnexpectedObject("nameserver")
-	}
-	return ns, nil
+	return obj.(*Nameserver), nil
 }
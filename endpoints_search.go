@@ -0,0 +1,408 @@
+package rdapclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ConformanceReverseSearch is the rdapConformance token of RFC 9536 reverse search.
+const ConformanceReverseSearch = "reverse_search"
+
+// SupportsReverseSearch reports whether an object's rdapConformance advertises RFC 9536.
+func SupportsReverseSearch(o CommonObject) bool { return o.SupportsExtension(ConformanceReverseSearch) }
+
+// ServerBase derives the RDAP base URL an object was served from, using its
+// "self" link (".../entity/H" gives "..."). It returns "" without a usable link.
+func ServerBase(o CommonObject) string {
+	class := lower(o.ObjectClassName)
+	if class == "ip network" {
+		class = "ip"
+	}
+	for _, l := range o.Links {
+		if !strings.EqualFold(l.Rel, "self") {
+			continue
+		}
+		u, err := url.Parse(l.Href)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			continue
+		}
+		if i := strings.LastIndex(u.Path, "/"+class+"/"); i >= 0 {
+			u.Path, u.RawPath, u.RawQuery, u.Fragment = u.Path[:i], "", "", ""
+			return u.String()
+		}
+	}
+	return ""
+}
+
+// ReverseSearchDomains runs an RFC 9536 reverse search at base for the domains
+// related to the entity handle in role (e.g. "registrant"; empty for any role).
+func (c *Client) ReverseSearchDomains(ctx context.Context, base, handle, role string) ([]Domain, error) {
+	u, err := joinSegments(base, []string{"domains", "reverse_search", "entity"}, 3)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{"handle": {handle}}
+	if role != "" {
+		q.Set("role", role)
+	}
+	m, _, err := c.getJSON(ctx, u+"?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Results []Domain `json:"domainSearchResults"`
+	}
+	if err := decodeInto(m, &res); err != nil {
+		return nil, err
+	}
+	return res.Results, nil
+}
+
+// DomainSearchParams is an RFC 9082 domain search: exactly one of Name (a
+// domain name pattern such as "exam*.com"), NSLDHName (a nameserver name
+// pattern) or NSIP (a nameserver address).
+type DomainSearchParams struct {
+	Name      string
+	NSLDHName string
+	NSIP      string
+	// Base is the server to search. Empty means the registry the bootstrap
+	// names for the pattern's TLD, or the default base when the TLD is itself
+	// a pattern or the search is by NSIP.
+	Base string
+}
+
+// DomainSearchResults is the response to a domain search.
+type DomainSearchResults struct {
+	Domains []Domain `json:"domainSearchResults"`
+	SearchMetadata
+}
+
+// SearchMetadata is what a search response says about its result set.
+type SearchMetadata struct {
+	Notices         []Notice        `json:"notices,omitempty"`
+	RDAPConformance []string        `json:"rdapConformance,omitempty"`
+	Paging          *PagingMetadata `json:"paging_metadata,omitempty"`
+	// Redacted applies to every result (RFC 9537).
+	Redacted []Redaction `json:"redacted,omitempty"`
+}
+
+// PagingMetadata is RFC 8977 paging information.
+type PagingMetadata struct {
+	TotalCount int    `json:"totalCount,omitempty"`
+	PageSize   int    `json:"pageSize,omitempty"`
+	PageNumber int    `json:"pageNumber,omitempty"`
+	Links      []Link `json:"links,omitempty"`
+}
+
+// Truncated reports whether the server says the results are incomplete: an
+// RFC 9083 "result set truncated ..." notice, or a "next" page.
+func (m SearchMetadata) Truncated() bool {
+	for _, n := range m.Notices {
+		if strings.HasPrefix(lower(n.Type), "result set truncated") {
+			return true
+		}
+	}
+	return m.Paging != nil && relHref(m.Paging.Links, "next") != ""
+}
+
+// SearchDomains runs an RFC 9082 domain search ("/domains?name=...",
+// "?nsLdhName=" or "?nsIp=").
+func (c *Client) SearchDomains(ctx context.Context, p DomainSearchParams) (*DomainSearchResults, error) {
+	q := url.Values{}
+	var pattern string
+	switch {
+	case p.Name != "" && p.NSLDHName == "" && p.NSIP == "":
+		pattern = lower(strings.TrimSuffix(p.Name, "."))
+		q.Set("name", pattern)
+	case p.NSLDHName != "" && p.Name == "" && p.NSIP == "":
+		pattern = lower(strings.TrimSuffix(p.NSLDHName, "."))
+		q.Set("nsLdhName", pattern)
+	case p.NSIP != "" && p.Name == "" && p.NSLDHName == "":
+		ip := net.ParseIP(p.NSIP)
+		if ip == nil {
+			return nil, fmt.Errorf("rdap: domain search: invalid nsIp %q", p.NSIP)
+		}
+		q.Set("nsIp", ip.String())
+	default:
+		return nil, fmt.Errorf("rdap: domain search needs exactly one of name, nsLdhName or nsIp")
+	}
+	base := p.Base
+	if base == "" {
+		b, err := c.searchBase(ctx, pattern)
+		if err != nil {
+			return nil, err
+		}
+		base = b
+	}
+	var res DomainSearchResults
+	if err := c.search(ctx, base, "domains", q, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DomainsByNameserver lists the domains delegated to the nameserver nsName
+// (a "/domains?nsLdhName=" search at the registry for its TLD). Check
+// Truncated: registries cap these results for busy hosting nameservers.
+func (c *Client) DomainsByNameserver(ctx context.Context, nsName string) (*DomainSearchResults, error) {
+	host, err := NormalizeFQDN(nsName)
+	if err != nil {
+		return nil, err
+	}
+	return c.SearchDomains(ctx, DomainSearchParams{NSLDHName: host})
+}
+
+// DomainsByNameserverIP lists the domains delegated to nameservers at ip (a
+// "/domains?nsIp=" search). An address does not say which registry to ask:
+// tld ("com") picks its registry from the bootstrap, and "" asks the default
+// base, an aggregator such as rdap.org unless configured otherwise.
+func (c *Client) DomainsByNameserverIP(ctx context.Context, ip, tld string) (*DomainSearchResults, error) {
+	p := DomainSearchParams{NSIP: ip}
+	if tl := trimDotLower(tld); tl != "" {
+		base, err := c.rdapBaseForTLD(ctx, tl)
+		if err != nil {
+			return nil, err
+		}
+		p.Base = base
+	}
+	return c.SearchDomains(ctx, p)
+}
+
+// NameserverSearchResults is the response to a nameserver search.
+type NameserverSearchResults struct {
+	Nameservers []Nameserver `json:"nameserverSearchResults"`
+	SearchMetadata
+}
+
+// SearchNameservers runs an RFC 9082 nameserver search by name
+// ("/nameservers?name=ns*.example.com") at the registry the bootstrap names
+// for the pattern's TLD, or the default base when the TLD is itself a pattern.
+func (c *Client) SearchNameservers(ctx context.Context, namePattern string) (*NameserverSearchResults, error) {
+	pattern := lower(strings.TrimSuffix(namePattern, "."))
+	if pattern == "" {
+		return nil, fmt.Errorf("rdap: nameserver search needs a name pattern")
+	}
+	base, err := c.searchBase(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+	var res NameserverSearchResults
+	if err := c.search(ctx, base, "nameservers", url.Values{"name": {pattern}}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// SearchNameserversByIP runs an RFC 9082 nameserver search by address
+// ("/nameservers?ip=192.0.2.1"), listing the nameservers that advertise ip as
+// glue. An address says nothing about which domain registry holds the
+// nameservers, so the search goes to the default base.
+func (c *Client) SearchNameserversByIP(ctx context.Context, ip string) (*NameserverSearchResults, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, fmt.Errorf("rdap: nameserver search: invalid ip %q", ip)
+	}
+	var res NameserverSearchResults
+	if err := c.search(ctx, c.defaultRDAPBase, "nameservers", url.Values{"ip": {addr.String()}}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// searchBase returns the registry to search for a name pattern: the bootstrap
+// base for its TLD, or the default base when the TLD is a pattern.
+func (c *Client) searchBase(ctx context.Context, pattern string) (string, error) {
+	if tld := lastLabel(pattern); tld != "" && !strings.Contains(tld, "*") {
+		return c.rdapBaseForTLD(ctx, tld)
+	}
+	return c.defaultRDAPBase, nil
+}
+
+// EntitySearchQuery is an RFC 9082 entity search: exactly one of FN (a full
+// name pattern such as "Example*") or Handle (a handle pattern).
+type EntitySearchQuery struct {
+	FN     string
+	Handle string
+	// Base is the server to search; entities have no bootstrap, so empty
+	// means the default base.
+	Base string
+}
+
+// EntitySearchResults is the response to an entity search.
+type EntitySearchResults struct {
+	Entities []Entity `json:"entitySearchResults"`
+	SearchMetadata
+}
+
+// SearchEntities runs an RFC 9082 entity search ("/entities?fn=..." or
+// "?handle=").
+func (c *Client) SearchEntities(ctx context.Context, query EntitySearchQuery) (*EntitySearchResults, error) {
+	q := url.Values{}
+	switch {
+	case query.FN != "" && query.Handle == "":
+		q.Set("fn", query.FN)
+	case query.Handle != "" && query.FN == "":
+		q.Set("handle", query.Handle)
+	default:
+		return nil, fmt.Errorf("rdap: entity search needs exactly one of fn or handle")
+	}
+	base := query.Base
+	if base == "" {
+		base = c.defaultRDAPBase
+	}
+	var res EntitySearchResults
+	if err := c.search(ctx, base, "entities", q, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// HelpResponse is a server's RFC 9083 help response: its notices (terms of
+// service, rate limits, search policy) and the specifications it conforms to.
+type HelpResponse struct {
+	Notices         []Notice `json:"notices,omitempty"`
+	RDAPConformance []string `json:"rdapConformance,omitempty"`
+	Links           []Link   `json:"links,omitempty"`
+}
+
+// Help fetches the "/help" resource of a registry, given as a base URL
+// ("https://rdap.verisign.com/com/v1") or a TLD whose bootstrap base to use.
+func (c *Client) Help(ctx context.Context, baseOrTLD string) (*HelpResponse, error) {
+	base := baseOrTLD
+	if !strings.Contains(base, "://") {
+		b, err := c.rdapBaseForTLD(ctx, trimDotLower(baseOrTLD))
+		if err != nil {
+			return nil, err
+		}
+		base = b
+	}
+	u, err := joinSegments(base, []string{"help"}, 1)
+	if err != nil {
+		return nil, err
+	}
+	m, _, err := c.getJSON(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	var res HelpResponse
+	if err := decodeInto(m, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// search GETs base/path?q and decodes the response into res.
+func (c *Client) search(ctx context.Context, base, path string, q url.Values, res any) error {
+	if base == "" {
+		return fmt.Errorf("rdap: %s search: no RDAP base", path)
+	}
+	u, err := joinSegments(base, []string{path}, 1)
+	if err != nil {
+		return err
+	}
+	m, _, err := c.getJSON(ctx, u+"?"+q.Encode())
+	if err != nil {
+		return err
+	}
+	return decodeInto(m, res)
+}
+
+// maxSearchPages bounds how many RFC 8977 "next" pages a linked search follows.
+const maxSearchPages = 50
+
+// EntityNetworks returns the networks registered to e: its inline networks when
+// the server sent them, otherwise the results of the network search it links
+// to (RIPE and APNIC link an "ips" search instead of inlining), across pages.
+// Prefer it over reading Entity.Networks directly.
+func (c *Client) EntityNetworks(ctx context.Context, e *Entity) ([]IPNetwork, error) {
+	if len(e.Networks) > 0 {
+		return e.Networks, nil
+	}
+	return linkedSearch[IPNetwork](ctx, c, fetchedFrom(e.Meta), e.Links, "ipSearchResults", "ips", "networks")
+}
+
+// EntityAutnums is EntityNetworks for autnums ("autnums" search links).
+func (c *Client) EntityAutnums(ctx context.Context, e *Entity) ([]Autnum, error) {
+	if len(e.Autnums) > 0 {
+		return e.Autnums, nil
+	}
+	return linkedSearch[Autnum](ctx, c, fetchedFrom(e.Meta), e.Links, "autnumSearchResults", "autnums")
+}
+
+// linkedSearch fetches the search linked from links (by rel, or by the last
+// path segment of its href, matching one of names) and decodes every result in
+// member, following paging_metadata "next" links. No matching link is not an
+// error: the object simply has none. from is the URL the object holding links
+// was fetched from ("" when unknown); each next page link is checked against
+// the page it was fetched from.
+func linkedSearch[T any](ctx context.Context, c *Client, from string, links []Link, member string, names ...string) ([]T, error) {
+	var out []T
+	u := searchLinkHref(links, names)
+	for page := 0; u != "" && page < maxSearchPages; page++ {
+		if err := c.CheckLink(from, u); err != nil {
+			return out, err
+		}
+		f, err := c.getResponse(ctx, u)
+		if err != nil {
+			return out, err
+		}
+		m := f.m
+		b, err := json.Marshal(m[member])
+		if err != nil {
+			return out, err
+		}
+		var results []T
+		if err := json.Unmarshal(b, &results); err != nil {
+			return out, err
+		}
+		out = append(out, results...)
+
+		var paging struct {
+			Meta struct {
+				Links []Link `json:"links"`
+			} `json:"paging_metadata"`
+			Links []Link `json:"links"`
+		}
+		if err := decodeInto(m, &paging); err != nil {
+			return out, err
+		}
+		from = f.final
+		if u = relHref(paging.Meta.Links, "next"); u == "" {
+			u = relHref(paging.Links, "next")
+		}
+	}
+	return out, nil
+}
+
+// searchLinkHref returns the first absolute http(s) link whose rel or final
+// path segment is one of names.
+func searchLinkHref(links []Link, names []string) string {
+	for _, l := range links {
+		u, err := url.Parse(l.Href)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			continue
+		}
+		last := path.Base(strings.TrimSuffix(u.Path, "/"))
+		for _, n := range names {
+			if strings.EqualFold(l.Rel, n) || strings.EqualFold(last, n) {
+				return l.Href
+			}
+		}
+	}
+	return ""
+}
+
+// relHref returns the href of the first link with rel, or "".
+func relHref(links []Link, rel string) string {
+	for _, l := range links {
+		if strings.EqualFold(l.Rel, rel) {
+			return l.Href
+		}
+	}
+	return ""
+}
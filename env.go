@@ -0,0 +1,103 @@
+package rdapclient
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variable suffixes read by ConfigFromEnv, after "<prefix>_".
+const (
+	EnvUserAgent          = "UA"
+	EnvTimeout            = "TIMEOUT"              // Go duration, e.g. "20s"
+	EnvDNSBootstrap       = "DNS_BOOTSTRAP"        // IANA DNS bootstrap URL
+	EnvIPBootstrap        = "IP_BOOTSTRAP"         // IANA IP bootstrap URL
+	EnvASNBootstrap       = "ASN_BOOTSTRAP"        // IANA ASN bootstrap URL
+	EnvObjectTagBootstrap = "OBJECT_TAG_BOOTSTRAP" // IANA object-tags bootstrap URL
+	EnvDefaultBase        = "DEFAULT_BASE"         // fallback RDAP base
+	EnvMaxRetries         = "MAX_RETRIES"          // integer, 0 disables retries
+	EnvTLDCacheSize       = "TLD_CACHE_SIZE"       // entries
+	EnvResponseCacheSize  = "RESPONSE_CACHE_SIZE"  // entries
+	EnvRateLimit          = "RATE_LIMIT"           // requests per second
+	EnvHTTPProxy          = "HTTP_PROXY"           // proxy URL
+	EnvDiskCache          = "DISK_CACHE"           // directory for WithDiskCache, no size limit
+)
+
+// ConfigFromEnv reads a Config from environment variables named prefix + "_" +
+// one of the Env* suffixes (e.g. prefix "RDAPCTL" reads RDAPCTL_TIMEOUT).
+// Unset variables leave the field zero; malformed ones are reported together
+// while the rest of the Config is still filled in.
+func ConfigFromEnv(prefix string) (Config, error) {
+	p := strings.TrimSuffix(prefix, "_")
+	if p != "" {
+		p += "_"
+	}
+	get := func(k string) string { return strings.TrimSpace(os.Getenv(p + k)) }
+
+	var (
+		cfg  Config
+		errs []string
+	)
+	bad := func(k string, err error) { errs = append(errs, fmt.Sprintf("%s%s: %v", p, k, err)) }
+
+	cfg.UserAgent = get(EnvUserAgent)
+	cfg.BootstrapURL = get(EnvDNSBootstrap)
+	cfg.IPBootstrapURL = get(EnvIPBootstrap)
+	cfg.ASNBootstrapURL = get(EnvASNBootstrap)
+	cfg.ObjectTagBootstrapURL = get(EnvObjectTagBootstrap)
+	cfg.DefaultRDAPBase = get(EnvDefaultBase)
+	cfg.DiskCacheDir = get(EnvDiskCache)
+	if v := get(EnvTimeout); v != "" {
+		if d, err := time.ParseDuration(v); err != nil {
+			bad(EnvTimeout, err)
+		} else {
+			cfg.Timeout = Duration(d)
+		}
+	}
+	if v := get(EnvMaxRetries); v != "" {
+		if n, err := strconv.Atoi(v); err != nil {
+			bad(EnvMaxRetries, err)
+		} else {
+			cfg.MaxRetries = &n
+		}
+	}
+	for _, f := range []struct {
+		key string
+		dst *int
+	}{{EnvTLDCacheSize, &cfg.TLDCacheSize}, {EnvResponseCacheSize, &cfg.ResponseCacheSize}} {
+		if v := get(f.key); v != "" {
+			if n, err := strconv.Atoi(v); err != nil {
+				bad(f.key, err)
+			} else {
+				*f.dst = n
+			}
+		}
+	}
+	if v := get(EnvRateLimit); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err != nil {
+			bad(EnvRateLimit, err)
+		} else {
+			cfg.RateLimit = f
+		}
+	}
+	if v := get(EnvHTTPProxy); v != "" {
+		if _, err := parseProxyURL(v); err != nil {
+			bad(EnvHTTPProxy, err)
+		} else {
+			cfg.HTTPProxy = v
+		}
+	}
+	if len(errs) > 0 {
+		return cfg, fmt.Errorf("rdap env: %s", strings.Join(errs, "; "))
+	}
+	return cfg, nil
+}
+
+// FromEnv returns the Options described by the prefix's environment variables
+// (see ConfigFromEnv), skipping malformed values.
+func FromEnv(prefix string) []Option {
+	cfg, _ := ConfigFromEnv(prefix)
+	return cfg.Options()
+}
@@ -1,10 +1,208 @@
 package rdapclient
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
 
-// ErrUnexpectedObject indicates the RDAP response was not the expected object class.
-type ErrUnexpectedObject string
+// ErrUnexpectedObject indicates the RDAP response was not the expected object
+// class. URL is the lookup that returned it (the body stays in the client's
+// response cache under that URL) and Raw is the decoded response. It is
+// returned by value, so errors.As takes a *ErrUnexpectedObject target.
+type ErrUnexpectedObject struct {
+	Want string
+	Got  string
+	URL  string
+	Raw  json.RawMessage
+}
 
 func (e ErrUnexpectedObject) Error() string {
-	return fmt.Sprintf("unexpected RDAP objectClassName, want %s", string(e))
+	return fmt.Sprintf("rdap GET %s: unexpected RDAP objectClassName %q, want %s", e.URL, e.Got, e.Want)
+}
+
+// ErrAmbiguousObject is returned when a lookup URL answers with a search
+// results wrapper (e.g. domainSearchResults) holding more than one object.
+// Candidates names each result by ldhName, or handle when it has none.
+type ErrAmbiguousObject struct {
+	URL        string
+	Want       string
+	Candidates []string
+}
+
+func (e *ErrAmbiguousObject) Error() string {
+	return fmt.Sprintf("rdap GET %s: %d %s results for a lookup: %s", e.URL, len(e.Candidates), e.Want, strings.Join(e.Candidates, ", "))
+}
+
+// LookupAttempt records one classification Lookup tried and why it failed.
+type LookupAttempt struct {
+	Kind Kind
+	Err  error
+}
+
+// ErrLookupFailed is returned by Lookup when every attempted classification failed.
+// Tried is in attempt order; it is empty when AllowedKinds excluded every candidate.
+type ErrLookupFailed struct {
+	Query string
+	Tried []LookupAttempt
+}
+
+func (e *ErrLookupFailed) Error() string {
+	if len(e.Tried) == 0 {
+		return fmt.Sprintf("rdap lookup %q: no allowed kind matches query", e.Query)
+	}
+	parts := make([]string, 0, len(e.Tried))
+	for _, t := range e.Tried {
+		parts = append(parts, fmt.Sprintf("%s (%v)", t.Kind, t.Err))
+	}
+	return fmt.Sprintf("rdap lookup %q: tried %s", e.Query, strings.Join(parts, ", "))
+}
+
+// Unwrap exposes each attempt's error to errors.Is / errors.As.
+func (e *ErrLookupFailed) Unwrap() []error {
+	errs := make([]error, 0, len(e.Tried))
+	for _, t := range e.Tried {
+		errs = append(errs, t.Err)
+	}
+	return errs
+}
+
+// HTTPError is returned when a registry answers with a non-success status
+// (after retries, for retryable ones). RetryAfter is the server's Retry-After
+// delay, uncapped, or 0 when none was sent.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration
+	Body       string
+}
+
+// Gone reports a 410: the registry says the object was removed for good, not
+// merely unknown to it.
+func (e *HTTPError) Gone() bool { return e.StatusCode == 410 }
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("rdap GET %s: %s", e.URL, e.Status)
+	}
+	return fmt.Sprintf("rdap GET %s: %s: %s", e.URL, e.Status, e.Body)
+}
+
+// Sentinels an HTTPError (and so an RDAPError or LegalBlockError) matches
+// with errors.Is, by status. None of these statuses is retried.
+var (
+	// ErrNotFound is a 404 or 410: the registry has no such object. A 404
+	// is cached for a few minutes.
+	ErrNotFound = errors.New("rdap: object not found")
+	// ErrGone is a 410 only: the object was permanently removed. It is
+	// cached for a week.
+	ErrGone = errors.New("rdap: object removed")
+	// ErrUnauthorized is a 401 or 403: the registry wants credentials or
+	// refuses this client. It is never cached, so fixed credentials take
+	// effect at once.
+	ErrUnauthorized = errors.New("rdap: unauthorized")
+	// ErrBadRequest is a 400: the registry rejected the query as malformed,
+	// which usually means a bug in the caller or the client.
+	ErrBadRequest = errors.New("rdap: bad request")
+	// ErrUnprocessable is a 422: the query is well-formed but the registry
+	// will not answer it, such as a search it does not support.
+	ErrUnprocessable = errors.New("rdap: unprocessable request")
+	// ErrLegallyBlocked is a 451: the registry may not serve the object for
+	// legal reasons. It is cached for a day; see LegalBlockError.
+	ErrLegallyBlocked = errors.New("rdap: unavailable for legal reasons")
+)
+
+// Is matches e against the status sentinels (ErrNotFound, ErrUnauthorized, ...).
+func (e *HTTPError) Is(target error) bool {
+	switch e.StatusCode {
+	case 404:
+		return target == ErrNotFound
+	case 410:
+		return target == ErrNotFound || target == ErrGone
+	case 401, 403:
+		return target == ErrUnauthorized
+	case 400:
+		return target == ErrBadRequest
+	case 422:
+		return target == ErrUnprocessable
+	case 451:
+		return target == ErrLegallyBlocked
+	}
+	return false
+}
+
+// LegalBlockError is returned for a 451 (RFC 7725). BlockedBy is the entity
+// implementing the block, from the response's Link rel="blocked-by", if sent.
+// Err is the registry's answer, an *HTTPError or *RDAPError.
+type LegalBlockError struct {
+	BlockedBy string
+	Err       error
+}
+
+func (e *LegalBlockError) Error() string {
+	if e.BlockedBy == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (blocked by %s)", e.Err, e.BlockedBy)
+}
+
+// Unwrap exposes the HTTP status to errors.As(*HTTPError) and ErrorCode.
+func (e *LegalBlockError) Unwrap() error { return e.Err }
+
+// RDAPError is returned instead of a bare HTTPError when the error response
+// carries an RFC 9083 error body (errorCode, title, description). The HTTP
+// details stay reachable through the embedded HTTPError, and errors.As finds
+// either type.
+type RDAPError struct {
+	*HTTPError
+	ErrorCode       int      `json:"errorCode"`
+	Title           string   `json:"title,omitempty"`
+	Description     []string `json:"description,omitempty"`
+	Notices         []Notice `json:"notices,omitempty"`
+	RDAPConformance []string `json:"rdapConformance,omitempty"`
+	Lang            string   `json:"lang,omitempty"`
+}
+
+func (e *RDAPError) Error() string {
+	msg := e.Title
+	if len(e.Description) > 0 {
+		msg = strings.TrimSpace(msg + " (" + strings.Join(e.Description, " ") + ")")
+	}
+	if msg == "" {
+		msg = fmt.Sprintf("error code %d", e.ErrorCode)
+	}
+	return fmt.Sprintf("rdap GET %s: %s: %s", e.URL, e.Status, msg)
+}
+
+// Unwrap exposes the HTTP status to errors.As(*HTTPError) and ErrorCode.
+func (e *RDAPError) Unwrap() error { return e.HTTPError }
+
+// statusError returns he as an *RDAPError when its body decodes as an RDAP
+// error object, else he itself.
+func statusError(he *HTTPError) error {
+	var re RDAPError
+	if json.Unmarshal([]byte(he.Body), &re) != nil || (re.ErrorCode == 0 && re.Title == "" && len(re.Description) == 0) {
+		return he
+	}
+	re.HTTPError = he
+	return &re
+}
+
+// blockedBy returns the target of a Link header's rel="blocked-by" entry.
+func blockedBy(h http.Header) string {
+	for _, v := range h.Values("Link") {
+		for _, part := range strings.Split(v, ",") {
+			target, params, ok := strings.Cut(part, ";")
+			params = strings.NewReplacer(" ", "", `"`, "").Replace(lower(params))
+			if !ok || !strings.Contains(params, "rel=blocked-by") {
+				continue
+			}
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return ""
 }
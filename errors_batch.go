@@ -0,0 +1,243 @@
+package rdapclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Error codes reported by ErrorCode, stable for machine processing.
+const (
+	CodeNotFound         = "not_found"
+	CodeGone             = "gone"
+	CodeRateLimited      = "rate_limited"
+	CodeUnauthorized     = "unauthorized"
+	CodeBadRequest       = "bad_request"
+	CodeUnprocessable    = "unprocessable"
+	CodeLegalBlock       = "legal_block"
+	CodeHTTP             = "http_error"
+	CodeUnexpectedObject = "unexpected_object"
+	CodeAmbiguousObject  = "ambiguous_object"
+	CodeNestingTooDeep   = "nesting_too_deep"
+	CodeTooLarge         = "response_too_large"
+	CodeLinkBlocked      = "link_blocked"
+	CodeHopLimit         = "hop_limit"
+	CodeBudgetExceeded   = "budget_exceeded"
+	CodeLookupFailed     = "lookup_failed"
+	CodeTimeout          = "timeout"
+	CodeCanceled         = "canceled"
+	CodeNetwork          = "network"
+	CodeOther            = "error"
+)
+
+// ErrorCode classifies err into one of the Code* constants.
+func ErrorCode(err error) string {
+	var (
+		he *HTTPError
+		ue ErrUnexpectedObject
+		ae *ErrAmbiguousObject
+		de *ErrNestingTooDeep
+		te *ErrResponseTooLarge
+		lb *ErrLinkBlocked
+		hl *ErrHopLimit
+		be *ErrBudgetExceeded
+		le *ErrLookupFailed
+		ne net.Error
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &he):
+		switch he.StatusCode {
+		case 404:
+			return CodeNotFound
+		case 410:
+			return CodeGone
+		case 429:
+			return CodeRateLimited
+		case 401, 403:
+			return CodeUnauthorized
+		case 400:
+			return CodeBadRequest
+		case 422:
+			return CodeUnprocessable
+		case 451:
+			return CodeLegalBlock
+		}
+		return CodeHTTP
+	case errors.As(err, &ue):
+		return CodeUnexpectedObject
+	case errors.As(err, &ae):
+		return CodeAmbiguousObject
+	case errors.As(err, &de):
+		return CodeNestingTooDeep
+	case errors.As(err, &te):
+		return CodeTooLarge
+	case errors.As(err, &lb):
+		return CodeLinkBlocked
+	case errors.As(err, &hl):
+		return CodeHopLimit
+	case errors.As(err, &be):
+		return CodeBudgetExceeded
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.As(err, &ne):
+		if ne.Timeout() {
+			return CodeTimeout
+		}
+		return CodeNetwork
+	case errors.As(err, &le):
+		return CodeLookupFailed
+	}
+	return CodeOther
+}
+
+// ItemError is the failure of one item of a batch or walk: a query, handle or
+// graph node, the registry host it was sent to, and how many attempts were made.
+// Err keeps the typed error for errors.As.
+type ItemError struct {
+	ID       string
+	Registry string
+	Attempts int
+	Err      error
+}
+
+func (e *ItemError) Error() string {
+	if e.Registry == "" {
+		return fmt.Sprintf("%s: %v", e.ID, e.Err)
+	}
+	return fmt.Sprintf("%s (%s): %v", e.ID, e.Registry, e.Err)
+}
+
+func (e *ItemError) Unwrap() error { return e.Err }
+
+// itemErrorJSON is ItemError's JSON form.
+type itemErrorJSON struct {
+	ID         string  `json:"id"`
+	Code       string  `json:"code"`
+	Message    string  `json:"message"`
+	Registry   string  `json:"registry,omitempty"`
+	Attempts   int     `json:"attempts,omitempty"`
+	URL        string  `json:"url,omitempty"`
+	Status     int     `json:"status,omitempty"`
+	RetryAfter float64 `json:"retryAfter,omitempty"` // seconds
+}
+
+// MarshalJSON encodes e with its ErrorCode and, for registry errors, the
+// URL, HTTP status and Retry-After.
+func (e *ItemError) MarshalJSON() ([]byte, error) {
+	out := itemErrorJSON{ID: e.ID, Code: ErrorCode(e.Err), Registry: e.Registry, Attempts: e.Attempts}
+	if e.Err != nil {
+		out.Message = e.Err.Error()
+	}
+	var he *HTTPError
+	if errors.As(e.Err, &he) {
+		out.URL, out.Status, out.RetryAfter = he.URL, he.StatusCode, he.RetryAfter.Seconds()
+	}
+	var ue ErrUnexpectedObject
+	if out.URL == "" && errors.As(e.Err, &ue) {
+		out.URL = ue.URL
+	}
+	var de *ErrNestingTooDeep
+	if out.URL == "" && errors.As(e.Err, &de) {
+		out.URL = de.URL
+	}
+	var te *ErrResponseTooLarge
+	if out.URL == "" && errors.As(e.Err, &te) {
+		out.URL = te.URL
+	}
+	if out.Registry == "" && out.URL != "" {
+		if u, err := url.Parse(out.URL); err == nil {
+			out.Registry = u.Host
+		}
+	}
+	return json.Marshal(out)
+}
+
+// BatchError collects the failures of a batch or walk. It is safe for
+// concurrent Add; the zero value is ready to use.
+type BatchError struct {
+	mu    sync.Mutex
+	Items []*ItemError
+}
+
+// Add records a failure; a nil err is ignored.
+func (b *BatchError) Add(id, registry string, attempts int, err error) {
+	if err == nil {
+		return
+	}
+	b.mu.Lock()
+	b.Items = append(b.Items, &ItemError{ID: id, Registry: registry, Attempts: attempts, Err: err})
+	b.mu.Unlock()
+}
+
+// Len returns the number of failures.
+func (b *BatchError) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.Items)
+}
+
+// Err returns b, or nil when nothing failed.
+func (b *BatchError) Err() error {
+	if b == nil || b.Len() == 0 {
+		return nil
+	}
+	return b
+}
+
+func (b *BatchError) Error() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch len(b.Items) {
+	case 0:
+		return "rdap batch: no errors"
+	case 1:
+		return "rdap batch: " + b.Items[0].Error()
+	}
+	codes := map[string]int{}
+	var order []string
+	for _, it := range b.Items {
+		c := ErrorCode(it.Err)
+		if codes[c] == 0 {
+			order = append(order, c)
+		}
+		codes[c]++
+	}
+	parts := make([]string, len(order))
+	for i, c := range order {
+		parts[i] = fmt.Sprintf("%d %s", codes[c], c)
+	}
+	return fmt.Sprintf("rdap batch: %d items failed (%s); first: %v", len(b.Items), strings.Join(parts, ", "), b.Items[0])
+}
+
+// Unwrap exposes every item's error to errors.Is / errors.As.
+func (b *BatchError) Unwrap() []error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]error, len(b.Items))
+	for i, it := range b.Items {
+		out[i] = it
+	}
+	return out
+}
+
+// MarshalJSON encodes b as {"errors": [...]} of ItemError objects.
+func (b *BatchError) MarshalJSON() ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	items := b.Items
+	if items == nil {
+		items = []*ItemError{}
+	}
+	return json.Marshal(struct {
+		Errors []*ItemError `json:"errors"`
+	}{items})
+}
@@ -0,0 +1,90 @@
+package rdapclient
+
+import (
+	"strings"
+	"time"
+)
+
+// Event actions registered with IANA (RFC 9083 section 10.2.3 and the RDAP
+// JSON values registry).
+const (
+	EventRegistration             = "registration"
+	EventReregistration           = "reregistration"
+	EventExpiration               = "expiration"
+	EventLastChanged              = "last changed"
+	EventDeletion                 = "deletion"
+	EventReinstantiation          = "reinstantiation"
+	EventTransfer                 = "transfer"
+	EventLocked                   = "locked"
+	EventUnlocked                 = "unlocked"
+	EventLastRDAPUpdate           = "last update of RDAP database"
+	EventRegistrarExpiration      = "registrar expiration"
+	EventEnumValidationExpiration = "enum validation expiration"
+)
+
+// eventLayouts are the eventDate formats seen in the wild: RFC 3339 as the
+// RFC requires, then the zone-less and date-only forms some registries send
+// (read as UTC).
+var eventLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// ParseEventDate parses an RDAP eventDate.
+func ParseEventDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range eventLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Time parses e's eventDate, false when it does not parse.
+func (e Event) Time() (time.Time, bool) { return ParseEventDate(e.EventDate) }
+
+// Time parses e's eventDate, false when it does not parse.
+func (e EventNoActor) Time() (time.Time, bool) { return ParseEventDate(e.EventDate) }
+
+// EventTime returns the date of o's first event with action (case-insensitive,
+// e.g. EventExpiration), and false when there is none or it does not parse.
+func (o CommonObject) EventTime(action string) (time.Time, bool) {
+	for _, e := range o.Events {
+		if strings.EqualFold(e.EventAction, action) {
+			return ParseEventDate(e.EventDate)
+		}
+	}
+	return time.Time{}, false
+}
+
+// Registration, Expiration and LastChanged are the common EventTime lookups,
+// zero when the event is missing or unparseable; handy in text/template
+// (`{{.LDHName}} {{.Expiration}}`), which cannot take EventTime's two results.
+func (o CommonObject) Registration() time.Time { return o.eventOrZero(EventRegistration) }
+
+func (o CommonObject) Expiration() time.Time { return o.eventOrZero(EventExpiration) }
+
+func (o CommonObject) LastChanged() time.Time { return o.eventOrZero(EventLastChanged) }
+
+func (o CommonObject) eventOrZero(action string) time.Time {
+	t, _ := o.EventTime(action)
+	return t
+}
+
+// LastRDAPUpdate is when the server's database was last updated, zero when
+// unknown; it dates the response rather than the object.
+func (o CommonObject) LastRDAPUpdate() time.Time { return o.eventOrZero(EventLastRDAPUpdate) }
+
+// RegistrationDate is when d was registered, false when the registry does not say.
+func (d *Domain) RegistrationDate() (time.Time, bool) { return d.EventTime(EventRegistration) }
+
+// ExpirationDate is when d's registration expires: the registry's expiration
+// event, else the registrar's ("registrar expiration", which registrars list
+// when they renew ahead of the registry), false when neither parses.
+func (d *Domain) ExpirationDate() (time.Time, bool) {
+	if t, ok := d.EventTime(EventExpiration); ok {
+		return t, true
+	}
+	return d.EventTime(EventRegistrarExpiration)
+}
+
+// LastChangedDate is when d was last changed, false when the registry does not say.
+func (d *Domain) LastChangedDate() (time.Time, bool) { return d.EventTime(EventLastChanged) }
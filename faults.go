@@ -0,0 +1,89 @@
+package rdapclient
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InjectedFault is what a FaultInjector does to one request: wait Delay, then
+// fail with Err, answer with Status, or (both zero) send the request on.
+type InjectedFault struct {
+	Delay  time.Duration
+	Err    error
+	Status int
+}
+
+// FaultInjector decides the fault for each outgoing request; see
+// WithFaultInjector.
+type FaultInjector func(*http.Request) InjectedFault
+
+// ErrInjected is the error of requests failed by RandomFaults. It is a
+// temporary net.Error, so the client retries it like a dropped connection.
+type ErrInjected struct{ URL string }
+
+func (e *ErrInjected) Error() string   { return fmt.Sprintf("rdap GET %s: injected fault", e.URL) }
+func (e *ErrInjected) Timeout() bool   { return false }
+func (e *ErrInjected) Temporary() bool { return true }
+
+// RandomFaults returns a FaultInjector that delays a request by delay with
+// probability delayP and, independently, fails it with *ErrInjected with
+// probability failP.
+func RandomFaults(delayP float64, delay time.Duration, failP float64) FaultInjector {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	return func(req *http.Request) InjectedFault {
+		mu.Lock()
+		d, f := rng.Float64(), rng.Float64()
+		mu.Unlock()
+		var out InjectedFault
+		if d < delayP {
+			out.Delay = delay
+		}
+		if f < failP {
+			out.Err = &ErrInjected{URL: req.URL.String()}
+		}
+		return out
+	}
+}
+
+// faultDoer applies a FaultInjector in front of next.
+type faultDoer struct {
+	next   Doer
+	inject FaultInjector
+}
+
+func (d *faultDoer) Do(req *http.Request) (*http.Response, error) {
+	f := d.inject(req)
+	if f.Delay > 0 {
+		t := time.NewTimer(f.Delay)
+		select {
+		case <-t.C:
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+	switch {
+	case f.Err != nil:
+		return nil, f.Err
+	case f.Status != 0:
+		body := fmt.Sprintf(`{"errorCode":%d,"title":"injected fault"}`, f.Status)
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+			StatusCode:    f.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"application/rdap+json"}},
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return d.next.Do(req)
+}
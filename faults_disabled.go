@@ -0,0 +1,7 @@
+//go:build !rdapfaults
+
+package rdapclient
+
+// faultInjection reports whether WithFaultInjector takes effect: only in
+// builds with the rdapfaults tag.
+const faultInjection = false
@@ -0,0 +1,20 @@
+package rdapclient
+
+import "context"
+
+type freshDataKey struct{}
+
+// WithFreshData returns a context under which lookups skip every local copy
+// that could be out of date: the response cache's TTL (the cached validators
+// are still sent, so an unchanged object costs a 304), the canonical cache and
+// the dataset. Use it where current data matters more than speed, such as
+// history and diff runs. Other requests on the client are unaffected.
+func WithFreshData(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshDataKey{}, true)
+}
+
+// wantsFresh reports whether ctx was made by WithFreshData.
+func wantsFresh(ctx context.Context) bool {
+	v, _ := ctx.Value(freshDataKey{}).(bool)
+	return v
+}
@@ -0,0 +1,145 @@
+package rdapclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// doerFunc adapts a function to the Doer interface.
+type doerFunc func(*http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(r *http.Request) (*http.Response, error) { return f(r) }
+
+// staticDoer answers every request with the given status and body.
+func staticDoer(status int, body []byte) Doer {
+	return doerFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Header:     http.Header{"Content-Type": {"application/rdap+json"}},
+			Body:       io.NopCloser(strings.NewReader(string(body))),
+			Request:    r,
+		}, nil
+	})
+}
+
+// Seed responses shaped like what registries actually return, trimmed for size.
+var fuzzSeedObjects = []string{
+	`{"objectClassName":"domain","ldhName":"EXAMPLE.COM","handle":"2336799_DOMAIN_COM-VRSN","status":["client delete prohibited","client transfer prohibited"],"nameservers":[{"objectClassName":"nameserver","ldhName":"A.IANA-SERVERS.NET"}],"secureDNS":{"delegationSigned":true,"dsData":[{"keyTag":370,"algorithm":13,"digestType":2,"digest":"BE74"}]},"events":[{"eventAction":"registration","eventDate":"1995-08-14T04:00:00Z"}],"rdapConformance":["rdap_level_0"]}`,
+	`{"objectClassName":"ip network","handle":"NET-8-8-8-0-2","startAddress":"8.8.8.0","endAddress":"8.8.8.255","ipVersion":"v4","name":"GOGL","type":"DIRECT ALLOCATION","parentHandle":"NET-8-0-0-0-0","entities":[{"objectClassName":"entity","handle":"GOGL","roles":["registrant"],"vcardArray":["vcard",[["version",{},"text","4.0"],["fn",{},"text","Google LLC"]]]}]}`,
+	`{"objectClassName":"autnum","handle":"AS15169","startAutnum":15169,"endAutnum":15169,"name":"GOOGLE","type":"DIRECT ALLOCATION"}`,
+	`{"objectClassName":"entity","handle":"ORG-GOGL1-RIPE","roles":["registrant"],"vcardArray":["vcard",[["fn",{},"text","Google"],["adr",{"label":"x"},"text",["","","","","","",""]]]],"networks":[{"objectClassName":"ip network","handle":"X"}]}`,
+	`{"objectClassName":"nameserver","ldhName":"ns1.google.com","ipAddresses":{"v4":["216.239.32.10"],"v6":["2001:4860:4802:32::a"]}}`,
+	`{"errorCode":404,"title":"Not Found","description":["object not found"]}`,
+	`{"domainSearchResults":[{"objectClassName":"domain","ldhName":"a.example"}]}`,
+	`{"objectClassName":"domain","entities":[{"entities":[{"entities":[{}]}]}],"events":[{"eventDate":12}]}`,
+	`[]`,
+	`null`,
+}
+
+func FuzzParseObject(f *testing.F) {
+	for _, s := range fuzzSeedObjects {
+		f.Add([]byte(s))
+	}
+	golden, _ := filepath.Glob(filepath.Join("testdata", "golden", "*.json"))
+	for _, g := range golden {
+		if b, err := os.ReadFile(g); err == nil {
+			f.Add(b)
+		}
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var m map[string]any
+		if json.Unmarshal(b, &m) != nil {
+			return
+		}
+		obj, err := ParseObject(m)
+		if err == nil && obj == nil {
+			t.Fatalf("nil object without error for %q", b)
+		}
+	})
+}
+
+func FuzzContact(f *testing.F) {
+	for _, s := range []string{
+		`["vcard",[["version",{},"text","4.0"],["fn",{},"text","Joe"],["kind",{},"text","individual"],["org",{"type":"work"},"text",["Example","Ops"]],["email",{"type":["work"]},"text","joe@example.com"],["tel",{"type":["voice","work"]},"uri","tel:+1.5555551234"],["adr",{"cc":"US"},"text",["","Suite 1",["1 Main St","Fl 2"],"Town","CA","94000","United States"]],["url",{},"uri","https://example.com"],["lang",{"pref":"1"},"language-tag","en"]]]`,
+		`["vcard",[["n",{},"text",["Doe","Jane","","Dr.",""]],["adr",{"label":"1 Main St\nTown"},"text",["","","","","","",""]]]]`,
+		`[["fn",{},"text","No wrapper"]]`,
+		`[["vcard",[["FN",{},"text","Extra array"]]]]`,
+		`["vcard",[["fn",{}],["adr",{},"text","flat"],["tel",{"type":"fax"},"text",12],[1,2,3],[]]]`,
+		`["vcard",null]`,
+		`"vcard"`,
+	} {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var e Entity
+		if json.Unmarshal([]byte(`{"objectClassName":"entity","vcardArray":`+string(b)+`}`), &e) != nil {
+			return
+		}
+		c := e.Contact()
+		if e.VCardArray == nil && c != nil {
+			t.Fatalf("contact %+v from no jCard", c)
+		}
+		if c == nil {
+			return
+		}
+		// A decoded contact always encodes.
+		if _, err := json.Marshal(c); err != nil {
+			t.Fatal(err)
+		}
+		_ = e.OrgName()
+	})
+}
+
+func FuzzGetJSON(f *testing.F) {
+	for _, s := range fuzzSeedObjects {
+		f.Add(200, []byte(s))
+		f.Add(404, []byte(s))
+	}
+	f.Add(304, []byte(nil))
+	f.Add(200, []byte(`{"a":`))
+	f.Fuzz(func(t *testing.T, status int, body []byte) {
+		if status < 100 || status > 599 {
+			return
+		}
+		c := New(WithHTTPDoer(staticDoer(status, body)), WithMaxRetries(0), WithBackoff(func(int) time.Duration { return 0 }))
+		m, _, err := c.getJSON(context.Background(), "https://rdap.example/domain/x.example")
+		if err == nil && m == nil && string(body) != "null" {
+			t.Fatalf("status %d: nil map without error", status)
+		}
+	})
+}
+
+func FuzzBootstrapDNS(f *testing.F) {
+	f.Add([]byte(`{"version":"1.0","services":[[["com","net"],["https://rdap.verisign.com/com/v1/"]],[["org"],["https://rdap.publicinterestregistry.org/rdap/"]]]}`))
+	f.Add([]byte(`{"services":[[["x"]],[[1,2],[null]],[["y"],[]]]}`))
+	f.Add([]byte(`{"services":null}`))
+	f.Fuzz(func(t *testing.T, body []byte) {
+		c := New(WithHTTPDoer(staticDoer(http.StatusOK, body)), WithDefaultRDAPBase("https://fallback.example"))
+		base, err := c.rdapBaseForTLD(context.Background(), "com")
+		if err == nil && base == "" {
+			t.Fatalf("empty base without error")
+		}
+	})
+}
+
+func FuzzBootstrapIPAndASN(f *testing.F) {
+	f.Add([]byte(`{"services":[[["8.0.0.0/8","2001:4860::/32"],["https://rdap.arin.net/registry/"]]]}`), "8.8.8.8")
+	f.Add([]byte(`{"services":[[["1-1876","15169"],["https://rdap.arin.net/registry/"]]]}`), "15169")
+	f.Add([]byte(`{"services":[[["::/0"],["https://x/"]]]}`), "2001:db8::/32")
+	f.Fuzz(func(t *testing.T, body []byte, q string) {
+		c := New(WithHTTPDoer(staticDoer(http.StatusOK, body)))
+		if a, err := c.rdapBaseForASN(context.Background(), q); err == nil && a == "" {
+			t.Fatalf("empty ASN base without error")
+		}
+		_, _ = c.rdapBaseForIP(context.Background(), q)
+		_, _, _ = parseASNRange(q)
+	})
+}
@@ -2,9 +2,19 @@ module github.com/datum-labs/rdap
 
 go 1.24.2
 
-require github.com/spf13/cobra v1.10.1
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/quic-go/quic-go v0.59.1
+	github.com/spf13/cobra v1.10.1
+	golang.org/x/net v0.50.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/quic-go/qpack v0.6.0 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
+	golang.org/x/crypto v0.48.0 // indirect
+	golang.org/x/sys v0.41.0 // indirect
+	golang.org/x/text v0.34.0 // indirect
 )
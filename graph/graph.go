@@ -0,0 +1,73 @@
+// Package graph holds the RDAP relationship graph produced by walks (rdapctl
+// tree, snapshot bundles) and tools that operate on it offline.
+package graph
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Graph is the {nodes, edges} document rdapctl emits for tree walks.
+type Graph struct {
+	Nodes map[string]Node `json:"nodes"`
+	Edges []Edge          `json:"edges"`
+
+	emit func(Patch) // WalkOptions.OnPatch of the walk building the graph
+}
+
+// Node is one RDAP object in the graph.
+type Node struct {
+	ID   string      `json:"id"`
+	Kind string      `json:"kind"` // domain | nameserver | entity | ip-network | autnum | link
+	Data interface{} `json:"data"` // the typed RDAP object (Domain, Nameserver, Entity, IPNetwork, Autnum) or link URL
+}
+
+// Edge is a directed relation between two node IDs; see Rel for direction rules.
+type Edge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Rel     Rel    `json:"rel"`
+	LinkRel string `json:"linkRel,omitempty"` // raw links[].rel when the edge came from a link
+}
+
+// New returns an empty graph.
+func New() *Graph { return &Graph{Nodes: map[string]Node{}, Edges: []Edge{}} }
+
+// AddNode inserts a node unless one with the same ID exists.
+func (g *Graph) AddNode(id, kind string, data interface{}) {
+	if _, ok := g.Nodes[id]; ok {
+		return
+	}
+	n := Node{ID: id, Kind: kind, Data: data}
+	g.Nodes[id] = n
+	if g.emit != nil {
+		g.emit(Patch{Op: PatchAddNode, Node: &n})
+	}
+}
+
+// AddEdge appends a relation.
+func (g *Graph) AddEdge(from, to string, rel Rel) {
+	g.AddLinkEdge(from, to, rel, "")
+}
+
+// AddLinkEdge appends a relation discovered through a link, keeping the link's own rel.
+func (g *Graph) AddLinkEdge(from, to string, rel Rel, linkRel string) {
+	e := Edge{From: from, To: to, Rel: rel, LinkRel: linkRel}
+	g.Edges = append(g.Edges, e)
+	if g.emit != nil {
+		g.emit(Patch{Op: PatchAddEdge, Edge: &e})
+	}
+}
+
+// Load decodes a graph previously written as JSON. Node data comes back as
+// generic JSON values (map[string]any), not typed RDAP objects.
+func Load(r io.Reader) (*Graph, error) {
+	g := New()
+	if err := json.NewDecoder(r).Decode(g); err != nil {
+		return nil, err
+	}
+	if g.Nodes == nil {
+		g.Nodes = map[string]Node{}
+	}
+	return g, nil
+}
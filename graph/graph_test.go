@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sampleGraph() *Graph {
+	g := New()
+	g.AddNode("domain:example.com", "domain", map[string]any{"ldhName": "example.com"})
+	g.AddNode("nameserver:ns1.example.net", "nameserver", map[string]any{"ldhName": "ns1.example.net"})
+	g.AddNode("entity:reg-1", "entity", map[string]any{"handle": "REG-1", "roles": []any{"registrant"}})
+	g.AddNode("entity:abuse-1", "entity", map[string]any{"handle": "ABUSE-1", "roles": []any{"abuse", "technical"}})
+	g.AddNode("ip-network:net-1", "ip-network", map[string]any{"handle": "NET-1", "country": "US"})
+	g.AddEdge("entity:reg-1", "domain:example.com", RelRegistrantOf)
+	g.AddEdge("nameserver:ns1.example.net", "domain:example.com", RelNameserverOf)
+	g.AddEdge("entity:abuse-1", "nameserver:ns1.example.net", RelAbuseOf)
+	g.AddEdge("entity:abuse-1", "ip-network:net-1", RelHolderOf)
+	return g
+}
+
+func TestQuery_RoleFilterAndProjection(t *testing.T) {
+	res, err := sampleGraph().Query("MATCH entity[role=registrant]->domain RETURN entity.handle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Rows, [][]string{{"REG-1"}}) || res.Columns[0] != "entity.handle" {
+		t.Fatalf("unexpected: %+v", res)
+	}
+}
+
+func TestQuery_AliasesRelationsAndHyphenKinds(t *testing.T) {
+	q := "MATCH e:entity[role~abu]-[ABUSE_CONTACT_OF]->ns:nameserver-[NAMESERVER_OF]->d:domain RETURN d.ldhName, e.handle, ns"
+	res, err := sampleGraph().Query(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"example.com", "ABUSE-1", "nameserver:ns1.example.net"}}
+	if !reflect.DeepEqual(res.Rows, want) {
+		t.Fatalf("got %+v", res.Rows)
+	}
+	res, err = sampleGraph().Query("MATCH entity-[HOLDER_OF]->n:ip-network[country=us] RETURN n")
+	if err != nil || !reflect.DeepEqual(res.Rows, [][]string{{"ip-network:net-1"}}) {
+		t.Fatalf("got %+v, %v", res, err)
+	}
+
+	// Wrong relation name prunes the path.
+	res, _ = sampleGraph().Query("MATCH nameserver-[REGISTRANT_OF]->domain")
+	if len(res.Rows) != 0 {
+		t.Fatalf("relation filter ignored: %+v", res.Rows)
+	}
+}
+
+func TestQuery_NegationAndWildcard(t *testing.T) {
+	res, err := sampleGraph().Query("MATCH e:entity[role!=registrant]->*[handle=NET-1] RETURN e.handle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Rows, [][]string{{"ABUSE-1"}}) {
+		t.Fatalf("got %+v", res.Rows)
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	for _, q := range []string{"", "domain->entity", "MATCH domain->", "MATCH domain[role] ", "MATCH domain RETURN x.handle", "MATCH domain-[x->entity"} {
+		if _, err := ParseQuery(q); err == nil {
+			t.Fatalf("%q: expected error", q)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	g, err := Load(strings.NewReader(`{"nodes":{"domain:a":{"id":"domain:a","kind":"domain","data":{"ldhName":"a"}}},"edges":[]}`))
+	if err != nil || len(g.Nodes) != 1 {
+		t.Fatalf("load: %v %+v", err, g)
+	}
+}
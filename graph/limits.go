@@ -0,0 +1,137 @@
+package graph
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// Truncation policies: which fetches a walk makes once WalkOptions.MaxRequests
+// runs short.
+const (
+	// TruncateDiscovery (the default) spends the budget in the order fetches
+	// are found, so the first objects to arrive can use all of it.
+	TruncateDiscovery = "discovery"
+	// TruncateBreadthFirst spends it when fetches are sent, nearest the seed
+	// first, taking each parent's first child before any parent's second.
+	// A cut walk keeps a layer of every branch instead of one branch deep.
+	TruncateBreadthFirst = "breadth-first"
+)
+
+// Limits is how a walk's limits shaped its graph, in its Summary. The maps
+// count by node kind; nil means nothing was left out that way.
+type Limits struct {
+	// Truncation is the policy in force when MaxRequests cut the walk short.
+	Truncation string `json:"truncation,omitempty"`
+	// Dropped are the queued fetches abandoned at MaxRequests
+	// (TruncateBreadthFirst; discovery order refuses them as they are found).
+	Dropped map[string]int `json:"dropped,omitempty"`
+	// OverQuota are the children left out by WalkOptions.KindQuota.
+	OverQuota map[string]int `json:"overQuota,omitempty"`
+	// SampleRate and SampledOut: the sampling rate and the children it left out.
+	SampleRate float64        `json:"sampleRate,omitempty"`
+	SampledOut map[string]int `json:"sampledOut,omitempty"`
+}
+
+// slot is where a fetch sits in the walk: its depth, and which child of its
+// parent it is, for breadth-first ordering.
+type slot struct{ depth, ord int }
+
+// nodeKind is the graph node kind of a client lookup kind.
+func nodeKind(k rdap.Kind) string {
+	if k == rdap.KindIP {
+		return KindIPNetwork
+	}
+	return string(k)
+}
+
+// admit decides whether the walk follows parentID's child of kind, key,
+// applying SampleRate and then KindQuota, and returns its slot. Sampling goes
+// first so the quota is filled from the sample instead of the sample thinning
+// an already capped set.
+func (w *Walker) admit(parentID, kind, key string, depth int) (slot, bool) {
+	if r := w.opts.SampleRate; r > 0 && r < 1 {
+		h := fnv.New64a()
+		h.Write([]byte(parentID + "\x00" + kind + ":" + key))
+		if float64(h.Sum64())/math.MaxUint64 >= r {
+			countKind(&w.limits.SampledOut, kind)
+			return slot{}, false
+		}
+	}
+	if q, ok := w.opts.KindQuota[kind]; ok {
+		n := w.quota[parentID+"\x00"+kind]
+		if n >= q {
+			countKind(&w.limits.OverQuota, kind)
+			return slot{}, false
+		}
+		w.quota[parentID+"\x00"+kind] = n + 1
+	}
+	if !w.breadthFirst() {
+		return slot{depth: depth + 1}, true
+	}
+	ord := w.children[parentID]
+	w.children[parentID] = ord + 1
+	return slot{depth + 1, ord}, true
+}
+
+func countKind(m *map[string]int, kind string) {
+	if *m == nil {
+		*m = map[string]int{}
+	}
+	(*m)[kind]++
+}
+
+// breadthFirst reports whether fetches are charged when sent rather than found.
+func (w *Walker) breadthFirst() bool {
+	return w.opts.Truncation == TruncateBreadthFirst && w.opts.MaxRequests > 0
+}
+
+// before orders fetches for breadth-first truncation.
+func (a *fetch) before(b *fetch) bool {
+	if a.at.depth != b.at.depth {
+		return a.at.depth < b.at.depth
+	}
+	if a.at.ord != b.at.ord {
+		return a.at.ord < b.at.ord
+	}
+	return a.seq < b.seq
+}
+
+// insert queues f in its host's queue, keeping breadth-first order.
+func (w *Walker) insert(q []*fetch, f *fetch) []*fetch {
+	i := sort.Search(len(q), func(i int) bool { return f.before(q[i]) })
+	q = append(q, nil)
+	copy(q[i+1:], q[i:])
+	q[i] = f
+	return q
+}
+
+// dropQueued abandons every queued fetch once the budget is spent.
+func (w *Walker) dropQueued() {
+	for h, q := range w.queues {
+		for _, f := range q {
+			countKind(&w.limits.Dropped, f.kind)
+		}
+		w.queues[h] = nil
+	}
+	w.limits.Truncation = TruncateBreadthFirst
+	w.report.Truncate()
+}
+
+// summary is the last Walk's Summary.
+func (w *Walker) summary() *Summary {
+	s := &Summary{Nodes: len(w.g.Nodes), Edges: len(w.g.Edges), Completeness: w.report.Report()}
+	l := w.limits
+	if s.Completeness.Truncated && l.Truncation == "" {
+		l.Truncation = TruncateDiscovery
+	}
+	if l.SampledOut != nil {
+		l.SampleRate = w.opts.SampleRate
+	}
+	if l.Truncation != "" || l.OverQuota != nil || l.SampledOut != nil {
+		s.Limits = &l
+	}
+	return s
+}
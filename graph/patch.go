@@ -0,0 +1,47 @@
+package graph
+
+import rdap "github.com/datum-labs/rdap"
+
+// Patch ops, in the order a walk emits them: nodes and edges as the graph
+// grows, then one PatchDone.
+const (
+	PatchAddNode = "addNode"
+	PatchAddEdge = "addEdge"
+	PatchDone    = "done"
+)
+
+// Patch is one incremental change to a graph being walked, for consumers
+// that render it as it grows (rdapctl tree --stream writes them as NDJSON).
+// An edge may arrive before the node it points to, or name one never added
+// (a hydration that failed); a consumer applying patches should allow both.
+type Patch struct {
+	Op      string   `json:"op"`
+	Node    *Node    `json:"node,omitempty"`
+	Edge    *Edge    `json:"edge,omitempty"`
+	Summary *Summary `json:"summary,omitempty"`
+}
+
+// Summary closes a patch stream: the size of the finished graph and how
+// complete it is.
+type Summary struct {
+	Nodes        int               `json:"nodes"`
+	Edges        int               `json:"edges"`
+	Completeness rdap.Completeness `json:"completeness"`
+	// Limits is set when truncation, quotas or sampling left objects out.
+	Limits *Limits `json:"limits,omitempty"`
+}
+
+// Apply replays p onto g, so a consumer of a patch stream can keep the same
+// Graph the walk returned. PatchDone changes nothing.
+func (g *Graph) Apply(p Patch) {
+	switch p.Op {
+	case PatchAddNode:
+		if p.Node != nil {
+			g.AddNode(p.Node.ID, p.Node.Kind, p.Node.Data)
+		}
+	case PatchAddEdge:
+		if p.Edge != nil {
+			g.AddLinkEdge(p.Edge.From, p.Edge.To, p.Edge.Rel, p.Edge.LinkRel)
+		}
+	}
+}
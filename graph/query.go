@@ -0,0 +1,349 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A tiny path-query language over a Graph:
+//
+//	MATCH e:entity-[REGISTRANT_OF]->d:domain RETURN e.handle, d.ldhName
+//	MATCH e:entity[role~abuse]->ns:nameserver-[NAMESERVER_OF]->d:domain RETURN d.ldhName, e.handle
+//
+// Edges run the way their relation reads (see Rel): from an entity to the
+// objects it serves, from a nameserver to its domains.
+//
+// A pattern is a chain of steps joined by "->" (any relation) or "-[rel]->".
+// A step is a node kind ("*" for any), optionally prefixed by "alias:" and
+// followed by "[field op value, ...]" filters, where op is "=", "!=" or "~"
+// (substring). Field names match node data case-insensitively; "id" and "kind"
+// refer to the node itself, and a singular name also matches its plural array
+// ("role" matches "roles"). Array fields match if any element matches.
+// RETURN lists "alias.field" or bare "alias" (the node ID); without RETURN the
+// IDs of every step are returned.
+
+// Query is a parsed MATCH expression.
+type Query struct {
+	Steps   []Step
+	Returns []Projection
+}
+
+// Step is one node in the pattern, reached from the previous step via Rel
+// (empty means any relation).
+type Step struct {
+	Alias   string
+	Kind    string
+	Rel     string
+	Filters []Filter
+}
+
+// Filter is a single field comparison.
+type Filter struct {
+	Field string
+	Op    string // "=", "!=", "~"
+	Value string
+}
+
+// Projection selects a value from a matched step.
+type Projection struct {
+	Alias string
+	Field string // empty: node ID
+}
+
+// Result is a table of matches.
+type Result struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Query parses expr and evaluates it against g.
+func (g *Graph) Query(expr string) (*Result, error) {
+	q, err := ParseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Eval(g), nil
+}
+
+// ParseQuery parses a MATCH expression.
+func ParseQuery(expr string) (*Query, error) {
+	s := strings.TrimSpace(expr)
+	if !hasPrefixFold(s, "MATCH ") {
+		return nil, fmt.Errorf("graph query: must start with MATCH")
+	}
+	s = strings.TrimSpace(s[len("MATCH "):])
+
+	var ret string
+	if i := indexFold(s, " RETURN "); i >= 0 {
+		ret = strings.TrimSpace(s[i+len(" RETURN "):])
+		s = strings.TrimSpace(s[:i])
+	}
+
+	q := &Query{}
+	rel := ""
+	for s != "" {
+		st, rest, err := parseStep(s)
+		if err != nil {
+			return nil, err
+		}
+		st.Rel = rel
+		q.Steps = append(q.Steps, st)
+		s = strings.TrimSpace(rest)
+		if s == "" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(s, "->"):
+			rel, s = "", s[2:]
+		case strings.HasPrefix(s, "-["):
+			end := strings.Index(s, "]->")
+			if end < 0 {
+				return nil, fmt.Errorf("graph query: unterminated relation in %q", s)
+			}
+			rel, s = strings.TrimSpace(s[2:end]), s[end+3:]
+		default:
+			return nil, fmt.Errorf("graph query: expected -> at %q", s)
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil, fmt.Errorf("graph query: pattern ends with a relation")
+		}
+	}
+	if len(q.Steps) == 0 {
+		return nil, fmt.Errorf("graph query: empty pattern")
+	}
+
+	if ret != "" {
+		for _, p := range strings.Split(ret, ",") {
+			p = strings.TrimSpace(p)
+			alias, field, _ := strings.Cut(p, ".")
+			if q.step(alias) < 0 {
+				return nil, fmt.Errorf("graph query: RETURN references unknown step %q", alias)
+			}
+			q.Returns = append(q.Returns, Projection{Alias: alias, Field: field})
+		}
+	} else {
+		for _, st := range q.Steps {
+			q.Returns = append(q.Returns, Projection{Alias: st.Alias})
+		}
+	}
+	return q, nil
+}
+
+func parseStep(s string) (Step, string, error) {
+	i := 0
+	for i < len(s) && (isIdent(rune(s[i])) || s[i] == ':' || s[i] == '*') {
+		// "-" belongs to kinds like ip-network, but "->" and "-[" start a relation.
+		if s[i] == '-' && i+1 < len(s) && (s[i+1] == '>' || s[i+1] == '[') {
+			break
+		}
+		i++
+	}
+	head := s[:i]
+	if head == "" {
+		return Step{}, "", fmt.Errorf("graph query: expected node kind at %q", s)
+	}
+	st := Step{Alias: head, Kind: head}
+	if a, k, ok := strings.Cut(head, ":"); ok {
+		st.Alias, st.Kind = a, k
+	}
+	rest := s[i:]
+	if strings.HasPrefix(rest, "[") {
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			return Step{}, "", fmt.Errorf("graph query: unterminated filter in %q", s)
+		}
+		for _, f := range strings.Split(rest[1:end], ",") {
+			flt, err := parseFilter(strings.TrimSpace(f))
+			if err != nil {
+				return Step{}, "", err
+			}
+			st.Filters = append(st.Filters, flt)
+		}
+		rest = rest[end+1:]
+	}
+	return st, rest, nil
+}
+
+func parseFilter(s string) (Filter, error) {
+	for _, op := range []string{"!=", "=", "~"} {
+		if i := strings.Index(s, op); i > 0 {
+			v := strings.Trim(strings.TrimSpace(s[i+len(op):]), `"'`)
+			return Filter{Field: strings.TrimSpace(s[:i]), Op: op, Value: v}, nil
+		}
+	}
+	return Filter{}, fmt.Errorf("graph query: bad filter %q", s)
+}
+
+func (q *Query) step(alias string) int {
+	for i, st := range q.Steps {
+		if st.Alias == alias {
+			return i
+		}
+	}
+	return -1
+}
+
+// Eval runs the query against g. Rows are de-duplicated and sorted.
+func (q *Query) Eval(g *Graph) *Result {
+	res := &Result{}
+	for _, p := range q.Returns {
+		col := p.Alias
+		if p.Field != "" {
+			col += "." + p.Field
+		}
+		res.Columns = append(res.Columns, col)
+	}
+
+	out := map[string][]string{}
+	data := map[string]map[string]any{} // node ID -> generic data, computed lazily
+	get := func(id string) map[string]any {
+		if m, ok := data[id]; ok {
+			return m
+		}
+		m := toMap(g.Nodes[id].Data)
+		data[id] = m
+		return m
+	}
+	adj := map[string][]Edge{}
+	for _, e := range g.Edges {
+		adj[e.From] = append(adj[e.From], e)
+	}
+
+	path := make([]string, len(q.Steps))
+	var walk func(i int, id string)
+	walk = func(i int, id string) {
+		n, ok := g.Nodes[id]
+		if !ok || !q.Steps[i].matches(n, get(id)) {
+			return
+		}
+		path[i] = id
+		if i == len(q.Steps)-1 {
+			row := make([]string, len(q.Returns))
+			for j, p := range q.Returns {
+				nid := path[q.step(p.Alias)]
+				if p.Field == "" {
+					row[j] = nid
+				} else {
+					row[j] = strings.Join(lookupField(g.Nodes[nid], get(nid), p.Field), ",")
+				}
+			}
+			out[strings.Join(row, "\x00")] = row
+			return
+		}
+		next := q.Steps[i+1]
+		for _, e := range adj[id] {
+			if next.Rel == "" || strings.EqualFold(string(e.Rel), next.Rel) {
+				walk(i+1, e.To)
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		walk(0, id)
+	}
+
+	keys := make([]string, 0, len(out))
+	for k := range out {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		res.Rows = append(res.Rows, out[k])
+	}
+	return res
+}
+
+func (st Step) matches(n Node, data map[string]any) bool {
+	if st.Kind != "*" && !strings.EqualFold(st.Kind, n.Kind) {
+		return false
+	}
+	for _, f := range st.Filters {
+		vals := lookupField(n, data, f.Field)
+		hit := false
+		for _, v := range vals {
+			switch f.Op {
+			case "~":
+				hit = strings.Contains(strings.ToLower(v), strings.ToLower(f.Value))
+			default:
+				hit = strings.EqualFold(v, f.Value)
+			}
+			if hit {
+				break
+			}
+		}
+		if f.Op == "!=" {
+			hit = !hit
+		}
+		if !hit {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupField returns the string values of field on a node.
+func lookupField(n Node, data map[string]any, field string) []string {
+	switch strings.ToLower(field) {
+	case "id":
+		return []string{n.ID}
+	case "kind":
+		return []string{n.Kind}
+	}
+	for _, name := range []string{field, field + "s"} {
+		for k, v := range data {
+			if strings.EqualFold(k, name) {
+				return flatten(v)
+			}
+		}
+	}
+	return nil
+}
+
+func flatten(v any) []string {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{x}
+	case []any:
+		var out []string
+		for _, e := range x {
+			out = append(out, flatten(e)...)
+		}
+		return out
+	case map[string]any:
+		b, _ := json.Marshal(x)
+		return []string{string(b)}
+	default:
+		return []string{fmt.Sprint(x)}
+	}
+}
+
+// toMap converts typed node data to generic JSON so fields can be addressed by name.
+func toMap(v any) map[string]any {
+	if m, ok := v.(map[string]any); ok {
+		return m
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	_ = json.Unmarshal(b, &m)
+	return m
+}
+
+func isIdent(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' }
+
+func hasPrefixFold(s, p string) bool { return len(s) >= len(p) && strings.EqualFold(s[:len(p)], p) }
+
+func indexFold(s, sub string) int { return strings.Index(strings.ToUpper(s), strings.ToUpper(sub)) }
@@ -0,0 +1,93 @@
+package graph
+
+import "strings"
+
+// Rel is a typed edge relation. Every edge reads as a sentence
+// "<From> is <Rel> <To>": an edge {From: entity:X, To: domain:Y, Rel: REGISTRAR_OF}
+// means entity X is the registrar of domain Y. Walks discover objects in the
+// opposite direction (domain -> entity), so the walker flips those edges to keep
+// this direction consistent for every relation.
+type Rel string
+
+const (
+	// Entity roles (RFC 9083 section 10.2.4): From is an entity, To the object it serves.
+	RelRegistrantOf     Rel = "REGISTRANT_OF"
+	RelRegistrarOf      Rel = "REGISTRAR_OF"
+	RelTechnicalOf      Rel = "TECHNICAL_CONTACT_OF"
+	RelAdministrativeOf Rel = "ADMINISTRATIVE_CONTACT_OF"
+	RelAbuseOf          Rel = "ABUSE_CONTACT_OF"
+	RelBillingOf        Rel = "BILLING_CONTACT_OF"
+	RelResellerOf       Rel = "RESELLER_OF"
+	RelSponsorOf        Rel = "SPONSOR_OF"
+	RelProxyOf          Rel = "PROXY_OF"
+	RelNotificationsOf  Rel = "NOTIFICATIONS_CONTACT_OF"
+	RelNOCOf            Rel = "NOC_OF"
+	// RelContactOf is used for entities with no (or an unrecognized) role.
+	RelContactOf Rel = "CONTACT_OF"
+
+	// RelMemberOf: From is an entity nested inside the entity To (e.g. a registrar's abuse contact).
+	RelMemberOf Rel = "MEMBER_OF"
+	// RelNameserverOf: From is a nameserver delegated for the domain To.
+	RelNameserverOf Rel = "NAMESERVER_OF"
+	// RelHolderOf: From is an entity holding the network or autnum To (entity networks/autnums members).
+	RelHolderOf Rel = "HOLDER_OF"
+	// RelParentNetwork: From is the parent network of the network To.
+	RelParentNetwork Rel = "PARENT_NETWORK"
+	// RelHostedIn: From is a nameserver whose glue address lies in the network To.
+	RelHostedIn Rel = "HOSTED_IN"
+	// RelAnnouncedBy: From is a network originated by the autnum To.
+	RelAnnouncedBy Rel = "ANNOUNCED_BY"
+	// RelSelfLink: From and To are the same object reached through a "self" link.
+	RelSelfLink Rel = "SELF_LINK"
+	// RelRelatedTo: From links to To with rel "related" or another non-structural link relation.
+	RelRelatedTo Rel = "RELATED_TO"
+)
+
+var roleRels = map[string]Rel{
+	"registrant":     RelRegistrantOf,
+	"registrar":      RelRegistrarOf,
+	"technical":      RelTechnicalOf,
+	"administrative": RelAdministrativeOf,
+	"abuse":          RelAbuseOf,
+	"billing":        RelBillingOf,
+	"reseller":       RelResellerOf,
+	"sponsor":        RelSponsorOf,
+	"proxy":          RelProxyOf,
+	"notifications":  RelNotificationsOf,
+	"noc":            RelNOCOf,
+}
+
+// RoleRels maps entity roles to relations, one per role; an entity without a
+// known role yields RelContactOf.
+func RoleRels(roles []string) []Rel {
+	var out []Rel
+	seen := map[Rel]bool{}
+	for _, r := range roles {
+		rel, ok := roleRels[strings.ToLower(strings.TrimSpace(r))]
+		if !ok || seen[rel] {
+			continue
+		}
+		seen[rel] = true
+		out = append(out, rel)
+	}
+	if len(out) == 0 {
+		out = append(out, RelContactOf)
+	}
+	return out
+}
+
+// LinkRel maps an RDAP link "rel" to a relation and reports whether the edge
+// should be reversed (the link target is the From side).
+func LinkRel(linkRel string) (rel Rel, reverse bool) {
+	switch strings.ToLower(linkRel) {
+	case "self":
+		return RelSelfLink, false
+	case "up":
+		// The target of an "up" link is the parent of the current object.
+		return RelParentNetwork, true
+	case "down":
+		return RelParentNetwork, false
+	default:
+		return RelRelatedTo, false
+	}
+}
@@ -0,0 +1,204 @@
+package graph
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SeenSet records the node IDs a walk has visited, so no object is fetched
+// twice and link cycles end. The default is a map held for one Walk; crawls
+// of millions of nodes can bound memory with a DiskSeen or their own store.
+// A set given in WalkOptions.Seen is kept across Walks, so several seeds
+// crawled in turn share it. A Walk calls Add only from its scheduling
+// goroutine, so the default need not lock; a set shared by Walks running at
+// the same time must be safe for concurrent use, like NewMemorySeen's and
+// DiskSeen.
+type SeenSet interface {
+	// Add records id, reporting whether it was not already present.
+	Add(id string) (bool, error)
+}
+
+// memorySeen is the default SeenSet.
+type memorySeen map[string]struct{}
+
+func (s memorySeen) Add(id string) (bool, error) {
+	if _, ok := s[id]; ok {
+		return false, nil
+	}
+	s[id] = struct{}{}
+	return true, nil
+}
+
+// NewMemorySeen returns an in-memory SeenSet that is safe for concurrent use,
+// for Walks that run in parallel over one crawl.
+func NewMemorySeen() SeenSet { return &lockedSeen{m: memorySeen{}} }
+
+type lockedSeen struct {
+	mu sync.Mutex
+	m  memorySeen
+}
+
+func (s *lockedSeen) Add(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m.Add(id)
+}
+
+// diskSeenShards spreads the set over this many files, so a lookup reads
+// about 1/diskSeenShards of it.
+const diskSeenShards = 4096
+
+// DiskSeen is a SeenSet on disk for crawls too big for memory. A bloom
+// filter in memory answers most IDs without disk access: only those it may
+// have seen are checked against 128-bit hashes of the IDs kept in shard
+// files under a directory. Memory stays at about 10 bits per expected ID.
+// Reopening the directory resumes the set. It is safe for concurrent use.
+type DiskSeen struct {
+	dir string
+
+	mu    sync.Mutex
+	bloom []uint64
+	bits  uint64 // bloom size in bits
+	k     int    // hash functions
+	n     int
+}
+
+// OpenDiskSeen opens or creates the set in dir, sizing its bloom filter for
+// expected IDs at a 1% false-positive rate (more IDs only make disk checks
+// more frequent).
+func OpenDiskSeen(dir string, expected int) (*DiskSeen, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	expected = max(expected, 1024)
+	bits := uint64(math.Ceil(-float64(expected) * math.Log(0.01) / (math.Ln2 * math.Ln2)))
+	bits = (bits + 63) / 64 * 64
+	s := &DiskSeen{
+		dir:   dir,
+		bloom: make([]uint64, bits/64),
+		bits:  bits,
+		k:     max(1, int(math.Round(float64(bits)/float64(expected)*math.Ln2))),
+	}
+	// Load the IDs of an earlier run into the filter. A run that died
+	// mid-write leaves a torn record at the end of a shard; it is cut off, or
+	// every record appended after it would be misaligned.
+	for shard := 0; shard < diskSeenShards; shard++ {
+		b, err := os.ReadFile(s.shardPath(shard))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if torn := len(b) % 16; torn != 0 {
+			b = b[:len(b)-torn]
+			if err := os.Truncate(s.shardPath(shard), int64(len(b))); err != nil {
+				return nil, fmt.Errorf("seen set: %w", err)
+			}
+		}
+		for ; len(b) >= 16; b = b[16:] {
+			s.mark(b[:16])
+			s.n++
+		}
+	}
+	return s, nil
+}
+
+// Len returns the number of IDs recorded.
+func (s *DiskSeen) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.n
+}
+
+// Add records id, reporting whether it was not already present.
+func (s *DiskSeen) Add(id string) (bool, error) {
+	sum := sha256.Sum256([]byte(id))
+	h := sum[:16]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shard := int(binary.BigEndian.Uint16(h) % diskSeenShards)
+	if s.maybe(h) {
+		found, err := s.inShard(shard, h)
+		if err != nil || found {
+			return false, err
+		}
+	}
+	f, err := os.OpenFile(s.shardPath(shard), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return false, err
+	}
+	end, err := f.Seek(0, io.SeekEnd)
+	if err == nil {
+		if _, err = f.Write(h); err != nil {
+			_ = f.Truncate(end) // drop a short write's torn record
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return false, fmt.Errorf("seen set: %w", err)
+	}
+	s.mark(h)
+	s.n++
+	return true, nil
+}
+
+func (s *DiskSeen) shardPath(shard int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%03x.seen", shard))
+}
+
+// inShard reports whether the shard file holds h.
+func (s *DiskSeen) inShard(shard int, h []byte) (bool, error) {
+	f, err := os.Open(s.shardPath(shard))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	buf := make([]byte, 16*1024)
+	for {
+		n, err := io.ReadFull(f, buf)
+		for rec := buf[:n-n%16]; len(rec) > 0; rec = rec[16:] {
+			if bytes.Equal(rec[:16], h) {
+				return true, nil
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// positions calls fn with h's k bloom bit positions (double hashing).
+func (s *DiskSeen) positions(h []byte, fn func(uint64) bool) {
+	h1, h2 := binary.BigEndian.Uint64(h), binary.BigEndian.Uint64(h[8:])|1
+	for i := 0; i < s.k; i++ {
+		if !fn((h1 + uint64(i)*h2) % s.bits) {
+			return
+		}
+	}
+}
+
+func (s *DiskSeen) mark(h []byte) {
+	s.positions(h, func(p uint64) bool { s.bloom[p/64] |= 1 << (p % 64); return true })
+}
+
+func (s *DiskSeen) maybe(h []byte) bool {
+	all := true
+	s.positions(h, func(p uint64) bool { all = s.bloom[p/64]&(1<<(p%64)) != 0; return all })
+	return all
+}
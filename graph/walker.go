@@ -0,0 +1,778 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// Node kinds produced by the Walker.
+const (
+	KindDomain     = "domain"
+	KindNameserver = "nameserver"
+	KindEntity     = "entity"
+	KindIPNetwork  = "ip-network"
+	KindAutnum     = "autnum"
+)
+
+// DefaultWalkConcurrency is the number of registries a Walker talks to at once
+// when WalkOptions.Concurrency is unset.
+const DefaultWalkConcurrency = 4
+
+// WalkOptions controls how far a Walker goes.
+type WalkOptions struct {
+	MaxDepth    int  // maximum recursion depth from the seed
+	FollowLinks bool // chase links[] that look like RDAP object URLs (best-effort)
+	// PivotGlue looks up each nameserver glue IP at its RIR, adding the covering
+	// network (HOSTED_IN), and follows every network's origin autnums
+	// (ANNOUNCED_BY) where the RIR reports them. Both are off by default.
+	PivotGlue bool
+	// ReverseSearch expands entities into the domains they are registrant of,
+	// where the serving registry advertises RFC 9536 reverse search.
+	ReverseSearch bool
+	// MaxRequests caps the client calls one Walk makes; 0 means unlimited.
+	MaxRequests int
+	// Concurrency bounds the fetches in flight at once, across registries.
+	// 0 means DefaultWalkConcurrency.
+	Concurrency int
+	// HostConcurrency bounds those in flight to one registry host; 0 means 1.
+	// Raising it speeds up walks that stay within one registry; pair it with
+	// the client's WithHostRateLimit to stay under the registry's limits.
+	HostConcurrency int
+	// OnPatch, when set, receives every node and edge as the walk adds it,
+	// then a PatchDone with the Summary. It runs on the walk's scheduling
+	// goroutine, never concurrently, and should not block for long.
+	OnPatch func(Patch)
+	// Seen records the visited node IDs; nil keeps them in memory for one
+	// Walk. Set a DiskSeen to bound memory in crawls of millions of nodes.
+	Seen SeenSet
+	// Truncation picks which fetches MaxRequests leaves out:
+	// TruncateDiscovery (the default) or TruncateBreadthFirst.
+	Truncation string
+	// KindQuota caps the children of each kind one node may add, e.g.
+	// {"entity": 20} for at most 20 entities per domain or network.
+	KindQuota map[string]int
+	// SampleRate, between 0 and 1, follows that share of each node's
+	// children, chosen by a hash of their IDs so reruns pick the same ones.
+	// 0 follows them all. KindQuota then caps the sampled children.
+	SampleRate float64
+}
+
+// Walker flushes the RDAP graph reachable from a seed object. Edges follow the
+// direction rules documented on Rel. A Walker is single-use per Walk call.
+//
+// Pending fetches are queued per registry host and issued round-robin across
+// hosts, one in flight per host (HostConcurrency), so a walk spanning several
+// registries keeps each of them busy instead of bursting depth-first at one
+// and tripping its rate limit while the others sit idle.
+//
+// Fetches run in parallel, but their results are merged into the graph in
+// the order they were sent, and a fetch keeps its slot until then. The same
+// responses therefore always give the same graph, edge order and patch
+// stream, however the registries' response times interleave.
+type Walker struct {
+	c    *rdap.Client
+	opts WalkOptions
+	seen SeenSet
+	g    *Graph
+	reqs int
+
+	queues map[string][]*fetch // host -> pending fetches
+	hosts  []string            // round-robin order, by first appearance
+	next   int                 // index in hosts to serve first
+
+	errs *rdap.BatchError // failed fetches of the current Walk
+
+	// report records the current Walk's completeness, and feeds the
+	// caller's when ctx has one (rdap.WithCompleteness)
+	report *rdap.CompletenessReport
+
+	// chains holds, per node reached through links, the hops that led to it,
+	// so link chains are bounded by the client's cross-host hop limit
+	chains map[string][]rdap.Hop
+
+	// docs holds, per node, the URL its document was fetched from (embedded
+	// objects inherit their owner's): the source the link policy checks the
+	// node's links against
+	docs map[string]string
+
+	// limits records what truncation, quotas and sampling left out; quota
+	// and children count each node's admitted children (by kind, and in
+	// all), seq numbers fetches in the order found
+	limits   Limits
+	quota    map[string]int
+	children map[string]int
+	seq      int
+	last     *Summary
+}
+
+// fetch is one pending client call. done runs on the scheduling goroutine,
+// so graph updates never race with each other.
+type fetch struct {
+	id   string // what is fetched, for error reports
+	kind string // node kind it yields
+	at   slot
+	seq  int
+	host string
+	do   func(context.Context) (any, error)
+	done func(obj any)
+}
+
+// NewWalker returns a Walker that fetches through c.
+func NewWalker(c *rdap.Client, opts WalkOptions) *Walker {
+	return &Walker{c: c, opts: opts}
+}
+
+// Walk builds the graph reachable from seed, a typed object returned by the
+// client (*rdap.Domain, *rdap.Nameserver, *rdap.Entity, *rdap.IPNetwork, *rdap.Autnum).
+// Individual fetch failures are skipped (see Errors); only an unusable seed is an error.
+func (w *Walker) Walk(ctx context.Context, seed any) (*Graph, error) {
+	w.seen = w.opts.Seen
+	if w.seen == nil {
+		w.seen = memorySeen{}
+	}
+	w.g = New()
+	w.g.emit = w.opts.OnPatch
+	w.reqs = 0
+	w.queues = map[string][]*fetch{}
+	w.hosts, w.next = nil, 0
+	w.errs = &rdap.BatchError{}
+	w.chains = map[string][]rdap.Hop{}
+	w.docs = map[string]string{}
+	w.limits, w.quota, w.children, w.seq = Limits{}, map[string]int{}, map[string]int{}, 0
+	ctx, w.report = rdap.WithCompleteness(ctx)
+	if err := w.walk(ctx, seed, 0); err != nil {
+		return nil, err
+	}
+	w.run(ctx)
+	w.last = w.summary()
+	if w.g.emit != nil {
+		w.g.emit(Patch{Op: PatchDone, Summary: w.last})
+		w.g.emit = nil
+	}
+	return w.g, nil
+}
+
+// Summary describes the last Walk's graph: its size, completeness and what
+// truncation, quotas and sampling left out. It is nil before a Walk.
+func (w *Walker) Summary() *Summary { return w.last }
+
+// Errors returns the fetches the last Walk skipped because they failed, or nil
+// when none did.
+func (w *Walker) Errors() *rdap.BatchError {
+	if w.errs == nil || w.errs.Len() == 0 {
+		return nil
+	}
+	return w.errs
+}
+
+// Completeness reports how complete the last Walk's graph is: the objects
+// fetched (and how many came from caches), those served stale, the fetches
+// that failed or were refused, and whether MaxRequests cut the walk short.
+func (w *Walker) Completeness() rdap.Completeness {
+	if w.report == nil {
+		return rdap.Completeness{}
+	}
+	return w.report.Report()
+}
+
+// NodeID is the graph identity for an object of kind with key (handle or name).
+func NodeID(kind, key string) string { return kind + ":" + strings.ToLower(key) }
+
+// objectID returns the node ID of a typed RDAP object.
+func objectID(obj any) string {
+	switch v := obj.(type) {
+	case *rdap.Domain:
+		return NodeID(KindDomain, v.LDHName)
+	case *rdap.Nameserver:
+		return NodeID(KindNameserver, nameserverKey(v))
+	case *rdap.Entity:
+		return NodeID(KindEntity, v.Handle)
+	case *rdap.IPNetwork:
+		return NodeID(KindIPNetwork, v.Handle)
+	case *rdap.Autnum:
+		return NodeID(KindAutnum, v.Handle)
+	}
+	return ""
+}
+
+// nameserverKey is a nameserver's identity: the A-label of its name, so the
+// punycoded and Unicode forms some registries mix are a single node. Names
+// that fail IDNA mapping fall back to their lowercase form as given.
+func nameserverKey(ns *rdap.Nameserver) string {
+	name := ns.LDHName
+	if name == "" {
+		name = ns.UnicodeName
+	}
+	if a, err := rdap.NormalizeFQDN(name); err == nil {
+		return a
+	}
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// canonicalNameserver rewrites a Unicode ldhName to its A-label, keeping the
+// Unicode form in UnicodeName, so node data carries both names.
+func canonicalNameserver(ns *rdap.Nameserver) {
+	key := nameserverKey(ns)
+	if ns.UnicodeName == "" && !isASCII(ns.LDHName) {
+		ns.UnicodeName = ns.LDHName
+	}
+	if ns.LDHName == "" || !isASCII(ns.LDHName) {
+		ns.LDHName = key
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// add marks id visited, reporting whether it is new. A seen set that fails
+// skips the node and reports it, rather than risk a revisit loop.
+func (w *Walker) add(id string) bool {
+	added, err := w.seen.Add(id)
+	if err != nil {
+		w.errs.Add(id, "", 0, err)
+		w.report.Failed(id, "", 0, err)
+		return false
+	}
+	return added
+}
+
+// spend takes one request from the MaxRequests budget, reporting false when
+// it is exhausted. Under TruncateBreadthFirst fetches are charged when sent.
+func (w *Walker) spend() bool {
+	if w.breadthFirst() {
+		return true
+	}
+	return w.charge()
+}
+
+// charge takes one request from the MaxRequests budget.
+func (w *Walker) charge() bool {
+	if w.opts.MaxRequests > 0 && w.reqs >= w.opts.MaxRequests {
+		w.report.Truncate()
+		return false
+	}
+	w.reqs++
+	return true
+}
+
+// enqueue schedules a lookup of kind k for key and calls done with the object
+// once it arrives. It reports false when the request budget is spent.
+func (w *Walker) enqueue(ctx context.Context, k rdap.Kind, key string, at slot, done func(obj any)) bool {
+	if !w.spend() {
+		return false
+	}
+	host := ""
+	if base, err := w.c.RegistryBase(ctx, k, key, ""); err == nil {
+		host = hostOf(base)
+	}
+	w.push(&fetch{id: string(k) + ":" + key, kind: nodeKind(k), at: at, host: host, done: done, do: func(ctx context.Context) (any, error) {
+		switch k {
+		case rdap.KindDomain:
+			return w.c.Domain(ctx, key)
+		case rdap.KindNameserver:
+			return w.c.Nameserver(ctx, key)
+		case rdap.KindEntity:
+			return w.c.Entity(ctx, key, "")
+		case rdap.KindIP:
+			return w.c.IP(ctx, key)
+		default:
+			return w.c.Autnum(ctx, key)
+		}
+	}})
+	return true
+}
+
+func (w *Walker) push(f *fetch) {
+	if _, ok := w.queues[f.host]; !ok {
+		w.hosts = append(w.hosts, f.host)
+	}
+	w.seq++
+	f.seq = w.seq
+	if w.breadthFirst() {
+		w.queues[f.host] = w.insert(w.queues[f.host], f)
+		return
+	}
+	w.queues[f.host] = append(w.queues[f.host], f)
+}
+
+// pick returns the index in hosts of the next host to send a fetch to, or
+// -1: round-robin over hosts with work and room under HostConcurrency, or
+// under TruncateBreadthFirst the one whose next fetch comes first.
+func (w *Walker) pick(load map[string]int) int {
+	per := max(1, w.opts.HostConcurrency)
+	best, n := -1, len(w.hosts)
+	for i := 0; i < n; i++ {
+		idx := (w.next + i) % n
+		h := w.hosts[idx]
+		if load[h] >= per || len(w.queues[h]) == 0 {
+			continue
+		}
+		if !w.breadthFirst() {
+			return idx
+		}
+		if best < 0 || w.queues[h][0].before(w.queues[w.hosts[best]][0]) {
+			best = idx
+		}
+	}
+	return best
+}
+
+func hostOf(base string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// run drains the queues: hosts are served round-robin, one fetch in flight per
+// host and at most Concurrency overall, until nothing is pending.
+func (w *Walker) run(ctx context.Context) {
+	type result struct {
+		f   *fetch
+		obj any
+		err error
+		// recorded is set when the client already reported err (a failed
+		// lookup), so it is not counted twice
+		recorded bool
+	}
+	conc := w.opts.Concurrency
+	if conc <= 0 {
+		conc = DefaultWalkConcurrency
+	}
+	type arrival struct {
+		n int // dispatch order
+		result
+	}
+	// A fetch counts against conc and its host's load from dispatch until its
+	// result is merged, in dispatch order, so which fetch is sent next never
+	// depends on which response came back first.
+	results := make(chan arrival, conc)
+	load := map[string]int{}
+	ready := map[int]result{}
+	sent, merged := 0, 0
+	for {
+		if ctx.Err() == nil {
+			for sent-merged < conc {
+				idx := w.pick(load)
+				if idx < 0 {
+					break
+				}
+				if w.breadthFirst() && !w.charge() {
+					w.dropQueued()
+					break
+				}
+				h := w.hosts[idx]
+				q := w.queues[h]
+				f := q[0]
+				w.queues[h] = q[1:]
+				load[h]++
+				w.next = (idx + 1) % len(w.hosts)
+				go func(n int) {
+					fctx, fr := rdap.WithCompleteness(ctx)
+					obj, err := f.do(fctx)
+					results <- arrival{n, result{f, obj, err, len(fr.Report().Failed) > 0}}
+				}(sent)
+				sent++
+			}
+		}
+		if sent == merged {
+			return
+		}
+		a := <-results
+		ready[a.n] = a.result
+		for {
+			r, ok := ready[merged]
+			if !ok {
+				break
+			}
+			delete(ready, merged)
+			merged++
+			load[r.f.host]--
+			switch {
+			case r.err != nil:
+				w.errs.Add(r.f.id, r.f.host, 1, r.err)
+				if !r.recorded {
+					w.report.Failed(r.f.id, r.f.host, 1, r.err)
+				}
+			case r.obj != nil:
+				r.f.done(r.obj)
+			}
+		}
+	}
+}
+
+func (w *Walker) walk(ctx context.Context, obj any, depth int) error {
+	if obj == nil || depth > w.opts.MaxDepth {
+		return nil
+	}
+	switch v := obj.(type) {
+	case *rdap.Domain:
+		id := NodeID(KindDomain, v.LDHName)
+		if w.add(id) {
+			w.g.AddNode(id, KindDomain, v)
+			w.fetchedFrom(id, v.Meta)
+			listed := map[string]bool{}
+			for _, ns := range v.Nameservers {
+				key := nameserverKey(&ns)
+				if key == "" || listed[key] {
+					continue
+				}
+				listed[key] = true
+				at, ok := w.admit(id, KindNameserver, key, depth)
+				if !ok {
+					continue
+				}
+				if !w.enqueue(ctx, rdap.KindNameserver, key, at, func(obj any) {
+					w.g.AddEdge(objectID(obj), id, RelNameserverOf)
+					_ = w.walk(ctx, obj, depth+1)
+				}) {
+					break
+				}
+			}
+			w.walkEntities(ctx, id, v.Entities, depth)
+			w.walkLinks(ctx, id, v.Links, depth)
+		}
+	case *rdap.Nameserver:
+		canonicalNameserver(v)
+		id := NodeID(KindNameserver, nameserverKey(v))
+		if w.add(id) {
+			w.g.AddNode(id, KindNameserver, v)
+			w.fetchedFrom(id, v.Meta)
+			w.walkGlue(ctx, id, v.IPAddresses, depth)
+			w.walkEntities(ctx, id, v.Entities, depth)
+			w.walkLinks(ctx, id, v.Links, depth)
+		}
+	case *rdap.IPNetwork:
+		id := NodeID(KindIPNetwork, v.Handle)
+		if w.add(id) {
+			w.g.AddNode(id, KindIPNetwork, v)
+			w.fetchedFrom(id, v.Meta)
+			w.walkOrigins(ctx, id, v.OriginAutnums, depth)
+			w.walkEntities(ctx, id, v.Entities, depth)
+			w.walkLinks(ctx, id, v.Links, depth)
+		}
+	case *rdap.Autnum:
+		id := NodeID(KindAutnum, v.Handle)
+		if w.add(id) {
+			w.g.AddNode(id, KindAutnum, v)
+			w.fetchedFrom(id, v.Meta)
+			w.walkEntities(ctx, id, v.Entities, depth)
+			w.walkLinks(ctx, id, v.Links, depth)
+		}
+	case *rdap.Entity:
+		id := NodeID(KindEntity, v.Handle)
+		if w.add(id) {
+			w.g.AddNode(id, KindEntity, v)
+			w.fetchedFrom(id, v.Meta)
+			holds := func(obj any) {
+				w.g.AddEdge(id, objectID(obj), RelHolderOf)
+				_ = w.walk(ctx, obj, depth+1)
+			}
+			for _, a := range v.Autnums {
+				at, ok := w.admit(id, KindAutnum, a.Handle, depth)
+				if !ok {
+					continue
+				}
+				if !w.enqueue(ctx, rdap.KindAutnum, a.Handle, at, holds) {
+					break
+				}
+			}
+			for _, n := range v.Networks {
+				at, ok := w.admit(id, KindIPNetwork, n.Handle, depth)
+				if !ok {
+					continue
+				}
+				if !w.enqueue(ctx, rdap.KindIP, n.Handle, at, holds) {
+					break
+				}
+			}
+			// Nested entities (e.g. a registrar's abuse contact) are members of this one.
+			for _, e := range v.Entities {
+				if e.Handle == "" {
+					continue
+				}
+				at, ok := w.admit(id, KindEntity, e.Handle, depth)
+				if !ok {
+					continue
+				}
+				if !w.entity(ctx, e, w.docs[id], at, func(obj any) {
+					w.g.AddEdge(objectID(obj), id, RelMemberOf)
+					_ = w.walk(ctx, obj, depth+1)
+				}) {
+					break
+				}
+			}
+			w.walkReverse(ctx, id, v, depth)
+			w.walkLinks(ctx, id, v.Links, depth)
+		}
+	default:
+		return errors.New("unknown seed type")
+	}
+	return nil
+}
+
+// walkOrigins follows a network's origin autnums (ANNOUNCED_BY) when
+// PivotGlue is set, like the glue pivot that usually finds the network.
+func (w *Walker) walkOrigins(ctx context.Context, netID string, asns []int64, depth int) {
+	if !w.opts.PivotGlue {
+		return
+	}
+	for _, asn := range asns {
+		key := strconv.FormatInt(asn, 10)
+		at, ok := w.admit(netID, KindAutnum, key, depth)
+		if !ok {
+			continue
+		}
+		if !w.enqueue(ctx, rdap.KindAutnum, key, at, func(obj any) {
+			w.g.AddEdge(netID, objectID(obj), RelAnnouncedBy)
+			_ = w.walk(ctx, obj, depth+1)
+		}) {
+			return
+		}
+	}
+}
+
+// walkGlue resolves a nameserver's glue addresses to their networks when
+// PivotGlue is set. Addresses in the same network yield a single edge.
+func (w *Walker) walkGlue(ctx context.Context, nsID string, addrs *rdap.IPAddresses, depth int) {
+	if !w.opts.PivotGlue || addrs == nil {
+		return
+	}
+	linked := map[string]bool{}
+	for _, ip := range append(append([]string{}, addrs.V4...), addrs.V6...) {
+		at, ok := w.admit(nsID, KindIPNetwork, ip, depth)
+		if !ok {
+			continue
+		}
+		if !w.enqueue(ctx, rdap.KindIP, ip, at, func(obj any) {
+			netID := objectID(obj)
+			if linked[netID] {
+				return
+			}
+			linked[netID] = true
+			w.g.AddEdge(nsID, netID, RelHostedIn)
+			_ = w.walk(ctx, obj, depth+1)
+		}) {
+			return
+		}
+	}
+}
+
+// walkReverse adds the domains ent is registrant of when ReverseSearch is set
+// and ent's registry supports it. Results are walked as returned by the search.
+func (w *Walker) walkReverse(ctx context.Context, entID string, ent *rdap.Entity, depth int) {
+	if !w.opts.ReverseSearch || ent.Handle == "" || !rdap.SupportsReverseSearch(ent.CommonObject) {
+		return
+	}
+	base := rdap.ServerBase(ent.CommonObject)
+	if base == "" || !w.spend() {
+		return
+	}
+	handle := ent.Handle
+	w.push(&fetch{
+		id:   "reverse_search:" + handle,
+		kind: KindDomain,
+		at:   slot{depth + 1, 0},
+		host: hostOf(base),
+		do: func(ctx context.Context) (any, error) {
+			return w.c.ReverseSearchDomains(ctx, base, handle, "registrant")
+		},
+		done: func(obj any) {
+			doms := obj.([]rdap.Domain)
+			for i := range doms {
+				d := &doms[i]
+				if d.LDHName == "" {
+					continue
+				}
+				if _, ok := w.admit(entID, KindDomain, d.LDHName, depth); !ok {
+					continue
+				}
+				w.g.AddEdge(entID, NodeID(KindDomain, d.LDHName), RelRegistrantOf)
+				_ = w.walk(ctx, d, depth+1)
+			}
+		},
+	})
+}
+
+// walkEntities fetches each embedded entity and links it to ownerID with one
+// edge per role. Roles come from the embedding object: they describe the
+// entity's relation to that owner, not the entity itself.
+func (w *Walker) walkEntities(ctx context.Context, ownerID string, ents []rdap.Entity, depth int) {
+	for _, e := range ents {
+		roles := e.Roles
+		at, ok := w.admit(ownerID, KindEntity, e.Handle, depth)
+		if !ok {
+			continue
+		}
+		if !w.entity(ctx, e, w.docs[ownerID], at, func(obj any) {
+			entID := objectID(obj)
+			for _, rel := range RoleRels(roles) {
+				w.g.AddEdge(entID, ownerID, rel)
+			}
+			_ = w.walk(ctx, obj, depth+1)
+		}) {
+			return
+		}
+	}
+}
+
+// entity passes an embedded entity to done, hydrating it first when it is a
+// stub (see rdap.Entity.IsStub); a fully embedded entity costs no request,
+// and its links count as found in src, its owner's document.
+// It reports false when the request budget is spent.
+func (w *Walker) entity(ctx context.Context, e rdap.Entity, src string, at slot, done func(obj any)) bool {
+	if !e.IsStub() {
+		if e.Handle == "" {
+			return true
+		}
+		if id := NodeID(KindEntity, e.Handle); w.docs[id] == "" {
+			w.docs[id] = src
+		}
+		done(&e)
+		return true
+	}
+	if !w.spend() {
+		return false
+	}
+	host := ""
+	if base := rdap.ServerBase(e.CommonObject); base != "" {
+		host = hostOf(base)
+	} else if base, err := w.c.RegistryBase(ctx, rdap.KindEntity, e.Handle, ""); err == nil {
+		host = hostOf(base)
+	}
+	w.push(&fetch{id: "entity:" + e.Handle, kind: KindEntity, at: at, host: host, done: done, do: func(ctx context.Context) (any, error) {
+		if err := w.c.Hydrate(ctx, &e); err != nil {
+			return nil, err
+		}
+		return &e, nil
+	}})
+	return true
+}
+
+// fetchedFrom records where node id's document was fetched from, when m (the
+// object's Meta) says: only top-level responses carry one.
+func (w *Walker) fetchedFrom(id string, m *rdap.Meta) {
+	if m != nil && m.FinalURL != "" {
+		w.docs[id] = m.FinalURL
+	}
+}
+
+// walkLinks tries to follow RDAP link relations that look like domain/entity/ns/autnum/ip.
+// This is best-effort and safe-guards with parsing & small pattern matches.
+func (w *Walker) walkLinks(ctx context.Context, fromID string, links []rdap.Link, depth int) {
+	if !w.opts.FollowLinks {
+		return
+	}
+	for _, l := range links {
+		u, k, h, ok := linkTarget(l.Href)
+		if !ok {
+			continue
+		}
+		// Several links with one rel to the same object (the registrar's
+		// RDAP copy and its web page): follow only the one Links.Best picks.
+		if rels := strings.Fields(l.Rel); len(rels) > 0 {
+			if best, ok := rdap.Links(links).Best(rels[0]); ok && best != l {
+				if _, bk, bh, ok := linkTarget(best.Href); ok && bk == k && bh == h {
+					continue
+				}
+			}
+		}
+		// Links are attacker-influenced: only follow what the client's link
+		// policy would fetch from the document they were fetched in.
+		if err := w.c.CheckLink(w.docs[fromID], l.Href); err != nil {
+			w.errs.Add(l.Href, u.Host, 0, err)
+			w.report.Failed(l.Href, u.Host, 0, err)
+			continue
+		}
+		chain := w.chains[fromID]
+		hop := rdap.Hop{From: w.docs[fromID], To: l.Href}
+		if hop.From == "" && len(chain) > 0 {
+			hop.From = chain[len(chain)-1].To
+		}
+		chain = append(chain[:len(chain):len(chain)], hop)
+		if err := w.c.CheckHops(chain); err != nil {
+			w.errs.Add(l.Href, u.Host, 0, err)
+			w.report.Failed(l.Href, u.Host, 0, err)
+			continue
+		}
+		at, ok := w.admit(fromID, nodeKind(k), h, depth)
+		if !ok {
+			continue
+		}
+		linkRel := l.Rel
+		if !w.enqueue(ctx, k, h, at, func(obj any) {
+			if t := rdap.HopTrailFrom(ctx); t != nil {
+				t.Add(hop)
+			}
+			if id := objectID(obj); w.chains[id] == nil {
+				w.chains[id] = chain
+			}
+			rel, reverse := LinkRel(linkRel)
+			if reverse {
+				w.g.AddLinkEdge(objectID(obj), fromID, rel, linkRel)
+			} else {
+				w.g.AddLinkEdge(fromID, objectID(obj), rel, linkRel)
+			}
+			_ = w.walk(ctx, obj, depth+1)
+		}) {
+			return
+		}
+	}
+}
+
+// linkTarget parses an RDAP object URL into the kind and handle it names.
+func linkTarget(href string) (u *url.URL, k rdap.Kind, h string, ok bool) {
+	if href == "" {
+		return nil, "", "", false
+	}
+	u, err := url.Parse(href)
+	if err != nil || u.Path == "" {
+		return nil, "", "", false
+	}
+	// Common RDAP paths: /domain/<name> /entity/<handle> /nameserver/<name> /autnum/<n> /ip/<cidr>
+	path := strings.ToLower(u.Path)
+	h = tail(path)
+	if h == "" {
+		return nil, "", "", false
+	}
+	switch {
+	case strings.Contains(path, "/domain/"):
+		k = rdap.KindDomain
+	case strings.Contains(path, "/nameserver/"):
+		k = rdap.KindNameserver
+	case strings.Contains(path, "/entity/"):
+		k = rdap.KindEntity
+	case strings.Contains(path, "/autnum/"):
+		k = rdap.KindAutnum
+	case strings.Contains(path, "/ip/"):
+		// Keep CIDR lengths: /ip/192.0.2.0/24 names the prefix, not "24".
+		k, h = rdap.KindIP, path[strings.Index(path, "/ip/")+len("/ip/"):]
+	default:
+		// Ignore other link types quietly
+		return nil, "", "", false
+	}
+	return u, k, h, true
+}
+
+var slashTail = regexp.MustCompile(`/([^/]+)$`)
+
+func tail(p string) string {
+	m := slashTail.FindStringSubmatch(p)
+	if len(m) == 2 {
+		return m[1]
+	}
+	return ""
+}
@@ -0,0 +1,878 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// newRegistry serves a tiny registry: bootstrap plus fixed objects by path.
+func newRegistry(t *testing.T, objects map[string]string) (*httptest.Server, *rdap.Client) {
+	t.Helper()
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/dns.json"):
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/"]]]}`, srvURL))
+			return
+		case strings.HasSuffix(r.URL.Path, "/ipv4.json"):
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["192.0.2.0/24"],["%s/"]]]}`, srvURL))
+			return
+		case strings.HasSuffix(r.URL.Path, "/asn.json"):
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["64496-64511"],["%s/"]]]}`, srvURL))
+			return
+		}
+		body, ok := objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, strings.ReplaceAll(body, "{{base}}", srvURL))
+	}))
+	t.Cleanup(ts.Close)
+	srvURL = ts.URL
+	return ts, newRegistryClient(ts, nil)
+}
+
+// newRegistryClient returns a client whose requests all land on ts. Entity
+// lookups without a TLD hint go to rdap.org, so the original host of every
+// object request is appended to hosts (when non-nil) before rerouting.
+func newRegistryClient(ts *httptest.Server, hosts *[]string) *rdap.Client {
+	var mu sync.Mutex
+	doer := doerFunc(func(r *http.Request) (*http.Response, error) {
+		if hosts != nil && !strings.HasSuffix(r.URL.Path, ".json") {
+			mu.Lock()
+			*hosts = append(*hosts, r.URL.Host)
+			mu.Unlock()
+		}
+		r.URL.Scheme, r.URL.Host = "http", strings.TrimPrefix(ts.URL, "http://")
+		return ts.Client().Do(r)
+	})
+	return rdap.New(rdap.WithHTTPDoer(doer), rdap.WithBootstrapURL(ts.URL+"/dns.json"), rdap.WithDefaultRDAPBase(ts.URL), rdap.WithMaxRetries(0))
+}
+
+type doerFunc func(*http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(r *http.Request) (*http.Response, error) { return f(r) }
+
+func hasEdge(g *Graph, from, to string, rel Rel) bool {
+	for _, e := range g.Edges {
+		if e.From == from && e.To == to && e.Rel == rel {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWalker_TypedRelationsAndDirection(t *testing.T) {
+	_, c := newRegistry(t, map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"nameservers":[{"objectClassName":"nameserver","ldhName":"ns1.a.example"}],
+			"entities":[{"objectClassName":"entity","handle":"REG","roles":["registrar","sponsor"]}],
+			"links":[{"rel":"related","href":"{{base}}/domain/b.example"}]}`,
+		"/domain/b.example":         `{"objectClassName":"domain","ldhName":"b.example"}`,
+		"/nameserver/ns1.a.example": `{"objectClassName":"nameserver","ldhName":"ns1.a.example"}`,
+		"/entity/REG": `{"objectClassName":"entity","handle":"REG",
+			"entities":[{"objectClassName":"entity","handle":"ABUSE","roles":["abuse"]}]}`,
+		"/entity/ABUSE": `{"objectClassName":"entity","handle":"ABUSE"}`,
+	})
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewWalker(c, WalkOptions{MaxDepth: 5, FollowLinks: true}).Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		from, to string
+		rel      Rel
+	}{
+		{"nameserver:ns1.a.example", "domain:a.example", RelNameserverOf},
+		{"entity:reg", "domain:a.example", RelRegistrarOf},
+		{"entity:reg", "domain:a.example", RelSponsorOf},
+		{"entity:abuse", "entity:reg", RelMemberOf},
+		{"domain:a.example", "domain:b.example", RelRelatedTo},
+	}
+	for _, ck := range checks {
+		if !hasEdge(g, ck.from, ck.to, ck.rel) {
+			t.Fatalf("missing edge %s -%s-> %s; have %+v", ck.from, ck.rel, ck.to, g.Edges)
+		}
+	}
+	for _, e := range g.Edges {
+		if e.Rel == RelRelatedTo && e.LinkRel != "related" {
+			t.Fatalf("link-derived edge should keep raw rel: %+v", e)
+		}
+	}
+}
+
+// A link's value is the server's claim; the link policy judges it by the URL
+// its document was fetched from, so a value naming another registrable domain
+// does not open that domain up.
+func TestWalker_LinkSourceIsFetchURL(t *testing.T) {
+	_, c := newRegistry(t, map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"links":[{"rel":"related","value":"https://rdap.evil.example/domain/a.example","href":"https://whois.evil.example/domain/c.example"}]}`,
+		"/domain/c.example": `{"objectClassName":"domain","ldhName":"c.example"}`,
+	})
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWalker(c, WalkOptions{MaxDepth: 5, FollowLinks: true})
+	g, err := w.Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lb *rdap.ErrLinkBlocked
+	if err := w.Errors(); !errors.As(err, &lb) || lb.From != seed.Meta.FinalURL {
+		t.Fatalf("errors = %v, want *ErrLinkBlocked from %s", err, seed.Meta.FinalURL)
+	}
+	if _, ok := g.Nodes["domain:c.example"]; ok {
+		t.Fatal("followed a link the policy only allows by its value")
+	}
+}
+
+// The queries the README and the query language documentation show run
+// against a walked graph, so they keep up with edge directions.
+func TestQuery_DocumentedExamplesOnWalk(t *testing.T) {
+	_, c := newRegistry(t, map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"nameservers":[{"objectClassName":"nameserver","ldhName":"ns1.a.example"}],
+			"entities":[{"objectClassName":"entity","handle":"REG","roles":["registrar"]},
+				{"objectClassName":"entity","handle":"HOLDER","roles":["registrant"]}]}`,
+		"/nameserver/ns1.a.example": `{"objectClassName":"nameserver","ldhName":"ns1.a.example",
+			"entities":[{"objectClassName":"entity","handle":"NOC","roles":["abuse"]}]}`,
+		"/entity/REG":    `{"objectClassName":"entity","handle":"REG","roles":["registrar"]}`,
+		"/entity/HOLDER": `{"objectClassName":"entity","handle":"HOLDER","roles":["registrant"]}`,
+		"/entity/NOC":    `{"objectClassName":"entity","handle":"NOC","roles":["abuse"]}`,
+	})
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewWalker(c, WalkOptions{MaxDepth: 5}).Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for q, want := range map[string][][]string{
+		// README
+		"MATCH e:entity-[REGISTRAR_OF]->domain RETURN e.handle": {{"REG"}},
+		// graph/query.go and rdapctl graph query --help
+		"MATCH e:entity-[REGISTRANT_OF]->d:domain RETURN e.handle, d.ldhName":                            {{"HOLDER", "a.example"}},
+		"MATCH e:entity[role~abuse]->ns:nameserver-[NAMESERVER_OF]->d:domain RETURN d.ldhName, e.handle": {{"a.example", "NOC"}},
+		"MATCH e:entity-[REGISTRANT_OF]->domain RETURN e.handle":                                         {{"HOLDER"}},
+	} {
+		res, err := g.Query(q)
+		if err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+		if !reflect.DeepEqual(res.Rows, want) {
+			t.Errorf("%s: got %v, want %v", q, res.Rows, want)
+		}
+	}
+}
+
+func TestWalker_NameserverIDNDedup(t *testing.T) {
+	_, c := newRegistry(t, map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"nameservers":[{"objectClassName":"nameserver","ldhName":"ns1.xn--bcher-kva.example"},
+				{"objectClassName":"nameserver","ldhName":"NS1.bücher.example."}]}`,
+		"/nameserver/ns1.xn--bcher-kva.example": `{"objectClassName":"nameserver","ldhName":"ns1.bücher.example"}`,
+	})
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewWalker(c, WalkOptions{MaxDepth: 5}).Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for id, n := range g.Nodes {
+		if n.Kind == KindNameserver {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) != 1 || ids[0] != "nameserver:ns1.xn--bcher-kva.example" {
+		t.Fatalf("nameserver nodes = %v", ids)
+	}
+	ns := g.Nodes[ids[0]].Data.(*rdap.Nameserver)
+	if ns.LDHName != "ns1.xn--bcher-kva.example" || ns.UnicodeName != "ns1.bücher.example" {
+		t.Fatalf("node data should keep both names: %+v", ns)
+	}
+	n := 0
+	for _, e := range g.Edges {
+		if e.Rel == RelNameserverOf {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Fatalf("want one NAMESERVER_OF edge, got %d", n)
+	}
+}
+
+func TestRoleRelsAndLinkRel(t *testing.T) {
+	if got := RoleRels([]string{"Registrant", "registrant", "bogus"}); len(got) != 1 || got[0] != RelRegistrantOf {
+		t.Fatalf("RoleRels: %v", got)
+	}
+	if got := RoleRels(nil); len(got) != 1 || got[0] != RelContactOf {
+		t.Fatalf("RoleRels(nil): %v", got)
+	}
+	if rel, rev := LinkRel("up"); rel != RelParentNetwork || !rev {
+		t.Fatalf("up: %v %v", rel, rev)
+	}
+}
+
+func TestWalker_PivotGlue(t *testing.T) {
+	objects := map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"nameservers":[{"objectClassName":"nameserver","ldhName":"ns1.a.example"}]}`,
+		"/nameserver/ns1.a.example": `{"objectClassName":"nameserver","ldhName":"ns1.a.example",
+			"ipAddresses":{"v4":["192.0.2.53","192.0.2.54"]}}`,
+		"/ip/192.0.2.53": `{"objectClassName":"ip network","handle":"NET-192-0-2-0-1","startAddress":"192.0.2.0",
+			"endAddress":"192.0.2.255","arin_originas0_originautnums":[64500]}`,
+		"/ip/192.0.2.54": `{"objectClassName":"ip network","handle":"NET-192-0-2-0-1","startAddress":"192.0.2.0",
+			"endAddress":"192.0.2.255","arin_originas0_originautnums":[64500]}`,
+		"/autnum/64500": `{"objectClassName":"autnum","handle":"AS64500","startAutnum":64500,"endAutnum":64500}`,
+	}
+	_, c := newRegistry(t, objects)
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := NewWalker(c, WalkOptions{MaxDepth: 5}).Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.Nodes["ip-network:net-192-0-2-0-1"]; ok {
+		t.Fatal("glue pivot must be opt-in")
+	}
+	// So is the origin autnum pivot, also from a network seed.
+	network, err := c.IP(ctx, "192.0.2.53")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err = NewWalker(c, WalkOptions{MaxDepth: 5}).Walk(ctx, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.Nodes["autnum:as64500"]; ok || len(g.Edges) != 0 {
+		t.Fatalf("origin autnum pivot must be opt-in: %+v", g.Edges)
+	}
+
+	g, err = NewWalker(c, WalkOptions{MaxDepth: 5, PivotGlue: true}).Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasEdge(g, "nameserver:ns1.a.example", "ip-network:net-192-0-2-0-1", RelHostedIn) {
+		t.Fatalf("missing HOSTED_IN edge: %+v", g.Edges)
+	}
+	if !hasEdge(g, "ip-network:net-192-0-2-0-1", "autnum:as64500", RelAnnouncedBy) {
+		t.Fatalf("missing ANNOUNCED_BY edge: %+v", g.Edges)
+	}
+	n := 0
+	for _, e := range g.Edges {
+		if e.Rel == RelHostedIn {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Fatalf("glue addresses in one network should yield one edge, got %d", n)
+	}
+}
+
+func TestWalker_ReverseSearchBoundedByMaxRequests(t *testing.T) {
+	objects := map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"entities":[{"objectClassName":"entity","handle":"H1","roles":["registrant"]}]}`,
+		"/entity/H1": `{"objectClassName":"entity","handle":"H1","rdapConformance":["rdap_level_0","reverse_search"],
+			"links":[{"rel":"self","href":"{{base}}/entity/H1"}]}`,
+		"/domains/reverse_search/entity": `{"domainSearchResults":[
+			{"objectClassName":"domain","ldhName":"b.example"},
+			{"objectClassName":"domain","ldhName":"c.example"}]}`,
+	}
+	ts, c := newRegistry(t, objects)
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := NewWalker(c, WalkOptions{MaxDepth: 5, ReverseSearch: true}).Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{"domain:a.example", "domain:b.example", "domain:c.example"} {
+		if !hasEdge(g, "entity:h1", d, RelRegistrantOf) {
+			t.Fatalf("missing REGISTRANT_OF -> %s: %+v", d, g.Edges)
+		}
+	}
+
+	// One request for the entity; the search itself is over budget.
+	w := NewWalker(c, WalkOptions{MaxDepth: 5, ReverseSearch: true, MaxRequests: 1})
+	g, err = w.Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.Nodes["domain:b.example"]; ok {
+		t.Fatal("reverse search should be skipped once MaxRequests is spent")
+	}
+	if rep := w.Completeness(); !rep.Truncated || rep.Complete() {
+		t.Fatalf("completeness = %+v", rep)
+	}
+
+	if base := rdap.ServerBase(rdap.CommonObject{ObjectClassName: "entity",
+		Links: []rdap.Link{{Rel: "self", Href: ts.URL + "/rdap/entity/H1"}}}); base != ts.URL+"/rdap" {
+		t.Fatalf("ServerBase = %q", base)
+	}
+}
+
+func TestWalker_RoundRobinAcrossHosts(t *testing.T) {
+	objects := map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"nameservers":[{"ldhName":"ns1.a.example"},{"ldhName":"ns2.a.example"},{"ldhName":"ns3.a.example"}],
+			"entities":[{"handle":"E1","roles":["technical"]},{"handle":"E2","roles":["technical"]},{"handle":"E3","roles":["technical"]}]}`,
+	}
+	for _, n := range []string{"1", "2", "3"} {
+		objects["/nameserver/ns"+n+".a.example"] = `{"objectClassName":"nameserver","ldhName":"ns` + n + `.a.example"}`
+		objects["/entity/E"+n] = `{"objectClassName":"entity","handle":"E` + n + `"}`
+	}
+	ts, c := newRegistry(t, objects)
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hosts []string
+	c = newRegistryClient(ts, &hosts)
+	g, err := NewWalker(c, WalkOptions{MaxDepth: 2, Concurrency: 1}).Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 7 || len(hosts) != 6 {
+		t.Fatalf("nodes=%d requests=%v", len(g.Nodes), hosts)
+	}
+	// Nameservers resolve to the registry, entities (no TLD hint) to rdap.org:
+	// the scheduler must alternate between them rather than drain one first.
+	for i := 1; i < len(hosts); i++ {
+		if hosts[i] == hosts[i-1] {
+			t.Fatalf("consecutive requests to %s: %v", hosts[i], hosts)
+		}
+	}
+}
+
+func TestWalker_HydratesOnlyStubEntities(t *testing.T) {
+	objects := map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"entities":[
+				{"objectClassName":"entity","handle":"FULL","roles":["registrant"],"vcardArray":["vcard",[["fn",{},"text","Inline"]]]},
+				{"objectClassName":"entity","handle":"STUB","roles":["technical"],"links":[{"rel":"self","href":"{{base}}/rdap/entity/STUB"}]}]}`,
+		"/rdap/entity/STUB": `{"objectClassName":"entity","handle":"STUB","vcardArray":["vcard",[["fn",{},"text","Fetched"]]]}`,
+	}
+	ts, c := newRegistry(t, objects)
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hosts []string
+	c = newRegistryClient(ts, &hosts)
+	g, err := NewWalker(c, WalkOptions{MaxDepth: 3}).Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 1 {
+		t.Fatalf("want one request (the stub's self link), got %v", hosts)
+	}
+	if !hasEdge(g, "entity:full", "domain:a.example", RelRegistrantOf) || !hasEdge(g, "entity:stub", "domain:a.example", RelTechnicalOf) {
+		t.Fatalf("edges: %+v", g.Edges)
+	}
+	stub := g.Nodes["entity:stub"].Data.(*rdap.Entity)
+	if stub.IsStub() || len(stub.Roles) != 1 {
+		t.Fatalf("stub not hydrated: %+v", stub)
+	}
+}
+
+func TestWalker_ReportsFailedFetches(t *testing.T) {
+	ts, c := newRegistry(t, map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"nameservers":[{"objectClassName":"nameserver","ldhName":"ns1.a.example"},
+				{"objectClassName":"nameserver","ldhName":"ns2.a.example"}]}`,
+		"/nameserver/ns1.a.example": `{"objectClassName":"nameserver","ldhName":"ns1.a.example"}`,
+	})
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWalker(c, WalkOptions{MaxDepth: 5})
+	if _, err := w.Walk(ctx, seed); err != nil {
+		t.Fatal(err)
+	}
+	be := w.Errors()
+	if be == nil || len(be.Items) != 1 {
+		t.Fatalf("errors = %v", be)
+	}
+	it := be.Items[0]
+	if it.ID != "nameserver:ns2.a.example" || it.Registry != strings.TrimPrefix(ts.URL, "http://") || rdap.ErrorCode(it.Err) != rdap.CodeNotFound {
+		t.Fatalf("item = %+v (%s)", it, rdap.ErrorCode(it.Err))
+	}
+	// The report has the failed lookup once, next to what was fetched.
+	if rep := w.Completeness(); rep.Fetched != 1 || len(rep.Failed) != 1 || rep.Complete() {
+		t.Fatalf("completeness = %+v", rep)
+	}
+}
+
+func TestWalker_PatchStream(t *testing.T) {
+	_, c := newRegistry(t, map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"nameservers":[{"objectClassName":"nameserver","ldhName":"ns1.a.example"}],
+			"entities":[{"objectClassName":"entity","handle":"REG","roles":["registrar"]}]}`,
+		"/nameserver/ns1.a.example": `{"objectClassName":"nameserver","ldhName":"ns1.a.example"}`,
+		"/entity/REG":               `{"objectClassName":"entity","handle":"REG"}`,
+	})
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var patches []Patch
+	g, err := NewWalker(c, WalkOptions{MaxDepth: 5, OnPatch: func(p Patch) { patches = append(patches, p) }}).Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) == 0 || patches[0].Op != PatchAddNode || patches[0].Node.ID != "domain:a.example" {
+		t.Fatalf("stream should open with the seed node: %+v", patches)
+	}
+	last := patches[len(patches)-1]
+	if last.Op != PatchDone || last.Summary == nil || last.Summary.Nodes != len(g.Nodes) || last.Summary.Edges != len(g.Edges) || !last.Summary.Completeness.Complete() {
+		t.Fatalf("last patch = %+v, want a summary of %d nodes and %d edges", last, len(g.Nodes), len(g.Edges))
+	}
+	replayed := New()
+	for _, p := range patches {
+		replayed.Apply(p)
+	}
+	if len(replayed.Nodes) != len(g.Nodes) || len(replayed.Edges) != len(g.Edges) {
+		t.Fatalf("replayed %d nodes, %d edges; walk built %d, %d", len(replayed.Nodes), len(replayed.Edges), len(g.Nodes), len(g.Edges))
+	}
+	for i, e := range g.Edges {
+		if replayed.Edges[i] != e {
+			t.Fatalf("edge %d = %+v, want %+v", i, replayed.Edges[i], e)
+		}
+	}
+}
+
+func TestDiskSeen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenDiskSeen(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5000; i++ {
+		if added, err := s.Add(fmt.Sprintf("domain:d%d.example", i)); err != nil || !added {
+			t.Fatalf("Add #%d = %v, %v; want new", i, added, err)
+		}
+	}
+	if added, _ := s.Add("domain:d42.example"); added {
+		t.Fatal("a recorded ID was added again")
+	}
+	// Reopening resumes the set.
+	s, err = OpenDiskSeen(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 5000 {
+		t.Fatalf("Len = %d after reopening, want 5000", s.Len())
+	}
+	if added, _ := s.Add("domain:d4999.example"); added {
+		t.Fatal("reopened set forgot an ID")
+	}
+	if added, _ := s.Add("domain:new.example"); !added {
+		t.Fatal("new ID reported as seen")
+	}
+}
+
+func TestDiskSeen_TornRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenDiskSeen(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add("domain:a.example"); err != nil {
+		t.Fatal(err)
+	}
+	shards, _ := filepath.Glob(filepath.Join(dir, "*.seen"))
+	if len(shards) != 1 {
+		t.Fatalf("shards = %v", shards)
+	}
+	// A run killed mid-write leaves part of a record behind.
+	f, err := os.OpenFile(shards[0], os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte("torn..."))
+	_ = f.Close()
+
+	// An ID of the same shard, appended after reopening, must stay readable.
+	var other string
+	for i := 0; other == ""; i++ {
+		id := fmt.Sprintf("domain:d%d.example", i)
+		if sum := sha256.Sum256([]byte(id)); s.shardPath(int(binary.BigEndian.Uint16(sum[:])%diskSeenShards)) == shards[0] {
+			other = id
+		}
+	}
+	if s, err = OpenDiskSeen(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(shards[0]); err != nil || fi.Size() != 16 {
+		t.Fatalf("shard not cut back to whole records: %v %v", fi.Size(), err)
+	}
+	if added, err := s.Add(other); err != nil || !added {
+		t.Fatalf("Add(%s) = %v, %v", other, added, err)
+	}
+	if s, err = OpenDiskSeen(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"domain:a.example", other} {
+		if added, err := s.Add(id); err != nil || added {
+			t.Fatalf("%s forgotten after a torn record: %v", id, err)
+		}
+	}
+	if s.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", s.Len())
+	}
+}
+
+func TestWalker_SharedSeenSet(t *testing.T) {
+	_, c := newRegistry(t, map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"entities":[{"objectClassName":"entity","handle":"REG","roles":["registrar"]}]}`,
+		"/domain/b.example": `{"objectClassName":"domain","ldhName":"b.example",
+			"entities":[{"objectClassName":"entity","handle":"REG","roles":["registrar"]}]}`,
+		"/entity/REG": `{"objectClassName":"entity","handle":"REG"}`,
+	})
+	seen, err := OpenDiskSeen(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	w := NewWalker(c, WalkOptions{MaxDepth: 3, Seen: seen})
+	for _, name := range []string{"a.example", "b.example"} {
+		d, err := c.Domain(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := w.Walk(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, hasReg := g.Nodes["entity:reg"]
+		if want := name == "a.example"; hasReg != want {
+			t.Fatalf("walk of %s: registrar node present = %v, want %v", name, hasReg, want)
+		}
+	}
+	if seen.Len() != 3 {
+		t.Fatalf("seen %d IDs, want 3", seen.Len())
+	}
+}
+
+func TestWalker_KindQuota(t *testing.T) {
+	var ents []string
+	for i := 1; i <= 5; i++ {
+		ents = append(ents, fmt.Sprintf(`{"objectClassName":"entity","handle":"C%d","roles":["technical"],"vcardArray":["vcard",[["fn",{},"text","C%d"]]]}`, i, i))
+	}
+	_, c := newRegistry(t, map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example","entities":[` + strings.Join(ents, ",") + `]}`,
+	})
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWalker(c, WalkOptions{MaxDepth: 2, KindQuota: map[string]int{KindEntity: 2}})
+	g, err := w.Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 3 {
+		t.Fatalf("nodes = %v, want the domain and 2 entities", g.Nodes)
+	}
+	if l := w.Summary().Limits; l == nil || l.OverQuota[KindEntity] != 3 || l.Truncation != "" {
+		t.Fatalf("limits = %+v", l)
+	}
+}
+
+func TestWalker_BreadthFirstTruncation(t *testing.T) {
+	objects := map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"nameservers":[{"ldhName":"ns1.a.example"},{"ldhName":"ns2.a.example"}]}`,
+	}
+	for _, n := range []string{"1", "2"} {
+		objects["/nameserver/ns"+n+".a.example"] = `{"objectClassName":"nameserver","ldhName":"ns` + n + `.a.example",
+			"entities":[{"handle":"T` + n + `A","roles":["technical"]},{"handle":"T` + n + `B","roles":["technical"]}]}`
+		for _, s := range []string{"A", "B"} {
+			objects["/entity/T"+n+s] = `{"objectClassName":"entity","handle":"T` + n + s + `"}`
+		}
+	}
+	_, c := newRegistry(t, objects)
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entities := func(g *Graph) (per [2]int) {
+		for id := range g.Nodes {
+			if strings.HasPrefix(id, "entity:t1") {
+				per[0]++
+			} else if strings.HasPrefix(id, "entity:t2") {
+				per[1]++
+			}
+		}
+		return per
+	}
+
+	// Discovery order spends the budget on the first nameserver's entities.
+	w := NewWalker(c, WalkOptions{MaxDepth: 3, MaxRequests: 4, Concurrency: 1})
+	g, err := w.Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if per := entities(g); per != [2]int{2, 0} {
+		t.Fatalf("discovery: entities per nameserver = %v", per)
+	}
+	if l := w.Summary().Limits; l == nil || l.Truncation != TruncateDiscovery {
+		t.Fatalf("discovery: limits = %+v", l)
+	}
+
+	// Breadth-first gives each nameserver one before either gets a second.
+	w = NewWalker(c, WalkOptions{MaxDepth: 3, MaxRequests: 4, Concurrency: 1, Truncation: TruncateBreadthFirst})
+	g, err = w.Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if per := entities(g); per != [2]int{1, 1} {
+		t.Fatalf("breadth-first: entities per nameserver = %v", per)
+	}
+	l := w.Summary().Limits
+	if l == nil || l.Truncation != TruncateBreadthFirst || l.Dropped[KindEntity] != 2 {
+		t.Fatalf("breadth-first: limits = %+v", l)
+	}
+	if !w.Completeness().Truncated {
+		t.Fatal("breadth-first walk should report truncation")
+	}
+}
+
+func TestWalker_Sampling(t *testing.T) {
+	var ents []string
+	for i := 1; i <= 20; i++ {
+		ents = append(ents, fmt.Sprintf(`{"objectClassName":"entity","handle":"S%d","roles":["technical"],"vcardArray":["vcard",[["fn",{},"text","S%d"]]]}`, i, i))
+	}
+	_, c := newRegistry(t, map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example","entities":[` + strings.Join(ents, ",") + `]}`,
+	})
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []int
+	for run := 0; run < 2; run++ {
+		w := NewWalker(c, WalkOptions{MaxDepth: 2, SampleRate: 0.5})
+		g, err := w.Walk(ctx, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := w.Summary().Limits
+		if l == nil || l.SampleRate != 0.5 || len(g.Nodes)-1+l.SampledOut[KindEntity] != 20 {
+			t.Fatalf("nodes=%d limits=%+v", len(g.Nodes), l)
+		}
+		kept = append(kept, len(g.Nodes))
+	}
+	// Sampling hashes the objects rather than drawing at random, so a rerun
+	// keeps the same ones.
+	if kept[0] != kept[1] || kept[0] == 1 || kept[0] == 21 {
+		t.Fatalf("kept %v nodes across runs", kept)
+	}
+
+	// With a quota too, the quota is filled from the sample.
+	w := NewWalker(c, WalkOptions{MaxDepth: 2, SampleRate: 0.5, KindQuota: map[string]int{KindEntity: 3}})
+	g, err := w.Walk(ctx, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := w.Summary().Limits
+	if len(g.Nodes) != 4 || l.SampledOut[KindEntity] != 21-kept[0] || l.OverQuota[KindEntity] != kept[0]-4 {
+		t.Fatalf("nodes=%d limits=%+v", len(g.Nodes), l)
+	}
+}
+
+// slowRegistry serves objects like newRegistry, but each object request
+// sleeps delay(path) first and the busiest moment per original host is
+// recorded in peak.
+func slowRegistry(t *testing.T, objects map[string]string, delay func(path string) time.Duration, peak map[string]int) *rdap.Client {
+	ts, _ := newRegistry(t, objects)
+	var mu sync.Mutex
+	inflight := map[string]int{}
+	doer := doerFunc(func(r *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(r.URL.Path, ".json") {
+			host := r.URL.Host
+			mu.Lock()
+			inflight[host]++
+			peak[host] = max(peak[host], inflight[host])
+			mu.Unlock()
+			time.Sleep(delay(r.URL.Path))
+			defer func() {
+				mu.Lock()
+				inflight[host]--
+				mu.Unlock()
+			}()
+		}
+		r.URL.Scheme, r.URL.Host = "http", strings.TrimPrefix(ts.URL, "http://")
+		return ts.Client().Do(r)
+	})
+	return rdap.New(rdap.WithHTTPDoer(doer), rdap.WithBootstrapURL(ts.URL+"/dns.json"), rdap.WithDefaultRDAPBase(ts.URL), rdap.WithMaxRetries(0))
+}
+
+func TestWalker_DeterministicMerge(t *testing.T) {
+	objects := map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"nameservers":[{"ldhName":"ns1.a.example"},{"ldhName":"ns2.a.example"},{"ldhName":"ns3.a.example"}],
+			"entities":[{"handle":"E1","roles":["technical"]},{"handle":"E2","roles":["technical"]},{"handle":"E3","roles":["technical"]}]}`,
+	}
+	for _, n := range []string{"1", "2", "3"} {
+		objects["/nameserver/ns"+n+".a.example"] = `{"objectClassName":"nameserver","ldhName":"ns` + n + `.a.example",
+			"entities":[{"handle":"T` + n + `","roles":["technical"]}]}`
+		objects["/entity/E"+n] = `{"objectClassName":"entity","handle":"E` + n + `"}`
+		objects["/entity/T"+n] = `{"objectClassName":"entity","handle":"T` + n + `"}`
+	}
+	ctx := context.Background()
+
+	var want []string
+	for run := 0; run < 4; run++ {
+		// Each run answers in a different order: later objects first on odd runs.
+		delay := func(path string) time.Duration {
+			d := time.Duration(path[len(path)-1]%4) * 5 * time.Millisecond
+			if run%2 == 1 {
+				d = 20*time.Millisecond - d
+			}
+			return d
+		}
+		peak := map[string]int{}
+		c := slowRegistry(t, objects, delay, peak)
+		seed, err := c.Domain(ctx, "a.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		w := NewWalker(c, WalkOptions{MaxDepth: 3, Concurrency: 6, HostConcurrency: 3, OnPatch: func(p Patch) {
+			switch p.Op {
+			case PatchAddNode:
+				got = append(got, "node "+p.Node.ID)
+			case PatchAddEdge:
+				got = append(got, fmt.Sprintf("edge %s %s %s", p.Edge.From, p.Edge.Rel, p.Edge.To))
+			}
+		}})
+		g, err := w.Walk(ctx, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(g.Nodes) != 10 {
+			t.Fatalf("run %d: %d nodes", run, len(g.Nodes))
+		}
+		if peak["rdap.org"] < 2 {
+			t.Errorf("run %d: at most %d entity lookups in flight, want parallel ones", run, peak["rdap.org"])
+		}
+		if run == 0 {
+			want = got
+		} else if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("run %d merged differently:\n%s\nwant:\n%s", run, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
+
+func TestWalker_HostConcurrencyDefault(t *testing.T) {
+	objects := map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"entities":[{"handle":"E1","roles":["technical"]},{"handle":"E2","roles":["technical"]},{"handle":"E3","roles":["technical"]}]}`,
+	}
+	for _, n := range []string{"1", "2", "3"} {
+		objects["/entity/E"+n] = `{"objectClassName":"entity","handle":"E` + n + `"}`
+	}
+	peak := map[string]int{}
+	c := slowRegistry(t, objects, func(string) time.Duration { return 10 * time.Millisecond }, peak)
+	ctx := context.Background()
+	seed, err := c.Domain(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWalker(c, WalkOptions{MaxDepth: 2, Concurrency: 8}).Walk(ctx, seed); err != nil {
+		t.Fatal(err)
+	}
+	if peak["rdap.org"] != 1 {
+		t.Fatalf("%d requests in flight to one host, want 1", peak["rdap.org"])
+	}
+}
+
+func TestWalker_ConcurrentWalksShareSeenSet(t *testing.T) {
+	_, c := newRegistry(t, map[string]string{
+		"/domain/a.example": `{"objectClassName":"domain","ldhName":"a.example",
+			"entities":[{"objectClassName":"entity","handle":"REG","roles":["registrar"]}]}`,
+		"/domain/b.example": `{"objectClassName":"domain","ldhName":"b.example",
+			"entities":[{"objectClassName":"entity","handle":"REG","roles":["registrar"]}]}`,
+		"/entity/REG": `{"objectClassName":"entity","handle":"REG"}`,
+	})
+	ctx := context.Background()
+	seen := NewMemorySeen()
+	var wg sync.WaitGroup
+	graphs := make([]*Graph, 2)
+	for i, name := range []string{"a.example", "b.example"} {
+		d, err := c.Domain(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g, err := NewWalker(c, WalkOptions{MaxDepth: 3, Seen: seen}).Walk(ctx, d)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			graphs[i] = g
+		}()
+	}
+	wg.Wait()
+	regs := 0
+	for _, g := range graphs {
+		if _, ok := g.Nodes["entity:reg"]; ok {
+			regs++
+		}
+	}
+	if regs != 1 {
+		t.Fatalf("registrar walked %d times, want once", regs)
+	}
+}
@@ -0,0 +1,97 @@
+package rdapclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// h3RetryAfter is how long a host that failed over HTTP/3 is sent straight to
+// the fallback transport before QUIC is tried again.
+const h3RetryAfter = 10 * time.Minute
+
+// h3HandshakeTimeout bounds a QUIC handshake, so a network that drops UDP
+// costs a request this long before it falls back.
+const h3HandshakeTimeout = 3 * time.Second
+
+// newHTTP3Transport returns the HTTP/3 RoundTripper of WithHTTP3.
+func newHTTP3Transport() http.RoundTripper {
+	return &http3.Transport{QUICConfig: &quic.Config{HandshakeIdleTimeout: h3HandshakeTimeout}}
+}
+
+// h3Doer sends https requests over an HTTP/3 client first and falls back
+// to next (HTTP/2 or 1.1) when QUIC fails, e.g. because UDP is blocked or the
+// registry does not speak it. The protocol each host last answered with and
+// the number of fallbacks are reported by Health.
+type h3Doer struct {
+	h3   Doer // an *http.Client around the HTTP/3 RoundTripper
+	next Doer
+	now  func() time.Time
+
+	mu        sync.Mutex
+	broken    map[string]time.Time // host -> retry QUIC after
+	protos    map[string]string    // host -> last response protocol
+	fallbacks int
+}
+
+func newH3Doer(h3, next Doer, now func() time.Time) *h3Doer {
+	return &h3Doer{h3: h3, next: next, now: now, broken: map[string]time.Time{}, protos: map[string]string{}}
+}
+
+func (d *h3Doer) Do(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if req.URL.Scheme == "https" && d.usable(host) {
+		resp, err := d.h3.Do(req)
+		if err == nil {
+			d.record(host, resp.Proto, false)
+			return resp, nil
+		}
+		// A response with the error means QUIC worked and the redirect
+		// policy refused where it led; the fallback would refuse it too.
+		if resp != nil || req.Context().Err() != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		d.broken[host] = d.now().Add(h3RetryAfter)
+		d.mu.Unlock()
+		resp, err = d.next.Do(req.Clone(req.Context()))
+		if err == nil {
+			d.record(host, resp.Proto, true)
+		}
+		return resp, err
+	}
+	resp, err := d.next.Do(req)
+	if err == nil {
+		d.record(host, resp.Proto, false)
+	}
+	return resp, err
+}
+
+func (d *h3Doer) usable(host string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	until, ok := d.broken[host]
+	return !ok || d.now().After(until)
+}
+
+func (d *h3Doer) record(host, proto string, fellBack bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.protos[host] = proto
+	if fellBack {
+		d.fallbacks++
+	}
+}
+
+func (d *h3Doer) stats() (map[string]string, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make(map[string]string, len(d.protos))
+	for h, p := range d.protos {
+		out[h] = p
+	}
+	return out, d.fallbacks
+}
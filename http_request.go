@@ -1,29 +1,140 @@
 package rdapclient
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"time"
 )
 
 // getJSON performs a GET with validators, caching, retries & rate-limit handling.
+// Under WithFreshData the cached body is only used after the server answers 304.
+// Concurrent calls for the same URL share one request and its retries.
+// The header is nil for a fresh cache hit, and carries a Warning for an
+// expired copy served because the registry failed (WithStaleIfError).
 func (c *Client) getJSON(ctx context.Context, u string) (map[string]any, http.Header, error) {
+	f, err := c.getResponse(ctx, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.cached && !f.stale {
+		return f.m, nil, nil
+	}
+	return f.m, f.hdr, nil
+}
+
+// getResponse is getJSON with the whole response: body, headers, final URL
+// and fetch time, for Meta.
+func (c *Client) getResponse(ctx context.Context, u string) (fetched, error) {
+	// remembered 404/410 or 451
+	if err := c.respCache.Negative(u); err != nil && !wantsFresh(ctx) {
+		c.log.DebugContext(ctx, "rdap negative cache hit", "url", u)
+		return fetched{}, err
+	}
 	// strong cache hit (fresh TTL)
-	if body, ok := c.respCache.Get(u); ok {
-		var m map[string]any
-		if err := json.Unmarshal(body, &m); err == nil {
-			return m, nil, nil
+	if body, ok := c.respCache.Get(u); ok && !wantsFresh(ctx) {
+		if m, err := decodeBody(body); err == nil {
+			c.log.DebugContext(ctx, "rdap cache hit", "url", u)
+			return c.cachedResponse(u, m, body), nil
+		}
+	}
+	for {
+		v, err, shared := c.flights.Do(ctx, "GET "+u, func() (any, error) { return c.fetchJSON(ctx, u) })
+		if shared && errors.Is(err, context.Canceled) && ctx.Err() == nil {
+			continue // the caller we were waiting on gave up; try ourselves
+		}
+		if err != nil {
+			if body, ok := c.staleAnswer(ctx, u, err); ok {
+				if m, derr := decodeBody(body); derr == nil {
+					f := c.cachedResponse(u, m, body)
+					f.stale = true
+					if f.hdr == nil {
+						f.hdr = http.Header{}
+					}
+					f.hdr.Set("Warning", staleWarning)
+					return f, nil
+				}
+			}
+			return fetched{}, err
 		}
+		f := v.(fetched)
+		if !shared {
+			return f, nil
+		}
+		c.log.DebugContext(ctx, "rdap GET shared with a concurrent caller", "url", u)
+		// The decoded map belongs to the caller that fetched it; decode our own.
+		f.m, err = decodeBody(f.body)
+		return f, err
+	}
+}
+
+// fetched is the result of one fetchJSON, shared by concurrent getJSON
+// callers, or an answer from the response cache.
+type fetched struct {
+	m      map[string]any
+	body   []byte
+	hdr    http.Header
+	final  string
+	proto  string
+	at     time.Time
+	cached bool
+	stale  bool
+}
+
+// cachedResponse is u's cached answer, with the headers and fetch time it
+// was stored with.
+func (c *Client) cachedResponse(u string, m map[string]any, body []byte) fetched {
+	f := fetched{m: m, body: body, final: u, cached: true}
+	if meta, ok := c.respCache.Meta(u); ok {
+		f.hdr, f.at = meta.header.Clone(), meta.fetchedAt
+	}
+	if v, ok := c.redirects.Load(u); ok {
+		f.final = v.(string)
+	}
+	return f
+}
+
+// meta is f's Meta for lookup URL u.
+func (f fetched) meta(u string) *Meta {
+	return &Meta{Raw: f.body, Header: f.hdr, FetchedAt: f.at, URL: u, FinalURL: f.final, Proto: f.proto, CacheHit: f.cached}
+}
+
+// finalURL is where resp's request ended up after redirects, or u.
+func finalURL(resp *http.Response, u string) string {
+	if resp.Request != nil && resp.Request.URL != nil {
+		return resp.Request.URL.String()
 	}
+	return u
+}
 
+// fetchJSON is getJSON's network path.
+func (c *Client) fetchJSON(ctx context.Context, u string) (fetched, error) {
 	useValidators := true     // send ETag/Last-Modified initially
 	didUnconditional := false // ensure we only try once without validators
+	host := ""
+	if pu, err := url.Parse(u); err == nil {
+		host = pu.Host
+	}
+
+	job := JobFrom(ctx)
 
 	for attempt := 1; ; attempt++ {
+		if err := c.bandwidth.check(host, job, c.now()); err != nil {
+			return fetched{}, err
+		}
+		if err := c.hostRates.wait(ctx, host); err != nil {
+			return fetched{}, err
+		}
+		release, err := c.guard.acquire(ctx, host)
+		if err != nil {
+			return fetched{}, err
+		}
 		reqCtx, cancel := context.WithTimeout(ctx, c.baseTimeout)
 
 		req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, u, nil)
@@ -42,31 +153,40 @@ func (c *Client) getJSON(ctx context.Context, u string) (map[string]any, http.He
 			}
 		}
 
+		start := time.Now()
 		resp, err := c.hc.Do(req)
+		release()
 		if err != nil {
 			cancel()
+			if ctx.Err() == nil && isRetryableNetErr(err) {
+				c.guard.failed(host, 0)
+			}
 			if attempt <= c.maxRetries && isRetryableNetErr(err) {
+				wait := c.backoff(attempt)
+				c.log.InfoContext(ctx, "rdap retry", "url", u, "attempt", attempt, "err", err, "wait", wait)
 				select {
-				case <-time.After(c.backoff(attempt)):
+				case <-time.After(wait):
 					continue
 				case <-ctx.Done():
-					return nil, nil, ctx.Err()
+					return fetched{}, ctx.Err()
 				}
 			}
-			return nil, nil, err
+			return fetched{}, err
 		}
+		c.log.DebugContext(ctx, "rdap GET", "url", u, "status", resp.StatusCode, "attempt", attempt, "elapsed", time.Since(start))
 
 		switch resp.StatusCode {
 		case http.StatusNotModified:
-			io.Copy(io.Discard, resp.Body)
+			n, _ := io.Copy(io.Discard, resp.Body)
 			resp.Body.Close()
 			cancel()
+			c.bandwidth.add(host, job, n, c.now())
+			c.guard.ok(host)
 
 			if body := c.respCache.FreshBody(u); body != nil {
-				var m map[string]any
-				if json.Unmarshal(body, &m) == nil {
+				if m, err := decodeBody(body); err == nil {
 					c.respCache.UpdateFreshness(u, resp.Header)
-					return m, resp.Header, nil
+					return fetched{m: m, body: body, hdr: resp.Header, final: finalURL(resp, u), proto: resp.Proto, at: c.now()}, nil
 				}
 			}
 
@@ -76,49 +196,109 @@ func (c *Client) getJSON(ctx context.Context, u string) (map[string]any, http.He
 				useValidators = false
 				continue
 			}
-			return nil, nil, fmt.Errorf("rdap GET %s: 304 but no cached body", u)
+			return fetched{}, fmt.Errorf("rdap GET %s: 304 but no cached body", u)
 
 		case http.StatusOK:
-			b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+			b, err := io.ReadAll(io.LimitReader(resp.Body, c.sizes.max+1))
 			resp.Body.Close()
 			cancel()
+			c.bandwidth.add(host, job, int64(len(b)), c.now())
 			if err != nil {
-				return nil, nil, err
+				return fetched{}, err
+			}
+			c.guard.ok(host)
+			if int64(len(b)) > c.sizes.max {
+				c.sizes.overCap(host, u, c.now())
+				c.log.WarnContext(ctx, "rdap response over size cap", "url", u, "limit", c.sizes.max)
+				return fetched{}, &ErrResponseTooLarge{URL: u, Bytes: int64(len(b)), Limit: c.sizes.max}
 			}
-			var m map[string]any
-			if err := json.Unmarshal(b, &m); err != nil {
-				return nil, nil, err
+			if a := c.sizes.observe(host, u, int64(len(b)), c.now()); a != nil {
+				c.log.WarnContext(ctx, "rdap response anomalously large", "url", u, "bytes", a.Bytes, "typical", a.Typical, "rejected", a.Rejected)
+				if a.Rejected {
+					return fetched{}, &ErrResponseTooLarge{URL: u, Bytes: a.Bytes, Limit: c.sizes.max, Typical: a.Typical}
+				}
+			}
+			if c.maxNesting > 0 && nestedDeeper(b, c.maxNesting) {
+				return fetched{}, &ErrNestingTooDeep{URL: u, Limit: c.maxNesting}
+			}
+			m, err := decodeBody(b)
+			if err != nil {
+				return fetched{}, err
 			}
 			c.respCache.Store(u, b, resp.Header)
-			return m, resp.Header, nil
+			// After redirects (e.g. rdap.org to the authoritative server) the final
+			// URL gets the same body, so asking for it directly is a cache hit too.
+			final := finalURL(resp, u)
+			if final != u {
+				c.respCache.Store(final, b, resp.Header)
+				c.redirects.Store(u, final)
+			}
+			return fetched{m: m, body: b, hdr: resp.Header, final: final, proto: resp.Proto, at: c.now()}, nil
 
 		case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusInternalServerError:
 			wait := retryAfter(resp.Header, c.backoff(attempt))
-			io.Copy(io.Discard, resp.Body)
+			b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+			n, _ := io.Copy(io.Discard, resp.Body)
 			resp.Body.Close()
 			cancel()
+			c.bandwidth.add(host, job, int64(len(b))+n, c.now())
+			c.guard.failed(host, parseRetryAfter(resp.Header))
 			if attempt <= c.maxRetries {
+				c.log.InfoContext(ctx, "rdap retry", "url", u, "attempt", attempt, "status", resp.StatusCode, "wait", wait)
 				select {
 				case <-time.After(wait):
 					continue
 				case <-ctx.Done():
-					return nil, nil, ctx.Err()
+					return fetched{}, ctx.Err()
 				}
 			}
-			return nil, nil, fmt.Errorf("rdap GET %s: %s", u, resp.Status)
+			return fetched{}, statusError(&HTTPError{URL: u, StatusCode: resp.StatusCode, Status: resp.Status, RetryAfter: parseRetryAfter(resp.Header), Body: string(b)})
 
 		default:
 			b, _ := io.ReadAll(io.LimitReader(resp.Body, 512<<10))
 			resp.Body.Close()
 			cancel()
-			if resp.StatusCode == http.StatusNotFound {
-				c.respCache.StoreNegative(u, 5*time.Minute)
+			c.bandwidth.add(host, job, int64(len(b)), c.now())
+			c.guard.ok(host)
+			err := statusError(&HTTPError{URL: u, StatusCode: resp.StatusCode, Status: resp.Status, RetryAfter: parseRetryAfter(resp.Header), Body: string(b)})
+			// Other 4xx are not remembered: a 401/403 may clear with new
+			// credentials, and a 400 or 422 is the query's fault.
+			switch resp.StatusCode {
+			case http.StatusNotFound:
+				c.respCache.StoreError(u, err, notFoundTTL)
+			case http.StatusGone:
+				c.respCache.StoreError(u, err, goneTTL)
+			case http.StatusUnavailableForLegalReasons:
+				err = &LegalBlockError{BlockedBy: blockedBy(resp.Header), Err: err}
+				c.respCache.StoreError(u, err, legalBlockTTL)
+			case http.StatusBadRequest:
+				c.log.WarnContext(ctx, "rdap registry rejected request as malformed", "url", u)
 			}
-			return nil, nil, fmt.Errorf("rdap GET %s: %s: %s", u, resp.Status, string(b))
+			return fetched{}, err
 		}
 	}
 }
 
+// decodeBody decodes a response object. Some servers answer lookup URLs with a
+// bare search-style array instead; its first element is used.
+func decodeBody(b []byte) (map[string]any, error) {
+	if t := bytes.TrimLeft(b, " \t\r\n"); len(t) > 0 && t[0] == '[' {
+		var arr []map[string]any
+		if err := json.Unmarshal(b, &arr); err != nil {
+			return nil, err
+		}
+		if len(arr) == 0 || arr[0] == nil {
+			return nil, errors.New("rdap: empty array response")
+		}
+		return arr[0], nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func isRetryableNetErr(err error) bool {
 	var ne net.Error
 	if errorsAs(err, &ne) && (ne.Timeout() || temporary(ne)) {
@@ -0,0 +1,85 @@
+package rdapclient
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidDomain reports a domain or host name rejected before any request was built.
+type ErrInvalidDomain struct {
+	Input  string
+	Reason string
+}
+
+func (e *ErrInvalidDomain) Error() string {
+	return fmt.Sprintf("invalid domain name %q: %s", e.Input, e.Reason)
+}
+
+const (
+	maxDomainOctets = 253
+	maxLabelOctets  = 63
+)
+
+// NormalizeFQDN validates a domain name and returns its lowercase A-label form
+// without the trailing dot. The name is mapped and checked per UTS #46 for
+// lookup (case folding, width mapping, NFC, disallowed code points, the Bidi
+// and ContextJ rules) and Unicode labels become punycode; the result is then
+// checked for label lengths, LDH characters and the 253-octet limit.
+func NormalizeFQDN(name string) (string, error) {
+	s := strings.TrimSpace(name)
+	s = strings.TrimSuffix(s, ".")
+	if s == "" {
+		return "", &ErrInvalidDomain{Input: name, Reason: "empty name"}
+	}
+	if !utf8.ValidString(s) {
+		return "", &ErrInvalidDomain{Input: name, Reason: "not valid UTF-8"}
+	}
+	for _, l := range strings.Split(s, ".") {
+		if l == "" {
+			return "", &ErrInvalidDomain{Input: name, Reason: "empty label"}
+		}
+	}
+	a, err := lookupProfile.ToASCII(s)
+	if err != nil {
+		return "", &ErrInvalidDomain{Input: name, Reason: strings.TrimPrefix(err.Error(), "idna: ")}
+	}
+
+	labels := strings.Split(a, ".")
+	for _, l := range labels {
+		if l == "" {
+			return "", &ErrInvalidDomain{Input: name, Reason: "empty label"}
+		}
+		if len(l) > maxLabelOctets {
+			return "", &ErrInvalidDomain{Input: name, Reason: fmt.Sprintf("label %q exceeds %d octets", l, maxLabelOctets)}
+		}
+		if err := checkLDH(l); err != nil {
+			return "", &ErrInvalidDomain{Input: name, Reason: err.Error()}
+		}
+	}
+	if len(a) > maxDomainOctets {
+		return "", &ErrInvalidDomain{Input: name, Reason: fmt.Sprintf("name exceeds %d octets", maxDomainOctets)}
+	}
+	return a, nil
+}
+
+// lookupProfile is IDNA's lookup profile without the UTS #46 CheckHyphens
+// rule: hosts such as "r3---sn-abc.googlevideo.com" are in common use, so only
+// leading and trailing hyphens are refused (checkLDH).
+var lookupProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.Transitional(false), idna.CheckHyphens(false))
+
+func checkLDH(label string) error {
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return fmt.Errorf("label %q starts or ends with a hyphen", label)
+	}
+	for i := 0; i < len(label); i++ {
+		b := label[i]
+		if b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b == '-' {
+			continue
+		}
+		return fmt.Errorf("label %q contains invalid character %q", label, b)
+	}
+	return nil
+}
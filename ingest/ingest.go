@@ -0,0 +1,173 @@
+// Package ingest turns zone files and escrow-derived domain lists into the
+// set of registered names to enrich over RDAP, and tells which names are new
+// since the previous run so they can be looked up first.
+package ingest
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ReadNames returns the sorted, de-duplicated domain names in r, lower-case
+// and without the trailing dot. Two input shapes are accepted, line by line:
+//
+//   - plain lists, one name per line (escrow exports, CZDS-derived lists);
+//   - master-file zone records ("owner [ttl] [class] type rdata"), of which
+//     only NS records count, since delegations are what a TLD zone registers.
+//     $ORIGIN is honoured and relative owners are qualified with it.
+//
+// zone (e.g. "com") seeds $ORIGIN and is excluded from the result, as is
+// anything outside it when it is set. Comments (";" or "#") and blank lines
+// are ignored.
+func ReadNames(r io.Reader, zone string) ([]string, error) {
+	zone = normalize(zone)
+	origin := zone
+	seen := map[string]bool{}
+	var last string // owner of the previous record, for blank-owner continuation lines
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexAny(line, ";#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if strings.EqualFold(fields[0], "$ORIGIN") && len(fields) > 1 {
+			origin = normalize(fields[1])
+			continue
+		}
+		if strings.HasPrefix(fields[0], "$") {
+			continue // $TTL, $INCLUDE, ...
+		}
+
+		var name string
+		switch {
+		case len(fields) == 1:
+			name = qualify(fields[0], origin, true)
+		default:
+			if line[0] == ' ' || line[0] == '\t' {
+				fields = append([]string{last}, fields...)
+			} else {
+				last = fields[0]
+			}
+			if !isNS(fields) {
+				continue
+			}
+			name = qualify(fields[0], origin, false)
+		}
+		if name == "" || name == zone || (zone != "" && !strings.HasSuffix(name, "."+zone)) {
+			continue
+		}
+		seen[name] = true
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(seen))
+	for n := range seen {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// isNS reports whether a record's type (after optional TTL and class) is NS.
+func isNS(fields []string) bool {
+	for _, f := range fields[1:] {
+		switch strings.ToUpper(f) {
+		case "NS":
+			return true
+		case "IN", "CH", "HS":
+			continue
+		}
+		if f[0] >= '0' && f[0] <= '9' {
+			continue // TTL
+		}
+		return false
+	}
+	return false
+}
+
+// qualify makes owner absolute. In plain lists (list true) bare names are
+// taken as given unless they have no dot at all.
+func qualify(owner, origin string, list bool) string {
+	if owner == "@" {
+		return origin
+	}
+	if strings.HasSuffix(owner, ".") {
+		return normalize(owner)
+	}
+	n := normalize(owner)
+	if origin == "" || (list && strings.Contains(n, ".")) {
+		return n
+	}
+	return n + "." + origin
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(s), "."))
+}
+
+// Diff splits cur against prev (both sorted) into names that appeared and
+// names that disappeared.
+func Diff(prev, cur []string) (added, removed []string) {
+	i, j := 0, 0
+	for i < len(prev) || j < len(cur) {
+		switch {
+		case j == len(cur) || (i < len(prev) && prev[i] < cur[j]):
+			removed = append(removed, prev[i])
+			i++
+		case i == len(prev) || cur[j] < prev[i]:
+			added = append(added, cur[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return added, removed
+}
+
+// LoadNames reads a name list written by SaveNames; a missing file is an
+// empty list with ok false (no previous run).
+func LoadNames(path string) (names []string, ok bool, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	names, err = ReadNames(f, "")
+	return names, err == nil, err
+}
+
+// SaveNames writes names one per line, atomically.
+func SaveNames(path string, names []string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, n := range names {
+		w.WriteString(n)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,54 @@
+package ingest
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadNames_ZoneFile(t *testing.T) {
+	zone := `$ORIGIN example.
+$TTL 86400
+@            IN SOA a.nic.example. hostmaster.example. 1 2 3 4 5
+@            IN NS  a.nic.example.
+alpha        86400 IN NS ns1.alpha.example.
+             86400 IN NS ns2.alpha.example.
+BETA.example. NS ns1.host.test.
+ns1.alpha    IN A 192.0.2.1 ; glue, not a delegation
+gamma.other. IN NS ns.other.   ; out of zone
+`
+	got, err := ReadNames(strings.NewReader(zone), "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"alpha.example", "beta.example"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("names = %v, want %v", got, want)
+	}
+}
+
+func TestReadNames_ListAndDiff(t *testing.T) {
+	got, err := ReadNames(strings.NewReader("# escrow export\nDelta.example\nalpha\n\nalpha.example.\n"), "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"alpha.example", "delta.example"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("names = %v, want %v", got, want)
+	}
+
+	added, removed := Diff([]string{"alpha.example", "beta.example"}, got)
+	if !reflect.DeepEqual(added, []string{"delta.example"}) || !reflect.DeepEqual(removed, []string{"beta.example"}) {
+		t.Fatalf("diff: added %v removed %v", added, removed)
+	}
+
+	path := filepath.Join(t.TempDir(), "names.txt")
+	if _, ok, err := LoadNames(path); ok || err != nil {
+		t.Fatalf("missing file: ok=%v err=%v", ok, err)
+	}
+	if err := SaveNames(path, got); err != nil {
+		t.Fatal(err)
+	}
+	if back, ok, err := LoadNames(path); !ok || err != nil || !reflect.DeepEqual(back, got) {
+		t.Fatalf("round trip: %v %v %v", back, ok, err)
+	}
+}
@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Pending jobs are indexed in two heaps so Next does not scan the queue:
+// waiting orders them by NotBefore, and Next moves those that have come due
+// to ready, which orders them by priority, then ID. Entries are not removed
+// when a job changes under them; an entry is live only while it is the
+// job's latest (queued[id] == seq), and stale ones are dropped when they
+// surface.
+
+// entry is one pending job in an index, as it was when scheduled.
+type entry struct {
+	id, priority int
+	notBefore    time.Time
+	seq          uint64
+}
+
+type index struct {
+	entries []entry
+	less    func(a, b entry) bool
+}
+
+func (x *index) Len() int           { return len(x.entries) }
+func (x *index) Less(a, b int) bool { return x.less(x.entries[a], x.entries[b]) }
+func (x *index) Swap(a, b int)      { x.entries[a], x.entries[b] = x.entries[b], x.entries[a] }
+func (x *index) Push(v any)         { x.entries = append(x.entries, v.(entry)) }
+func (x *index) Pop() any {
+	e := x.entries[len(x.entries)-1]
+	x.entries = x.entries[:len(x.entries)-1]
+	return e
+}
+
+func byPriority(a, b entry) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.id < b.id
+}
+
+func byNotBefore(a, b entry) bool {
+	if !a.notBefore.Equal(b.notBefore) {
+		return a.notBefore.Before(b.notBefore)
+	}
+	return a.id < b.id
+}
+
+// schedule indexes j as runnable, replacing any entry it had. Callers hold q.mu.
+func (q *Queue) schedule(j *Job) {
+	q.seq++
+	q.queued[j.ID] = q.seq
+	heap.Push(&q.waiting, entry{id: j.ID, priority: j.Priority, notBefore: j.NotBefore, seq: q.seq})
+}
+
+// unschedule drops j's entry, if any. Callers hold q.mu.
+func (q *Queue) unschedule(id int) { delete(q.queued, id) }
+
+func (q *Queue) live(e entry) bool { return q.queued[e.id] == e.seq }
+
+// pick unschedules and returns the next runnable job at now, or nil and the
+// time the soonest waiting job comes due (zero when none waits). Callers hold
+// q.mu.
+func (q *Queue) pick(now time.Time) (*Job, time.Time) {
+	for q.waiting.Len() > 0 && !q.waiting.entries[0].notBefore.After(now) {
+		if e := heap.Pop(&q.waiting).(entry); q.live(e) {
+			heap.Push(&q.ready, e)
+		}
+	}
+	for q.ready.Len() > 0 {
+		if e := heap.Pop(&q.ready).(entry); q.live(e) {
+			q.unschedule(e.id)
+			return q.jobs[e.id], time.Time{}
+		}
+	}
+	for q.waiting.Len() > 0 {
+		if e := q.waiting.entries[0]; q.live(e) {
+			return nil, e.notBefore
+		}
+		heap.Pop(&q.waiting)
+	}
+	return nil, time.Time{}
+}
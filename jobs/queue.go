@@ -0,0 +1,368 @@
+// Package jobs is a small persistent work queue for long RDAP sweeps.
+//
+// A queue lives in a directory as an append-only journal (one JSON record per
+// state change) and is replayed and compacted on Open, so a sweep interrupted
+// by a crash or Ctrl-C resumes where it stopped. Jobs that hit a rate limit
+// are rescheduled for the registry's Retry-After instead of being retried hot.
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JournalName is the journal file inside a queue directory.
+const JournalName = "queue.jsonl"
+
+// ErrDrained is returned by Next when no job is pending or in progress.
+var ErrDrained = errors.New("jobs: queue drained")
+
+// State is a job's lifecycle state.
+type State string
+
+const (
+	StatePending State = "pending"
+	StateDone    State = "done"
+	StateFailed  State = "failed"
+)
+
+// Job is one unit of work: a query and its retry bookkeeping. Runnable jobs
+// with a higher Priority are handed out first, then by ID.
+type Job struct {
+	ID        int       `json:"id"`
+	Query     string    `json:"query"`
+	State     State     `json:"state"`
+	Priority  int       `json:"priority,omitempty"`
+	Attempts  int       `json:"attempts,omitempty"`
+	NotBefore time.Time `json:"notBefore,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// Queue is a directory-backed job queue, safe for concurrent workers.
+// Jobs handed out by Next are leased in memory only: after a restart they are
+// pending again, so a job interrupted mid-flight is simply redone.
+type Queue struct {
+	mu      sync.Mutex
+	dir     string
+	f       *os.File
+	jobs    map[int]*Job
+	byQuery map[string]int
+	leased  map[int]bool
+	nextID  int
+	changed chan struct{} // closed and replaced on every state change
+
+	// The pending jobs not leased out (see index.go).
+	ready, waiting index
+	queued         map[int]uint64
+	seq            uint64
+
+	now func() time.Time
+}
+
+// Open opens (creating if needed) the queue in dir.
+func Open(dir string) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	q := &Queue{
+		dir:     dir,
+		jobs:    map[int]*Job{},
+		byQuery: map[string]int{},
+		leased:  map[int]bool{},
+		nextID:  1,
+		changed: make(chan struct{}),
+		ready:   index{less: byPriority},
+		waiting: index{less: byNotBefore},
+		queued:  map[int]uint64{},
+		now:     time.Now,
+	}
+	if err := q.replay(); err != nil {
+		return nil, err
+	}
+	for _, j := range q.sorted() {
+		if j.State == StatePending {
+			q.schedule(j)
+		}
+	}
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *Queue) replay() error {
+	f, err := os.Open(filepath.Join(q.dir, JournalName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		var j Job
+		if err := json.Unmarshal(sc.Bytes(), &j); err != nil {
+			// A torn final write from a crash is expected; anything else is not.
+			if !sc.Scan() {
+				break
+			}
+			return fmt.Errorf("jobs: %s line %d: %w", JournalName, line, err)
+		}
+		q.jobs[j.ID] = &j
+		q.byQuery[j.Query] = j.ID
+		if j.ID >= q.nextID {
+			q.nextID = j.ID + 1
+		}
+	}
+	return sc.Err()
+}
+
+// compact rewrites the journal with one record per job and reopens it for appends.
+func (q *Queue) compact() error {
+	path := filepath.Join(q.dir, JournalName)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, j := range q.sorted() {
+		if err := enc.Encode(j); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	q.f, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	return err
+}
+
+func (q *Queue) sorted() []*Job {
+	out := make([]*Job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		out = append(out, j)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].ID < out[b].ID })
+	return out
+}
+
+// write journals j and wakes waiters. Callers hold q.mu.
+func (q *Queue) write(j *Job) error {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	if _, err := q.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	close(q.changed)
+	q.changed = make(chan struct{})
+	return nil
+}
+
+// Add enqueues queries, skipping any already in the queue (in any state),
+// so re-adding the same input on resume is harmless. It returns how many were new.
+func (q *Queue) Add(queries ...string) (int, error) { return q.AddPriority(0, queries...) }
+
+// AddPriority is Add for jobs of the given priority.
+func (q *Queue) AddPriority(priority int, queries ...string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, s := range queries {
+		if _, ok := q.byQuery[s]; ok || s == "" {
+			continue
+		}
+		j := &Job{ID: q.nextID, Query: s, State: StatePending, Priority: priority}
+		q.nextID++
+		q.jobs[j.ID] = j
+		q.byQuery[s] = j.ID
+		q.schedule(j)
+		if err := q.write(j); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Requeue makes finished (done or failed) jobs for queries pending again at
+// priority, with fresh attempts, e.g. to refresh results on a schedule. Queries
+// not in the queue and jobs still pending are left alone. It returns how many
+// were requeued.
+func (q *Queue) Requeue(priority int, queries ...string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, s := range queries {
+		id, ok := q.byQuery[s]
+		if !ok {
+			continue
+		}
+		j := q.jobs[id]
+		if j.State == StatePending {
+			continue
+		}
+		j.State, j.Priority, j.Attempts, j.NotBefore, j.LastError = StatePending, priority, 0, time.Time{}, ""
+		q.schedule(j)
+		if err := q.write(j); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Next leases the next runnable job, waiting for rescheduled jobs to come due.
+// It returns ErrDrained once nothing is pending or leased.
+func (q *Queue) Next(ctx context.Context) (Job, error) {
+	for {
+		q.mu.Lock()
+		now := q.now()
+		pick, soonest := q.pick(now)
+		if pick != nil {
+			q.leased[pick.ID] = true
+			j := *pick
+			q.mu.Unlock()
+			return j, nil
+		}
+		if soonest.IsZero() && len(q.leased) == 0 {
+			q.mu.Unlock()
+			return Job{}, ErrDrained
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		var (
+			t     *time.Timer
+			timer <-chan time.Time
+		)
+		if !soonest.IsZero() {
+			t = time.NewTimer(soonest.Sub(now))
+			timer = t.C
+		}
+		select {
+		case <-ctx.Done():
+			err := ctx.Err()
+			if t != nil {
+				t.Stop()
+			}
+			return Job{}, err
+		case <-changed:
+		case <-timer:
+		}
+		if t != nil {
+			t.Stop()
+		}
+	}
+}
+
+// Complete marks a leased job done.
+func (q *Queue) Complete(id int) error {
+	return q.settle(id, func(j *Job) {
+		j.State, j.LastError = StateDone, ""
+	})
+}
+
+// Retry returns a leased job to the queue, runnable no earlier than after from now.
+func (q *Queue) Retry(id int, after time.Duration, cause error) error {
+	return q.settle(id, func(j *Job) {
+		j.Attempts++
+		j.NotBefore = q.now().Add(after).UTC()
+		j.LastError = errString(cause)
+	})
+}
+
+// Fail marks a leased job permanently failed.
+func (q *Queue) Fail(id int, cause error) error {
+	return q.settle(id, func(j *Job) {
+		j.Attempts++
+		j.State, j.LastError = StateFailed, errString(cause)
+	})
+}
+
+// Release returns a leased job to the queue as it was, without counting an
+// attempt, for work that was paused rather than tried (a used-up byte budget).
+func (q *Queue) Release(id int) error {
+	return q.settle(id, func(*Job) {})
+}
+
+func (q *Queue) settle(id int, fn func(*Job)) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return fmt.Errorf("jobs: unknown job %d", id)
+	}
+	delete(q.leased, id)
+	q.unschedule(id)
+	fn(j)
+	if j.State == StatePending {
+		q.schedule(j)
+	}
+	return q.write(j)
+}
+
+// Jobs returns a copy of every job, in ID order.
+func (q *Queue) Jobs() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Job, 0, len(q.jobs))
+	for _, j := range q.sorted() {
+		out = append(out, *j)
+	}
+	return out
+}
+
+// Counts reports how many jobs are in each state.
+func (q *Queue) Counts() map[State]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := map[State]int{}
+	for _, j := range q.jobs {
+		out[j.State]++
+	}
+	return out
+}
+
+// Close flushes and closes the journal.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.f == nil {
+		return nil
+	}
+	err := q.f.Sync()
+	if cerr := q.f.Close(); err == nil {
+		err = cerr
+	}
+	q.f = nil
+	return err
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
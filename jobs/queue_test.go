@@ -0,0 +1,236 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQueue_AddDedupesAndSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := q.Add("a.example", "b.example", "a.example", ""); err != nil || n != 2 {
+		t.Fatalf("add: n=%d err=%v", n, err)
+	}
+	j, err := q.Next(context.Background())
+	if err != nil || j.Query != "a.example" {
+		t.Fatalf("next: %+v %v", j, err)
+	}
+	if err := q.Complete(j.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Leased but never settled: pending again after a restart.
+	if _, err := q.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if n, _ := q.Add("a.example", "b.example", "c.example"); n != 1 {
+		t.Fatalf("re-add on resume: want 1 new, got %d", n)
+	}
+	c := q.Counts()
+	if c[StateDone] != 1 || c[StatePending] != 2 {
+		t.Fatalf("counts after reopen: %v", c)
+	}
+	j, err = q.Next(context.Background())
+	if err != nil || j.Query != "b.example" {
+		t.Fatalf("resume order: %+v %v", j, err)
+	}
+}
+
+func TestQueue_RetryWaitsForNotBefore(t *testing.T) {
+	q, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	base := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	q.now = func() time.Time { return base }
+
+	_, _ = q.Add("slow.example", "fast.example")
+	j, _ := q.Next(context.Background())
+	if err := q.Retry(j.ID, time.Hour, errors.New("429")); err != nil {
+		t.Fatal(err)
+	}
+	// The rescheduled job is skipped while it is not yet due.
+	j2, err := q.Next(context.Background())
+	if err != nil || j2.Query != "fast.example" {
+		t.Fatalf("want fast.example, got %+v %v", j2, err)
+	}
+	_ = q.Complete(j2.ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.Next(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want to wait for Retry-After, got %v", err)
+	}
+
+	q.mu.Lock()
+	q.now = func() time.Time { return base.Add(time.Hour) }
+	q.mu.Unlock()
+	j3, err := q.Next(context.Background())
+	if err != nil || j3.Query != "slow.example" || j3.Attempts != 1 || j3.LastError != "429" {
+		t.Fatalf("due retry: %+v %v", j3, err)
+	}
+	// Released work comes back without an attempt counted.
+	if err := q.Release(j3.ID); err != nil {
+		t.Fatal(err)
+	}
+	if j3, err = q.Next(context.Background()); err != nil || j3.Query != "slow.example" || j3.Attempts != 1 {
+		t.Fatalf("released: %+v %v", j3, err)
+	}
+	if err := q.Fail(j3.ID, errors.New("gone")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Next(context.Background()); !errors.Is(err, ErrDrained) {
+		t.Fatalf("want ErrDrained, got %v", err)
+	}
+}
+
+func TestOpen_ToleratesTornFinalLine(t *testing.T) {
+	dir := t.TempDir()
+	journal := `{"id":1,"query":"a.example","state":"done"}` + "\n" + `{"id":2,"query":"b.exa`
+	if err := os.WriteFile(filepath.Join(dir, JournalName), []byte(journal), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	q, err := Open(dir)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer q.Close()
+	if js := q.Jobs(); len(js) != 1 || js[0].State != StateDone {
+		t.Fatalf("jobs: %+v", js)
+	}
+}
+
+func TestQueue_PriorityAndRequeue(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	q.Add("old1.example", "old2.example")
+	q.AddPriority(10, "new.example")
+
+	var order []string
+	for {
+		j, err := q.Next(ctx)
+		if errors.Is(err, ErrDrained) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		order = append(order, j.Query)
+		if err := q.Complete(j.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(order) != 3 || order[0] != "new.example" || order[1] != "old1.example" {
+		t.Fatalf("order = %v", order)
+	}
+
+	if n, err := q.Requeue(5, "old2.example", "missing.example"); err != nil || n != 1 {
+		t.Fatalf("requeue: n=%d err=%v", n, err)
+	}
+	q.Close()
+
+	q, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	j, err := q.Next(ctx)
+	if err != nil || j.Query != "old2.example" || j.Priority != 5 || j.Attempts != 0 {
+		t.Fatalf("after reopen: %+v %v", j, err)
+	}
+}
+
+func TestQueue_NextOrdersByPriorityDueTimeAndID(t *testing.T) {
+	q, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	base := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	q.now = func() time.Time { return base }
+	ctx := context.Background()
+	for i := 0; i < 300; i++ {
+		q.AddPriority(i%3, fmt.Sprintf("q%03d.example", i))
+	}
+	// Reschedule a high-priority job and requeue a finished one at the top:
+	// both change jobs the index already holds.
+	j, _ := q.Next(ctx)
+	if j.Priority != 2 || j.Query != "q002.example" {
+		t.Fatalf("first: %+v", j)
+	}
+	_ = q.Retry(j.ID, time.Minute, errors.New("429"))
+	j, _ = q.Next(ctx)
+	_ = q.Complete(j.ID)
+	q.Requeue(9, j.Query)
+
+	var got []Job
+	for i := 0; i < 299; i++ {
+		j, err := q.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, j)
+		_ = q.Complete(j.ID)
+	}
+	if got[0].Query != "q005.example" || got[0].Priority != 9 {
+		t.Fatalf("requeued job not first: %+v", got[0])
+	}
+	for i := 2; i < len(got); i++ {
+		a, b := got[i-1], got[i]
+		if a.Priority < b.Priority || a.Priority == b.Priority && a.ID > b.ID {
+			t.Fatalf("%+v before %+v", a, b)
+		}
+	}
+	q.mu.Lock()
+	q.now = func() time.Time { return base.Add(time.Minute) }
+	q.mu.Unlock()
+	if j, err := q.Next(ctx); err != nil || j.Query != "q002.example" || j.Attempts != 1 {
+		t.Fatalf("due retry: %+v %v", j, err)
+	} else {
+		_ = q.Complete(j.ID)
+	}
+	if _, err := q.Next(ctx); !errors.Is(err, ErrDrained) {
+		t.Fatalf("want ErrDrained, got %v", err)
+	}
+}
+
+func BenchmarkQueue_Next(b *testing.B) {
+	q, err := Open(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer q.Close()
+	for i := 0; i < 10000; i++ {
+		q.AddPriority(i%5, fmt.Sprintf("q%d.example", i))
+	}
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j, err := q.Next(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = q.Release(j.ID)
+	}
+}
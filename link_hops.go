@@ -0,0 +1,134 @@
+package rdapclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxRedirects is how many redirects one request follows unless
+// WithMaxRedirects says otherwise.
+const DefaultMaxRedirects = 5
+
+// DefaultMaxLinkHops is how many cross-host hops a chain of followed links may
+// make unless WithMaxLinkHops says otherwise.
+const DefaultMaxLinkHops = 4
+
+// Hop is one step from a document to another: a followed link, or a redirect.
+type Hop struct {
+	From     string `json:"from,omitempty"` // "" when the linking document is unknown
+	To       string `json:"to"`
+	Redirect bool   `json:"redirect,omitempty"`
+}
+
+// crossHost reports whether h moves to a different host.
+func (h Hop) crossHost() bool {
+	f, err1 := url.Parse(h.From)
+	t, err2 := url.Parse(h.To)
+	return err1 != nil || err2 != nil || !strings.EqualFold(f.Host, t.Host)
+}
+
+// ErrHopLimit is returned when following a link would make more cross-host
+// hops than the client allows (WithMaxLinkHops), or a request is redirected
+// more often than it allows (WithMaxRedirects; Redirect is set). Hops is the
+// chain up to and including the refused step.
+type ErrHopLimit struct {
+	Href     string
+	Limit    int
+	Redirect bool
+	Hops     []Hop
+}
+
+func (e *ErrHopLimit) Error() string {
+	if e.Redirect {
+		return fmt.Sprintf("rdap GET %s: stopped after %d redirects", e.Href, e.Limit)
+	}
+	return fmt.Sprintf("rdap link %s: more than %d cross-host hops", e.Href, e.Limit)
+}
+
+// HopTrail records the hops made under a context from WithHopTrail.
+type HopTrail struct {
+	mu   sync.Mutex
+	hops []Hop
+}
+
+// Hops returns the recorded hops, oldest first.
+func (t *HopTrail) Hops() []Hop {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Hop(nil), t.hops...)
+}
+
+// Add records h.
+func (t *HopTrail) Add(h Hop) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hops = append(t.hops, h)
+}
+
+type hopTrailKey struct{}
+
+// WithHopTrail returns a context whose FollowLink calls and redirects are
+// recorded in the returned trail. FollowLink also counts the trail's
+// cross-host hops against the client's limit, so following a chain of links
+// under one such context stops when registries refer to each other in a
+// cycle.
+func WithHopTrail(ctx context.Context) (context.Context, *HopTrail) {
+	t := &HopTrail{}
+	return context.WithValue(ctx, hopTrailKey{}, t), t
+}
+
+// HopTrailFrom returns the trail of a context from WithHopTrail, or nil.
+func HopTrailFrom(ctx context.Context) *HopTrail {
+	t, _ := ctx.Value(hopTrailKey{}).(*HopTrail)
+	return t
+}
+
+// CheckHops reports whether a chain of followed links stays within the
+// client's cross-host hop limit, with an *ErrHopLimit when it does not.
+// Redirect hops are bounded per request instead and do not count.
+func (c *Client) CheckHops(hops []Hop) error {
+	if c.maxLinkHops <= 0 {
+		return nil
+	}
+	n := 0
+	for _, h := range hops {
+		if !h.Redirect && h.crossHost() {
+			n++
+		}
+	}
+	if n > c.maxLinkHops {
+		return &ErrHopLimit{Href: hops[len(hops)-1].To, Limit: c.maxLinkHops, Hops: append([]Hop(nil), hops...)}
+	}
+	return nil
+}
+
+// checkRedirect is the http.Client CheckRedirect of clients New builds: it
+// refuses redirects to another origin the LinkPolicy would not follow as a
+// link, records each redirect in the request's hop trail and stops after
+// maxRedirects.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if prev := via[len(via)-1].URL; req.URL.Scheme != prev.Scheme || req.URL.Host != prev.Host {
+		if err := c.CheckLink(prev.String(), req.URL.String()); err != nil {
+			return err
+		}
+	}
+	if t := HopTrailFrom(req.Context()); t != nil {
+		t.Add(Hop{From: via[len(via)-1].URL.String(), To: req.URL.String(), Redirect: true})
+	}
+	if len(via) <= c.maxRedirects {
+		return nil
+	}
+	hops := make([]Hop, len(via))
+	for i, r := range via {
+		to := req.URL.String()
+		if i+1 < len(via) {
+			to = via[i+1].URL.String()
+		}
+		hops[i] = Hop{From: r.URL.String(), To: to, Redirect: true}
+	}
+	return &ErrHopLimit{Href: via[0].URL.String(), Limit: c.maxRedirects, Redirect: true, Hops: hops}
+}
@@ -0,0 +1,189 @@
+package rdapclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// LinkPolicy decides which links[].href targets the client will fetch
+// (Client.FollowLink, stub hydration by "self" link, linked and paged
+// searches) and which links graph walks follow. Links come from crawled,
+// attacker-influenced data, so the zero value is strict: a registry host
+// named in the bootstrap files (or the default base), or else an https URL on
+// the registrable domain of the document the link came from that is not an IP
+// literal in a loopback, private, link-local or otherwise internal range.
+type LinkPolicy struct {
+	// AllowHTTP permits plain http:// targets.
+	AllowHTTP bool `json:"allowHTTP,omitempty" yaml:"allowHTTP,omitempty"`
+	// AllowPrivateIPs permits IP-literal targets in internal ranges.
+	AllowPrivateIPs bool `json:"allowPrivateIPs,omitempty" yaml:"allowPrivateIPs,omitempty"`
+	// Hosts are further allowed hosts; a leading dot (".example.net") allows
+	// the domain and every subdomain.
+	Hosts []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	// AnyHost skips the host allowlist; the scheme and IP checks still apply.
+	AnyHost bool `json:"anyHost,omitempty" yaml:"anyHost,omitempty"`
+}
+
+// ErrLinkBlocked is returned for a link the client's LinkPolicy refuses.
+// From is the document the link appeared in, when known.
+type ErrLinkBlocked struct {
+	Href   string
+	From   string
+	Reason string
+}
+
+func (e *ErrLinkBlocked) Error() string {
+	return fmt.Sprintf("rdap link %s: blocked by link policy: %s", e.Href, e.Reason)
+}
+
+// registryHosts is the set of hosts the bootstrap files name as RDAP services.
+type registryHosts struct {
+	mu    sync.RWMutex
+	hosts map[string]bool
+}
+
+func (r *registryHosts) add(bases ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hosts == nil {
+		r.hosts = map[string]bool{}
+	}
+	for _, b := range bases {
+		if u, err := url.Parse(b); err == nil && u.Hostname() != "" {
+			r.hosts[strings.ToLower(u.Hostname())] = true
+		}
+	}
+}
+
+func (r *registryHosts) has(host string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.hosts[host]
+}
+
+// CheckLink reports whether the client's LinkPolicy lets it fetch href,
+// found in the document at from ("" when unknown), with an *ErrLinkBlocked
+// saying why not. from must be the URL that document was fetched from (its
+// Meta.FinalURL), never a value read from the document.
+func (c *Client) CheckLink(from, href string) error {
+	p := c.linkPolicy
+	block := func(format string, args ...any) error {
+		return &ErrLinkBlocked{Href: href, From: from, Reason: fmt.Sprintf(format, args...)}
+	}
+	u, err := url.Parse(href)
+	if err != nil || u.Host == "" {
+		return block("not an absolute URL")
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	scheme := strings.ToLower(u.Scheme)
+	// Registries from the bootstrap files (or the client's configuration) and
+	// hosts the policy lists are trusted as they are.
+	if (scheme == "https" || scheme == "http") && (c.registries.has(host) || hostListed(host, p.Hosts)) {
+		return nil
+	}
+	switch scheme {
+	case "https":
+	case "http":
+		if !p.AllowHTTP {
+			return block("http not allowed")
+		}
+	default:
+		return block("scheme %q not allowed", u.Scheme)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if internalIP(ip) && !p.AllowPrivateIPs {
+			return block("internal address %s", ip)
+		}
+	} else if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		if !p.AllowPrivateIPs {
+			return block("internal host %s", host)
+		}
+	}
+	if p.AnyHost {
+		return nil
+	}
+	if fu, err := url.Parse(from); err == nil && fu.Hostname() != "" {
+		if d := registrableDomain(host); d != "" && d == registrableDomain(strings.ToLower(fu.Hostname())) {
+			return nil
+		}
+	}
+	return block("host %s is not a known registry", host)
+}
+
+// FollowLink fetches the RDAP object l points to, after checking it against
+// the client's LinkPolicy. The document l came from is not known, so only
+// registry and listed hosts pass; FollowLinkFrom also allows its registrable
+// domain. Under a WithHopTrail context the hop is recorded, and refused with
+// *ErrHopLimit once the trail would exceed the client's cross-host hop limit.
+func (c *Client) FollowLink(ctx context.Context, l Link) (Object, error) {
+	return c.FollowLinkFrom(ctx, "", l)
+}
+
+// FollowLinkFrom is FollowLink for a link in the response fetched from from,
+// the containing object's Meta.FinalURL: that URL, not l.Value or any other
+// field the server sets, counts for the same-registrable-domain rule and is
+// the hop's From. With from unknown the hop starts where the trail ended.
+func (c *Client) FollowLinkFrom(ctx context.Context, from string, l Link) (Object, error) {
+	if err := c.CheckLink(from, l.Href); err != nil {
+		return nil, err
+	}
+	if t := HopTrailFrom(ctx); t != nil {
+		hop := Hop{From: from, To: l.Href}
+		if hops := t.Hops(); hop.From == "" && len(hops) > 0 {
+			hop.From = hops[len(hops)-1].To
+		}
+		if err := c.CheckHops(append(t.Hops(), hop)); err != nil {
+			return nil, err
+		}
+		t.Add(hop)
+	}
+	f, err := c.getResponse(ctx, l.Href)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := c.parseObject(f.m)
+	if err != nil {
+		return nil, err
+	}
+	setMeta(obj, f.meta(l.Href))
+	return obj, nil
+}
+
+// internalIP reports addresses no registry link should point at.
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() ||
+		ip.To4() != nil && ip.To4()[0] == 100 && ip.To4()[1]&0xc0 == 64 // 100.64.0.0/10 (CGNAT)
+}
+
+func hostListed(host string, hosts []string) bool {
+	for _, h := range hosts {
+		h = strings.ToLower(h)
+		if host == h || strings.HasPrefix(h, ".") && (host == h[1:] || strings.HasSuffix(host, h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// registrableDomain approximates host's registrable domain without a public
+// suffix list: the last two labels, or three under two-letter country codes
+// with a short second level ("rdap.nic.co.uk" -> "nic.co.uk").
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	labels := strings.Split(host, ".")
+	n := 2
+	if len(labels) >= 3 && len(labels[len(labels)-1]) == 2 && len(labels[len(labels)-2]) <= 3 {
+		n = 3
+	}
+	if len(labels) < n {
+		return ""
+	}
+	return strings.Join(labels[len(labels)-n:], ".")
+}
@@ -0,0 +1,95 @@
+package rdapclient
+
+import (
+	"mime"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Links is a response's links array, for choosing among links that share a
+// relation: a registry often lists several "related" links, say the
+// registrar's RDAP copy next to its web page.
+type Links []Link
+
+// Best returns the link with relation rel a client should follow (see
+// Ranked). ok is false when no link has an absolute http(s) href with rel.
+func (ls Links) Best(rel string, langs ...string) (l Link, ok bool) {
+	r := ls.Ranked(rel, langs...)
+	if len(r) == 0 {
+		return Link{}, false
+	}
+	return r[0], true
+}
+
+// Ranked returns the links with relation rel and an absolute http(s) href,
+// best first: type application/rdap+json, then other JSON, then no type,
+// then anything else. Among those, a link whose hreflang matches an earlier
+// entry of langs ("en" matches "en-GB") comes first, then links without
+// hreflang. Ties keep document order. A link's rel may list several
+// relations separated by spaces.
+func (ls Links) Ranked(rel string, langs ...string) []Link {
+	var out []Link
+	for _, l := range ls {
+		if hasRel(l.Rel, rel) && absoluteHTTP(l.Href) {
+			out = append(out, l)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if a, b := linkTypeRank(out[i].Type), linkTypeRank(out[j].Type); a != b {
+			return a > b
+		}
+		return hreflangRank(out[i].HrefLang, langs) < hreflangRank(out[j].HrefLang, langs)
+	})
+	return out
+}
+
+// hasRel reports whether the space-separated relation list rels has rel.
+func hasRel(rels, rel string) bool {
+	for _, r := range strings.Fields(rels) {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func absoluteHTTP(href string) bool {
+	u, err := url.Parse(href)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// linkTypeRank orders media types by how likely they are to be RDAP.
+func linkTypeRank(t string) int {
+	if t == "" {
+		return 1
+	}
+	mt, _, err := mime.ParseMediaType(t)
+	switch {
+	case err != nil:
+		return 0
+	case mt == "application/rdap+json":
+		return 3
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return 2
+	}
+	return 0
+}
+
+// hreflangRank is the index in langs of the first tag matching hreflang,
+// len(langs) when hreflang is empty and len(langs)+1 when nothing matches.
+// Without langs every link ranks the same.
+func hreflangRank(hreflang string, langs []string) int {
+	if len(langs) == 0 {
+		return 0
+	}
+	if hreflang = strings.TrimSpace(hreflang); hreflang == "" {
+		return len(langs)
+	}
+	for i, lang := range langs {
+		if strings.EqualFold(hreflang, lang) || len(hreflang) > len(lang) && hreflang[len(lang)] == '-' && strings.EqualFold(hreflang[:len(lang)], lang) {
+			return i
+		}
+	}
+	return len(langs) + 1
+}
@@ -13,46 +13,148 @@ var (
 	reNSHost = regexp.MustCompile(`(?i)^(ns\d+|dns\d+)[.-]`) // cheap heuristic
 )
 
+// Kind identifies the RDAP object class a Lookup query was classified as.
+type Kind string
+
+const (
+	KindAutnum     Kind = "autnum"
+	KindIP         Kind = "ip"
+	KindNameserver Kind = "nameserver"
+	KindEntity     Kind = "entity"
+	KindDomain     Kind = "domain"
+)
+
+// LookupOptions tunes LookupWithOptions.
+type LookupOptions struct {
+	// TLDHint helps pick the registry base for entity lookups (can be "").
+	TLDHint string
+	// AllowedKinds constrains which classes may be attempted; empty allows all.
+	AllowedKinds []Kind
+}
+
+func (o LookupOptions) allows(k Kind) bool {
+	if len(o.AllowedKinds) == 0 {
+		return true
+	}
+	for _, a := range o.AllowedKinds {
+		if a == k {
+			return true
+		}
+	}
+	return false
+}
+
 // Lookup auto-detects the query type and calls the appropriate RDAP method.
 // Optionally pass a tldHint for Entity lookups (can be "").
 func (c *Client) Lookup(ctx context.Context, q string, tldHint string) (any, error) {
+	return c.LookupWithOptions(ctx, q, LookupOptions{TLDHint: tldHint})
+}
+
+// LookupWithOptions is Lookup with caller constraints. Every classification that
+// was attempted is recorded; if none succeeds the result is an *ErrLookupFailed.
+func (c *Client) LookupWithOptions(ctx context.Context, q string, opts LookupOptions) (any, error) {
 	s := strings.TrimSpace(q)
+	fail := &ErrLookupFailed{Query: s}
+
+	for _, k := range classify(s, opts) {
+		if !opts.allows(k) {
+			continue
+		}
+		obj, err := c.lookupKind(ctx, k, s, opts.TLDHint)
+		if err == nil {
+			return obj, nil
+		}
+		fail.Tried = append(fail.Tried, LookupAttempt{Kind: k, Err: err})
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fail
+}
 
+// classify returns the ordered chain of kinds worth attempting for s.
+func classify(s string, opts LookupOptions) []Kind {
 	// 1) ASN: "AS15169" or "15169"
 	if reASN.MatchString(s) {
-		return c.Autnum(ctx, s)
+		return []Kind{KindAutnum}
 	}
 
 	// 2) IP or CIDR
-	if pfx, err := netip.ParsePrefix(s); err == nil {
-		// Normalize form (avoid mixed-case or stray spaces)
-		return c.IP(ctx, pfx.String())
+	if _, err := netip.ParsePrefix(s); err == nil {
+		return []Kind{KindIP}
 	}
-	if ip, err := netip.ParseAddr(s); err == nil {
-		return c.IP(ctx, ip.String())
+	if _, err := netip.ParseAddr(s); err == nil {
+		return []Kind{KindIP}
 	}
 
 	// 3) Nameserver host heuristic (still a domain – try Nameserver first)
+	// 4) Entity handle (registry-specific). If caller passed a hint, try Entity.
+	// Common entity handles contain '-' or all-caps alpha + digits (e.g., "ORG-EXAMPLE1").
+	// 5) Default: treat as FQDN domain
 	ls := strings.ToLower(s)
-	switch {
-	case reNSHost.MatchString(ls):
-		if ns, err := c.Nameserver(ctx, ls); err == nil {
-			return ns, nil
+	var chain []Kind
+	if reNSHost.MatchString(ls) {
+		chain = append(chain, KindNameserver)
+	}
+	if opts.TLDHint != "" && looksLikeEntityHandle(ls) || isTaggedHandle(s) {
+		chain = append(chain, KindEntity)
+	}
+	chain = append(chain, KindDomain)
+
+	// Explicitly allowed kinds the heuristics skipped still get a turn, after the guesses.
+	for _, k := range opts.AllowedKinds {
+		if (k == KindNameserver || k == KindEntity) && !containsKind(chain, k) {
+			chain = append(chain, k)
 		}
-		// fall through to Domain if nameserver path 404s at registry
 	}
+	return chain
+}
 
-	// 4) Entity handle (registry-specific). If caller passed a hint, try Entity.
-	// Common entity handles contain '-' or all-caps alpha + digits (e.g., "ORG-EXAMPLE1").
-	if tldHint != "" && looksLikeEntityHandle(ls) {
-		if e, err := c.Entity(ctx, s, tldHint); err == nil {
-			return e, nil
+func (c *Client) lookupKind(ctx context.Context, k Kind, s, tldHint string) (any, error) {
+	switch k {
+	case KindAutnum:
+		return c.Autnum(ctx, s)
+	case KindIP:
+		// Normalize form (avoid mixed-case or stray spaces)
+		if pfx, err := netip.ParsePrefix(s); err == nil {
+			return c.IP(ctx, pfx.String())
+		}
+		ip, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, err
+		}
+		return c.IP(ctx, ip.String())
+	case KindNameserver:
+		return c.Nameserver(ctx, strings.ToLower(s))
+	case KindEntity:
+		return c.Entity(ctx, s, tldHint)
+	default:
+		return c.Domain(ctx, strings.ToLower(s))
+	}
+}
+
+func containsKind(ks []Kind, k Kind) bool {
+	for _, x := range ks {
+		if x == k {
+			return true
 		}
-		// fall back to domain next
 	}
+	return false
+}
 
-	// 5) Default: treat as FQDN domain
-	return c.Domain(ctx, ls)
+// isTaggedHandle reports whether s reads as an RFC 8521 tagged handle
+// ("ABC123-ARIN") rather than a name: no dots, an upper-case tag of letters.
+func isTaggedHandle(s string) bool {
+	tag := handleTag(s)
+	if tag == "" || strings.Contains(s, ".") || s[len(s)-len(tag):] != tag {
+		return false
+	}
+	for _, r := range tag {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
 }
 
 func looksLikeEntityHandle(s string) bool {
@@ -73,3 +175,24 @@ func looksLikeEntityHandle(s string) bool {
 	}
 	return hasAlpha && hasDigit
 }
+
+// RegistryBase returns the RDAP base URL a lookup of kind k for q is sent to,
+// with the same fallbacks the object methods apply. Schedulers use it to group
+// requests by registry before issuing them.
+func (c *Client) RegistryBase(ctx context.Context, k Kind, q, tldHint string) (string, error) {
+	switch k {
+	case KindAutnum:
+		return c.rdapBaseForASN(ctx, q)
+	case KindIP:
+		return c.rdapBaseForIP(ctx, q)
+	case KindEntity:
+		return c.rdapBaseForEntity(ctx, q, tldHint), nil
+	case KindNameserver:
+		if base, err := c.rdapBaseForDomain(ctx, q); err == nil && base != "" {
+			return base, nil
+		}
+		return "https://rdap.org", nil
+	default:
+		return c.rdapBaseForDomain(ctx, q)
+	}
+}
@@ -0,0 +1,68 @@
+package rdapclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Meta is how a looked-up object was obtained, for pipelines that keep the
+// registry's response next to the parsed model. Lookups attach it to the
+// top-level object as CommonObject.Meta; nested objects have none.
+type Meta struct {
+	// Raw is the response body as the registry sent it: for a lookup answered
+	// by a search results wrapper, the whole wrapper.
+	Raw json.RawMessage
+	// Header is the response's HTTP headers. A revalidated object has the
+	// 304's; a cache hit has those of the response that was cached.
+	Header http.Header
+	// FetchedAt is when the registry sent the response, or last confirmed it
+	// with a 304. It is zero for dataset objects.
+	FetchedAt time.Time
+	// URL is the lookup URL and FinalURL where its redirects ended (URL
+	// itself when there were none).
+	URL      string
+	FinalURL string
+	// Proto is the protocol the registry answered with ("HTTP/1.1",
+	// "HTTP/2.0", "HTTP/3.0"; see WithHTTP3). It is empty for cache hits.
+	Proto string
+	// CacheHit is set when the registry was not asked: the response cache,
+	// the canonical cache or the dataset answered. An expired copy served
+	// because the registry failed (WithStaleIfError) is a cache hit too, and
+	// its Header carries a Warning.
+	CacheHit bool
+}
+
+// fetchedFrom is the URL the response m describes was fetched from, where its
+// redirects ended, or "" without one: the document links in it came from,
+// for the LinkPolicy. It never comes from the response itself.
+func fetchedFrom(m *Meta) string {
+	if m == nil {
+		return ""
+	}
+	return m.FinalURL
+}
+
+// topObject returns the CommonObject of a top-level object, or nil.
+func topObject(obj Object) *CommonObject {
+	switch v := obj.(type) {
+	case *Domain:
+		return &v.CommonObject
+	case *Nameserver:
+		return &v.CommonObject
+	case *Entity:
+		return &v.CommonObject
+	case *IPNetwork:
+		return &v.CommonObject
+	case *Autnum:
+		return &v.CommonObject
+	}
+	return nil
+}
+
+// setMeta attaches m to obj.
+func setMeta(obj Object, m *Meta) {
+	if top := topObject(obj); top != nil {
+		top.Meta = m
+	}
+}
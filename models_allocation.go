@@ -0,0 +1,94 @@
+package rdapclient
+
+import "strings"
+
+// AllocationType is a registry-neutral reading of the free-form "type" member
+// of IP networks and autnums. Each RIR spells it differently (ARIN "DIRECT
+// ALLOCATION", RIPE "ASSIGNED PA", APNIC "ALLOCATED PORTABLE", ...); the
+// normalized value groups them so results can be compared across registries.
+// The raw string stays in the object's Type field.
+type AllocationType string
+
+const (
+	// AllocationNone means the server sent no type.
+	AllocationNone AllocationType = ""
+	// AllocationAllocated is a block an RIR allocated to an LIR/ISP for further
+	// assignment (ARIN DIRECT ALLOCATION, RIPE ALLOCATED PA, APNIC ALLOCATED PORTABLE).
+	AllocationAllocated AllocationType = "allocated"
+	// AllocationSubAllocated is part of an allocation handed down to another
+	// provider (ARIN REALLOCATION, RIPE SUB-ALLOCATED PA / ALLOCATED-BY-LIR,
+	// APNIC ALLOCATED NON-PORTABLE).
+	AllocationSubAllocated AllocationType = "sub-allocated"
+	// AllocationAssigned is a block assigned for use by an end user, directly by
+	// the RIR or by a provider (ARIN DIRECT ASSIGNMENT / REASSIGNMENT, RIPE
+	// ASSIGNED PA / PI / ANYCAST, APNIC ASSIGNED PORTABLE / NON-PORTABLE).
+	AllocationAssigned AllocationType = "assigned"
+	// AllocationLegacy is address space or an ASN registered before the RIRs
+	// (RIPE LEGACY, ARIN early registrations).
+	AllocationLegacy AllocationType = "legacy"
+	// AllocationReserved is space held back by IANA or an RIR (RESERVED, IETF special use).
+	AllocationReserved AllocationType = "reserved"
+	// AllocationAvailable is space the RIR holds but has not yet handed out.
+	AllocationAvailable AllocationType = "available"
+	// AllocationOther is a type this package does not recognize; see the raw Type.
+	AllocationOther AllocationType = "other"
+)
+
+// ParseAllocationType maps a raw RDAP "type" value onto AllocationType.
+// Matching ignores case and separators ("ASSIGNED PA", "assigned-pa" and
+// "Assigned_PA" are the same); unknown values give AllocationOther.
+func ParseAllocationType(raw string) AllocationType {
+	s := strings.Join(strings.FieldsFunc(lower(raw), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_' || r == '/'
+	}), " ")
+	switch {
+	case s == "":
+		return AllocationNone
+	case strings.Contains(s, "legacy"), strings.Contains(s, "early registration"):
+		return AllocationLegacy
+	case strings.Contains(s, "reserved"), strings.Contains(s, "special use"):
+		return AllocationReserved
+	case s == "available", s == "unallocated", strings.Contains(s, "not allocated"):
+		return AllocationAvailable
+	case strings.Contains(s, "reallocat"), strings.Contains(s, "sub allocat"),
+		s == "allocated non portable", s == "allocated by lir":
+		return AllocationSubAllocated
+	case strings.Contains(s, "assign"):
+		return AllocationAssigned
+	case strings.Contains(s, "allocat"):
+		return AllocationAllocated
+	}
+	return AllocationOther
+}
+
+// AllocationType returns n's normalized type (see ParseAllocationType).
+func (n *IPNetwork) AllocationType() AllocationType { return ParseAllocationType(n.Type) }
+
+// AllocationType returns a's normalized type (see ParseAllocationType).
+func (a *Autnum) AllocationType() AllocationType { return ParseAllocationType(a.Type) }
+
+// countryAliases maps codes registries use that are not ISO 3166-1 alpha-2.
+var countryAliases = map[string]string{
+	"UK": "GB",
+}
+
+// NormalizeCountry returns the upper-case two-letter code for an RDAP "country"
+// value, trimming space and mapping common aliases (UK to GB). RIPE's "EU" and
+// "ZZ" (unknown) are kept as they are. It returns "" for anything that is not
+// a two-letter code.
+func NormalizeCountry(raw string) string {
+	s := strings.ToUpper(strings.TrimSpace(raw))
+	if alias, ok := countryAliases[s]; ok {
+		return alias
+	}
+	if len(s) != 2 || s[0] < 'A' || s[0] > 'Z' || s[1] < 'A' || s[1] > 'Z' {
+		return ""
+	}
+	return s
+}
+
+// CountryCode returns n's country normalized by NormalizeCountry.
+func (n *IPNetwork) CountryCode() string { return NormalizeCountry(n.Country) }
+
+// CountryCode returns a's country normalized by NormalizeCountry.
+func (a *Autnum) CountryCode() string { return NormalizeCountry(a.Country) }
@@ -0,0 +1,83 @@
+package rdapclient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizeClassNames fills in a missing objectClassName on every object nested
+// in obj (entities, nameservers, networks, autnums), inferred from the member
+// it appears under. Some servers omit it on nested objects, which otherwise
+// breaks Validate and helpers such as ServerBase. Present values are kept.
+func NormalizeClassNames(obj Object) {
+	walkNested(obj, "", func(_ string, want string, c *CommonObject) {
+		if c.ObjectClassName == "" {
+			c.ObjectClassName = want
+		}
+	})
+}
+
+// checkClassNames returns an error naming every nested object whose
+// objectClassName is missing or does not match where it appears.
+func checkClassNames(obj Object) error {
+	var bad []string
+	walkNested(obj, "", func(path, want string, c *CommonObject) {
+		if !strings.EqualFold(c.ObjectClassName, want) {
+			bad = append(bad, fmt.Sprintf("%s=%q", path, c.ObjectClassName))
+		}
+	})
+	if len(bad) > 0 {
+		return fmt.Errorf("rdap: nested objectClassName mismatch (strict parsing): %s", strings.Join(bad, ", "))
+	}
+	return nil
+}
+
+// walkNested calls fn for every object nested in obj (not obj itself) with its
+// JSON path and the class name its position implies.
+func walkNested(obj Object, path string, fn func(path, want string, c *CommonObject)) {
+	at := func(member string, i int) string {
+		if path == "" {
+			return fmt.Sprintf("%s[%d]", member, i)
+		}
+		return fmt.Sprintf("%s.%s[%d]", path, member, i)
+	}
+	entities := func(es []Entity) {
+		for i := range es {
+			p := at("entities", i)
+			fn(p, "entity", &es[i].CommonObject)
+			walkNested(&es[i], p, fn)
+		}
+	}
+	switch v := obj.(type) {
+	case *Domain:
+		entities(v.Entities)
+		for i := range v.Nameservers {
+			p := at("nameservers", i)
+			fn(p, "nameserver", &v.Nameservers[i].CommonObject)
+			walkNested(&v.Nameservers[i], p, fn)
+		}
+		if v.Network != nil {
+			p := strings.TrimPrefix(path+".network", ".")
+			fn(p, "ip network", &v.Network.CommonObject)
+			walkNested(v.Network, p, fn)
+		}
+	case *Nameserver:
+		entities(v.Entities)
+	case *IPNetwork:
+		entities(v.Entities)
+	case *Autnum:
+		entities(v.Entities)
+	case *Entity:
+		entities(v.Entities)
+		for i := range v.Networks {
+			p := at("networks", i)
+			fn(p, "ip network", &v.Networks[i].CommonObject)
+			walkNested(&v.Networks[i], p, fn)
+		}
+		for i := range v.Autnums {
+			p := at("autnums", i)
+			fn(p, "autnum", &v.Autnums[i].CommonObject)
+			walkNested(&v.Autnums[i], p, fn)
+		}
+	}
+}
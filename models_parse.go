@@ -1,8 +1,10 @@
 package rdapclient
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 )
 
 // Object is a union interface implemented by all object classes.
@@ -67,6 +69,182 @@ func ParseObject(m map[string]any) (Object, error) {
 	}
 }
 
+// DecodeHook adjusts an object after standard decoding; raw is the response it
+// was decoded from. Returning an error fails the lookup.
+type DecodeHook func(raw json.RawMessage, obj Object) error
+
+// parseObject is ParseObject followed by nested objectClassName handling
+// (filled in when lenient, checked when strict), extension gating and the
+// client's decode hooks for the object's class. Hooks see top-level objects only, not nested ones.
+func (c *Client) parseObject(m map[string]any) (Object, error) {
+	obj, err := ParseObject(m)
+	if err != nil {
+		return nil, err
+	}
+	if c.strictParsing {
+		if err := checkClassNames(obj); err != nil {
+			return nil, err
+		}
+	} else {
+		NormalizeClassNames(obj)
+	}
+	if c.gateExtensions {
+		gateExtensions(obj)
+	}
+	if len(c.decodeHooks) == 0 {
+		return obj, nil
+	}
+	class := lower(obj.GetObjectClassName())
+	hooks := append(append([]DecodeHook(nil), c.decodeHooks[""]...), c.decodeHooks[class]...)
+	if len(hooks) == 0 {
+		return obj, nil
+	}
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hooks {
+		if err := h(raw, obj); err != nil {
+			return nil, fmt.Errorf("rdap decode hook (%s): %w", class, err)
+		}
+	}
+	return obj, nil
+}
+
+// fetchObject GETs a lookup URL and parses the response, failing with
+// ErrUnexpectedObject unless its objectClassName is want.
+// A dataset (WithDataset), then an equivalent URL's object in the canonical
+// cache (WithCanonicalCache), answer u before the network does, unless ctx
+// asks for fresh data (WithFreshData).
+func (c *Client) fetchObject(ctx context.Context, u, want string) (Object, error) {
+	obj, cached, err := c.fetchObjectFrom(ctx, u, want)
+	if r := CompletenessFrom(ctx); r != nil {
+		if err != nil {
+			r.Failed(u, urlHost(u), 0, err)
+		} else {
+			r.Fetched(cached)
+		}
+	}
+	return obj, err
+}
+
+// fetchObjectFrom is fetchObject; cached reports whether a fresh local copy
+// answered.
+func (c *Client) fetchObjectFrom(ctx context.Context, u, want string) (obj Object, cached bool, err error) {
+	if _, seg, id := splitLookupURL(u); seg != "" {
+		if obj, ok := c.fromDataset(ctx, Kind(seg), id, want); ok {
+			if top := topObject(obj); top != nil && top.Meta != nil {
+				top.Meta.URL, top.Meta.FinalURL = u, u
+			}
+			return obj, true, nil
+		}
+	}
+	if c.canon != nil && !wantsFresh(ctx) {
+		if e, ok := c.canon.get(u); ok {
+			if obj, err := c.parseObject(e.raw); err == nil && lower(obj.GetObjectClassName()) == want {
+				if e.meta != nil {
+					meta := *e.meta
+					meta.URL, meta.CacheHit = u, true
+					setMeta(obj, &meta)
+				}
+				return obj, true, nil
+			}
+		}
+	}
+	f, err := c.getResponse(ctx, u)
+	if err != nil {
+		return nil, false, err
+	}
+	m := f.m
+	cached = f.cached && !f.stale
+	if m, err = unwrapSearchResults(u, want, m); err != nil {
+		return nil, false, err
+	}
+	obj, err = c.parseObject(m)
+	if err != nil {
+		return nil, false, err
+	}
+	if got := obj.GetObjectClassName(); lower(got) != want {
+		raw, _ := json.Marshal(m)
+		return nil, false, ErrUnexpectedObject{Want: want, Got: got, URL: u, Raw: raw}
+	}
+	setMeta(obj, f.meta(u))
+	if c.canon != nil {
+		var final string
+		if v, ok := c.redirects.Load(u); ok {
+			final = v.(string)
+		}
+		c.canon.put(u, obj, m, final)
+	}
+	return obj, cached, nil
+}
+
+// searchResultMembers are the RFC 9083 search result arrays (plus the ip and
+// autnum ones some servers use) that may wrap a lookup answer.
+var searchResultMembers = []string{
+	"domainSearchResults", "nameserverSearchResults", "entitySearchResults",
+	"ipSearchResults", "autnumSearchResults",
+}
+
+// unwrapSearchResults returns the single object inside a search results
+// wrapper served at lookup URL u, or m itself when it is not a wrapper.
+// Several results fail with *ErrAmbiguousObject.
+func unwrapSearchResults(u, want string, m map[string]any) (map[string]any, error) {
+	if _, ok := m["objectClassName"]; ok {
+		return m, nil
+	}
+	for _, member := range searchResultMembers {
+		arr, ok := m[member].([]any)
+		if !ok {
+			continue
+		}
+		var objs []map[string]any
+		for _, v := range arr {
+			if o, ok := v.(map[string]any); ok {
+				objs = append(objs, o)
+			}
+		}
+		switch len(objs) {
+		case 0:
+			return nil, ErrUnexpectedObject{Want: want, URL: u}
+		case 1:
+			return objs[0], nil
+		}
+		names := make([]string, 0, len(objs))
+		for _, o := range objs {
+			name, _ := o["ldhName"].(string)
+			if name == "" {
+				name, _ = o["handle"].(string)
+			}
+			names = append(names, name)
+		}
+		return nil, &ErrAmbiguousObject{URL: u, Want: want, Candidates: names}
+	}
+	return m, nil
+}
+
+// fromDataset returns the object of kind k for id from the client's dataset,
+// provided it parses as class want. WithFreshData contexts skip the dataset.
+func (c *Client) fromDataset(ctx context.Context, k Kind, id, want string) (Object, bool) {
+	if c.dataset == nil || wantsFresh(ctx) {
+		return nil, false
+	}
+	b, ok := c.dataset.line(k, id)
+	if !ok || c.maxNesting > 0 && nestedDeeper(b, c.maxNesting) {
+		return nil, false
+	}
+	m, err := c.dataset.raw(k, id)
+	if err != nil {
+		return nil, false
+	}
+	obj, err := c.parseObject(m)
+	if err != nil || lower(obj.GetObjectClassName()) != want {
+		return nil, false
+	}
+	setMeta(obj, &Meta{Raw: append(json.RawMessage(nil), b...), CacheHit: true})
+	return obj, true
+}
+
 func decodeInto(m map[string]any, v any) error {
 	b, err := json.Marshal(m)
 	if err != nil {
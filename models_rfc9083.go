@@ -71,6 +71,11 @@ type CommonObject struct {
 	// Top-level-only (but harmless if present elsewhere)
 	RDAPConformance []string `json:"rdapConformance,omitempty"`
 	Notices         []Notice `json:"notices,omitempty"`
+	// Redacted lists the fields the server removed or altered (RFC 9537).
+	Redacted []Redaction `json:"redacted,omitempty"`
+
+	// Meta is the response a lookup decoded this object from; see Meta.
+	Meta *Meta `json:"-"`
 }
 
 // VariantName represents a single variant domain label.
@@ -122,8 +127,10 @@ type Entity struct {
 	Roles        []string       `json:"roles,omitempty"`
 	PublicIDs    []PublicID     `json:"publicIds,omitempty"`
 	AsEventActor []EventNoActor `json:"asEventActor,omitempty"`
-	Networks     []IPNetwork    `json:"networks,omitempty"`
-	Autnums      []Autnum       `json:"autnums,omitempty"`
+	// Networks and Autnums hold inline arrays only; RIPE and APNIC link a
+	// search instead. Use Client.EntityNetworks / EntityAutnums to get either.
+	Networks []IPNetwork `json:"networks,omitempty"`
+	Autnums  []Autnum    `json:"autnums,omitempty"`
 }
 
 // Nameserver represents the RDAP nameserver object class.
@@ -132,6 +139,9 @@ type Nameserver struct {
 	LDHName     string       `json:"ldhName,omitempty"`
 	UnicodeName string       `json:"unicodeName,omitempty"`
 	IPAddresses *IPAddresses `json:"ipAddresses,omitempty"`
+	// Synthetic marks an object no server published, built from the parent
+	// domain's nameserver entry (see WithSynthesizedNameservers).
+	Synthetic bool `json:"synthetic,omitempty"`
 }
 
 // Domain represents the RDAP domain object class.
@@ -156,6 +166,10 @@ type IPNetwork struct {
 	Type         string `json:"type,omitempty"`
 	Country      string `json:"country,omitempty"`
 	ParentHandle string `json:"parentHandle,omitempty"`
+	// OriginAutnums lists origin ASNs for the network (ARIN "arin_originas0" extension).
+	OriginAutnums []int64 `json:"arin_originas0_originautnums,omitempty"`
+	// CIDRs is the network as CIDR blocks (the "cidr0" extension).
+	CIDRs []CIDR0 `json:"cidr0_cidrs,omitempty"`
 }
 
 // Autnum represents the RDAP autnum object class.
@@ -177,3 +191,12 @@ func (d *Domain) Validate() bool     { return lower(d.ObjectClassName) == "domai
 func (n *Nameserver) Validate() bool { return lower(n.ObjectClassName) == "nameserver" }
 func (i *IPNetwork) Validate() bool  { return lower(i.ObjectClassName) == "ip network" }
 func (a *Autnum) Validate() bool     { return lower(a.ObjectClassName) == "autnum" }
+
+// IsStub reports whether e carries only identification (handle, roles, links,
+// class name) as embedded entities often do, so the full record must be fetched
+// (see Client.Hydrate) before its contact data or relations can be used.
+func (e *Entity) IsStub() bool {
+	return e.VCardArray == nil && len(e.PublicIDs) == 0 && len(e.AsEventActor) == 0 &&
+		len(e.Networks) == 0 && len(e.Autnums) == 0 && len(e.Entities) == 0 &&
+		len(e.Status) == 0 && len(e.Events) == 0 && len(e.Remarks) == 0
+}
@@ -0,0 +1,158 @@
+package rdapclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sources of a NameserverResolution.
+const (
+	NameserverFromRegistry  = "registry"  // the registry for the host's TLD
+	NameserverFromRegistrar = "registrar" // the registrar's RDAP service, linked from the parent domain
+	NameserverFromGlue      = "glue"      // built from the parent domain's embedded nameserver entry
+)
+
+// NameserverAttempt is one step of the fallback chain that failed.
+type NameserverAttempt struct {
+	Source string `json:"source"`
+	URL    string `json:"url,omitempty"`
+	Err    error  `json:"-"`
+}
+
+// NameserverResolution is a nameserver object and where it came from.
+type NameserverResolution struct {
+	Nameserver *Nameserver `json:"nameserver"`
+	// Source is NameserverFromRegistry, NameserverFromRegistrar or NameserverFromGlue.
+	Source string `json:"source"`
+	// URL is the registrar lookup URL for NameserverFromRegistrar.
+	URL string `json:"url,omitempty"`
+	// Parent is the domain consulted for the registrar link and glue, when it was.
+	Parent string `json:"parent,omitempty"`
+	// Tried lists the steps that failed before Source, in order.
+	Tried []NameserverAttempt `json:"tried,omitempty"`
+}
+
+// ErrNameserverUnavailable is returned by NameserverWithFallback when every
+// step of the chain failed.
+type ErrNameserverUnavailable struct {
+	Host  string
+	Tried []NameserverAttempt
+}
+
+func (e *ErrNameserverUnavailable) Error() string {
+	parts := make([]string, 0, len(e.Tried))
+	for _, t := range e.Tried {
+		parts = append(parts, fmt.Sprintf("%s (%v)", t.Source, t.Err))
+	}
+	return fmt.Sprintf("rdap nameserver %q: tried %s", e.Host, strings.Join(parts, ", "))
+}
+
+// Unwrap exposes each step's error to errors.Is / errors.As.
+func (e *ErrNameserverUnavailable) Unwrap() []error {
+	errs := make([]error, 0, len(e.Tried))
+	for _, t := range e.Tried {
+		errs = append(errs, t.Err)
+	}
+	return errs
+}
+
+// NameserverWithFallback looks host up at its TLD's registry and, when the
+// registry has no object for it, tries the registrar's RDAP service (the
+// rel="related" domain link of the parent domain, subject to the client's
+// LinkPolicy) and finally the parent domain's own nameserver entry, whose
+// name and glue addresses are all a registry may publish. The parent is the
+// registrable domain of host, so this only helps for in-bailiwick names.
+func (c *Client) NameserverWithFallback(ctx context.Context, host string) (*NameserverResolution, error) {
+	host, err := NormalizeFQDN(host)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := c.registryNameserver(ctx, host)
+	if err == nil {
+		return &NameserverResolution{Nameserver: ns, Source: NameserverFromRegistry}, nil
+	}
+	if ctx.Err() != nil {
+		return nil, err
+	}
+	return c.nameserverFallback(ctx, host, err)
+}
+
+// nameserverFallback runs the registrar and glue steps for host after the
+// registry failed with regErr.
+func (c *Client) nameserverFallback(ctx context.Context, host string, regErr error) (*NameserverResolution, error) {
+	res := &NameserverResolution{Tried: []NameserverAttempt{{Source: NameserverFromRegistry, Err: regErr}}}
+	res.Parent = registrableDomain(host)
+	if res.Parent == "" {
+		return nil, &ErrNameserverUnavailable{Host: host, Tried: res.Tried}
+	}
+	d, err := c.Domain(ctx, res.Parent)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		res.Tried = append(res.Tried, NameserverAttempt{Source: NameserverFromRegistrar, Err: fmt.Errorf("parent domain %s: %w", res.Parent, err)})
+		return nil, &ErrNameserverUnavailable{Host: host, Tried: res.Tried}
+	}
+
+	if u, from := registrarNameserverURL(d, host); u != "" {
+		err := c.CheckLink(from, u)
+		var obj Object
+		if err == nil {
+			obj, err = c.fetchObject(ctx, u, "nameserver")
+		}
+		if err == nil {
+			res.Nameserver, res.Source, res.URL = obj.(*Nameserver), NameserverFromRegistrar, u
+			return res, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		res.Tried = append(res.Tried, NameserverAttempt{Source: NameserverFromRegistrar, URL: u, Err: err})
+	} else {
+		res.Tried = append(res.Tried, NameserverAttempt{Source: NameserverFromRegistrar, Err: errors.New("no registrar link on " + res.Parent)})
+	}
+
+	if ns := glueNameserver(d, host); ns != nil {
+		res.Nameserver, res.Source = ns, NameserverFromGlue
+		return res, nil
+	}
+	res.Tried = append(res.Tried, NameserverAttempt{Source: NameserverFromGlue, Err: fmt.Errorf("%s lists no nameserver %s", res.Parent, host)})
+	return nil, &ErrNameserverUnavailable{Host: host, Tried: res.Tried}
+}
+
+// registrarNameserverURL derives the registrar's nameserver lookup URL for
+// host from d's rel="related" link to the registrar's copy of d, returning it
+// and the URL d was fetched from, the document the link came from.
+func registrarNameserverURL(d *Domain, host string) (string, string) {
+	for _, l := range Links(d.Links).Ranked("related") {
+		if t := strings.ToLower(l.Type); t != "" && !strings.Contains(t, "json") {
+			continue
+		}
+		i := strings.LastIndex(l.Href, "/domain/")
+		if i < 0 {
+			continue
+		}
+		u, err := BuildObjectURL(l.Href[:i], "nameserver", host)
+		if err != nil {
+			continue
+		}
+		return u, fetchedFrom(d.Meta)
+	}
+	return "", ""
+}
+
+// glueNameserver returns a copy of d's embedded entry for host as a
+// Synthetic nameserver object, or nil when d does not list it.
+func glueNameserver(d *Domain, host string) *Nameserver {
+	for i := range d.Nameservers {
+		n := &d.Nameservers[i]
+		if strings.EqualFold(strings.TrimSuffix(n.LDHName, "."), host) {
+			ns := cloneJSON(n)
+			ns.ObjectClassName, ns.Synthetic = "nameserver", true
+			return ns
+		}
+	}
+	return nil
+}
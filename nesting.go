@@ -0,0 +1,52 @@
+package rdapclient
+
+import "fmt"
+
+// DefaultMaxNestingDepth is how deeply a response's JSON may nest (objects and
+// arrays) unless WithMaxNestingDepth says otherwise. A domain with three levels
+// of nested entities and their jCards stays under 20.
+const DefaultMaxNestingDepth = 64
+
+// ErrNestingTooDeep is returned for a response nested deeper than the client's
+// limit (see WithMaxNestingDepth), e.g. entities within entities hundreds
+// deep from a hostile or broken server. The response is neither decoded nor
+// cached.
+type ErrNestingTooDeep struct {
+	URL   string
+	Limit int
+}
+
+func (e *ErrNestingTooDeep) Error() string {
+	return fmt.Sprintf("rdap GET %s: response nested deeper than %d levels", e.URL, e.Limit)
+}
+
+// nestedDeeper reports whether the JSON in b nests objects and arrays more
+// than limit deep. It only tracks brackets outside strings, so it runs before
+// (and instead of) decoding a hostile body, in one pass without allocating.
+func nestedDeeper(b []byte, limit int) bool {
+	depth, inString, escaped := 0, false, false
+	for _, ch := range b {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '{', '[':
+			if depth++; depth > limit {
+				return true
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package rdapclient
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Normalize returns a copy of o with the arrays whose order carries no meaning
+// sorted, recursively: status and roles, entities by handle, nameservers by
+// name, links by rel and href, and nameserver addresses. Servers reorder these
+// between responses, so compare and digest normalized objects to see only real
+// changes. o itself (often shared through the client's caches) is untouched;
+// objects other than the five classes are returned as they are.
+func Normalize(o Object) Object {
+	switch v := o.(type) {
+	case *Domain:
+		d := cloneJSON(v)
+		normalizeDomain(d)
+		return d
+	case *Nameserver:
+		n := cloneJSON(v)
+		normalizeNameserver(n)
+		return n
+	case *Entity:
+		e := cloneJSON(v)
+		normalizeEntity(e)
+		return e
+	case *IPNetwork:
+		n := cloneJSON(v)
+		normalizeCommon(&n.CommonObject)
+		return n
+	case *Autnum:
+		a := cloneJSON(v)
+		normalizeCommon(&a.CommonObject)
+		return a
+	}
+	return o
+}
+
+// cloneJSON deep-copies v through its JSON form, which is all an RDAP object has.
+func cloneJSON[T any](v *T) *T {
+	out := new(T)
+	b, err := json.Marshal(v)
+	if err != nil || json.Unmarshal(b, out) != nil {
+		return v
+	}
+	return out
+}
+
+func normalizeCommon(o *CommonObject) {
+	sort.Strings(o.Status)
+	sort.SliceStable(o.Links, func(i, j int) bool {
+		a, b := o.Links[i], o.Links[j]
+		if a.Rel != b.Rel {
+			return a.Rel < b.Rel
+		}
+		return a.Href < b.Href
+	})
+	for i := range o.Entities {
+		normalizeEntity(&o.Entities[i])
+	}
+	sort.SliceStable(o.Entities, func(i, j int) bool {
+		a, b := o.Entities[i], o.Entities[j]
+		if a.Handle != b.Handle {
+			return a.Handle < b.Handle
+		}
+		return strings.Join(a.Roles, ",") < strings.Join(b.Roles, ",")
+	})
+}
+
+func normalizeEntity(e *Entity) {
+	normalizeCommon(&e.CommonObject)
+	sort.Strings(e.Roles)
+	for i := range e.Networks {
+		normalizeCommon(&e.Networks[i].CommonObject)
+	}
+	for i := range e.Autnums {
+		normalizeCommon(&e.Autnums[i].CommonObject)
+	}
+}
+
+func normalizeNameserver(n *Nameserver) {
+	normalizeCommon(&n.CommonObject)
+	if n.IPAddresses != nil {
+		sort.Strings(n.IPAddresses.V4)
+		sort.Strings(n.IPAddresses.V6)
+	}
+}
+
+func normalizeDomain(d *Domain) {
+	normalizeCommon(&d.CommonObject)
+	for i := range d.Nameservers {
+		normalizeNameserver(&d.Nameservers[i])
+	}
+	sort.SliceStable(d.Nameservers, func(i, j int) bool {
+		return lower(d.Nameservers[i].LDHName) < lower(d.Nameservers[j].LDHName)
+	})
+	if d.Network != nil {
+		normalizeCommon(&d.Network.CommonObject)
+	}
+}
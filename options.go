@@ -1,6 +1,10 @@
 package rdapclient
 
-import "time"
+import (
+	"log/slog"
+	"net/url"
+	"time"
+)
 
 type Option func(*Client)
 
@@ -10,9 +14,15 @@ func WithTimeout(d time.Duration) Option  { return func(c *Client) { c.baseTimeo
 func WithBootstrapURL(u string) Option    { return func(c *Client) { c.bootstrapURL = u } }
 func WithIPBootstrapURL(u string) Option  { return func(c *Client) { c.ipBootstrapURL = u } }
 func WithASNBootstrapURL(u string) Option { return func(c *Client) { c.asnBootstrapURL = u } }
-func WithMaxRetries(n int) Option         { return func(c *Client) { c.maxRetries = n } }
-func WithBackoff(b Backoff) Option        { return func(c *Client) { c.backoff = b } }
-func WithHeader(k, v string) Option       { return func(c *Client) { c.headerExtra.Add(k, v) } }
+
+// WithObjectTagBootstrapURL replaces IANA's object-tags bootstrap (RFC 8521),
+// which routes entity handles ("ABC123-ARIN") to their registry by tag.
+func WithObjectTagBootstrapURL(u string) Option {
+	return func(c *Client) { c.objectTagBootstrapURL = u }
+}
+func WithMaxRetries(n int) Option   { return func(c *Client) { c.maxRetries = n } }
+func WithBackoff(b Backoff) Option  { return func(c *Client) { c.backoff = b } }
+func WithHeader(k, v string) Option { return func(c *Client) { c.headerExtra.Add(k, v) } }
 func WithDefaultRDAPBase(u string) Option {
 	return func(c *Client) {
 		if u != "" {
@@ -20,6 +30,10 @@ func WithDefaultRDAPBase(u string) Option {
 		}
 	}
 }
+
+// WithCacheSizes caps the registry bases kept for IP, ASN and entity lookups
+// (tldCap; the DNS bootstrap table is always kept whole) and the cached
+// responses (entityCap). Zero keeps a default.
 func WithCacheSizes(tldCap, entityCap int) Option {
 	return func(c *Client) {
 		if tldCap > 0 {
@@ -30,3 +44,223 @@ func WithCacheSizes(tldCap, entityCap int) Option {
 		}
 	}
 }
+
+// WithRecorder writes every HTTP exchange to a cassette file under dir.
+func WithRecorder(dir string) Option { return func(c *Client) { c.recordDir = dir } }
+
+// WithReplay serves responses from cassettes recorded by WithRecorder and never
+// uses the network; requests without a cassette fail with ErrCassetteMiss.
+// It takes precedence over WithHTTPDoer, and over WithRecorder, whose dir then
+// receives an unchanged copy of each cassette served.
+func WithReplay(dir string) Option { return func(c *Client) { c.replayDir = dir } }
+
+// WithLenientParsing controls nested objectClassName handling. Lenient (the
+// default) fills in a missing class from the object's position; strict fails
+// the lookup when a nested object's class is missing or wrong.
+func WithLenientParsing(on bool) Option { return func(c *Client) { c.strictParsing = !on } }
+
+// WithDecodeHook registers fn to run after an object of class ("domain",
+// "ip network", "autnum", "nameserver", "entity"; "" for all) is decoded by a
+// lookup, e.g. to normalize a registry's ipVersion "IPv4" to "v4". Hooks run in
+// registration order, those for "" first.
+func WithDecodeHook(class string, fn DecodeHook) Option {
+	return func(c *Client) {
+		if c.decodeHooks == nil {
+			c.decodeHooks = map[string][]DecodeHook{}
+		}
+		k := lower(class)
+		c.decodeHooks[k] = append(c.decodeHooks[k], fn)
+	}
+}
+
+// WithRateLimit caps the client at perSecond HTTP requests per second, spacing
+// requests evenly. Zero or less means unlimited. Replay mode is never limited.
+func WithRateLimit(perSecond float64) Option { return func(c *Client) { c.rateLimit = perSecond } }
+
+// WithHostRateLimit caps lookups against registry host ("rdap.verisign.com")
+// at rps requests per second, so a walk fanning out across one registry does
+// not trip its throttling. Host "" sets the default for every host without a
+// limit of its own. Each host has a token bucket holding a second's worth of
+// requests (at least one); every request attempt, retries included, waits
+// for a token first. Zero or less lifts the limit. It applies on top of
+// WithRateLimit.
+func WithHostRateLimit(host string, rps float64) Option {
+	return func(c *Client) { c.hostRates.set(host, rps) }
+}
+
+// WithHTTPProxy routes requests through the proxy at u (http, https or socks5).
+// It applies to the default HTTP client or one set with WithHTTPDoer when that
+// is an *http.Client; other Doers are left alone.
+func WithHTTPProxy(u *url.URL) Option { return func(c *Client) { c.proxyURL = u } }
+
+// WithHTTP3 sends https requests over HTTP/3 (QUIC, with quic-go) first,
+// falling back to the regular HTTP/2/1.1 client for a host whose QUIC attempt
+// fails. The protocol each registry answered with shows up in Health and
+// Meta.Proto. Redirects and the timeout follow the regular client's. HTTP/3
+// is not used with WithHTTPProxy, since QUIC cannot go through the proxy.
+func WithHTTP3(on bool) Option {
+	return func(c *Client) {
+		c.http3 = nil
+		if on {
+			c.http3 = newHTTP3Transport()
+		}
+	}
+}
+
+// WithCanonicalCache keeps up to size looked-up objects keyed by (registry
+// base, objectClassName, handle), so a lookup by a different URL for the same
+// object is answered from memory: /ip/192.0.2.1 after /ip/192.0.2.0/24, a
+// self link after a lookup, an autnum by number after its handle. An IP query
+// inside a cached network's range is served that network (the most specific
+// cached one), which misses a more-specific assignment not yet seen. Entries
+// live as long as the response cache's default TTL. size <= 0 disables it.
+func WithCanonicalCache(size int) Option {
+	return func(c *Client) {
+		if size <= 0 {
+			c.canon = nil
+			return
+		}
+		c.canon = newCanonCache(size, c.respCache.defTTL, func() time.Time { return c.now() })
+	}
+}
+
+// WithDataset answers lookups from ds, a local read-only mirror opened with
+// OpenDataset, before any request is made; objects it lacks are fetched as
+// usual. The caller keeps ownership of ds and closes it.
+func WithDataset(ds *Dataset) Option { return func(c *Client) { c.dataset = ds } }
+
+// WithBootstrapTTL sets how long registry base URLs from the IANA bootstrap
+// files are used. Past soft (default 6h) a base is still served while a
+// background fetch refreshes it, so lookups do not wait on IANA; past hard
+// (default 7 days) it is dropped and the next lookup fetches the file itself.
+// hard is raised to soft if lower.
+func WithBootstrapTTL(soft, hard time.Duration) Option {
+	return func(c *Client) {
+		if soft <= 0 {
+			return
+		}
+		for _, bc := range []*ttlCache[string]{c.dnsBases, c.rdapBaseCache} {
+			bc.ttl = soft
+			bc.grace = max(hard-soft, 0)
+		}
+	}
+}
+
+// WithDegradedHostConcurrency caps concurrent attempts to a registry host while
+// it is failing with retryable errors (5xx, 429, timeouts), default
+// DefaultDegradedHostConcurrency. Concurrent lookups of the same URL always
+// share one request and its retries. n <= 0 removes the cap.
+func WithDegradedHostConcurrency(n int) Option { return func(c *Client) { c.guard.limit = n } }
+
+// WithMaxNestingDepth fails responses whose JSON nests objects and arrays
+// more than n deep with *ErrNestingTooDeep before they are decoded or cached,
+// so decoding and every later traversal (nested class names, graph walks,
+// gateway transforms) stay bounded; dataset objects that deep are skipped.
+// The default is DefaultMaxNestingDepth; n <= 0 removes the limit.
+func WithMaxNestingDepth(n int) Option { return func(c *Client) { c.maxNesting = n } }
+
+// WithMaxRedirects bounds how many redirects one request follows, default
+// DefaultMaxRedirects; 0 follows none. The request then fails with an
+// *ErrHopLimit. It applies to the default HTTP client or an *http.Client set
+// with WithHTTPDoer that has no CheckRedirect of its own.
+func WithMaxRedirects(n int) Option {
+	return func(c *Client) {
+		if n >= 0 {
+			c.maxRedirects = n
+		}
+	}
+}
+
+// WithMaxLinkHops bounds the cross-host hops of a chain of followed links
+// (FollowLink under one WithHopTrail context, links a graph walk follows),
+// default DefaultMaxLinkHops; n <= 0 removes the limit.
+func WithMaxLinkHops(n int) Option { return func(c *Client) { c.maxLinkHops = n } }
+
+// WithFaultInjector runs f before every request the client sends, delaying
+// or failing it as f says (RandomFaults for random slowness and errors), so
+// services can rehearse registry trouble. It only takes effect in builds with
+// the rdapfaults tag (go build -tags rdapfaults); elsewhere it is ignored, so
+// it cannot reach production by accident.
+func WithFaultInjector(f FaultInjector) Option { return func(c *Client) { c.faults = f } }
+
+// WithSynthesizedNameservers makes Nameserver (and so graph walks) answer a
+// host that 404s at its registry and at the registrar with the parent
+// domain's entry for it, marked Synthetic, instead of an error, so graphs
+// and reports have no holes where registries publish no nameserver objects.
+func WithSynthesizedNameservers(on bool) Option { return func(c *Client) { c.synthesizeNS = on } }
+
+// WithByteBudget caps the response bytes downloaded per calendar month, in
+// total, per registry host and per job (see WithJob). Once a limit is used
+// up, requests it covers fail with *ErrBudgetExceeded without being sent
+// until the month ends. Usage is kept in memory; processes that restart
+// persist BandwidthUsage and restore it with SetBandwidthUsage.
+func WithByteBudget(b ByteBudget) Option { return func(c *Client) { c.bandwidth.budget = b } }
+
+// WithStaleIfError lets a cached response up to d past its expiry answer a
+// lookup whose registry fails (network errors, timeouts, 5xx, 429, a used-up
+// byte budget) instead of the error. Such answers are logged and listed in
+// the Completeness of a WithCompleteness context. Off by default.
+func WithStaleIfError(d time.Duration) Option { return func(c *Client) { c.staleIfError = d } }
+
+// WithSettleWindow sets how long, after NoteChange reports a changed object,
+// its responses are cached for at most ttl, so polls see the registry settle
+// (a transfer completing, statuses changing in steps) instead of the cached
+// state. Validators are still sent, so an unchanged object costs a 304.
+// Normal TTLs apply again after the window. The default is 30 minutes with a
+// zero TTL (the cache is bypassed); a zero window turns it off.
+func WithSettleWindow(window, ttl time.Duration) Option {
+	return func(c *Client) { c.settleWindow, c.settleTTL = window, ttl }
+}
+
+// WithDiskCache keeps the response cache in dir as well as in memory, so
+// bodies, ETags and remembered 404/410/451 answers, including those of the
+// bootstrap files, survive restarts: the first lookup after one can be a
+// cache hit or a 304. The in-memory LRU stays in front; files past maxBytes
+// (0 for no limit) are removed least recently used first. A directory that
+// cannot be created is logged and the cache stays in memory.
+func WithDiskCache(dir string, maxBytes int64) Option {
+	return func(c *Client) { c.diskDir, c.diskMaxBytes = dir, maxBytes }
+}
+
+// WithExtensionGating makes the client decode extension members only when
+// the response declares their extension in rdapConformance: redacted
+// (ConformanceRedacted), cidr0_cidrs (ConformanceCIDR0) and
+// arin_originas0_originautnums (ConformanceArinOriginAS0). Off by default,
+// since some servers send extension members without the token.
+func WithExtensionGating(on bool) Option { return func(c *Client) { c.gateExtensions = on } }
+
+// WithMaxResponseSize caps how much of a lookup response is read, default
+// DefaultMaxResponseSize. A larger response fails with *ErrResponseTooLarge
+// and is logged and listed in Health.SizeAnomalies rather than truncated.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.sizes.max = n
+		}
+	}
+}
+
+// WithSizeAnomalies flags responses more than factor times their registry's
+// typical size (a moving average, once the host has answered a few times;
+// responses under 64 KiB are never flagged), default DefaultSizeAnomalyFactor.
+// Flagged responses are logged at Warn and listed in Health.SizeAnomalies;
+// with reject they also fail with *ErrResponseTooLarge. factor <= 0 turns
+// detection off.
+func WithSizeAnomalies(factor float64, reject bool) Option {
+	return func(c *Client) { c.sizes.factor, c.sizes.reject = factor, reject }
+}
+
+// WithLinkPolicy sets which links[].href targets the client fetches and graph
+// walks follow; see LinkPolicy for the strict default.
+func WithLinkPolicy(p LinkPolicy) Option { return func(c *Client) { c.linkPolicy = p } }
+
+// WithLogger sends the client's diagnostics to l: retries and backoff waits at
+// Info, each request, cache hit and background bootstrap refresh at Debug.
+// The default discards them; a nil l keeps the default.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		if l != nil {
+			c.log = l
+		}
+	}
+}
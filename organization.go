@@ -0,0 +1,36 @@
+package rdapclient
+
+import "strings"
+
+// Organization returns the organization holding a: the top-level entity with
+// the "registrant" role, else the first whose vCard kind is "org", or nil.
+// RIRs often embed it as a stub; see Client.Hydrate.
+func (a *Autnum) Organization() *Entity { return organization(a.Entities) }
+
+// Organization is Autnum.Organization for a network.
+func (n *IPNetwork) Organization() *Entity { return organization(n.Entities) }
+
+func organization(es []Entity) *Entity {
+	for i := range es {
+		for _, r := range es[i].Roles {
+			if strings.EqualFold(r, "registrant") {
+				return &es[i]
+			}
+		}
+	}
+	for i := range es {
+		if strings.EqualFold(es[i].VCardText("kind"), "org") {
+			return &es[i]
+		}
+	}
+	return nil
+}
+
+// OrgName is a display name for an organization entity: its vCard "org",
+// else "fn", else its handle.
+func (e *Entity) OrgName() string {
+	if org := e.VCardText("org"); org != "" {
+		return org
+	}
+	return e.RegistrarName()
+}
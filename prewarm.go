@@ -0,0 +1,113 @@
+package rdapclient
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// prewarmConcurrency bounds how many hosts Prewarm contacts at once.
+const prewarmConcurrency = 8
+
+// WarmHost is the outcome of pre-warming one registry host.
+type WarmHost struct {
+	Host    string        `json:"host"`
+	Queries int           `json:"queries"` // input queries routed to this host
+	Resolve time.Duration `json:"resolve"` // DNS lookup time
+	Connect time.Duration `json:"connect"` // time to a first response (TCP/QUIC + TLS + HEAD)
+	Err     string        `json:"error,omitempty"`
+}
+
+// Prewarm prepares the client for a batch of queries: each query's registry
+// is found through the bootstrap files (using its first classification, as
+// Lookup would), then every distinct host is resolved and sent a HEAD request
+// so its connection, TLS handshake included, is pooled before the first real
+// lookup. Failures are reported per host and never fatal. In replay mode, or
+// when no query maps to a host, it returns nil.
+func (c *Client) Prewarm(ctx context.Context, queries []string) []WarmHost {
+	if c.replayDir != "" {
+		return nil
+	}
+	bases := map[string]string{} // host -> base
+	counts := map[string]int{}
+	for _, q := range queries {
+		kinds := classify(q, LookupOptions{})
+		if len(kinds) == 0 {
+			continue
+		}
+		base, err := c.RegistryBase(ctx, kinds[0], q, "")
+		if err != nil || base == "" {
+			continue
+		}
+		u, err := url.Parse(base)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		if _, ok := bases[u.Host]; !ok {
+			bases[u.Host] = base
+		}
+		counts[u.Host]++
+	}
+
+	out := make([]WarmHost, 0, len(bases))
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, prewarmConcurrency)
+	)
+	for host, base := range bases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			w := c.warmHost(ctx, host, base)
+			w.Queries = counts[host]
+			mu.Lock()
+			out = append(out, w)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Slice(out, func(a, b int) bool { return out[a].Host < out[b].Host })
+	return out
+}
+
+func (c *Client) warmHost(ctx context.Context, host, base string) WarmHost {
+	w := WarmHost{Host: host}
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+	start := time.Now()
+	if _, err := net.DefaultResolver.LookupHost(ctx, name); err != nil {
+		w.Err = err.Error()
+		return w
+	}
+	w.Resolve = time.Since(start)
+
+	reqCtx, cancel := context.WithTimeout(ctx, c.baseTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodHead, base, nil)
+	if err != nil {
+		w.Err = err.Error()
+		return w
+	}
+	req.Header.Set("User-Agent", c.ua)
+	start = time.Now()
+	resp, err := c.transport.Do(req)
+	if err != nil {
+		w.Err = err.Error()
+		return w
+	}
+	// Any status will do: the point is the pooled connection.
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	w.Connect = time.Since(start)
+	return w
+}
@@ -0,0 +1,174 @@
+// Package proxy serves RDAP lookups over HTTP through one shared rdap Client,
+// so a team gets a single gateway with a shared cache and bootstrap state.
+//
+// Paths follow RFC 9082 (/domain/{name}, /ip/{addr}[/{len}], /autnum/{n},
+// /nameserver/{name}, /entity/{handle}[?tld=hint]). GET /usage reports the
+// caller's quota consumption for the day; admin keys may GET /usage/all.
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// KeyHeader is the request header carrying the caller's API key. A bearer
+// token in Authorization is accepted as well.
+const KeyHeader = "X-API-Key"
+
+// Options configures a Server.
+type Options struct {
+	// Quotas enforces per-key limits; nil admits every request.
+	Quotas *Quotas
+	// Filters names the transformers (see Filters) applied to responses for
+	// callers whose Limits.Filters is nil.
+	Filters []string
+}
+
+// Server is an http.Handler answering RDAP queries via an rdap.Client.
+type Server struct {
+	c    *rdap.Client
+	opts Options
+}
+
+// New returns a Server backed by c.
+func New(c *rdap.Client, opts Options) *Server {
+	return &Server{c: c, opts: opts}
+}
+
+// ErrorResponse is an RFC 9083 section 6 error body.
+type ErrorResponse struct {
+	ErrorCode   int      `json:"errorCode"`
+	Title       string   `json:"title"`
+	Description []string `json:"description,omitempty"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	key := apiKey(r)
+	q := s.opts.Quotas
+	if q != nil && !q.Known(key) {
+		writeError(w, http.StatusUnauthorized, "missing or unknown API key")
+		return
+	}
+
+	class, id, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if class == "usage" {
+		s.serveUsage(w, key, id)
+		return
+	}
+	kind, ok := kinds[class]
+	if !ok || id == "" {
+		writeError(w, http.StatusBadRequest, "unsupported query path", r.URL.Path)
+		return
+	}
+	if u, err := url.PathUnescape(id); err == nil {
+		id = u
+	}
+	tld := r.URL.Query().Get("tld")
+
+	if q != nil {
+		base, err := s.c.RegistryBase(r.Context(), kind, id, tld)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, "cannot resolve registry", err.Error())
+			return
+		}
+		if ok, reason := q.Allow(key, registryHost(base)); !ok {
+			retry := time.Until(q.ResetAt()).Round(time.Second)
+			w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())))
+			writeError(w, http.StatusTooManyRequests, "quota exceeded", reason)
+			return
+		}
+	}
+
+	obj, err := s.c.LookupWithOptions(r.Context(), id, rdap.LookupOptions{TLDHint: tld, AllowedKinds: []rdap.Kind{kind}})
+	if err != nil {
+		status := http.StatusBadGateway
+		var he *rdap.HTTPError
+		if errors.As(err, &he) && he.StatusCode >= 400 && he.StatusCode < 500 {
+			status = he.StatusCode
+		}
+		writeError(w, status, http.StatusText(status), err.Error())
+		return
+	}
+	if o, ok := obj.(rdap.Object); ok {
+		t, err := FilterChain(s.filters(key))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "misconfigured filters", err.Error())
+			return
+		}
+		t(o)
+	}
+	writeJSON(w, http.StatusOK, obj)
+}
+
+// filters returns the filter names for key: its own list when set (an empty
+// list opts out), else the server default.
+func (s *Server) filters(key string) []string {
+	if q := s.opts.Quotas; q != nil {
+		if l := q.limits(key); l.Filters != nil {
+			return l.Filters
+		}
+	}
+	return s.opts.Filters
+}
+
+// serveUsage answers /usage (the caller's own counters) and, for admin keys,
+// /usage/all (every tenant seen today).
+func (s *Server) serveUsage(w http.ResponseWriter, key, sub string) {
+	q := s.opts.Quotas
+	switch {
+	case q == nil:
+		writeError(w, http.StatusNotFound, "quotas are not enabled")
+	case sub == "":
+		writeJSON(w, http.StatusOK, q.Usage(key))
+	case sub == "all" && q.limits(key).Admin:
+		writeJSON(w, http.StatusOK, q.Report())
+	default:
+		writeError(w, http.StatusForbidden, "usage report requires an admin key")
+	}
+}
+
+var kinds = map[string]rdap.Kind{
+	"domain":     rdap.KindDomain,
+	"ip":         rdap.KindIP,
+	"autnum":     rdap.KindAutnum,
+	"nameserver": rdap.KindNameserver,
+	"entity":     rdap.KindEntity,
+}
+
+func apiKey(r *http.Request) string {
+	if k := r.Header.Get(KeyHeader); k != "" {
+		return k
+	}
+	if tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(tok)
+	}
+	return AnonymousKey
+}
+
+func registryHost(base string) string {
+	if u, err := url.Parse(base); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return base
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/rdap+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, title string, desc ...string) {
+	writeJSON(w, status, ErrorResponse{ErrorCode: status, Title: title, Description: desc})
+}
@@ -0,0 +1,186 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// newRegistry serves a DNS bootstrap for .example and .test (each on its own
+// host path) plus domain objects, and returns a client pointed at it.
+func newRegistry(t *testing.T) *rdap.Client {
+	t.Helper()
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/a/"]],[["test"],["%s/b/"]]]}`, srvURL, strings.Replace(srvURL, "127.0.0.1", "localhost", 1)))
+		case strings.Contains(r.URL.Path, "/domain/"):
+			name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"objectClassName":"domain","ldhName":%q}`, name))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	srvURL = ts.URL
+	return rdap.New(rdap.WithBootstrapURL(ts.URL + "/dns.json"))
+}
+
+func get(t *testing.T, h http.Handler, path, key string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if key != "" {
+		req.Header.Set(KeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_ProxiesLookup(t *testing.T) {
+	s := New(newRegistry(t), Options{})
+	rec := get(t, s, "/domain/a.example", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var d rdap.Domain
+	if err := json.Unmarshal(rec.Body.Bytes(), &d); err != nil || d.LDHName != "a.example" {
+		t.Fatalf("body: %s (%v)", rec.Body, err)
+	}
+}
+
+func TestServer_QuotasPerKeyAndPerRegistry(t *testing.T) {
+	q := &Quotas{Keys: map[string]Limits{
+		"k1":    {Name: "team-a", Daily: 3, PerRegistry: 2},
+		"k2":    {Name: "team-b", Daily: 1},
+		"admin": {Admin: true},
+	}}
+	s := New(newRegistry(t), Options{Quotas: q})
+
+	if rec := get(t, s, "/domain/a.example", "nope"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown key: want 401, got %d", rec.Code)
+	}
+	for _, p := range []string{"/domain/a.example", "/domain/b.example"} {
+		if rec := get(t, s, p, "k1"); rec.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", p, rec.Code, rec.Body)
+		}
+	}
+	// Third request to the same registry trips the per-registry cap...
+	rec := get(t, s, "/domain/c.example", "k1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("per-registry: want 429 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+	var e ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil || e.ErrorCode != 429 {
+		t.Fatalf("error body: %s", rec.Body)
+	}
+	// ...but another registry is still open until the daily cap.
+	if rec := get(t, s, "/domain/a.test", "k1"); rec.Code != http.StatusOK {
+		t.Fatalf("other registry: %d %s", rec.Code, rec.Body)
+	}
+	if rec := get(t, s, "/domain/b.test", "k1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("daily: want 429, got %d", rec.Code)
+	}
+	// One tenant exhausting its quota does not affect another.
+	if rec := get(t, s, "/domain/a.example", "k2"); rec.Code != http.StatusOK {
+		t.Fatalf("k2: %d", rec.Code)
+	}
+
+	var u Usage
+	if err := json.Unmarshal(get(t, s, "/usage", "k1").Body.Bytes(), &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "team-a" || u.Requests != 3 || u.Rejected != 2 {
+		t.Fatalf("usage: %+v", u)
+	}
+	if rec := get(t, s, "/usage/all", "k1"); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin report: want 403, got %d", rec.Code)
+	}
+	rec = get(t, s, "/usage/all", "admin")
+	var all []Usage
+	if err := json.Unmarshal(rec.Body.Bytes(), &all); err != nil || len(all) != 2 {
+		t.Fatalf("report: %+v %v", all, err)
+	}
+	// Tenants are named by label and key hash; the keys never leave.
+	if all[0].Name != "team-a" || all[0].KeyID != KeyID("k1") || len(all[0].KeyID) != 12 {
+		t.Fatalf("report: %+v", all)
+	}
+	if body := rec.Body.String(); strings.Contains(body, `"k1"`) || strings.Contains(body, `"k2"`) {
+		t.Fatalf("report leaks API keys: %s", body)
+	}
+}
+
+func TestQuotas_ResetAtUTCMidnight(t *testing.T) {
+	now := time.Date(2025, 3, 4, 23, 59, 0, 0, time.UTC)
+	q := &Quotas{Default: Limits{Daily: 1}, now: func() time.Time { return now }}
+	if ok, _ := q.Allow(AnonymousKey, "r"); !ok {
+		t.Fatal("first request refused")
+	}
+	if ok, _ := q.Allow(AnonymousKey, "r"); ok {
+		t.Fatal("second request admitted")
+	}
+	if got := q.ResetAt(); !got.Equal(time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("ResetAt: %v", got)
+	}
+	now = now.Add(2 * time.Minute)
+	if ok, _ := q.Allow(AnonymousKey, "r"); !ok {
+		t.Fatal("not reset after midnight")
+	}
+}
+
+func TestServer_FiltersPerKey(t *testing.T) {
+	reg := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"a.example",
+			"remarks":[{"description":["terms"]}],
+			"entities":[{"objectClassName":"entity","handle":"R1","roles":["registrant"],
+				"vcardArray":["vcard",[["fn",{},"text","Jane"],["email",{},"text","j@a.example"],["tel",{},"uri","tel:+1"]]],
+				"entities":[{"objectClassName":"entity","handle":"R2","vcardArray":["vcard",[["fn",{},"text","Nested"]]]}]}]}`)
+	})
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+	c := rdap.New(rdap.WithHTTPDoer(ts.Client()), rdap.WithBootstrapURL(ts.URL+"/missing"), rdap.WithDefaultRDAPBase(ts.URL))
+	q := &Quotas{Keys: map[string]Limits{
+		"ops":   {},
+		"abuse": {Filters: []string{}},
+		"sales": {Filters: []string{"redact-contact", "strip-remarks"}},
+	}}
+	s := New(c, Options{Quotas: q, Filters: []string{"strip-vcard"}})
+
+	decode := func(key string) rdap.Domain {
+		t.Helper()
+		rec := get(t, s, "/domain/a.example", key)
+		var d rdap.Domain
+		if err := json.Unmarshal(rec.Body.Bytes(), &d); err != nil || len(d.Entities) != 1 {
+			t.Fatalf("%s: %d %s", key, rec.Code, rec.Body)
+		}
+		return d
+	}
+	if d := decode("ops"); d.Entities[0].VCardArray != nil || d.Entities[0].Entities[0].VCardArray != nil {
+		t.Fatalf("default filter kept vcards: %+v", d.Entities[0])
+	}
+	if d := decode("abuse"); d.Entities[0].VCardArray == nil || len(d.Remarks) != 1 {
+		t.Fatalf("abuse key should be unfiltered: %+v", d)
+	}
+	d := decode("sales")
+	card, _ := json.Marshal(d.Entities[0].VCardArray)
+	if strings.Contains(string(card), "email") || strings.Contains(string(card), "tel") || !strings.Contains(string(card), "Jane") {
+		t.Fatalf("redact-contact: %s", card)
+	}
+	if len(d.Remarks) != 0 {
+		t.Fatalf("strip-remarks: %+v", d.Remarks)
+	}
+}
+
+func TestFilterChain_UnknownName(t *testing.T) {
+	if _, err := FilterChain([]string{"strip-vcard", "nope"}); err == nil {
+		t.Fatal("want error for unknown filter")
+	}
+}
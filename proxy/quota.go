@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AnonymousKey is the usage key for callers when no API keys are configured.
+const AnonymousKey = "anonymous"
+
+// Limits caps one tenant's usage per UTC day (zero means unlimited) and picks
+// the response filters it gets.
+type Limits struct {
+	Name        string `json:"name,omitempty"`        // label shown in usage reports
+	Daily       int    `json:"daily,omitempty"`       // requests per day across all registries
+	PerRegistry int    `json:"perRegistry,omitempty"` // requests per day to any one registry host
+	Admin       bool   `json:"admin,omitempty"`       // may read every tenant's usage
+	// Filters overrides Options.Filters for this key; an empty list disables filtering.
+	Filters []string `json:"filters,omitempty"`
+}
+
+// KeyID names an API key in usage reports without giving it away: the first
+// 12 hex digits of its SHA-256, or AnonymousKey for callers without one.
+func KeyID(key string) string {
+	if key == AnonymousKey {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Usage is one tenant's consumption for the current UTC day. Reports show
+// tenants by Name and KeyID, never by the key itself.
+type Usage struct {
+	KeyID      string         `json:"keyId"`
+	Name       string         `json:"name,omitempty"`
+	Day        string         `json:"day"`
+	Requests   int            `json:"requests"`
+	Rejected   int            `json:"rejected,omitempty"`
+	Registries map[string]int `json:"registries,omitempty"`
+	Limits     Limits         `json:"limits"`
+}
+
+// Quotas enforces per-API-key daily limits. When Keys is non-empty only those
+// keys are admitted; otherwise every caller shares Default under AnonymousKey.
+// Counters live in memory and reset at UTC midnight.
+type Quotas struct {
+	Default Limits
+	Keys    map[string]Limits
+
+	mu    sync.Mutex
+	day   string
+	usage map[string]*Usage
+	now   func() time.Time
+}
+
+// LoadKeys reads a JSON object mapping API key to Limits.
+func LoadKeys(r io.Reader) (map[string]Limits, error) {
+	var keys map[string]Limits
+	if err := json.NewDecoder(r).Decode(&keys); err != nil {
+		return nil, fmt.Errorf("proxy: keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Known reports whether key may use the proxy at all.
+func (q *Quotas) Known(key string) bool {
+	if len(q.Keys) == 0 {
+		return true
+	}
+	_, ok := q.Keys[key]
+	return ok
+}
+
+func (q *Quotas) limits(key string) Limits {
+	if l, ok := q.Keys[key]; ok {
+		return l
+	}
+	return q.Default
+}
+
+// Allow charges one request by key to registry and reports whether it fits
+// the key's limits; a rejected request is counted but not charged. When it
+// does not fit, reason says which limit was hit.
+func (q *Quotas) Allow(key, registry string) (ok bool, reason string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := q.usageLocked(key)
+	l := u.Limits
+	switch {
+	case l.Daily > 0 && u.Requests >= l.Daily:
+		u.Rejected++
+		return false, fmt.Sprintf("daily quota of %d requests exhausted", l.Daily)
+	case l.PerRegistry > 0 && u.Registries[registry] >= l.PerRegistry:
+		u.Rejected++
+		return false, fmt.Sprintf("daily quota of %d requests to %s exhausted", l.PerRegistry, registry)
+	}
+	u.Requests++
+	u.Registries[registry]++
+	return true, ""
+}
+
+// Usage returns a copy of key's usage for today.
+func (q *Quotas) Usage(key string) Usage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return copyUsage(q.usageLocked(key))
+}
+
+// Report returns today's usage for every key seen, sorted by name, then KeyID.
+func (q *Quotas) Report() []Usage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollLocked()
+	out := make([]Usage, 0, len(q.usage))
+	for _, u := range q.usage {
+		out = append(out, copyUsage(u))
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if out[a].Name != out[b].Name {
+			return out[a].Name < out[b].Name
+		}
+		return out[a].KeyID < out[b].KeyID
+	})
+	return out
+}
+
+// ResetAt is when today's counters reset (next UTC midnight).
+func (q *Quotas) ResetAt() time.Time {
+	y, m, d := q.clock().UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+func (q *Quotas) clock() time.Time {
+	if q.now != nil {
+		return q.now()
+	}
+	return time.Now()
+}
+
+// rollLocked drops yesterday's counters. Callers hold q.mu.
+func (q *Quotas) rollLocked() {
+	day := q.clock().UTC().Format(time.DateOnly)
+	if day != q.day {
+		q.day, q.usage = day, map[string]*Usage{}
+	}
+}
+
+func (q *Quotas) usageLocked(key string) *Usage {
+	q.rollLocked()
+	u, ok := q.usage[key]
+	if !ok {
+		l := q.limits(key)
+		u = &Usage{KeyID: KeyID(key), Name: l.Name, Day: q.day, Registries: map[string]int{}, Limits: l}
+		q.usage[key] = u
+	}
+	return u
+}
+
+func copyUsage(u *Usage) Usage {
+	c := *u
+	c.Registries = make(map[string]int, len(u.Registries))
+	for k, v := range u.Registries {
+		c.Registries[k] = v
+	}
+	return c
+}
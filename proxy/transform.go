@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// Transformer rewrites a typed object in place before it is returned to a
+// caller. Transformers see the top-level object and must walk nested objects
+// themselves; EachEntity and EachObject do that for the common cases.
+type Transformer func(rdap.Object)
+
+// Chain composes transformers, applied left to right.
+func Chain(ts ...Transformer) Transformer {
+	return func(o rdap.Object) {
+		for _, t := range ts {
+			t(o)
+		}
+	}
+}
+
+// Filters are the built-in transformers, by the names used in keys files
+// ("filters": ["strip-vcard"]) and the serve --filter flag.
+var Filters = map[string]Transformer{
+	// strip-vcard drops every entity's vcardArray.
+	"strip-vcard": StripVCards,
+	// redact-contact keeps names and organisations but drops email, phone and address.
+	"redact-contact": RedactVCardProperties("email", "tel", "adr"),
+	// strip-remarks drops remarks and notices, which often repeat terms-of-service text.
+	"strip-remarks": StripRemarks,
+}
+
+// FilterChain resolves filter names against Filters.
+func FilterChain(names []string) (Transformer, error) {
+	ts := make([]Transformer, 0, len(names))
+	for _, n := range names {
+		t, ok := Filters[n]
+		if !ok {
+			return nil, fmt.Errorf("proxy: unknown filter %q", n)
+		}
+		ts = append(ts, t)
+	}
+	return Chain(ts...), nil
+}
+
+// StripVCards removes contact cards from every entity in o.
+func StripVCards(o rdap.Object) {
+	EachEntity(o, func(e *rdap.Entity) { e.VCardArray = nil })
+}
+
+// StripRemarks removes remarks and notices from o and everything nested in it.
+func StripRemarks(o rdap.Object) {
+	EachObject(o, func(c *rdap.CommonObject) { c.Remarks, c.Notices = nil, nil })
+}
+
+// RedactVCardProperties returns a Transformer that deletes the named vCard
+// properties (case-insensitive, e.g. "email", "tel") from every entity's jCard.
+func RedactVCardProperties(props ...string) Transformer {
+	drop := map[string]bool{}
+	for _, p := range props {
+		drop[strings.ToLower(p)] = true
+	}
+	return func(o rdap.Object) {
+		EachEntity(o, func(e *rdap.Entity) {
+			card, ok := e.VCardArray.([]any)
+			if !ok || len(card) != 2 {
+				return
+			}
+			items, ok := card[1].([]any)
+			if !ok {
+				return
+			}
+			kept := make([]any, 0, len(items))
+			for _, it := range items {
+				if p, ok := it.([]any); ok && len(p) > 0 {
+					if name, _ := p[0].(string); drop[strings.ToLower(name)] {
+						continue
+					}
+				}
+				kept = append(kept, it)
+			}
+			e.VCardArray = []any{card[0], kept}
+		})
+	}
+}
+
+// EachEntity calls fn for every entity in o, including o itself and entities
+// nested under nameservers, networks and other entities.
+func EachEntity(o rdap.Object, fn func(*rdap.Entity)) {
+	visit(o, func(o rdap.Object) {
+		if e, ok := o.(*rdap.Entity); ok {
+			fn(e)
+		}
+	})
+}
+
+// EachObject calls fn for the common members of o and every object nested in it.
+func EachObject(o rdap.Object, fn func(*rdap.CommonObject)) {
+	visit(o, func(o rdap.Object) {
+		switch v := o.(type) {
+		case *rdap.Domain:
+			fn(&v.CommonObject)
+		case *rdap.Nameserver:
+			fn(&v.CommonObject)
+		case *rdap.IPNetwork:
+			fn(&v.CommonObject)
+		case *rdap.Autnum:
+			fn(&v.CommonObject)
+		case *rdap.Entity:
+			fn(&v.CommonObject)
+		}
+	})
+}
+
+// visit calls fn for o and, depth-first, every object nested in it.
+func visit(o rdap.Object, fn func(rdap.Object)) {
+	fn(o)
+	var entities []rdap.Entity
+	switch v := o.(type) {
+	case *rdap.Domain:
+		entities = v.Entities
+		for i := range v.Nameservers {
+			visit(&v.Nameservers[i], fn)
+		}
+		if v.Network != nil {
+			visit(v.Network, fn)
+		}
+	case *rdap.Nameserver:
+		entities = v.Entities
+	case *rdap.IPNetwork:
+		entities = v.Entities
+	case *rdap.Autnum:
+		entities = v.Entities
+	case *rdap.Entity:
+		entities = v.Entities
+		for i := range v.Networks {
+			visit(&v.Networks[i], fn)
+		}
+		for i := range v.Autnums {
+			visit(&v.Autnums[i], fn)
+		}
+	}
+	for i := range entities {
+		visit(&entities[i], fn)
+	}
+}
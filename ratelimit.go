@@ -0,0 +1,119 @@
+package rdapclient
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimitDoer spaces outgoing requests at least interval apart across the
+// whole client. A request whose context ends while waiting is not sent.
+type rateLimitDoer struct {
+	next     Doer
+	interval time.Duration
+
+	mu   sync.Mutex
+	slot time.Time // earliest start of the next request
+}
+
+func (r *rateLimitDoer) Do(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	now := time.Now()
+	start := r.slot
+	if start.Before(now) {
+		start = now
+	}
+	r.slot = start.Add(r.interval)
+	r.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+	return r.next.Do(req)
+}
+
+// hostLimiter is a token bucket per registry host, waited on before each
+// lookup request; see WithHostRateLimit.
+type hostLimiter struct {
+	mu      sync.Mutex
+	def     float64            // requests per second for hosts not in rates; 0 = unlimited
+	rates   map[string]float64 // host -> requests per second
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket refills at rate tokens per second up to burst. tokens goes
+// negative while callers wait for tokens they have reserved.
+type tokenBucket struct {
+	rate, burst, tokens float64
+	last                time.Time
+}
+
+// set sets host's rate; host "" sets the default.
+func (l *hostLimiter) set(host string, rps float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	host = lower(host)
+	if host == "" {
+		l.def = rps
+	} else {
+		if l.rates == nil {
+			l.rates = map[string]float64{}
+		}
+		l.rates[host] = rps
+	}
+	l.buckets = nil // rebuilt at the new rates
+}
+
+// wait takes a token from host's bucket, blocking until one is available or
+// ctx ends. Hosts without a rate are not limited.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	host = lower(host)
+	l.mu.Lock()
+	rate, ok := l.rates[host]
+	if !ok {
+		rate = l.def
+	}
+	if rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	b := l.buckets[host]
+	if b == nil {
+		// Up to a second's worth of requests may go out at once.
+		burst := max(1, math.Floor(rate))
+		b = &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+		if l.buckets == nil {
+			l.buckets = map[string]*tokenBucket{}
+		}
+		l.buckets[host] = b
+	}
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		b.tokens++ // not used after all
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
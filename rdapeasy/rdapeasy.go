@@ -0,0 +1,75 @@
+// Package rdapeasy is a context-free front end to the RDAP client for quick
+// scripts and examples:
+//
+//	d, err := rdapeasy.Domain("example.com")
+//
+// Every call goes through one shared client with conservative limits (a few
+// requests per second, two retries) and gives up after Timeout. Programs that
+// need cancellation, their own limits or several clients should use the
+// context-first API of the rdap package directly.
+package rdapeasy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// Timeout bounds each call, retries included. Set it before the first call.
+var Timeout = 30 * time.Second
+
+// EnvPrefix is the prefix of the environment variables (see
+// rdap.ConfigFromEnv) the shared client reads, e.g. RDAPEASY_DEFAULT_BASE.
+const EnvPrefix = "RDAPEASY"
+
+// Client returns the shared client, creating it on first use. Malformed
+// environment settings are ignored.
+var Client = sync.OnceValue(func() *rdap.Client {
+	opts := []rdap.Option{
+		rdap.WithUserAgent("rdapeasy/0.1 (+https://github.com/datum-labs/rdap)"),
+		rdap.WithRateLimit(5),
+		rdap.WithMaxRetries(2),
+	}
+	cfg, _ := rdap.ConfigFromEnv(EnvPrefix)
+	return rdap.New(append(opts, cfg.Options()...)...)
+})
+
+func call[T any](fn func(context.Context, *rdap.Client) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	return fn(ctx, Client())
+}
+
+// Domain looks up a domain name.
+func Domain(name string) (*rdap.Domain, error) {
+	return call(func(ctx context.Context, c *rdap.Client) (*rdap.Domain, error) { return c.Domain(ctx, name) })
+}
+
+// Nameserver looks up a nameserver host name.
+func Nameserver(host string) (*rdap.Nameserver, error) {
+	return call(func(ctx context.Context, c *rdap.Client) (*rdap.Nameserver, error) { return c.Nameserver(ctx, host) })
+}
+
+// IP looks up the network covering an address or CIDR prefix.
+func IP(addr string) (*rdap.IPNetwork, error) {
+	return call(func(ctx context.Context, c *rdap.Client) (*rdap.IPNetwork, error) { return c.IP(ctx, addr) })
+}
+
+// ASN looks up an autnum ("AS15169" or "15169").
+func ASN(asn string) (*rdap.Autnum, error) {
+	return call(func(ctx context.Context, c *rdap.Client) (*rdap.Autnum, error) { return c.Autnum(ctx, asn) })
+}
+
+// Entity looks up an entity by handle; tldHint picks the registry for
+// domain-registry handles ("" asks the default base).
+func Entity(handle, tldHint string) (*rdap.Entity, error) {
+	return call(func(ctx context.Context, c *rdap.Client) (*rdap.Entity, error) { return c.Entity(ctx, handle, tldHint) })
+}
+
+// Lookup detects what q is (domain, IP, ASN, entity handle) and looks it up;
+// the result is one of the typed objects above.
+func Lookup(q string) (any, error) {
+	return call(func(ctx context.Context, c *rdap.Client) (any, error) { return c.Lookup(ctx, q, "") })
+}
@@ -0,0 +1,41 @@
+package rdapeasy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+func TestDomain_SharedClientFromEnv(t *testing.T) {
+	var srvURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"services":[[["example"],["%s/"]]]}`, srvURL))
+		case "/domain/a.example":
+			_, _ = io.WriteString(w, `{"objectClassName":"domain","ldhName":"a.example"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	srvURL = ts.URL
+	t.Setenv("RDAPEASY_DNS_BOOTSTRAP", ts.URL+"/dns.json")
+	t.Setenv("RDAPEASY_MAX_RETRIES", "0")
+
+	d, err := Domain("A.Example")
+	if err != nil || d.LDHName != "a.example" {
+		t.Fatalf("Domain: %v %+v", err, d)
+	}
+	if Client() != Client() {
+		t.Fatal("Client should be shared")
+	}
+	_, err = Domain("missing.example")
+	if rdap.ErrorCode(err) != rdap.CodeNotFound {
+		t.Fatalf("missing: want not_found, got %v", err)
+	}
+}
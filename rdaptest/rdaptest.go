@@ -0,0 +1,239 @@
+// Package rdaptest runs a mock RDAP registry for tests: fixed objects by
+// path, bootstrap files that route every query to it, and scripted failures
+// (429/503 with Retry-After, slow responses, truncated bodies, connection
+// resets) to exercise retry, backoff and degraded-host settings end to end.
+//
+//	srv := rdaptest.NewServer(map[string]string{
+//		"/domain/example.com": `{"objectClassName":"domain","ldhName":"example.com"}`,
+//	})
+//	defer srv.Close()
+//	srv.Script("/domain/", rdaptest.Repeat(rdaptest.Fault{Status: 503, RetryAfter: time.Second}, 2)...)
+//	d, err := srv.Client(rdap.WithMaxRetries(2)).Domain(ctx, "example.com")
+package rdaptest
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// Fault is one scripted response. The zero Fault serves the request normally,
+// so scripts can interleave failures with successes.
+type Fault struct {
+	// Status answers with this status (e.g. 429 or 503) and an RDAP error body.
+	Status int
+	// RetryAfter adds a Retry-After header, in whole seconds.
+	RetryAfter time.Duration
+	// Delay waits this long before answering (or before the Status/Truncate/Reset).
+	Delay time.Duration
+	// Truncate sends the first half of the body under the full Content-Length
+	// and closes the connection.
+	Truncate bool
+	// Reset closes the connection with a TCP reset instead of answering.
+	// net/http retries a GET once by itself when a reused keep-alive
+	// connection fails this way, so script two resets to see the error.
+	Reset bool
+}
+
+// Repeat returns n copies of f, for outages: Repeat(Fault{Status: 503}, 3).
+func Repeat(f Fault, n int) []Fault {
+	out := make([]Fault, n)
+	for i := range out {
+		out[i] = f
+	}
+	return out
+}
+
+// Server is a mock RDAP registry. Its bootstrap files (/dns.json for the TLDs
+// of its domain and nameserver objects, /ip.json and /asn.json for every
+// address and ASN) send queries to the server itself; bootstrap requests are
+// never faulted.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]string
+	scripts []*script
+	hits    map[string]int
+}
+
+type script struct {
+	prefix string
+	faults []Fault
+}
+
+// NewServer starts a Server answering GETs for the object paths in objects
+// ("/domain/example.com" -> JSON body); other paths are 404s.
+func NewServer(objects map[string]string) *Server {
+	s := newServer(objects)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// NewTLSServer is NewServer over HTTPS, so Reset faults are TLS connection
+// resets. Server.Client trusts its certificate.
+func NewTLSServer(objects map[string]string) *Server {
+	s := newServer(objects)
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func newServer(objects map[string]string) *Server {
+	s := &Server{objects: map[string]string{}, hits: map[string]int{}}
+	for p, body := range objects {
+		s.objects[p] = body
+	}
+	return s
+}
+
+// Set adds or replaces the object at path.
+func (s *Server) Set(path, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[path] = body
+}
+
+// Script queues faults for the next requests whose path starts with prefix,
+// one per request, in order; once they are used up those requests are served
+// normally. Scripts are matched in the order they were added.
+func (s *Server) Script(prefix string, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts = append(s.scripts, &script{prefix: prefix, faults: faults})
+}
+
+// Hits returns how many requests for path the server has received.
+func (s *Server) Hits(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+// Client returns an rdap.Client whose bootstrap files and default base are
+// this server, with opts applied after those.
+func (s *Server) Client(opts ...rdap.Option) *rdap.Client {
+	return rdap.New(append([]rdap.Option{
+		rdap.WithHTTPDoer(s.Server.Client()),
+		rdap.WithBootstrapURL(s.URL + "/dns.json"),
+		rdap.WithIPBootstrapURL(s.URL + "/ip.json"),
+		rdap.WithASNBootstrapURL(s.URL + "/asn.json"),
+		rdap.WithDefaultRDAPBase(s.URL),
+	}, opts...)...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if body, ok := s.bootstrap(r.URL.Path); ok {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, body)
+		return
+	}
+	s.mu.Lock()
+	s.hits[r.URL.Path]++
+	var f Fault
+	for _, sc := range s.scripts {
+		if len(sc.faults) > 0 && strings.HasPrefix(r.URL.Path, sc.prefix) {
+			f, sc.faults = sc.faults[0], sc.faults[1:]
+			break
+		}
+	}
+	body, found := s.objects[r.URL.Path]
+	s.mu.Unlock()
+
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if f.Reset {
+		reset(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/rdap+json")
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Round(time.Second)/time.Second)))
+	}
+	status := http.StatusOK
+	switch {
+	case f.Status != 0:
+		status = f.Status
+		body = fmt.Sprintf(`{"errorCode":%d,"title":%q}`, status, http.StatusText(status))
+	case !found:
+		status = http.StatusNotFound
+		body = `{"errorCode":404,"title":"Not Found"}`
+	}
+	if f.Truncate {
+		// The server closes the connection when the handler writes less
+		// than the declared length.
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(status)
+		_, _ = fmt.Fprint(w, body[:len(body)/2])
+		return
+	}
+	w.WriteHeader(status)
+	_, _ = fmt.Fprint(w, body)
+}
+
+// bootstrap answers the bootstrap files with services pointing at s.
+func (s *Server) bootstrap(path string) (string, bool) {
+	var entries string
+	switch path {
+	case "/dns.json":
+		entries = `[]`
+		s.mu.Lock()
+		var tlds []string
+		seen := map[string]bool{}
+		for p := range s.objects {
+			for _, prefix := range []string{"/domain/", "/nameserver/"} {
+				if name, ok := strings.CutPrefix(p, prefix); ok {
+					tld := name[strings.LastIndex(name, ".")+1:]
+					if !seen[tld] {
+						seen[tld] = true
+						tlds = append(tlds, strconv.Quote(tld))
+					}
+				}
+			}
+		}
+		s.mu.Unlock()
+		if len(tlds) > 0 {
+			sort.Strings(tlds)
+			entries = "[" + strings.Join(tlds, ",") + "]"
+		}
+	case "/ip.json":
+		entries = `["0.0.0.0/0","::/0"]`
+	case "/asn.json":
+		entries = `["1-4294967295"]`
+	default:
+		return "", false
+	}
+	return fmt.Sprintf(`{"version":"1.0","services":[[%s,[%q]]]}`, entries, s.URL+"/"), true
+}
+
+// reset closes the connection under w with a TCP RST.
+func reset(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	if tc, ok := conn.(*tls.Conn); ok {
+		conn = tc.NetConn()
+	}
+	if tc, ok := conn.(*net.TCPConn); ok {
+		_ = tc.SetLinger(0)
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,64 @@
+package rdaptest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+var objects = map[string]string{
+	"/domain/example.com": `{"objectClassName":"domain","ldhName":"example.com"}`,
+	"/ip/192.0.2.1":       `{"objectClassName":"ip network","handle":"NET-1","startAddress":"192.0.2.0","endAddress":"192.0.2.255"}`,
+}
+
+func fastRetries(n int) []rdap.Option {
+	return []rdap.Option{rdap.WithMaxRetries(n), rdap.WithBackoff(rdap.ExponentialBackoff(time.Millisecond, 2, 5*time.Millisecond))}
+}
+
+func TestServer_OutageThenRecovery(t *testing.T) {
+	srv := NewServer(objects)
+	defer srv.Close()
+	srv.Script("/domain/", Fault{Status: 429}, Fault{Status: 503})
+	ctx := context.Background()
+
+	d, err := srv.Client(fastRetries(2)...).Domain(ctx, "example.com")
+	if err != nil || d.LDHName != "example.com" {
+		t.Fatalf("after outage: %v %+v", err, d)
+	}
+	if n := srv.Hits("/domain/example.com"); n != 3 {
+		t.Fatalf("hits = %d, want 3", n)
+	}
+
+	srv.Script("/ip/", Repeat(Fault{Status: 503}, 3)...)
+	_, err = srv.Client(fastRetries(1)...).IP(ctx, "192.0.2.1")
+	var he *rdap.HTTPError
+	if !errors.As(err, &he) || he.StatusCode != 503 {
+		t.Fatalf("retries exhausted: want 503, got %v", err)
+	}
+}
+
+func TestServer_SlowTruncatedReset(t *testing.T) {
+	srv := NewTLSServer(objects)
+	defer srv.Close()
+	ctx := context.Background()
+
+	srv.Script("/domain/", Fault{Delay: 200 * time.Millisecond})
+	if _, err := srv.Client(rdap.WithTimeout(50*time.Millisecond), rdap.WithMaxRetries(0)).Domain(ctx, "example.com"); rdap.ErrorCode(err) != rdap.CodeTimeout {
+		t.Fatalf("slow: want timeout, got %v", err)
+	}
+	srv.Script("/domain/", Fault{Truncate: true})
+	if _, err := srv.Client(rdap.WithMaxRetries(0)).Domain(ctx, "example.com"); err == nil {
+		t.Fatal("truncated: want error")
+	}
+	srv.Script("/domain/", Repeat(Fault{Reset: true}, 2)...)
+	if _, err := srv.Client(rdap.WithMaxRetries(0)).Domain(ctx, "example.com"); rdap.ErrorCode(err) != rdap.CodeNetwork {
+		t.Fatalf("reset: want network error, got %v", err)
+	}
+	srv.Script("/domain/", Repeat(Fault{Reset: true}, 2)...)
+	if _, err := srv.Client(fastRetries(1)...).Domain(ctx, "example.com"); err != nil {
+		t.Fatalf("reset then retry: %v", err)
+	}
+}
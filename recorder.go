@@ -0,0 +1,174 @@
+package rdapclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrCassetteMiss is returned in replay mode when no recording exists for a request.
+var ErrCassetteMiss = errors.New("rdap replay: no cassette for request")
+
+// Cassette is one recorded HTTP exchange, stored as JSON under the recorder dir.
+// Request headers and the body digest are kept so recordings can serve as
+// evidence; credentials (Authorization, cookies, WithHeader values) are not.
+type Cassette struct {
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	RequestHeader http.Header `json:"requestHeader,omitempty"`
+	Status        int         `json:"status"`
+	Header        http.Header `json:"header,omitempty"`
+	Body          []byte      `json:"body,omitempty"`
+	BodySHA256    string      `json:"bodySha256,omitempty"`
+	RecordedAt    time.Time   `json:"recordedAt"`
+}
+
+// CassettePath returns the file a request for method+url is recorded to.
+func CassettePath(dir, method, url string) string {
+	sum := sha256.Sum256([]byte(method + " " + url))
+	return filepath.Join(dir, hex.EncodeToString(sum[:12])+".json")
+}
+
+// credentialHeaders are left out of cassettes along with the client's
+// WithHeader headers, which often carry API keys.
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// recordingDoer passes requests to next and writes every exchange to dir.
+// Bodies over max are passed on unrecorded, for the client to refuse.
+type recordingDoer struct {
+	next   Doer
+	dir    string
+	max    int64
+	secret http.Header // WithHeader headers, not recorded
+	now    func() time.Time
+}
+
+func (r *recordingDoer) Do(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, r.max+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > r.max {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	path := CassettePath(r.dir, req.Method, req.URL.String())
+	// A 304 only makes sense against the validators of this process; keep an
+	// earlier full recording rather than replacing it with a body-less one.
+	if resp.StatusCode == http.StatusNotModified {
+		if _, statErr := os.Stat(path); statErr == nil {
+			return resp, nil
+		}
+	}
+	sum := sha256.Sum256(body)
+	cs := Cassette{
+		Method:        req.Method,
+		URL:           req.URL.String(),
+		RequestHeader: r.redact(req.Header),
+		Status:        resp.StatusCode,
+		Header:        r.redact(resp.Header),
+		Body:          body,
+		BodySHA256:    hex.EncodeToString(sum[:]),
+		RecordedAt:    r.now().UTC(),
+	}
+	if err := writeCassette(path, cs); err != nil {
+		return nil, fmt.Errorf("rdap record: %w", err)
+	}
+	return resp, nil
+}
+
+// redact returns a copy of h without credentials.
+func (r *recordingDoer) redact(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range credentialHeaders {
+		h.Del(k)
+	}
+	for k := range r.secret {
+		h.Del(k)
+	}
+	return h
+}
+
+func writeCassette(path string, cs Cassette) error {
+	b, err := json.MarshalIndent(cs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeCassetteFile(path, b)
+}
+
+func writeCassetteFile(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// A temp file of its own per write: concurrent recordings of one URL must
+	// not write into each other's file before the rename.
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0o644)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// replayDoer serves responses from cassettes and never touches the network.
+// With copyTo set, each cassette it serves is copied there unchanged.
+type replayDoer struct {
+	dir    string
+	copyTo string
+}
+
+func (r *replayDoer) Do(req *http.Request) (*http.Response, error) {
+	b, err := os.ReadFile(CassettePath(r.dir, req.Method, req.URL.String()))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s", ErrCassetteMiss, req.Method, req.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cs Cassette
+	if err := json.Unmarshal(b, &cs); err != nil {
+		return nil, fmt.Errorf("rdap replay: %w", err)
+	}
+	if r.copyTo != "" {
+		if err := writeCassetteFile(CassettePath(r.copyTo, req.Method, req.URL.String()), b); err != nil {
+			return nil, fmt.Errorf("rdap replay: %w", err)
+		}
+	}
+	return &http.Response{
+		StatusCode: cs.Status,
+		Status:     fmt.Sprintf("%d %s", cs.Status, http.StatusText(cs.Status)),
+		Header:     cs.Header,
+		Body:       io.NopCloser(bytes.NewReader(cs.Body)),
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,107 @@
+package rdapclient
+
+import "strings"
+
+// Redaction is one member of the RFC 9537 "redacted" array: a field the
+// server removed or altered, with JSONPath expressions locating it.
+type Redaction struct {
+	Name RedactionText `json:"name"`
+	// PrePath locates the field in the unredacted response (removal,
+	// partialValue, replacementValue); PostPath in this one (emptyValue,
+	// partialValue); ReplacementPath the value that stands in for it.
+	PrePath         string `json:"prePath,omitempty"`
+	PostPath        string `json:"postPath,omitempty"`
+	ReplacementPath string `json:"replacementPath,omitempty"`
+	// PathLang is the language of the paths; "jsonpath" when empty.
+	PathLang string         `json:"pathLang,omitempty"`
+	Method   string         `json:"method,omitempty"`
+	Reason   *RedactionText `json:"reason,omitempty"`
+}
+
+// RedactionText names a redacted field or the reason for it: a Type from
+// the IANA redacted names registry ("Registrant Email") or free Description.
+type RedactionText struct {
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Redaction methods (RFC 9537 section 3).
+const (
+	RedactionRemoval          = "removal"
+	RedactionEmptyValue       = "emptyValue"
+	RedactionPartialValue     = "partialValue"
+	RedactionReplacementValue = "replacementValue"
+)
+
+// Redacted field names registered for gTLD registration data.
+const (
+	RedactedRegistryDomainID       = "Registry Domain ID"
+	RedactedRegistrantID           = "Registry Registrant ID"
+	RedactedRegistrantName         = "Registrant Name"
+	RedactedRegistrantOrganization = "Registrant Organization"
+	RedactedRegistrantStreet       = "Registrant Street"
+	RedactedRegistrantCity         = "Registrant City"
+	RedactedRegistrantPostalCode   = "Registrant Postal Code"
+	RedactedRegistrantPhone        = "Registrant Phone"
+	RedactedRegistrantFax          = "Registrant Fax"
+	RedactedRegistrantEmail        = "Registrant Email"
+	RedactedTechID                 = "Registry Tech ID"
+	RedactedTechName               = "Tech Name"
+	RedactedTechPhone              = "Tech Phone"
+	RedactedTechEmail              = "Tech Email"
+	RedactedAdminID                = "Registry Admin ID"
+	RedactedAdminName              = "Admin Name"
+	RedactedAdminPhone             = "Admin Phone"
+	RedactedAdminEmail             = "Admin Email"
+)
+
+// Field returns the name of the redacted field: its registered type, else
+// its description.
+func (r Redaction) Field() string {
+	if r.Name.Type != "" {
+		return strings.TrimSpace(r.Name.Type)
+	}
+	return strings.TrimSpace(r.Name.Description)
+}
+
+// EffectiveMethod is r's method, defaulting to removal as RFC 9537 does.
+func (r Redaction) EffectiveMethod() string {
+	if r.Method == "" {
+		return RedactionRemoval
+	}
+	return r.Method
+}
+
+// RedactedFields returns the names of the fields o's server redacted,
+// without duplicates, in the order it listed them. A field that is missing
+// from o and not listed here is absent rather than withheld.
+func (o CommonObject) RedactedFields() []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, r := range o.Redacted {
+		f := r.Field()
+		if k := strings.ToLower(f); f != "" && !seen[k] {
+			seen[k] = true
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// IsRedacted reports whether o's server redacted field (a name such as
+// RedactedRegistrantEmail, matched without case).
+func (o CommonObject) IsRedacted(field string) bool {
+	_, ok := o.RedactionFor(field)
+	return ok
+}
+
+// RedactionFor returns the entry for field (matched without case), if o's
+// server redacted it.
+func (o CommonObject) RedactionFor(field string) (Redaction, bool) {
+	for _, r := range o.Redacted {
+		if strings.EqualFold(r.Field(), strings.TrimSpace(field)) {
+			return r, true
+		}
+	}
+	return Redaction{}, false
+}
@@ -0,0 +1,48 @@
+package rdapclient
+
+import "strings"
+
+// Registrar returns d's sponsoring registrar: the top-level entity with the
+// "registrar" role, or nil when the server lists none.
+func (d *Domain) Registrar() *Entity {
+	for i := range d.Entities {
+		for _, r := range d.Entities[i].Roles {
+			if strings.EqualFold(r, "registrar") {
+				return &d.Entities[i]
+			}
+		}
+	}
+	return nil
+}
+
+// RegistrarName is a display name for the registrar entity e: its vCard "fn",
+// else its handle.
+func (e *Entity) RegistrarName() string {
+	if fn := e.VCardText("fn"); fn != "" {
+		return fn
+	}
+	return e.Handle
+}
+
+// IANARegistrarID returns the registrar's IANA ID from its publicIds, or "".
+func (e *Entity) IANARegistrarID() string {
+	for _, p := range e.PublicIDs {
+		if strings.EqualFold(p.Type, "IANA Registrar ID") {
+			return p.Identifier
+		}
+	}
+	return ""
+}
+
+// VCardText returns the text value of the first property called name (e.g.
+// "fn", "org", "email") in e's jCard, or "" when there is none.
+func (e *Entity) VCardText(name string) string {
+	for _, p := range jcardProps(e.VCardArray) {
+		if strings.EqualFold(p.name, name) {
+			if s, ok := p.values[0].(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
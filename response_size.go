@@ -0,0 +1,116 @@
+package rdapclient
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultMaxResponseSize is the largest lookup response body the client reads
+// unless WithMaxResponseSize says otherwise.
+const DefaultMaxResponseSize = 1 << 20
+
+// DefaultSizeAnomalyFactor is how many times larger than its registry's
+// typical response a response must be to count as anomalous, unless
+// WithSizeAnomalies says otherwise.
+const DefaultSizeAnomalyFactor = 20
+
+const (
+	sizeMinSamples    = 8        // responses seen from a host before it has a typical size
+	sizeAnomalyFloor  = 64 << 10 // responses under this are never anomalous
+	sizeEWMAWeight    = 0.1      // weight of each new response in the typical size
+	sizeRecentAnomaly = 20       // anomalies kept for Health
+)
+
+// ErrResponseTooLarge is returned for a response over the client's body cap
+// (WithMaxResponseSize) instead of decoding a truncated body, and, with
+// WithSizeAnomalies(f, true), for one over f times its registry's typical
+// size. Typical is zero for the body cap.
+type ErrResponseTooLarge struct {
+	URL     string
+	Bytes   int64 // bytes read; over the cap, Limit+1
+	Limit   int64
+	Typical int64
+}
+
+func (e *ErrResponseTooLarge) Error() string {
+	if e.Typical > 0 {
+		return fmt.Sprintf("rdap GET %s: %d-byte response is anomalously large for this registry (typical %d)", e.URL, e.Bytes, e.Typical)
+	}
+	return fmt.Sprintf("rdap GET %s: response larger than %d bytes", e.URL, e.Limit)
+}
+
+// SizeAnomaly is a response much larger than its registry usually sends,
+// e.g. a wildcard search a misconfigured server answered in full.
+type SizeAnomaly struct {
+	URL      string    `json:"url"`
+	Host     string    `json:"host"`
+	Bytes    int64     `json:"bytes"`
+	Typical  int64     `json:"typical,omitempty"` // zero when the body cap was hit
+	At       time.Time `json:"at"`
+	Rejected bool      `json:"rejected,omitempty"`
+}
+
+// sizeTracker keeps a moving average of response sizes per registry host and
+// the most recent anomalies.
+type sizeTracker struct {
+	mu     sync.Mutex
+	max    int64
+	factor float64 // <= 0 disables anomaly detection
+	reject bool
+	hosts  map[string]*hostSize
+	recent []SizeAnomaly
+}
+
+type hostSize struct {
+	n       int
+	typical float64
+}
+
+func newSizeTracker() *sizeTracker {
+	return &sizeTracker{max: DefaultMaxResponseSize, factor: DefaultSizeAnomalyFactor, hosts: map[string]*hostSize{}}
+}
+
+// observe records a response of n bytes from host and returns the anomaly it
+// is, if any. Anomalies do not move the host's typical size, so a burst of
+// them does not become the new normal.
+func (t *sizeTracker) observe(host, url string, n int64, now time.Time) *SizeAnomaly {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	hs := t.hosts[host]
+	if hs == nil {
+		hs = &hostSize{}
+		t.hosts[host] = hs
+	}
+	if t.factor > 0 && hs.n >= sizeMinSamples && n >= sizeAnomalyFloor && float64(n) > t.factor*hs.typical {
+		return t.add(SizeAnomaly{URL: url, Host: host, Bytes: n, Typical: int64(hs.typical), At: now, Rejected: t.reject})
+	}
+	if hs.n++; hs.n == 1 {
+		hs.typical = float64(n)
+	} else {
+		hs.typical += sizeEWMAWeight * (float64(n) - hs.typical)
+	}
+	return nil
+}
+
+// overCap records a response that exceeded the body cap.
+func (t *sizeTracker) overCap(host, url string, now time.Time) *SizeAnomaly {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.add(SizeAnomaly{URL: url, Host: host, Bytes: t.max + 1, At: now, Rejected: true})
+}
+
+func (t *sizeTracker) add(a SizeAnomaly) *SizeAnomaly {
+	if len(t.recent) == sizeRecentAnomaly {
+		t.recent = append(t.recent[:0], t.recent[1:]...)
+	}
+	t.recent = append(t.recent, a)
+	return &a
+}
+
+// anomalies returns a copy of the recent anomalies, oldest first.
+func (t *sizeTracker) anomalies() []SizeAnomaly {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]SizeAnomaly(nil), t.recent...)
+}
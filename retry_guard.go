@@ -0,0 +1,94 @@
+package rdapclient
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultDegradedHostConcurrency is how many requests may be in flight to a
+// registry host while it is degraded, unless WithDegradedHostConcurrency says otherwise.
+const DefaultDegradedHostConcurrency = 2
+
+// degradedFor is how long a host counts as degraded after a retryable failure
+// when it sent no Retry-After.
+const degradedFor = 30 * time.Second
+
+// hostGuard limits concurrent attempts against registry hosts that are failing
+// (5xx, 429, timeouts), so many callers retrying at once do not pile onto an
+// ailing registry. Healthy hosts are not limited.
+type hostGuard struct {
+	mu    sync.Mutex
+	limit int
+	hosts map[string]*hostState
+	now   func() time.Time
+}
+
+type hostState struct {
+	degradedUntil time.Time
+	sem           chan struct{}
+}
+
+func newHostGuard(limit int, now func() time.Time) *hostGuard {
+	return &hostGuard{limit: limit, hosts: map[string]*hostState{}, now: now}
+}
+
+// acquire waits, while host is degraded, for one of its limited slots. The
+// returned release must be called once the attempt is over.
+func (g *hostGuard) acquire(ctx context.Context, host string) (release func(), err error) {
+	g.mu.Lock()
+	st, ok := g.hosts[host]
+	if !ok || !g.now().Before(st.degradedUntil) || g.limit <= 0 {
+		g.mu.Unlock()
+		return func() {}, nil
+	}
+	sem := st.sem
+	g.mu.Unlock()
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// failed marks host degraded for at least hold (degradedFor when zero).
+func (g *hostGuard) failed(host string, hold time.Duration) {
+	if hold <= 0 {
+		hold = degradedFor
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	st, ok := g.hosts[host]
+	if !ok {
+		st = &hostState{sem: make(chan struct{}, max(g.limit, 1))}
+		g.hosts[host] = st
+	}
+	if until := g.now().Add(hold); until.After(st.degradedUntil) {
+		st.degradedUntil = until
+	}
+}
+
+// ok clears host's degraded state after a successful response.
+func (g *hostGuard) ok(host string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if st, ok := g.hosts[host]; ok {
+		st.degradedUntil = time.Time{}
+	}
+}
+
+// degradedHosts lists the hosts currently limited, sorted.
+func (g *hostGuard) degradedHosts() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var out []string
+	for h, st := range g.hosts {
+		if g.now().Before(st.degradedUntil) {
+			out = append(out, h)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,244 @@
+// Package schema generates JSON Schemas (draft 2020-12) for the typed RDAP
+// models and the graph document, as rdapctl writes them, so downstream
+// pipelines can validate its output and generate bindings in other languages.
+// The schemas are derived from the Go types by reflection; the copies under
+// schemas/ in the repository are regenerated with `make schemas`.
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+	"github.com/datum-labs/rdap/graph"
+)
+
+// Draft is the JSON Schema dialect of every generated schema.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// roots are the documents a schema can be generated for.
+var roots = map[string]any{
+	"domain":     rdap.Domain{},
+	"nameserver": rdap.Nameserver{},
+	"entity":     rdap.Entity{},
+	"ip-network": rdap.IPNetwork{},
+	"autnum":     rdap.Autnum{},
+	"graph":      graph.Graph{},
+}
+
+// aliases are the other names For accepts, after rdapctl's subcommands.
+var aliases = map[string]string{"ip": "ip-network", "asn": "autnum", "ns": "nameserver"}
+
+// Names lists the documents For knows, sorted.
+func Names() []string {
+	out := make([]string, 0, len(roots))
+	for n := range roots {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// For returns the schema for the named document (see Names; "ip", "asn" and
+// "ns" are accepted too) as a JSON-ready map.
+func For(name string) (map[string]any, error) {
+	name = strings.ToLower(name)
+	if a, ok := aliases[name]; ok {
+		name = a
+	}
+	v, ok := roots[name]
+	if !ok {
+		return nil, fmt.Errorf("schema: unknown document %q (want one of %s)", name, strings.Join(Names(), ", "))
+	}
+	g := &generator{defs: map[string]any{}}
+	root := g.schema(reflect.TypeOf(v))
+	if name == "graph" {
+		g.graphNodeData()
+	}
+	out := map[string]any{
+		"$schema": Draft,
+		"title":   reflect.TypeOf(v).Name(),
+		"$defs":   g.defs,
+	}
+	for k, s := range root {
+		out[k] = s
+	}
+	return out, nil
+}
+
+// Marshal returns the named schema as indented JSON, as published.
+func Marshal(name string) ([]byte, error) {
+	s, err := For(name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// FileName is the file a schema is published under: "domain.schema.json".
+func FileName(name string) string { return name + ".schema.json" }
+
+// WriteAll writes every schema into dir under its FileName.
+func WriteAll(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, n := range Names() {
+		b, err := Marshal(n)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, FileName(n)), b, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	rawType       = reflect.TypeOf(json.RawMessage{})
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// generator collects the named struct types it meets under $defs, so shared
+// and recursive types (entities within entities) are described once.
+type generator struct {
+	defs map[string]any
+}
+
+func (g *generator) schema(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawType:
+		return map[string]any{}
+	case t.Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(jsonMarshaler):
+		return map[string]any{}
+	case t.Implements(textMarshaler) || reflect.PointerTo(t).Implements(textMarshaler):
+		return map[string]any{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = map[string]any{} // placeholder for recursive references
+			g.defs[t.Name()] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	// Interfaces (vcardArray, graph node data) hold any JSON value.
+	return map[string]any{}
+}
+
+// object describes a struct the way encoding/json writes it: embedded
+// structs inlined, fields without omitempty required (and, for pointers,
+// slices and maps, possibly null).
+func (g *generator) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	g.fields(t, props, &required)
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+func (g *generator) fields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.fields(ft, props, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+			switch f.Type.Kind() {
+			case reflect.Pointer, reflect.Slice, reflect.Map:
+				s = nullable(s)
+			}
+		}
+		props[name] = s
+	}
+}
+
+// nullable lets s also match null, as encoding/json writes nil pointers,
+// slices and maps without omitempty.
+func nullable(s map[string]any) map[string]any {
+	if t, ok := s["type"].(string); ok {
+		out := map[string]any{}
+		for k, v := range s {
+			out[k] = v
+		}
+		out["type"] = []string{t, "null"}
+		return out
+	}
+	return map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}}
+}
+
+// graphNodeData narrows a graph node's data from any value to the RDAP
+// object classes, or a URL string for link nodes.
+func (g *generator) graphNodeData() {
+	node, ok := g.defs["Node"].(map[string]any)
+	if !ok {
+		return
+	}
+	var alts []any
+	for _, v := range []any{rdap.Domain{}, rdap.Nameserver{}, rdap.Entity{}, rdap.IPNetwork{}, rdap.Autnum{}} {
+		alts = append(alts, g.schema(reflect.TypeOf(v)))
+	}
+	alts = append(alts, map[string]any{"type": "string"})
+	node["properties"].(map[string]any)["data"] = map[string]any{"anyOf": alts}
+}
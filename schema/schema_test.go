@@ -0,0 +1,63 @@
+package schema
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFor_Models(t *testing.T) {
+	s, err := For("ip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s["$ref"] != "#/$defs/IPNetwork" || s["$schema"] != Draft {
+		t.Fatalf("root = %v", s)
+	}
+	defs := s["$defs"].(map[string]any)
+	ent := defs["Entity"].(map[string]any)["properties"].(map[string]any)
+	// Entities nest entities and networks: recursive types go through $defs.
+	if items := ent["entities"].(map[string]any)["items"].(map[string]any); items["$ref"] != "#/$defs/Entity" {
+		t.Fatalf("entity.entities = %v", items)
+	}
+	if _, ok := ent["objectClassName"]; !ok {
+		t.Fatal("embedded CommonObject fields not inlined")
+	}
+	if _, err := For("zone"); err == nil {
+		t.Fatal("want error for unknown document")
+	}
+}
+
+func TestFor_GraphNodeData(t *testing.T) {
+	s, err := For("graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := s["$defs"].(map[string]any)["Node"].(map[string]any)
+	data := node["properties"].(map[string]any)["data"].(map[string]any)
+	if alts := data["anyOf"].([]any); len(alts) != 6 {
+		t.Fatalf("node data = %v", data)
+	}
+	if _, ok := s["$defs"].(map[string]any)["Domain"]; !ok {
+		t.Fatal("object classes missing from graph $defs")
+	}
+}
+
+// The schemas published in the repository must match the models; run
+// `make schemas` after changing them.
+func TestPublishedSchemasCurrent(t *testing.T) {
+	for _, n := range Names() {
+		want, err := Marshal(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join("..", "schemas", FileName(n)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("schemas/%s is stale; run make schemas", FileName(n))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package rdapclient
+
+import "errors"
+
+// NoteChange tells the client a watch or diff saw an object change: obj and
+// lookupErr are the lookup's result. The URLs it was fetched from (obj's
+// Meta, or the failed request's for a deletion) are cached for at most the
+// settle TTL until the WithSettleWindow window ends, and leave the canonical
+// cache, so the polls that follow see the registry's state as it settles.
+func (c *Client) NoteChange(obj any, lookupErr error) {
+	if c.settleWindow <= 0 {
+		return
+	}
+	var urls []string
+	if o, ok := obj.(Object); ok {
+		if top := topObject(o); top != nil && top.Meta != nil {
+			urls = append(urls, top.Meta.URL, top.Meta.FinalURL)
+		}
+	}
+	var he *HTTPError
+	if errors.As(lookupErr, &he) {
+		urls = append(urls, he.URL)
+	}
+	for _, u := range urls {
+		if u == "" {
+			continue
+		}
+		c.respCache.Settle(u, c.settleWindow, c.settleTTL)
+		if c.canon != nil {
+			c.canon.drop(u)
+		}
+	}
+}
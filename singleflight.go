@@ -0,0 +1,59 @@
+package rdapclient
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup collapses concurrent calls for the same key into one, like
+// golang.org/x/sync/singleflight without the dependency.
+type flightGroup struct {
+	mu sync.Mutex
+	m  map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+	val  any
+	err  error
+}
+
+// Do runs fn once for all callers that arrive while a call for key is in
+// flight; they all get its result, and shared is true for those that waited.
+// fn runs with the first caller's context; a waiter whose own ctx ends stops
+// waiting with ctx.Err().
+func (g *flightGroup) Do(ctx context.Context, key string, fn func() (any, error)) (val any, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = map[string]*flightCall{}
+	}
+	if call, ok := g.m[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.val, call.err, true
+		case <-ctx.Done():
+			return nil, ctx.Err(), true
+		}
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.m[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.m, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.val, call.err = fn()
+	return call.val, call.err, false
+}
+
+// InFlight reports whether a call for key is running.
+func (g *flightGroup) InFlight(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.m[key]
+	return ok
+}
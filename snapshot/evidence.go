@@ -0,0 +1,132 @@
+package snapshot
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EvidenceName is the in-bundle file listing a digest for every other entry.
+const EvidenceName = "evidence.json"
+
+// ErrBadSignature is returned when a detached signature does not match the bundle.
+var ErrBadSignature = errors.New("snapshot: signature does not match bundle")
+
+// EvidenceEntry records the SHA-256 of one bundle file.
+type EvidenceEntry struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+}
+
+// Evidence is written into every bundle and checked on Import, so any
+// modification of the graph, a cassette or the manifest is detected, as is a
+// file removed from or added to the bundle.
+type Evidence struct {
+	Algorithm string          `json:"algorithm"`
+	Entries   []EvidenceEntry `json:"entries"`
+}
+
+func (e *Evidence) add(name string, b []byte) {
+	sum := sha256.Sum256(b)
+	e.Entries = append(e.Entries, EvidenceEntry{Name: name, SHA256: hex.EncodeToString(sum[:]), Size: len(b)})
+}
+
+func (e *Evidence) check(name string, b []byte) error {
+	sum := sha256.Sum256(b)
+	for _, en := range e.Entries {
+		if en.Name == name {
+			if en.SHA256 != hex.EncodeToString(sum[:]) {
+				return fmt.Errorf("snapshot: digest mismatch for %s", name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("snapshot: %s is not listed in %s", name, EvidenceName)
+}
+
+// Sign returns a detached signature over the bundle bytes read from r.
+// Ed25519 keys sign the bytes directly; ECDSA and RSA keys sign their SHA-256.
+func Sign(r io.Reader, key crypto.Signer) ([]byte, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := key.Public().(ed25519.PublicKey); ok {
+		return key.Sign(rand.Reader, b, crypto.Hash(0))
+	}
+	sum := sha256.Sum256(b)
+	return key.Sign(rand.Reader, sum[:], crypto.SHA256)
+}
+
+// Verify checks a detached signature produced by Sign.
+func Verify(r io.Reader, sig []byte, pub crypto.PublicKey) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(b)
+	ok := false
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, b, sig)
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(k, sum[:], sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) == nil
+	default:
+		return fmt.Errorf("snapshot: unsupported public key type %T", pub)
+	}
+	if !ok {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// ParsePrivateKeyPEM reads a PKCS#8 (or SEC1 EC / PKCS#1 RSA) private key.
+func ParsePrivateKeyPEM(b []byte) (crypto.Signer, error) {
+	blk, _ := pem.Decode(b)
+	if blk == nil {
+		return nil, errors.New("snapshot: no PEM block in private key")
+	}
+	var key any
+	var err error
+	switch blk.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(blk.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(blk.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(blk.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	s, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("snapshot: key type %T cannot sign", key)
+	}
+	return s, nil
+}
+
+// ParsePublicKeyPEM reads a PKIX ("PUBLIC KEY") public key.
+func ParsePublicKeyPEM(b []byte) (crypto.PublicKey, error) {
+	blk, _ := pem.Decode(b)
+	if blk == nil {
+		return nil, errors.New("snapshot: no PEM block in public key")
+	}
+	k, err := x509.ParsePKIXPublicKey(blk.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	return k, nil
+}
@@ -0,0 +1,202 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// ChangeKind classifies what an observation changed about a stored object.
+type ChangeKind string
+
+const (
+	ChangeCreated  ChangeKind = "created"  // first time the object was seen
+	ChangeUpdated  ChangeKind = "updated"  // content digest differs from the last observation
+	ChangeDeleted  ChangeKind = "deleted"  // a stored object started returning 404
+	ChangeRemoved  ChangeKind = "removed"  // a stored object started returning 410: permanently removed
+	ChangeRestored ChangeKind = "restored" // a tombstoned object is served again
+)
+
+// Change is one event produced by History.Observe.
+type Change struct {
+	ID        string     `json:"id"`
+	Kind      ChangeKind `json:"kind"`
+	At        time.Time  `json:"at"`
+	OldDigest string     `json:"oldDigest,omitempty"`
+	NewDigest string     `json:"newDigest,omitempty"`
+}
+
+// Tombstone marks a stored object that the registry no longer serves.
+// Permanent is set once the registry answered 410 Gone for it.
+type Tombstone struct {
+	FirstMissing time.Time `json:"firstMissing"`
+	LastMissing  time.Time `json:"lastMissing"`
+	Status       int       `json:"status"`
+	Permanent    bool      `json:"permanent,omitempty"`
+}
+
+// Record is the history of one object, keyed by a caller-chosen ID (graph node
+// IDs such as "domain:example.com" work well).
+type Record struct {
+	ID          string          `json:"id"`
+	Digest      string          `json:"digest"` // SHA-256 of the object's JSON
+	FirstSeen   time.Time       `json:"firstSeen"`
+	LastSeen    time.Time       `json:"lastSeen"`
+	LastChanged time.Time       `json:"lastChanged"`
+	Tombstone   *Tombstone      `json:"tombstone,omitempty"`
+	Object      json.RawMessage `json:"object,omitempty"` // last object served
+}
+
+// History is a JSON-file store of object observations across repeated
+// lookups. Objects that disappear keep their record and gain a Tombstone, so
+// deletions (domains dropped, networks de-registered) surface as events
+// instead of silently vanishing from later snapshots.
+type History struct {
+	mu      sync.Mutex
+	path    string
+	records map[string]*Record
+}
+
+// OpenHistory loads the history at path, starting empty if it does not exist.
+func OpenHistory(path string) (*History, error) {
+	h := &History{path: path, records: map[string]*Record{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var recs []*Record
+	if err := json.Unmarshal(b, &recs); err != nil {
+		return nil, err
+	}
+	for _, r := range recs {
+		h.records[r.ID] = r
+	}
+	return h, nil
+}
+
+// Observe records the outcome of looking up id at time at: obj on success, or
+// the lookup error. A 404 or 410 (an *rdap.HTTPError anywhere in err's chain)
+// for a stored object tombstones it, as a deleted or, for 410, a removed
+// event; a 410 for an object tombstoned by a 404 makes the tombstone
+// permanent and is reported as removed. Other errors are treated as transient
+// and change nothing. The returned Change is nil when nothing changed.
+func (h *History) Observe(id string, obj any, lookupErr error, at time.Time) (*Change, error) {
+	at = at.UTC()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rec := h.records[id]
+
+	if lookupErr != nil {
+		status := missingStatus(lookupErr)
+		if status == 0 || rec == nil {
+			return nil, nil
+		}
+		gone := status == http.StatusGone
+		kind := ChangeDeleted
+		if gone {
+			kind = ChangeRemoved
+		}
+		if t := rec.Tombstone; t != nil {
+			t.LastMissing = at
+			if !gone || t.Permanent {
+				return nil, nil
+			}
+			t.Status, t.Permanent = status, true
+		} else {
+			rec.Tombstone = &Tombstone{FirstMissing: at, LastMissing: at, Status: status, Permanent: gone}
+		}
+		rec.LastChanged = at
+		return &Change{ID: id, Kind: kind, At: at, OldDigest: rec.Digest}, nil
+	}
+
+	// Digest the normalized object so a server reordering status, entities or
+	// links between lookups is not an update.
+	if o, ok := obj.(rdap.Object); ok {
+		obj = rdap.Normalize(o)
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(b)
+	digest := hex.EncodeToString(sum[:])
+	switch {
+	case rec == nil:
+		h.records[id] = &Record{ID: id, Digest: digest, FirstSeen: at, LastSeen: at, LastChanged: at, Object: b}
+		return &Change{ID: id, Kind: ChangeCreated, At: at, NewDigest: digest}, nil
+	case rec.Tombstone != nil:
+		ch := &Change{ID: id, Kind: ChangeRestored, At: at, OldDigest: rec.Digest, NewDigest: digest}
+		rec.Tombstone = nil
+		rec.Digest, rec.Object, rec.LastSeen, rec.LastChanged = digest, b, at, at
+		return ch, nil
+	case rec.Digest != digest:
+		ch := &Change{ID: id, Kind: ChangeUpdated, At: at, OldDigest: rec.Digest, NewDigest: digest}
+		rec.Digest, rec.Object, rec.LastSeen, rec.LastChanged = digest, b, at, at
+		return ch, nil
+	default:
+		rec.LastSeen = at
+		return nil, nil
+	}
+}
+
+// Record returns a copy of id's record.
+func (h *History) Record(id string) (Record, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.records[id]
+	if !ok {
+		return Record{}, false
+	}
+	c := *r
+	if r.Tombstone != nil {
+		t := *r.Tombstone
+		c.Tombstone = &t
+	}
+	return c, true
+}
+
+// Save writes the history back to its file atomically.
+func (h *History) Save() error {
+	h.mu.Lock()
+	recs := make([]*Record, 0, len(h.records))
+	for _, r := range h.records {
+		recs = append(recs, r)
+	}
+	sort.Slice(recs, func(a, b int) bool { return recs[a].ID < recs[b].ID })
+	b, err := json.MarshalIndent(recs, "", "  ")
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(h.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
+
+// missingStatus returns 404 or 410 when err says the object is gone, else 0.
+func missingStatus(err error) int {
+	var he *rdap.HTTPError
+	if errors.As(err, &he) && (he.StatusCode == http.StatusNotFound || he.StatusCode == http.StatusGone) {
+		return he.StatusCode
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+func TestHistory_Tombstone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hist.json")
+	h, err := OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dom := &rdap.Domain{LDHName: "example.com"}
+	notFound := fmt.Errorf("%w: %w", errors.New("lookup failed"), &rdap.HTTPError{StatusCode: 404, Status: "404 Not Found"})
+
+	if ch, _ := h.Observe("example.com", dom, nil, t0); ch == nil || ch.Kind != ChangeCreated {
+		t.Fatalf("first observe: %+v", ch)
+	}
+	if ch, _ := h.Observe("example.com", dom, nil, t0.Add(time.Hour)); ch != nil {
+		t.Fatalf("unchanged object should not emit: %+v", ch)
+	}
+	reordered := &rdap.Domain{LDHName: "example.com"}
+	dom.Status, reordered.Status = []string{"active", "client hold"}, []string{"client hold", "active"}
+	if ch, _ := h.Observe("example.com", dom, nil, t0.Add(time.Hour)); ch == nil || ch.Kind != ChangeUpdated {
+		t.Fatalf("status added: %+v", ch)
+	}
+	if ch, _ := h.Observe("example.com", reordered, nil, t0.Add(time.Hour)); ch != nil {
+		t.Fatalf("reordered status should not emit: %+v", ch)
+	}
+	// Transient failures and 404s for unknown objects change nothing.
+	if ch, _ := h.Observe("example.com", nil, &rdap.HTTPError{StatusCode: 503}, t0.Add(2*time.Hour)); ch != nil {
+		t.Fatalf("503 should not tombstone: %+v", ch)
+	}
+	if ch, _ := h.Observe("other.com", nil, notFound, t0); ch != nil {
+		t.Fatalf("404 for unknown id: %+v", ch)
+	}
+
+	if ch, _ := h.Observe("example.com", nil, notFound, t0.Add(3*time.Hour)); ch == nil || ch.Kind != ChangeDeleted {
+		t.Fatalf("want deleted, got %+v", ch)
+	}
+	if ch, _ := h.Observe("example.com", nil, notFound, t0.Add(4*time.Hour)); ch != nil {
+		t.Fatalf("deleted should be emitted once: %+v", ch)
+	}
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	h2, err := OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, ok := h2.Record("example.com")
+	if !ok || rec.Tombstone == nil {
+		t.Fatalf("tombstone not persisted: %+v", rec)
+	}
+	if !rec.Tombstone.FirstMissing.Equal(t0.Add(3*time.Hour)) || !rec.Tombstone.LastMissing.Equal(t0.Add(4*time.Hour)) || rec.Tombstone.Status != 404 {
+		t.Fatalf("tombstone = %+v", rec.Tombstone)
+	}
+	if ch, _ := h2.Observe("example.com", &rdap.Domain{LDHName: "example.com", UnicodeName: "example.com"}, nil, t0.Add(5*time.Hour)); ch == nil || ch.Kind != ChangeRestored {
+		t.Fatalf("want restored, got %+v", ch)
+	}
+	if rec, _ := h2.Record("example.com"); rec.Tombstone != nil {
+		t.Fatalf("tombstone should be cleared")
+	}
+}
+
+func TestHistory_Gone(t *testing.T) {
+	h, err := OpenHistory(filepath.Join(t.TempDir(), "hist.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	notFound := &rdap.HTTPError{StatusCode: 404, Status: "404 Not Found"}
+	gone := &rdap.HTTPError{StatusCode: 410, Status: "410 Gone"}
+	for _, id := range []string{"a.example", "b.example"} {
+		if _, err := h.Observe(id, &rdap.Domain{LDHName: id}, nil, t0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ch, _ := h.Observe("a.example", nil, gone, t0.Add(time.Hour)); ch == nil || ch.Kind != ChangeRemoved {
+		t.Fatalf("want removed, got %+v", ch)
+	}
+	if rec, _ := h.Record("a.example"); rec.Tombstone == nil || !rec.Tombstone.Permanent || rec.Tombstone.Status != 410 {
+		t.Fatalf("tombstone = %+v", rec.Tombstone)
+	}
+	// A later 404 does not undo a permanent removal.
+	if ch, _ := h.Observe("a.example", nil, notFound, t0.Add(2*time.Hour)); ch != nil {
+		t.Fatalf("404 after 410: %+v", ch)
+	}
+
+	// A 404 tombstone turns permanent when the registry starts answering 410.
+	if ch, _ := h.Observe("b.example", nil, notFound, t0.Add(time.Hour)); ch == nil || ch.Kind != ChangeDeleted {
+		t.Fatalf("want deleted, got %+v", ch)
+	}
+	if ch, _ := h.Observe("b.example", nil, gone, t0.Add(2*time.Hour)); ch == nil || ch.Kind != ChangeRemoved {
+		t.Fatalf("want removed, got %+v", ch)
+	}
+	rec, _ := h.Record("b.example")
+	if ts := rec.Tombstone; ts == nil || !ts.Permanent || ts.Status != 410 || !ts.FirstMissing.Equal(t0.Add(time.Hour)) {
+		t.Fatalf("tombstone = %+v", ts)
+	}
+	if ch, _ := h.Observe("b.example", nil, gone, t0.Add(3*time.Hour)); ch != nil {
+		t.Fatalf("removed should be emitted once: %+v", ch)
+	}
+}
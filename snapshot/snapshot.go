@@ -0,0 +1,263 @@
+// Package snapshot packages an RDAP walk into a portable bundle: the graph,
+// every raw HTTP exchange (as rdapclient cassettes) and a manifest. An
+// imported bundle can be served back offline with rdapclient.WithReplay.
+//
+// Every bundle carries evidence.json with a SHA-256 per file, verified on
+// Import; Sign/Verify add a detached signature over the whole bundle for
+// forensic chain-of-custody.
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// FormatVersion is bumped whenever the bundle layout changes incompatibly.
+const FormatVersion = 1
+
+// Import holds a bundle in memory until its evidence checks out, so it bounds
+// what a bundle may unpack to.
+const (
+	maxEntrySize  = 64 << 20  // bytes in one file
+	maxBundleSize = 512 << 20 // bytes in all files
+	maxZstdWindow = 64 << 20  // zstd window; Export's encoder uses 8 MiB
+)
+
+// Bundle layout (inside the zstd-compressed tar).
+const (
+	ManifestName = "manifest.json"
+	GraphName    = "graph.json"
+	CassetteDir  = "cassettes"
+)
+
+// Manifest describes a bundle.
+type Manifest struct {
+	Version   int       `json:"version"`
+	Seed      string    `json:"seed"`
+	CreatedAt time.Time `json:"createdAt"`
+	Generator string    `json:"generator,omitempty"`
+	Cassettes int       `json:"cassettes"`
+}
+
+// Export writes a zstd-compressed tar (.tar.zst) containing m, graph
+// (marshaled as JSON) and every file in cassetteDir. m.Version and
+// m.Cassettes are filled in.
+func Export(w io.Writer, m Manifest, graph any, cassetteDir string) error {
+	files, err := listFiles(cassetteDir)
+	if err != nil {
+		return err
+	}
+	m.Version = FormatVersion
+	m.Cassettes = len(files)
+
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+
+	ev := Evidence{Algorithm: "sha256"}
+	put := func(name string, b []byte) error {
+		ev.add(name, b)
+		return writeEntry(tw, name, b, m.CreatedAt)
+	}
+
+	mb, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := put(ManifestName, mb); err != nil {
+		return err
+	}
+	gb, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := put(GraphName, gb); err != nil {
+		return err
+	}
+	for _, name := range files {
+		b, err := os.ReadFile(filepath.Join(cassetteDir, name))
+		if err != nil {
+			return err
+		}
+		if err := put(path.Join(CassetteDir, name), b); err != nil {
+			return err
+		}
+	}
+	eb, err := json.MarshalIndent(ev, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeEntry(tw, EvidenceName, eb, m.CreatedAt); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Import extracts a bundle into dir and returns its manifest. It reads
+// .tar.zst bundles and the .tar.gz ones written before zstd. Cassettes land
+// in dir/cassettes, ready for rdapclient.WithReplay. Nothing is written
+// unless the bundle's files are exactly those evidence.json lists, with
+// matching digests: a bundle without evidence, or with an entry changed,
+// removed or added, is refused, and so is one whose files exceed 64 MiB each
+// or 512 MiB in all.
+func Import(r io.Reader, dir string) (*Manifest, error) {
+	dr, err := decompress(r)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	defer dr.Close()
+	tr := tar.NewReader(dr)
+
+	type entry struct {
+		name, dst string
+		body      []byte
+	}
+	var (
+		m       *Manifest
+		ev      *Evidence
+		entries []entry
+		total   int64
+	)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("snapshot: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		dst, err := safeJoin(dir, hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Size > maxEntrySize {
+			return nil, fmt.Errorf("snapshot: %s is %d bytes, over the %d-byte limit", hdr.Name, hdr.Size, maxEntrySize)
+		}
+		if total += hdr.Size; total > maxBundleSize {
+			return nil, fmt.Errorf("snapshot: bundle unpacks to over %d bytes", maxBundleSize)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot: %w", err)
+		}
+		switch hdr.Name {
+		case ManifestName:
+			m = new(Manifest)
+			if err := json.Unmarshal(b, m); err != nil {
+				return nil, fmt.Errorf("snapshot manifest: %w", err)
+			}
+			if m.Version > FormatVersion {
+				return nil, fmt.Errorf("snapshot: bundle version %d is newer than supported %d", m.Version, FormatVersion)
+			}
+		case EvidenceName:
+			ev = new(Evidence)
+			if err := json.Unmarshal(b, ev); err != nil {
+				return nil, fmt.Errorf("snapshot evidence: %w", err)
+			}
+		}
+		entries = append(entries, entry{name: hdr.Name, dst: dst, body: b})
+	}
+	if m == nil {
+		return nil, errors.New("snapshot: bundle has no manifest")
+	}
+	if ev == nil {
+		return nil, fmt.Errorf("snapshot: bundle has no %s", EvidenceName)
+	}
+	present := map[string]bool{}
+	for _, e := range entries {
+		if e.name == EvidenceName {
+			continue
+		}
+		if err := ev.check(e.name, e.body); err != nil {
+			return nil, err
+		}
+		present[e.name] = true
+	}
+	for _, en := range ev.Entries {
+		if !present[en.Name] {
+			return nil, fmt.Errorf("snapshot: %s lists %s, which the bundle lacks", EvidenceName, en.Name)
+		}
+	}
+	for _, e := range entries {
+		if err := os.MkdirAll(filepath.Dir(e.dst), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(e.dst, e.body, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// gzipMagic opens a gzip stream; anything else is read as zstd.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns the tar stream of a bundle, by its compression.
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(gzipMagic)); bytes.Equal(head, gzipMagic) {
+		return gzip.NewReader(br)
+	}
+	zr, err := zstd.NewReader(br, zstd.WithDecoderMaxWindow(maxZstdWindow), zstd.WithDecoderMaxMemory(maxBundleSize))
+	if err != nil {
+		return nil, err
+	}
+	return zr.IOReadCloser(), nil
+}
+
+// safeJoin rejects archive names that would escape dir.
+func safeJoin(dir, name string) (string, error) {
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("snapshot: unsafe path %q in bundle", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
+
+func writeEntry(tw *tar.Writer, name string, b []byte, mod time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(b)), ModTime: mod, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(b)
+	return err
+}
+
+func listFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasSuffix(e.Name(), ".json") {
+			out = append(out, e.Name())
+		}
+	}
+	return out, nil
+}
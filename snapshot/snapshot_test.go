@@ -0,0 +1,196 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "abc.json"), []byte(`{"url":"https://x/domain/a"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	m := Manifest{Seed: "a.example", CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+	if err := Export(&buf, m, map[string]any{"nodes": map[string]any{}}, src); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+		t.Fatalf("bundle is not zstd: % x", buf.Bytes()[:4])
+	}
+
+	dst := t.TempDir()
+	got, err := Import(&buf, dst)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if got.Seed != "a.example" || got.Cassettes != 1 || got.Version != FormatVersion {
+		t.Fatalf("manifest: %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dst, CassetteDir, "abc.json")); err != nil {
+		t.Fatalf("cassette not extracted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, GraphName)); err != nil {
+		t.Fatalf("graph not extracted: %v", err)
+	}
+}
+
+func TestImport_RejectsTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	_ = tw.WriteHeader(&tar.Header{Name: "../evil.json", Mode: 0o644, Size: 2, Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte("{}"))
+	_ = tw.Close()
+	_ = gz.Close()
+
+	if _, err := Import(&buf, t.TempDir()); err == nil {
+		t.Fatalf("expected traversal to be rejected")
+	}
+}
+
+func TestImport_Limits(t *testing.T) {
+	// Only the header of the oversized entry is needed: Import refuses it
+	// before reading the body.
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	_ = tw.WriteHeader(&tar.Header{Name: GraphName, Mode: 0o644, Size: maxEntrySize + 1, Typeflag: tar.TypeReg})
+	_ = tw.Flush()
+	_ = gz.Close()
+	if _, err := Import(&buf, t.TempDir()); err == nil || !strings.Contains(err.Error(), "over the") {
+		t.Fatalf("oversized entry: %v", err)
+	}
+
+	buf.Reset()
+	zw, err := zstd.NewWriter(&buf, zstd.WithWindowSize(2*maxZstdWindow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = zw.Write(bytes.Repeat([]byte{0}, 4<<20))
+	_ = zw.Close()
+	if _, err := Import(&buf, t.TempDir()); err == nil || !strings.Contains(err.Error(), "window") {
+		t.Fatalf("oversized zstd window: %v", err)
+	}
+}
+
+func TestSignVerify_DetectsTamper(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Export(&buf, Manifest{Seed: "a.example"}, nil, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	bundle := buf.Bytes()
+
+	sig, err := Sign(bytes.NewReader(bundle), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(bytes.NewReader(bundle), sig, pub); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	tampered := append([]byte(nil), bundle...)
+	tampered[len(tampered)-1] ^= 0xff
+	if err := Verify(bytes.NewReader(tampered), sig, pub); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("want ErrBadSignature, got %v", err)
+	}
+}
+
+func TestImport_EvidenceDigestMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	write := func(name, body string) {
+		_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg})
+		_, _ = tw.Write([]byte(body))
+	}
+	write(ManifestName, `{"version":1,"seed":"a"}`)
+	write(GraphName, `{"nodes":{}}`)
+	write(EvidenceName, `{"algorithm":"sha256","entries":[{"name":"manifest.json","sha256":"00"},{"name":"graph.json","sha256":"00"}]}`)
+	_ = tw.Close()
+	_ = gz.Close()
+
+	dst := t.TempDir()
+	if _, err := Import(&buf, dst); err == nil {
+		t.Fatalf("expected digest mismatch")
+	}
+	if _, err := os.Stat(filepath.Join(dst, GraphName)); !os.IsNotExist(err) {
+		t.Fatalf("nothing should be written when evidence fails")
+	}
+}
+
+func TestImport_EvidenceCoversEveryEntry(t *testing.T) {
+	src := t.TempDir()
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(`{}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := Export(&buf, Manifest{Seed: "a.example"}, nil, src); err != nil {
+		t.Fatal(err)
+	}
+	// rewrite copies the bundle's entries, letting edit drop (false) or
+	// change them, and appends extra.
+	rewrite := func(edit func(name string) bool, extra ...string) *bytes.Buffer {
+		dr, err := decompress(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dr.Close()
+		tr := tar.NewReader(dr)
+		var out bytes.Buffer
+		gz := gzip.NewWriter(&out)
+		tw := tar.NewWriter(gz)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				break
+			}
+			b, _ := io.ReadAll(tr)
+			if !edit(hdr.Name) {
+				continue
+			}
+			_ = writeEntry(tw, hdr.Name, b, hdr.ModTime)
+		}
+		for _, name := range extra {
+			_ = writeEntry(tw, name, []byte(`{}`), time.Time{})
+		}
+		_ = tw.Close()
+		_ = gz.Close()
+		return &out
+	}
+	keep := func(string) bool { return true }
+	cases := map[string]*bytes.Buffer{
+		"entry removed":    rewrite(func(n string) bool { return n != CassetteDir+"/b.json" }),
+		"evidence removed": rewrite(func(n string) bool { return n != EvidenceName }),
+		"entry added":      rewrite(keep, CassetteDir+"/c.json"),
+	}
+	for name, bundle := range cases {
+		dst := t.TempDir()
+		if _, err := Import(bundle, dst); err == nil {
+			t.Errorf("%s: import succeeded", name)
+		}
+		if _, err := os.Stat(filepath.Join(dst, ManifestName)); !os.IsNotExist(err) {
+			t.Errorf("%s: files written despite failed evidence", name)
+		}
+	}
+	if _, err := Import(rewrite(keep), t.TempDir()); err != nil {
+		t.Fatalf("unmodified bundle: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package rdapclient
+
+import "strings"
+
+// Status is an RDAP status value in its canonical spelling: the RDAP JSON
+// values registry's, which RFC 8056 maps the EPP statuses onto.
+type Status string
+
+// Statuses of the RDAP JSON values registry. The EPP status each one maps
+// from (RFC 8056) is noted where the name differs.
+const (
+	StatusValidated          Status = "validated"
+	StatusRenewProhibited    Status = "renew prohibited"
+	StatusUpdateProhibited   Status = "update prohibited"
+	StatusTransferProhibited Status = "transfer prohibited"
+	StatusDeleteProhibited   Status = "delete prohibited"
+	StatusProxy              Status = "proxy"
+	StatusPrivate            Status = "private"
+	StatusRemoved            Status = "removed"
+	StatusObscured           Status = "obscured"
+	StatusAssociated         Status = "associated" // EPP linked
+	StatusActive             Status = "active"     // EPP ok
+	StatusInactive           Status = "inactive"
+	StatusLocked             Status = "locked"
+	StatusAdministrative     Status = "administrative"
+	StatusReserved           Status = "reserved"
+
+	StatusPendingCreate   Status = "pending create"
+	StatusPendingRenew    Status = "pending renew"
+	StatusPendingTransfer Status = "pending transfer"
+	StatusPendingUpdate   Status = "pending update"
+	StatusPendingDelete   Status = "pending delete"
+	StatusPendingRestore  Status = "pending restore"
+
+	StatusAddPeriod        Status = "add period"
+	StatusAutoRenewPeriod  Status = "auto renew period"
+	StatusRenewPeriod      Status = "renew period"
+	StatusTransferPeriod   Status = "transfer period"
+	StatusRedemptionPeriod Status = "redemption period"
+
+	StatusClientDeleteProhibited   Status = "client delete prohibited"
+	StatusClientHold               Status = "client hold"
+	StatusClientRenewProhibited    Status = "client renew prohibited"
+	StatusClientTransferProhibited Status = "client transfer prohibited"
+	StatusClientUpdateProhibited   Status = "client update prohibited"
+
+	StatusServerDeleteProhibited   Status = "server delete prohibited"
+	StatusServerHold               Status = "server hold"
+	StatusServerRenewProhibited    Status = "server renew prohibited"
+	StatusServerTransferProhibited Status = "server transfer prohibited"
+	StatusServerUpdateProhibited   Status = "server update prohibited"
+)
+
+// knownStatuses maps statusKey of every spelling NormalizeStatus accepts to
+// the canonical Status.
+var knownStatuses = func() map[string]Status {
+	m := map[string]Status{
+		// EPP names that differ from the RDAP ones.
+		"ok":     StatusActive,
+		"linked": StatusAssociated,
+		// Registry variants seen in the wild.
+		"autorenewgraceperiod":             StatusAutoRenewPeriod,
+		"addgraceperiod":                   StatusAddPeriod,
+		"renewgraceperiod":                 StatusRenewPeriod,
+		"transfergraceperiod":              StatusTransferPeriod,
+		"redemptiongraceperiod":            StatusRedemptionPeriod,
+		"pendingdeleterestorable":          StatusRedemptionPeriod,
+		"pendingdeletescheduledforrelease": StatusPendingDelete,
+	}
+	for _, s := range []Status{
+		StatusValidated, StatusRenewProhibited, StatusUpdateProhibited, StatusTransferProhibited,
+		StatusDeleteProhibited, StatusProxy, StatusPrivate, StatusRemoved, StatusObscured, StatusAssociated,
+		StatusActive, StatusInactive, StatusLocked, StatusAdministrative, StatusReserved,
+		StatusPendingCreate, StatusPendingRenew, StatusPendingTransfer, StatusPendingUpdate,
+		StatusPendingDelete, StatusPendingRestore, StatusAddPeriod, StatusAutoRenewPeriod, StatusRenewPeriod,
+		StatusTransferPeriod, StatusRedemptionPeriod, StatusClientDeleteProhibited, StatusClientHold,
+		StatusClientRenewProhibited, StatusClientTransferProhibited, StatusClientUpdateProhibited,
+		StatusServerDeleteProhibited, StatusServerHold, StatusServerRenewProhibited,
+		StatusServerTransferProhibited, StatusServerUpdateProhibited,
+	} {
+		m[statusKey(string(s))] = s
+	}
+	return m
+}()
+
+// NormalizeStatus maps a status as a server sent it to its canonical Status:
+// RDAP ("client transfer prohibited") and EPP ("clientTransferProhibited",
+// "ok") spellings in any case, with hyphens or underscores, or followed by
+// the ICANN explanation URL ("clientHold https://icann.org/epp#clientHold").
+// Values it does not know come back trimmed and lower-cased; see Known.
+func NormalizeStatus(s string) Status {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "://"); i >= 0 {
+		if j := strings.LastIndexAny(s[:i], " \t("); j >= 0 {
+			s = strings.TrimSpace(s[:j])
+		}
+	}
+	if st, ok := knownStatuses[statusKey(s)]; ok {
+		return st
+	}
+	return Status(lower(s))
+}
+
+// Known reports whether s is one of the registry's statuses.
+func (s Status) Known() bool {
+	st, ok := knownStatuses[statusKey(string(s))]
+	return ok && st == s
+}
+
+// EPP returns the EPP spelling of s ("clientTransferProhibited"), or "" for
+// statuses EPP does not have.
+func (s Status) EPP() string {
+	switch s {
+	case StatusActive:
+		return "ok"
+	case StatusAssociated:
+		return "linked"
+	case StatusInactive, StatusPendingCreate, StatusPendingRenew, StatusPendingTransfer, StatusPendingUpdate,
+		StatusPendingDelete, StatusClientDeleteProhibited, StatusClientHold, StatusClientRenewProhibited,
+		StatusClientTransferProhibited, StatusClientUpdateProhibited, StatusServerDeleteProhibited,
+		StatusServerHold, StatusServerRenewProhibited, StatusServerTransferProhibited, StatusServerUpdateProhibited,
+		StatusAddPeriod, StatusAutoRenewPeriod, StatusRenewPeriod, StatusTransferPeriod, StatusRedemptionPeriod,
+		StatusPendingRestore:
+		words := strings.Fields(string(s))
+		for i := 1; i < len(words); i++ {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+		return strings.Join(words, "")
+	}
+	return ""
+}
+
+// Statuses returns o's status values normalized, without duplicates, in the
+// order the server sent them.
+func (o CommonObject) Statuses() []Status {
+	out := make([]Status, 0, len(o.Status))
+	seen := map[Status]bool{}
+	for _, s := range o.Status {
+		if st := NormalizeStatus(s); !seen[st] {
+			seen[st] = true
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+// HasStatus reports whether o carries any of statuses, however the server spelled it.
+func (o CommonObject) HasStatus(statuses ...Status) bool {
+	for _, s := range o.Status {
+		st := NormalizeStatus(s)
+		for _, want := range statuses {
+			if st == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsLocked reports whether d cannot be transferred away: the registrar's or
+// the registry's transfer prohibition, or the generic "transfer prohibited"
+// and "locked". See annotate.TransferLock (x/annotate) for which lock holds.
+func (d *Domain) IsLocked() bool {
+	return d.HasStatus(StatusClientTransferProhibited, StatusServerTransferProhibited, StatusTransferProhibited, StatusLocked)
+}
+
+// IsPendingDelete reports whether d is on its way out of the registry:
+// pending delete, or in the redemption period before it.
+func (d *Domain) IsPendingDelete() bool {
+	return d.HasStatus(StatusPendingDelete, StatusRedemptionPeriod)
+}
+
+// IsOnHold reports whether d is withheld from the DNS (client or server hold).
+func (d *Domain) IsOnHold() bool { return d.HasStatus(StatusClientHold, StatusServerHold) }
+
+// statusKey folds an RDAP status ("client transfer prohibited") and its EPP
+// spelling ("clientTransferProhibited") to the same key.
+func statusKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '_' {
+			return -1
+		}
+		return r
+	}, lower(s))
+}
@@ -2,9 +2,9 @@ package rdapclient
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
-	"path"
 	"strings"
 	"time"
 )
@@ -20,13 +20,48 @@ func lastLabel(domain string) string {
 
 func trimDotLower(s string) string { return strings.ToLower(strings.TrimPrefix(s, ".")) }
 
-func mustJoin(base, p1 string, more ...string) string {
-	u, _ := url.Parse(base)
-	u.Path = path.Join(u.Path, p1)
-	for _, m := range more {
-		u.Path = path.Join(u.Path, m)
+// BuildObjectURL returns the RFC 9082 lookup URL for an object of class
+// ("domain", "ip", "autnum", "nameserver", "entity", ...) identified by id.
+// The id is escaped per path segment; for "ip" a CIDR's prefix length becomes
+// its own segment as the RFC requires.
+func BuildObjectURL(base, class, id string) (string, error) {
+	if class == "" || strings.ContainsAny(class, "/?#%") {
+		return "", fmt.Errorf("invalid RDAP object class %q", class)
 	}
-	return u.String()
+	segs := []string{class, id}
+	if class == "ip" {
+		if addr, bits, ok := strings.Cut(id, "/"); ok {
+			segs = []string{class, addr, bits}
+		}
+	}
+	return joinSegments(base, segs, 1)
+}
+
+// joinSegments appends segs to base's path. Segments from index trusted on are
+// untrusted input: they must be non-empty, may not be "." or "..", and are
+// percent-escaped.
+func joinSegments(base string, segs []string, trusted int) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("RDAP base %q is not an absolute URL", base)
+	}
+	rawPath := strings.TrimRight(u.EscapedPath(), "/")
+	plainPath := strings.TrimRight(u.Path, "/")
+	for i, s := range segs {
+		if i >= trusted && (s == "" || s == "." || s == "..") {
+			return "", fmt.Errorf("invalid RDAP path segment %q", s)
+		}
+		rawPath += "/" + url.PathEscape(s)
+		plainPath += "/" + s
+	}
+	u.Path = plainPath
+	u.RawPath = rawPath
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), nil
 }
 
 func errorsAs(err error, target interface{}) bool { return errors.As(err, target) }
@@ -51,19 +86,26 @@ func copyHeaders(dst, src http.Header) {
 }
 
 func retryAfter(h http.Header, fallback time.Duration) time.Duration {
+	if d := parseRetryAfter(h); d > 0 && d < 10*time.Second {
+		return d
+	}
+	return fallback
+}
+
+// parseRetryAfter returns the Retry-After delay in h (seconds or HTTP-date), or 0.
+// A date is taken relative to the response's Date header, so local skew does not matter.
+func parseRetryAfter(h http.Header) time.Duration {
 	if v := h.Get("Retry-After"); v != "" {
-		if sec, err := time.ParseDuration(strings.TrimSpace(v) + "s"); err == nil {
-			if sec > 0 && sec < 10*time.Second {
-				return sec
-			}
+		if sec, err := time.ParseDuration(strings.TrimSpace(v) + "s"); err == nil && sec > 0 {
+			return sec
 		}
 		if t, err := time.Parse(time.RFC1123, v); err == nil {
-			if d := time.Until(t); d > 0 && d < 10*time.Second {
+			if d := t.Sub(serverNow(h, time.Now())); d > 0 {
 				return d
 			}
 		}
 	}
-	return fallback
+	return 0
 }
 
 // temporary reports whether err (or any wrapped error) implements Temporary() bool and returns true.
@@ -0,0 +1,71 @@
+package annotate
+
+import (
+	"strings"
+	"testing"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+func TestTransferLock(t *testing.T) {
+	d := &rdap.Domain{LDHName: "example.com"}
+	d.Status = []string{"active", "client transfer prohibited", "pendingTransfer"}
+	r := TransferLock(d)
+	if r.Verdict != TransferRegistrarLocked || !r.ClientTransferProhibited || r.ServerTransferProhibited || !r.PendingTransfer {
+		t.Fatalf("client lock: %+v", r)
+	}
+
+	d.Status = append(d.Status, "serverTransferProhibited")
+	if r := TransferLock(d); r.Verdict != TransferRegistryLocked {
+		t.Fatalf("server lock: %+v", r)
+	}
+
+	d.Status = []string{"active"}
+	d.Remarks = []rdap.Remark{{Title: "Registrar Lock", Description: []string{"Transfer lock enabled by registrar"}}, {Title: "Terms of use"}}
+	if r := TransferLock(d); r.Verdict != TransferRemarkOnly || len(r.LockRemarks) != 1 {
+		t.Fatalf("remark only: %+v", r)
+	}
+
+	d.Remarks = nil
+	if r := TransferLock(d); r.Verdict != TransferUnlocked {
+		t.Fatalf("unlocked: %+v", r)
+	}
+	d.Status = nil
+	if r := TransferLock(d); r.Verdict != TransferLockUnknown {
+		t.Fatalf("no status: %+v", r)
+	}
+}
+
+func TestNSClassifier(t *testing.T) {
+	pc := DefaultNSClassifier()
+	for host, want := range map[string]string{
+		"ADA.NS.CLOUDFLARE.COM.":        "Cloudflare",
+		"ns-1234.awsdns-12.co.uk":       "Amazon Route 53",
+		"dns1.p01.nsone.net":            "NS1",
+		"ns-cloud-a1.googledomains.com": "Google Cloud DNS",
+		"ns07.domaincontrol.com":        "GoDaddy",
+	} {
+		if p, ok := pc.Classify(host); !ok || p.Name != want {
+			t.Errorf("Classify(%q) = %+v %v, want %s", host, p, ok, want)
+		}
+	}
+	if p, _ := pc.Classify("ns07.domaincontrol.com"); !p.RegistrarDefault {
+		t.Error("GoDaddy: want registrar default")
+	}
+	if _, ok := pc.Classify("ns1.example.net"); ok {
+		t.Error("unknown host classified")
+	}
+
+	over, err := LoadNSClassifier(strings.NewReader(`[{"provider": "Corp DNS", "patterns": ["*.cloudflare.com"]}]`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &rdap.Domain{Nameservers: []rdap.Nameserver{{LDHName: "ada.ns.cloudflare.com"}, {LDHName: "ns-1.awsdns-1.org"}, {LDHName: "bob.ns.cloudflare.com"}}}
+	got := NameserverProviders(d, over)
+	if len(got) != 2 || got[0].Name != "Corp DNS" || got[1].Name != "Amazon Route 53" {
+		t.Fatalf("providers = %+v", got)
+	}
+	if _, err := LoadNSClassifier(strings.NewReader(`[{"provider": "x", "patterns": ["[a-"]}]`), nil); err == nil {
+		t.Fatal("bad pattern accepted")
+	}
+}
@@ -0,0 +1,104 @@
+package annotate
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+//go:embed nsproviders.json
+var defaultNSProviders []byte
+
+// NSProvider is a DNS provider a nameserver host was attributed to.
+type NSProvider struct {
+	Name string `json:"provider"`
+	// RegistrarDefault marks a registrar's bundled DNS (e.g. GoDaddy's
+	// domaincontrol.com) rather than a DNS service chosen on purpose.
+	RegistrarDefault bool `json:"registrarDefault,omitempty"`
+}
+
+// nsProviderRule is one entry of the pattern table (nsproviders.json, or an
+// override file in the same format).
+type nsProviderRule struct {
+	NSProvider
+	// Patterns are path.Match globs over the lower-case host without the
+	// trailing dot; "*" also matches dots ("*.nsone.net").
+	Patterns []string `json:"patterns"`
+}
+
+// NSClassifier maps nameserver hostnames to known DNS providers using a
+// pattern table. The zero value knows no providers; use DefaultNSClassifier.
+type NSClassifier struct {
+	rules []nsProviderRule
+}
+
+// DefaultNSClassifier returns a classifier for the providers in the embedded
+// table (Cloudflare, Route 53, NS1, Google, Azure, common registrar defaults ...).
+func DefaultNSClassifier() *NSClassifier { return defaultNSClassifier() }
+
+var defaultNSClassifier = sync.OnceValue(func() *NSClassifier {
+	var rules []nsProviderRule
+	if err := json.Unmarshal(defaultNSProviders, &rules); err != nil {
+		panic("rdap annotate: embedded nsproviders.json: " + err.Error())
+	}
+	return &NSClassifier{rules: rules}
+})
+
+// LoadNSClassifier reads an override table from r (a JSON array of
+// {"provider", "patterns", "registrarDefault"}) whose rules are tried before
+// those of base. A nil base means the embedded defaults.
+func LoadNSClassifier(r io.Reader, base *NSClassifier) (*NSClassifier, error) {
+	var rules []nsProviderRule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("rdap nameserver providers: %w", err)
+	}
+	for _, rule := range rules {
+		for _, p := range rule.Patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("rdap nameserver providers: %s: bad pattern %q", rule.Name, p)
+			}
+		}
+	}
+	if base == nil {
+		base = DefaultNSClassifier()
+	}
+	return &NSClassifier{rules: append(rules, base.rules...)}, nil
+}
+
+// Classify returns the provider serving host, and false when no rule matches.
+func (c *NSClassifier) Classify(host string) (NSProvider, bool) {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	for _, r := range c.rules {
+		for _, p := range r.Patterns {
+			if ok, _ := path.Match(p, host); ok {
+				return r.NSProvider, true
+			}
+		}
+	}
+	return NSProvider{}, false
+}
+
+// NameserverProviders classifies d's nameservers with c (nil means
+// DefaultNSClassifier) and returns the distinct providers in nameserver order.
+// Hosts no rule matches are left out.
+func NameserverProviders(d *rdap.Domain, c *NSClassifier) []NSProvider {
+	if c == nil {
+		c = DefaultNSClassifier()
+	}
+	var out []NSProvider
+	seen := map[string]bool{}
+	for _, ns := range d.Nameservers {
+		p, ok := c.Classify(ns.LDHName)
+		if ok && !seen[p.Name] {
+			seen[p.Name] = true
+			out = append(out, p)
+		}
+	}
+	return out
+}
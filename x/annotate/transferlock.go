@@ -0,0 +1,92 @@
+// Package annotate derives judgments from RDAP objects that RDAP itself does
+// not state: a domain's transfer-lock verdict, the DNS providers behind its
+// nameservers. It is experimental, outside the module's v1 commitment (see
+// the rdapclient package documentation): verdicts, names and signatures may
+// change between minor releases as the heuristics improve.
+package annotate
+
+import (
+	"strings"
+
+	rdap "github.com/datum-labs/rdap"
+)
+
+// TransferLockVerdict summarizes how well a domain is protected against an
+// unauthorized transfer.
+type TransferLockVerdict string
+
+const (
+	// TransferRegistryLocked: the registry holds serverTransferProhibited
+	// (typically a paid "registry lock"); the strongest protection.
+	TransferRegistryLocked TransferLockVerdict = "registry-locked"
+	// TransferRegistrarLocked: the registrar set clientTransferProhibited.
+	TransferRegistrarLocked TransferLockVerdict = "registrar-locked"
+	// TransferRemarkOnly: no prohibiting status, but a remark claims a lock.
+	// Worth a manual check: remarks are free text and not enforced.
+	TransferRemarkOnly TransferLockVerdict = "remark-only"
+	// TransferUnlocked: the domain has statuses, none of which prohibit transfer.
+	TransferUnlocked TransferLockVerdict = "unlocked"
+	// TransferLockUnknown: the server sent no status at all.
+	TransferLockUnknown TransferLockVerdict = "unknown"
+)
+
+// TransferLockReport is the result of TransferLock.
+type TransferLockReport struct {
+	Domain                   string              `json:"domain"`
+	Verdict                  TransferLockVerdict `json:"verdict"`
+	ClientTransferProhibited bool                `json:"clientTransferProhibited"`
+	ServerTransferProhibited bool                `json:"serverTransferProhibited"`
+	PendingTransfer          bool                `json:"pendingTransfer,omitempty"`
+	// LockRemarks holds the text of remarks that mention a transfer or
+	// registrar lock, for the auditor to read.
+	LockRemarks []string `json:"lockRemarks,omitempty"`
+}
+
+// TransferLock combines d's transfer statuses (RFC 8056 "client transfer
+// prohibited", "server transfer prohibited", "pending transfer"; EPP spellings
+// such as "clientTransferProhibited" are accepted too) and any lock remarks
+// into one verdict, for domain-portfolio audits.
+func TransferLock(d *rdap.Domain) TransferLockReport {
+	r := TransferLockReport{Domain: d.LDHName}
+	if r.Domain == "" {
+		r.Domain = d.UnicodeName
+	}
+	for _, s := range d.Status {
+		switch rdap.NormalizeStatus(s) {
+		case rdap.StatusClientTransferProhibited:
+			r.ClientTransferProhibited = true
+		case rdap.StatusServerTransferProhibited:
+			r.ServerTransferProhibited = true
+		case rdap.StatusPendingTransfer:
+			r.PendingTransfer = true
+		}
+	}
+	for _, rm := range d.Remarks {
+		text := strings.TrimSpace(strings.Join(append([]string{rm.Title}, rm.Description...), " "))
+		if l := strings.ToLower(text); strings.Contains(l, "lock") && containsAny(l, "transfer", "registrar", "registry") {
+			r.LockRemarks = append(r.LockRemarks, text)
+		}
+	}
+	switch {
+	case r.ServerTransferProhibited:
+		r.Verdict = TransferRegistryLocked
+	case r.ClientTransferProhibited:
+		r.Verdict = TransferRegistrarLocked
+	case len(r.LockRemarks) > 0:
+		r.Verdict = TransferRemarkOnly
+	case len(d.Status) == 0:
+		r.Verdict = TransferLockUnknown
+	default:
+		r.Verdict = TransferUnlocked
+	}
+	return r
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
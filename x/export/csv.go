@@ -0,0 +1,268 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	rdap "github.com/datum-labs/rdap"
+	"github.com/datum-labs/rdap/graph"
+)
+
+// Tabular output flattens RDAP objects into rows for spreadsheets: one table
+// per object class, named by its graph node kind, with a fixed schema of
+// columns. Multi-valued fields (statuses, roles, nameservers, addresses) are
+// joined with ", "; dates are RFC 3339 in UTC, empty when the server gives
+// none.
+
+// column is one field of a class's table.
+type column[T any] struct {
+	name  string
+	value func(T) string
+}
+
+// table is the schema of one class: its column names and a row function.
+type table struct {
+	columns []string
+	row     func(rdap.Object) ([]string, bool)
+}
+
+func tableOf[T rdap.Object](cols ...column[T]) table {
+	t := table{row: func(o rdap.Object) ([]string, bool) {
+		v, ok := o.(T)
+		if !ok {
+			return nil, false
+		}
+		row := make([]string, len(cols))
+		for i, c := range cols {
+			row[i] = c.value(v)
+		}
+		return row, true
+	}}
+	for _, c := range cols {
+		t.columns = append(t.columns, c.name)
+	}
+	return t
+}
+
+var tables = map[string]table{
+	graph.KindDomain: tableOf(
+		column[*rdap.Domain]{"ldhName", func(d *rdap.Domain) string { return d.LDHName }},
+		column[*rdap.Domain]{"unicodeName", func(d *rdap.Domain) string { return d.UnicodeName }},
+		column[*rdap.Domain]{"handle", func(d *rdap.Domain) string { return d.Handle }},
+		column[*rdap.Domain]{"status", func(d *rdap.Domain) string { return join(d.Status) }},
+		column[*rdap.Domain]{"registrar", func(d *rdap.Domain) string {
+			if r := d.Registrar(); r != nil {
+				return r.RegistrarName()
+			}
+			return ""
+		}},
+		column[*rdap.Domain]{"registrarIanaId", func(d *rdap.Domain) string {
+			if r := d.Registrar(); r != nil {
+				return r.IANARegistrarID()
+			}
+			return ""
+		}},
+		column[*rdap.Domain]{"registration", func(d *rdap.Domain) string { return date(d.RegistrationDate()) }},
+		column[*rdap.Domain]{"expiration", func(d *rdap.Domain) string { return date(d.ExpirationDate()) }},
+		column[*rdap.Domain]{"lastChanged", func(d *rdap.Domain) string { return date(d.LastChangedDate()) }},
+		column[*rdap.Domain]{"nameservers", func(d *rdap.Domain) string {
+			var ns []string
+			for _, n := range d.Nameservers {
+				ns = append(ns, n.LDHName)
+			}
+			return join(ns)
+		}},
+		column[*rdap.Domain]{"delegationSigned", func(d *rdap.Domain) string {
+			return strconv.FormatBool(d.SecureDNS != nil && d.SecureDNS.DelegationSigned)
+		}},
+		column[*rdap.Domain]{"port43", func(d *rdap.Domain) string { return d.Port43 }},
+	),
+	graph.KindEntity: tableOf(
+		column[*rdap.Entity]{"handle", func(e *rdap.Entity) string { return e.Handle }},
+		column[*rdap.Entity]{"roles", func(e *rdap.Entity) string { return join(e.Roles) }},
+		column[*rdap.Entity]{"kind", func(e *rdap.Entity) string { return contact(e).Kind }},
+		column[*rdap.Entity]{"fullName", func(e *rdap.Entity) string { return contact(e).FullName }},
+		column[*rdap.Entity]{"org", func(e *rdap.Entity) string { return contact(e).Org }},
+		column[*rdap.Entity]{"email", func(e *rdap.Entity) string { return join(contact(e).Emails) }},
+		column[*rdap.Entity]{"phone", func(e *rdap.Entity) string {
+			var ps []string
+			for _, p := range contact(e).Phones {
+				ps = append(ps, p.Number)
+			}
+			return join(ps)
+		}},
+		column[*rdap.Entity]{"country", func(e *rdap.Entity) string { return node{obj: e}.country() }},
+		column[*rdap.Entity]{"ianaRegistrarId", func(e *rdap.Entity) string { return e.IANARegistrarID() }},
+		column[*rdap.Entity]{"status", func(e *rdap.Entity) string { return join(e.Status) }},
+		column[*rdap.Entity]{"registration", func(e *rdap.Entity) string { return date(e.EventTime(rdap.EventRegistration)) }},
+		column[*rdap.Entity]{"lastChanged", func(e *rdap.Entity) string { return date(e.EventTime(rdap.EventLastChanged)) }},
+	),
+	graph.KindNameserver: tableOf(
+		column[*rdap.Nameserver]{"ldhName", func(n *rdap.Nameserver) string { return n.LDHName }},
+		column[*rdap.Nameserver]{"unicodeName", func(n *rdap.Nameserver) string { return n.UnicodeName }},
+		column[*rdap.Nameserver]{"handle", func(n *rdap.Nameserver) string { return n.Handle }},
+		column[*rdap.Nameserver]{"ipv4", func(n *rdap.Nameserver) string {
+			if n.IPAddresses == nil {
+				return ""
+			}
+			return join(n.IPAddresses.V4)
+		}},
+		column[*rdap.Nameserver]{"ipv6", func(n *rdap.Nameserver) string {
+			if n.IPAddresses == nil {
+				return ""
+			}
+			return join(n.IPAddresses.V6)
+		}},
+		column[*rdap.Nameserver]{"status", func(n *rdap.Nameserver) string { return join(n.Status) }},
+		column[*rdap.Nameserver]{"lastChanged", func(n *rdap.Nameserver) string { return date(n.EventTime(rdap.EventLastChanged)) }},
+	),
+	graph.KindIPNetwork: tableOf(
+		column[*rdap.IPNetwork]{"handle", func(n *rdap.IPNetwork) string { return n.Handle }},
+		column[*rdap.IPNetwork]{"startAddress", func(n *rdap.IPNetwork) string { return n.StartAddress }},
+		column[*rdap.IPNetwork]{"endAddress", func(n *rdap.IPNetwork) string { return n.EndAddress }},
+		column[*rdap.IPNetwork]{"ipVersion", func(n *rdap.IPNetwork) string { return n.IPVersion }},
+		column[*rdap.IPNetwork]{"name", func(n *rdap.IPNetwork) string { return n.Name }},
+		column[*rdap.IPNetwork]{"type", func(n *rdap.IPNetwork) string { return n.Type }},
+		column[*rdap.IPNetwork]{"country", func(n *rdap.IPNetwork) string { return n.CountryCode() }},
+		column[*rdap.IPNetwork]{"parentHandle", func(n *rdap.IPNetwork) string { return n.ParentHandle }},
+		column[*rdap.IPNetwork]{"organization", func(n *rdap.IPNetwork) string { return orgName(n.Organization()) }},
+		column[*rdap.IPNetwork]{"status", func(n *rdap.IPNetwork) string { return join(n.Status) }},
+		column[*rdap.IPNetwork]{"registration", func(n *rdap.IPNetwork) string { return date(n.EventTime(rdap.EventRegistration)) }},
+		column[*rdap.IPNetwork]{"lastChanged", func(n *rdap.IPNetwork) string { return date(n.EventTime(rdap.EventLastChanged)) }},
+	),
+	graph.KindAutnum: tableOf(
+		column[*rdap.Autnum]{"handle", func(a *rdap.Autnum) string { return a.Handle }},
+		column[*rdap.Autnum]{"startAutnum", func(a *rdap.Autnum) string { return strconv.FormatInt(a.StartAutnum, 10) }},
+		column[*rdap.Autnum]{"endAutnum", func(a *rdap.Autnum) string { return strconv.FormatInt(a.EndAutnum, 10) }},
+		column[*rdap.Autnum]{"name", func(a *rdap.Autnum) string { return a.Name }},
+		column[*rdap.Autnum]{"type", func(a *rdap.Autnum) string { return a.Type }},
+		column[*rdap.Autnum]{"country", func(a *rdap.Autnum) string { return a.CountryCode() }},
+		column[*rdap.Autnum]{"organization", func(a *rdap.Autnum) string { return orgName(a.Organization()) }},
+		column[*rdap.Autnum]{"status", func(a *rdap.Autnum) string { return join(a.Status) }},
+		column[*rdap.Autnum]{"registration", func(a *rdap.Autnum) string { return date(a.EventTime(rdap.EventRegistration)) }},
+		column[*rdap.Autnum]{"lastChanged", func(a *rdap.Autnum) string { return date(a.EventTime(rdap.EventLastChanged)) }},
+	),
+}
+
+// Columns returns the column names of a class's table ("domain", "entity",
+// "nameserver", "ip-network" or "autnum"), or nil for an unknown class.
+func Columns(class string) []string {
+	return tables[class].columns
+}
+
+// Flatten returns the table obj belongs in and its row there, the values in
+// the order of Columns. A map, as a loaded graph or dataset holds objects, is
+// read through rdap.ParseObject.
+func Flatten(obj any) (class string, row []string, err error) {
+	o, ok := obj.(rdap.Object)
+	if m, isMap := obj.(map[string]any); isMap {
+		if o, err = rdap.ParseObject(m); err != nil {
+			return "", nil, err
+		}
+		ok = true
+	}
+	if ok {
+		for class, t := range tables {
+			if row, ok := t.row(o); ok {
+				return class, row, nil
+			}
+		}
+	}
+	return "", nil, fmt.Errorf("rdap export: no table for %T", obj)
+}
+
+// CSV writes objects as CSV, one table per class. Leading columns named when
+// it is made (a batch's query, say) come before each class's own.
+type CSV struct {
+	open   func(class string) (w io.Writer, header bool, err error)
+	lead   []string
+	tables map[string]*csv.Writer
+}
+
+// NewCSV returns a CSV that sends each class's table to the writer open
+// returns the first time an object of that class is written; header says
+// whether the table is new and needs its header row (false to append to one
+// written before). lead names columns whose values Write takes first.
+func NewCSV(open func(class string) (w io.Writer, header bool, err error), lead ...string) *CSV {
+	return &CSV{open: open, lead: lead, tables: map[string]*csv.Writer{}}
+}
+
+// Write adds obj's row to its class's table, after the lead values. A value
+// a spreadsheet would read as a formula (one starting with =, +, -, @, a tab
+// or a carriage return) is written with a leading ' so it stays text:
+// registrants choose most of these strings.
+func (c *CSV) Write(obj any, lead ...string) error {
+	if len(lead) != len(c.lead) {
+		return fmt.Errorf("rdap export: %d lead values for %d lead columns", len(lead), len(c.lead))
+	}
+	class, row, err := Flatten(obj)
+	if err != nil {
+		return err
+	}
+	w, ok := c.tables[class]
+	if !ok {
+		out, header, err := c.open(class)
+		if err != nil {
+			return err
+		}
+		w = csv.NewWriter(out)
+		c.tables[class] = w
+		if header {
+			if err := w.Write(append(c.lead[:len(c.lead):len(c.lead)], Columns(class)...)); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Write(Escape(append(lead[:len(lead):len(lead)], row...)))
+}
+
+// Escape prefixes with ' each cell a spreadsheet would read as a formula, in
+// place, and returns cells: for CSV written next to Write's, such as a batch's
+// failures.
+func Escape(cells []string) []string {
+	for i, v := range cells {
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			cells[i] = "'" + v
+		}
+	}
+	return cells
+}
+
+// Flush flushes every table opened so far and returns the first error.
+func (c *CSV) Flush() error {
+	var first error
+	for _, w := range c.tables {
+		w.Flush()
+		if err := w.Error(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func contact(e *rdap.Entity) rdap.Contact {
+	if c := e.Contact(); c != nil {
+		return *c
+	}
+	return rdap.Contact{}
+}
+
+func orgName(e *rdap.Entity) string {
+	if e == nil {
+		return ""
+	}
+	return e.OrgName()
+}
+
+func join(vs []string) string { return strings.Join(vs, ", ") }
+
+func date(t time.Time, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
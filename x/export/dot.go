@@ -0,0 +1,42 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/datum-labs/rdap/graph"
+)
+
+// DOT writes g as a Graphviz digraph: one box per node labeled with its kind
+// and handle and filled by kind, one arrow per edge labeled with its
+// relation. Nodes that edges name but the walk never fetched are drawn
+// dashed. Render it with dot -Tsvg.
+func DOT(w io.Writer, g *graph.Graph) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph rdap {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, `  node [shape=box, style="rounded,filled", fontname="Helvetica", fontsize=10, fontcolor=white];`)
+	fmt.Fprintln(bw, `  edge [fontname="Helvetica", fontsize=8, color="#777777"];`)
+	for _, n := range nodes(g) {
+		color := kindColors[n.Kind]
+		if color == "" {
+			color = "#999999"
+		}
+		fmt.Fprintf(bw, "  %s [label=%s, fillcolor=%s];\n", dotID(n.ID), dotID(n.Kind+"\n"+n.handle()), dotID(color))
+	}
+	for _, id := range missing(g) {
+		fmt.Fprintf(bw, "  %s [label=%s, style=dashed, fontcolor=black];\n", dotID(id), dotID(id))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  %s -> %s [label=%s];\n", dotID(e.From), dotID(e.To), dotID(string(e.Rel)))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotID quotes s as a DOT string, with newlines as line breaks.
+func dotID(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
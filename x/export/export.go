@@ -0,0 +1,145 @@
+// Package export writes walk graphs in the formats of other tools: Graphviz
+// DOT for rendered diagrams, GraphML for graph tools such as Gephi and yEd,
+// Mermaid for diagrams in Markdown. It also flattens RDAP objects into CSV
+// tables, one schema per object class, for review in a spreadsheet, and into
+// aligned text tables for reading in a terminal. It is
+// experimental, outside the module's v1 commitment (see the rdapclient
+// package documentation): layouts, labels, attributes and columns may change
+// between minor releases.
+package export
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	rdap "github.com/datum-labs/rdap"
+	"github.com/datum-labs/rdap/graph"
+)
+
+// kindColors are the fill colors of node kinds, shared with rdapctl tree --open.
+var kindColors = map[string]string{
+	graph.KindDomain:     "#1f77b4",
+	graph.KindNameserver: "#2ca02c",
+	graph.KindEntity:     "#ff7f0e",
+	graph.KindIPNetwork:  "#9467bd",
+	graph.KindAutnum:     "#d62728",
+	"link":               "#7f7f7f",
+}
+
+// node is a graph node with the fields exporters show, read alike from typed
+// objects (a fresh walk) and generic JSON (a loaded graph).
+type node struct {
+	graph.Node
+	fields map[string]any
+	obj    rdap.Object // typed RDAP object, when the data is one
+}
+
+// nodes returns g's nodes sorted by ID, so output does not depend on map order.
+func nodes(g *graph.Graph) []node {
+	out := make([]node, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nd := node{Node: n, fields: fieldsOf(n.Data)}
+		if o, ok := n.Data.(rdap.Object); ok {
+			nd.obj = o
+		} else if o, err := rdap.ParseObject(nd.fields); err == nil {
+			nd.obj = o
+		}
+		out = append(out, nd)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// missing returns the IDs edges name that g has no node for (a fetch that
+// failed or was cut by the walk's limits), sorted.
+func missing(g *graph.Graph) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, e := range g.Edges {
+		for _, id := range []string{e.From, e.To} {
+			if _, ok := g.Nodes[id]; !ok && !seen[id] {
+				seen[id] = true
+				out = append(out, id)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func fieldsOf(data any) map[string]any {
+	if m, ok := data.(map[string]any); ok {
+		return m
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	_ = json.Unmarshal(b, &m)
+	return m
+}
+
+// str returns the string field name, or "".
+func (n node) str(name string) string {
+	s, _ := n.fields[name].(string)
+	return s
+}
+
+// handle is the name a node is known by: a domain's or nameserver's LDH
+// name, otherwise its handle, else the key of its ID.
+func (n node) handle() string {
+	for _, f := range []string{"ldhName", "handle", "unicodeName"} {
+		if s := n.str(f); s != "" {
+			return s
+		}
+	}
+	if s, ok := n.Data.(string); ok && n.Kind == "link" {
+		return s
+	}
+	return keyOf(n.ID)
+}
+
+// keyOf is the part of a node ID after its kind.
+func keyOf(id string) string {
+	if _, key, ok := strings.Cut(id, ":"); ok {
+		return key
+	}
+	return id
+}
+
+// country is the ISO 3166 code of a network's or autnum's country, or of an
+// entity's first contact address that has one.
+func (n node) country() string {
+	switch o := n.obj.(type) {
+	case *rdap.IPNetwork:
+		return o.CountryCode()
+	case *rdap.Autnum:
+		return o.CountryCode()
+	case *rdap.Entity:
+		if c := o.Contact(); c != nil {
+			for _, a := range c.Addresses {
+				if cc := rdap.NormalizeCountry(a.CountryCode); cc != "" {
+					return cc
+				}
+				if cc := rdap.NormalizeCountry(a.Country); cc != "" {
+					return cc
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// statuses returns the node's RDAP status values.
+func (n node) statuses() []string {
+	list, _ := n.fields["status"].([]any)
+	var out []string
+	for _, s := range list {
+		if s, ok := s.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,262 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	rdap "github.com/datum-labs/rdap"
+	"github.com/datum-labs/rdap/graph"
+)
+
+// testGraph is a small walk: a domain, its registrar (typed) and a
+// nameserver as a loaded graph holds it (generic JSON), plus an edge to a
+// nameserver that was never fetched.
+func testGraph() *graph.Graph {
+	g := graph.New()
+	g.AddNode("domain:example.com", graph.KindDomain, &rdap.Domain{LDHName: "example.com"})
+	g.AddNode("entity:reg-1", graph.KindEntity, &rdap.Entity{CommonObject: rdap.CommonObject{Handle: "REG-1"}})
+	g.AddNode("nameserver:ns1.example.net", graph.KindNameserver, map[string]any{"ldhName": "ns1.example.net"})
+	g.AddNode("ip-network:net-192-0-2-0-1", graph.KindIPNetwork, map[string]any{"objectClassName": "ip network",
+		"handle": "NET-192-0-2-0-1", "country": "de", "status": []any{"active", "validated"}})
+	g.AddEdge("entity:reg-1", "domain:example.com", graph.RelRegistrarOf)
+	g.AddEdge("nameserver:ns1.example.net", "domain:example.com", graph.RelNameserverOf)
+	g.AddEdge("nameserver:ns2.example.net", "domain:example.com", graph.RelNameserverOf)
+	g.AddLinkEdge("domain:example.com", "ip-network:net-192-0-2-0-1", graph.RelRelatedTo, "related")
+	return g
+}
+
+func TestDOT(t *testing.T) {
+	var b bytes.Buffer
+	if err := DOT(&b, testGraph()); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"digraph rdap {\n",
+		`"domain:example.com" [label="domain\nexample.com", fillcolor="#1f77b4"];`,
+		`"entity:reg-1" [label="entity\nREG-1", fillcolor="#ff7f0e"];`,
+		`"nameserver:ns1.example.net" [label="nameserver\nns1.example.net", fillcolor="#2ca02c"];`,
+		`"nameserver:ns2.example.net" [label="nameserver:ns2.example.net", style=dashed, fontcolor=black];`,
+		`"entity:reg-1" -> "domain:example.com" [label="REGISTRAR_OF"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+	if !strings.HasSuffix(out, "}\n") {
+		t.Errorf("unterminated digraph:\n%s", out)
+	}
+	if got := dotID(`a "b"` + "\n" + `c\d`); got != `"a \"b\"\nc\\d"` {
+		t.Errorf("dotID = %s", got)
+	}
+}
+
+func TestGraphML(t *testing.T) {
+	var b bytes.Buffer
+	if err := GraphML(&b, testGraph()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "<?xml") {
+		t.Fatalf("no XML header:\n%s", b.String())
+	}
+	var doc graphMLDoc
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	attrs := func(ds []graphMLData) map[string]string {
+		m := map[string]string{}
+		for _, d := range ds {
+			m[d.Key] = d.Value
+		}
+		return m
+	}
+	nodes := map[string]map[string]string{}
+	for _, n := range doc.Graph.Nodes {
+		nodes[n.ID] = attrs(n.Data)
+	}
+	if len(nodes) != 5 || doc.Graph.EdgeDefault != "directed" {
+		t.Fatalf("nodes = %v", nodes)
+	}
+	if n := nodes["ip-network:net-192-0-2-0-1"]; n["kind"] != "ip-network" || n["handle"] != "NET-192-0-2-0-1" ||
+		n["country"] != "DE" || n["status"] != "active, validated" {
+		t.Errorf("network = %v", n)
+	}
+	if n := nodes["entity:reg-1"]; n["label"] != "REG-1" || n["fetched"] != "" {
+		t.Errorf("registrar = %v", n)
+	}
+	if n := nodes["nameserver:ns2.example.net"]; n["fetched"] != "false" || n["kind"] != "nameserver" {
+		t.Errorf("unfetched nameserver = %v", n)
+	}
+	if len(doc.Graph.Edges) != 4 {
+		t.Fatalf("edges = %+v", doc.Graph.Edges)
+	}
+	if e := doc.Graph.Edges[3]; e.Source != "domain:example.com" || attrs(e.Data)["rel"] != "RELATED_TO" || attrs(e.Data)["linkRel"] != "related" {
+		t.Errorf("link edge = %+v", e)
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	var b bytes.Buffer
+	if err := Mermaid(&b, testGraph()); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	// Node IDs follow sorted graph IDs, unfetched nodes last.
+	for _, want := range []string{
+		"graph TD\n  subgraph kind_domain[\"domain\"]\n    n0[\"example.com\"]\n  end\n",
+		"  subgraph kind_nameserver[\"nameserver\"]\n    n3[\"ns1.example.net\"]\n    n4[\"ns2.example.net\"]\n  end\n",
+		"  subgraph kind_ip_network[\"ip-network\"]\n    n2[\"NET-192-0-2-0-1\"]\n  end\n",
+		"  n1 -->|\"REGISTRAR_OF\"| n0\n",
+		"  n4 -->|\"NAMESERVER_OF\"| n0\n",
+		"  class n0 kind_domain\n",
+		"  class n3,n4 kind_nameserver\n",
+		"  class n4 unfetched\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if got := mermaidText(`a "b" | c`); got != `"a #quot;b#quot; #124; c"` {
+		t.Errorf("mermaidText = %s", got)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var d rdap.Domain
+	if err := json.Unmarshal([]byte(`{"objectClassName":"domain","ldhName":"example.com","status":["active","client transfer prohibited"],
+		"events":[{"eventAction":"registration","eventDate":"1995-08-14T04:00:00Z"},{"eventAction":"expiration","eventDate":"2030-08-13T00:00:00-04:00"}],
+		"nameservers":[{"objectClassName":"nameserver","ldhName":"a.iana-servers.net"},{"objectClassName":"nameserver","ldhName":"b.iana-servers.net"}],
+		"secureDNS":{"delegationSigned":true},
+		"entities":[{"objectClassName":"entity","handle":"376","roles":["registrar"],"publicIds":[{"type":"IANA Registrar ID","identifier":"376"}],
+			"vcardArray":["vcard",[["version",{},"text","4.0"],["fn",{},"text","RESERVED-IANA"]]]}]}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	class, row, err := Flatten(&d)
+	if err != nil || class != graph.KindDomain {
+		t.Fatalf("Flatten = %q, %v", class, err)
+	}
+	got := map[string]string{}
+	for i, c := range Columns(class) {
+		got[c] = row[i]
+	}
+	for c, want := range map[string]string{
+		"ldhName":          "example.com",
+		"status":           "active, client transfer prohibited",
+		"registrar":        "RESERVED-IANA",
+		"registrarIanaId":  "376",
+		"registration":     "1995-08-14T04:00:00Z",
+		"expiration":       "2030-08-13T04:00:00Z",
+		"lastChanged":      "",
+		"nameservers":      "a.iana-servers.net, b.iana-servers.net",
+		"delegationSigned": "true",
+	} {
+		if got[c] != want {
+			t.Errorf("%s = %q, want %q", c, got[c], want)
+		}
+	}
+
+	// One table per class, each with its header once; lead columns first.
+	bufs := map[string]*bytes.Buffer{}
+	w := NewCSV(func(class string) (io.Writer, bool, error) {
+		bufs[class] = &bytes.Buffer{}
+		return bufs[class], true, nil
+	}, "query")
+	for q, obj := range map[string]any{
+		"example.com":     &d,
+		"example.org":     &rdap.Domain{LDHName: "example.org"},
+		"ns1.example.net": map[string]any{"objectClassName": "nameserver", "ldhName": "ns1.example.net", "ipAddresses": map[string]any{"v4": []any{"192.0.2.1"}}},
+	} {
+		if err := w.Write(obj, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(bufs[graph.KindDomain].String()), "\n"); len(lines) != 3 ||
+		!strings.HasPrefix(lines[0], "query,ldhName,unicodeName,handle,status,registrar,") {
+		t.Errorf("domain table:\n%s", bufs[graph.KindDomain])
+	}
+	if got := bufs[graph.KindNameserver].String(); got != "query,ldhName,unicodeName,handle,ipv4,ipv6,status,lastChanged\nns1.example.net,ns1.example.net,,,192.0.2.1,,,\n" {
+		t.Errorf("nameserver table:\n%s", got)
+	}
+	if err := w.Write(&d); err == nil {
+		t.Error("Write without the lead value succeeded")
+	}
+	if _, _, err := Flatten("example.com"); err == nil {
+		t.Error("Flatten of a string succeeded")
+	}
+}
+
+func TestCSV_EscapesFormulas(t *testing.T) {
+	var b bytes.Buffer
+	w := NewCSV(func(string) (io.Writer, bool, error) { return &b, false, nil }, "query")
+	e := &rdap.Entity{CommonObject: rdap.CommonObject{Handle: "=HYPERLINK(\"http://x.example\")", Status: []string{"+1", "active"}}}
+	e.Roles = []string{"@registrant"}
+	if err := w.Write(e, "-2+3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(&rdap.Entity{CommonObject: rdap.CommonObject{Handle: "\tTAB", Status: []string{"\rCR"}}}, "x=1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "'-2+3,\"'=HYPERLINK(\"\"http://x.example\"\")\",'@registrant,,,,,,,,\"'+1, active\",,\n" +
+		"x=1,'\tTAB,,,,,,,,,\"'\rCR\",,\n"
+	if got := b.String(); got != want {
+		t.Errorf("CSV =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestTables(t *testing.T) {
+	d := &rdap.Domain{LDHName: "example.com", CommonObject: rdap.CommonObject{
+		Status: []string{"active", "client hold"},
+		Events: []rdap.Event{{EventAction: "registration", EventDate: "1995-08-14T04:00:00Z"}},
+	}}
+	var b bytes.Buffer
+	if err := Record(&b, d); err != nil {
+		t.Fatal(err)
+	}
+	// Empty fields left out, values aligned, dates as days.
+	for _, want := range []string{
+		"ldh name:           example.com\n",
+		"status:             active, client hold\n",
+		"registration:       1995-08-14\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "expiration") {
+		t.Errorf("empty field shown:\n%s", b.String())
+	}
+
+	b.Reset()
+	l := NewLines(&b, "query")
+	if err := l.Write(d, "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.WriteError(errors.New("not found"), "example.org"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "query ") || !strings.HasSuffix(lines[0], " status") {
+		t.Fatalf("lines:\n%s", b.String())
+	}
+	// Columns line up with the header.
+	if i := strings.Index(lines[0], "kind"); strings.Index(lines[1], "domain") != i || strings.Index(lines[2], "error") != i {
+		t.Errorf("kind column not aligned:\n%s", b.String())
+	}
+	if i := strings.Index(lines[0], "registered"); !strings.HasPrefix(lines[1][i:], "1995-08-14") {
+		t.Errorf("registered column not aligned:\n%s", b.String())
+	}
+	if !strings.HasSuffix(lines[2], "error      not found") {
+		t.Errorf("error line = %q", lines[2])
+	}
+}
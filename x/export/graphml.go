@@ -0,0 +1,115 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/datum-labs/rdap/graph"
+)
+
+// graphMLKeys declares the attributes GraphML writes, by id.
+var graphMLKeys = []graphMLKey{
+	{ID: "label", For: "node", Name: "label", Type: "string"},
+	{ID: "kind", For: "node", Name: "kind", Type: "string"},
+	{ID: "handle", For: "node", Name: "handle", Type: "string"},
+	{ID: "country", For: "node", Name: "country", Type: "string"},
+	{ID: "status", For: "node", Name: "status", Type: "string"},
+	{ID: "fetched", For: "node", Name: "fetched", Type: "boolean", Default: "true"},
+	{ID: "rel", For: "edge", Name: "rel", Type: "string"},
+	{ID: "linkRel", For: "edge", Name: "linkRel", Type: "string"},
+}
+
+type graphMLDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	NS      string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID      string `xml:"id,attr"`
+	For     string `xml:"for,attr"`
+	Name    string `xml:"attr.name,attr"`
+	Type    string `xml:"attr.type,attr"`
+	Default string `xml:"default,omitempty"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// GraphML writes g as a directed GraphML graph for Gephi, yEd and the like.
+// Nodes carry kind, handle (also the label), ISO country code (networks,
+// autnums, and entities with an address) and status (comma-separated);
+// edges carry the relation and, for links, the link's own rel. Nodes that
+// edges name but the walk never fetched are included with fetched=false.
+func GraphML(w io.Writer, g *graph.Graph) error {
+	doc := graphMLDoc{
+		NS:    "http://graphml.graphdrawing.org/xmlns",
+		Keys:  graphMLKeys,
+		Graph: graphMLGraph{ID: "rdap", EdgeDefault: "directed"},
+	}
+	data := func(ds []graphMLData, key, value string) []graphMLData {
+		if value == "" {
+			return ds
+		}
+		return append(ds, graphMLData{key, value})
+	}
+	for _, n := range nodes(g) {
+		var ds []graphMLData
+		ds = data(ds, "label", n.handle())
+		ds = data(ds, "kind", n.Kind)
+		ds = data(ds, "handle", n.handle())
+		ds = data(ds, "country", n.country())
+		ds = data(ds, "status", strings.Join(n.statuses(), ", "))
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: n.ID, Data: ds})
+	}
+	for _, id := range missing(g) {
+		kind, key, _ := strings.Cut(id, ":")
+		var ds []graphMLData
+		ds = data(ds, "label", key)
+		ds = data(ds, "kind", kind)
+		ds = data(ds, "handle", key)
+		ds = data(ds, "fetched", strconv.FormatBool(false))
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: id, Data: ds})
+	}
+	for i, e := range g.Edges {
+		var ds []graphMLData
+		ds = data(ds, "rel", string(e.Rel))
+		ds = data(ds, "linkRel", e.LinkRel)
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{ID: fmt.Sprintf("e%d", i), Source: e.From, Target: e.To, Data: ds})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,113 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/datum-labs/rdap/graph"
+)
+
+// kindOrder is the order Mermaid lays out kind groups in; other kinds follow
+// alphabetically.
+var kindOrder = []string{graph.KindDomain, graph.KindNameserver, graph.KindEntity, graph.KindIPNetwork, graph.KindAutnum, "link"}
+
+// Mermaid writes g as a Mermaid "graph TD" flowchart: nodes grouped into one
+// subgraph per kind and colored by it, edges labeled with their relation.
+// Nodes that edges name but the walk never fetched are drawn dashed. The
+// output has no Markdown fence; wrap it in ```mermaid to embed it in a
+// README or GitHub issue.
+func Mermaid(w io.Writer, g *graph.Graph) error {
+	type item struct{ id, label string }
+	groups := map[string][]item{}
+	ids := map[string]string{} // graph node ID -> Mermaid ID
+	var unfetched []string
+	add := func(kind, id, label string) {
+		ids[id] = fmt.Sprintf("n%d", len(ids))
+		groups[kind] = append(groups[kind], item{ids[id], label})
+	}
+	for _, n := range nodes(g) {
+		add(n.Kind, n.ID, n.handle())
+	}
+	for _, id := range missing(g) {
+		kind, key, _ := strings.Cut(id, ":")
+		add(kind, id, key)
+		unfetched = append(unfetched, ids[id])
+	}
+
+	kinds := append([]string(nil), kindOrder...)
+	var extra []string
+	for k := range groups {
+		if !contains(kindOrder, k) {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	kinds = append(kinds, extra...)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "graph TD")
+	for _, k := range kinds {
+		items := groups[k]
+		if len(items) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "  subgraph %s[%s]\n", mermaidClass(k), mermaidText(k))
+		for _, it := range items {
+			fmt.Fprintf(bw, "    %s[%s]\n", it.id, mermaidText(it.label))
+		}
+		fmt.Fprintln(bw, "  end")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  %s -->|%s| %s\n", ids[e.From], mermaidText(string(e.Rel)), ids[e.To])
+	}
+	for _, k := range kinds {
+		items := groups[k]
+		if len(items) == 0 {
+			continue
+		}
+		color := kindColors[k]
+		if color == "" {
+			color = "#999999"
+		}
+		var members []string
+		for _, it := range items {
+			members = append(members, it.id)
+		}
+		fmt.Fprintf(bw, "  classDef %s fill:%s,color:#fff,stroke:%s\n", mermaidClass(k), color, color)
+		fmt.Fprintf(bw, "  class %s %s\n", strings.Join(members, ","), mermaidClass(k))
+	}
+	if len(unfetched) > 0 {
+		fmt.Fprintln(bw, "  classDef unfetched fill:#fff,color:#333,stroke:#999,stroke-dasharray:4 3")
+		fmt.Fprintf(bw, "  class %s unfetched\n", strings.Join(unfetched, ","))
+	}
+	return bw.Flush()
+}
+
+// mermaidClass turns a node kind into a Mermaid identifier ("ip-network" ->
+// "kind_ip_network").
+func mermaidClass(kind string) string {
+	return "kind_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, kind)
+}
+
+// mermaidText quotes s as Mermaid label text, using entity codes for the
+// characters that would end it.
+func mermaidText(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "|", "#124;", "\n", " ").Replace(s) + `"`
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
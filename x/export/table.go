@@ -0,0 +1,149 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Tables for people read the same flattened rows as CSV: Record lays one
+// object out as aligned label/value lines, Lines prints one line per object
+// in fixed-width columns, for batch results. Dates are shown as days.
+
+// dateColumns are the columns that hold RFC 3339 timestamps.
+var dateColumns = map[string]bool{"registration": true, "expiration": true, "lastChanged": true}
+
+// Record writes obj as a two-column table, one field per line with the values
+// aligned, leaving out empty fields.
+func Record(w io.Writer, obj any) error {
+	class, row, err := Flatten(obj)
+	if err != nil {
+		return err
+	}
+	cols := Columns(class)
+	width := 0
+	for _, c := range cols {
+		width = max(width, len(label(c))+1)
+	}
+	var b strings.Builder
+	for i, c := range cols {
+		v := row[i]
+		if v == "" {
+			continue
+		}
+		if dateColumns[c] {
+			v = day(v)
+		}
+		fmt.Fprintf(&b, "%-*s  %s\n", width, label(c)+":", v)
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// label turns a column name into words: "registrarIanaId" is "registrar iana id".
+func label(col string) string {
+	var b strings.Builder
+	for i, r := range col {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// day is the date of an RFC 3339 timestamp.
+func day(ts string) string {
+	if len(ts) >= 10 {
+		return ts[:10]
+	}
+	return ts
+}
+
+// The columns of Lines and their widths (the last runs to the end of the
+// line). A wider value pushes the rest of its line right rather than being
+// cut.
+var (
+	lineColumns = []string{"kind", "name", "holder", "registered", "expires", "status"}
+	lineWidths  = []int{10, 32, 28, 10, 10, 0}
+	leadWidth   = 32
+)
+
+// Lines writes objects one per line for batch results: kind, name (LDH name,
+// else handle), holder (registrar, else organization or contact name),
+// registration and expiration days, and statuses. Lines stream as objects
+// are written, in fixed-width columns, so they align without buffering.
+type Lines struct {
+	w      io.Writer
+	lead   []string
+	header bool
+}
+
+// NewLines returns a Lines writing to w, with leading columns named by lead
+// (a batch's query, say) whose values Write takes first.
+func NewLines(w io.Writer, lead ...string) *Lines {
+	return &Lines{w: w, lead: lead}
+}
+
+// Write prints obj's line, after the lead values.
+func (l *Lines) Write(obj any, lead ...string) error {
+	class, row, err := Flatten(obj)
+	if err != nil {
+		return err
+	}
+	f := map[string]string{}
+	for i, c := range Columns(class) {
+		f[c] = row[i]
+	}
+	first := func(cols ...string) string {
+		for _, c := range cols {
+			if f[c] != "" {
+				return f[c]
+			}
+		}
+		return ""
+	}
+	return l.line(lead, []string{
+		class,
+		first("ldhName", "handle", "unicodeName"),
+		first("registrar", "organization", "org", "fullName"),
+		day(f["registration"]),
+		day(f["expiration"]),
+		f["status"],
+	})
+}
+
+// WriteError prints a line for a query that failed: kind "error" and the
+// message in place of the object's fields.
+func (l *Lines) WriteError(err error, lead ...string) error {
+	return l.line(lead, []string{"error", err.Error()})
+}
+
+func (l *Lines) line(lead, cells []string) error {
+	if len(lead) != len(l.lead) {
+		return fmt.Errorf("rdap export: %d lead values for %d lead columns", len(lead), len(l.lead))
+	}
+	var b strings.Builder
+	if !l.header {
+		l.header = true
+		l.format(&b, l.lead, lineColumns)
+	}
+	l.format(&b, lead, cells)
+	_, err := io.WriteString(l.w, b.String())
+	return err
+}
+
+func (l *Lines) format(b *strings.Builder, lead, cells []string) {
+	var parts []string
+	for _, v := range lead {
+		parts = append(parts, fmt.Sprintf("%-*s", leadWidth, v))
+	}
+	for i, v := range cells {
+		if i < len(cells)-1 {
+			v = fmt.Sprintf("%-*s", lineWidths[i], v)
+		}
+		parts = append(parts, v)
+	}
+	b.WriteString(strings.TrimRight(strings.Join(parts, " "), " ") + "\n")
+}